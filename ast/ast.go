@@ -51,6 +51,7 @@ type (
 type (
 	Program struct {
 		Stmts []Stmt
+		File  string // source path, set for programs loaded from disk
 	}
 )
 
@@ -99,6 +100,10 @@ type MatchPattern struct {
 	IsOk    bool
 	IsSome  bool
 	Binding *Identifier
+
+	// enum arms name a variant and bind each of its payload values
+	Variant  *Identifier
+	Bindings []*Identifier
 }
 
 type TypeMatchArm struct {
@@ -118,6 +123,16 @@ type (
 		annotatable
 	}
 
+	// TupleDeclarationStmt declares one variable for each element of a
+	// tuple, as in const (q, r) = divmod(a, b);
+	TupleDeclarationStmt struct {
+		Token    token.Token // token.Mut or token.Const
+		Names    []*Identifier
+		Value    Expr
+		Constant bool
+		annotatable
+	}
+
 	ReturnStmt struct {
 		Token       token.Token
 		ReturnValue Expr
@@ -132,6 +147,7 @@ type (
 
 	BlockStmt struct {
 		Token token.Token
+		End   token.Token // the closing }
 		Stmts []Stmt
 		Scope Scope
 		annotatable
@@ -173,9 +189,18 @@ type (
 	}
 
 	StructDefinitionStmt struct {
-		Token token.Token
-		Name  *Identifier
-		Type  types.StructType
+		Token      token.Token
+		Name       *Identifier
+		Type       types.StructType
+		FieldNames []*Identifier // where each of Type.Fields is declared
+		annotatable
+	}
+
+	EnumDefinitionStmt struct {
+		Token        token.Token
+		Name         *Identifier
+		Type         types.EnumType
+		VariantNames []*Identifier // where each of Type.Variants is declared
 		annotatable
 	}
 
@@ -187,9 +212,10 @@ type (
 	}
 
 	InterfaceDefinitionStmt struct {
-		Token token.Token
-		Name  *Identifier
-		Type  types.InterfaceType
+		Token       token.Token
+		Name        *Identifier
+		Type        types.InterfaceType
+		MethodNames []*Identifier // where each of Type.Methods is declared
 		annotatable
 	}
 
@@ -225,6 +251,7 @@ type (
 		Token    token.Token
 		Key      *Identifier
 		Value    *Identifier
+		Names    []*Identifier // instead of Value when each element is a destructured tuple
 		Body     *BlockStmt
 		Iterable Expr
 		annotatable
@@ -236,6 +263,18 @@ type (
 		annotatable
 	}
 
+	// DeferStmt runs CallExpr when the enclosing function returns. The
+	// typechecker binds the call's arguments to hidden locals in Bindings and
+	// wraps the call on them in Closure, which captures their values when
+	// the defer statement runs
+	DeferStmt struct {
+		Token    token.Token
+		CallExpr Expr
+		Bindings []*VarDeclarationStmt
+		Closure  *FunctionLiteral
+		annotatable
+	}
+
 	SendStmt struct {
 		Token token.Token
 		Chan  Expr
@@ -285,6 +324,13 @@ type (
 		noCast
 	}
 
+	TupleLiteral struct {
+		Token    token.Token // the (
+		Elements []Expr
+		resolvable
+		noCast
+	}
+
 	NullLiteral struct {
 		Token token.Token
 		resolvable
@@ -314,12 +360,13 @@ type (
 	}
 
 	StructLiteral struct {
-		Token    token.Token
-		Name     string
-		Fields   []string
-		Values   []Expr
-		Module   string
-		TypeArgs []types.Type
+		Token      token.Token
+		Name       string
+		Fields     []string
+		FieldNames []*Identifier // where each of Fields is written, absent for generated literals
+		Values     []Expr
+		Module     string
+		TypeArgs   []types.Type
 		resolvable
 		castable
 	}
@@ -408,6 +455,8 @@ type (
 		ErrArm      *MatchArm
 		SomeArm     *MatchArm
 		NoneArm     *MatchArm
+		Arms        []*MatchArm // enum variant arms
+		Default     *BlockStmt
 		SubjectType types.Type
 		noCast
 		resolvable
@@ -445,6 +494,16 @@ type (
 		noCast
 		resolvable
 	}
+
+	// TryExpr is value?, which unwraps an ok or some and returns an err or
+	// none from the enclosing function
+	TryExpr struct {
+		Token      token.Token // token.Question
+		Value      Expr
+		ReturnType types.Type // the enclosing function's return type
+		resolvable
+		castable
+	}
 )
 
 // Node interfaces
@@ -460,6 +519,10 @@ func (v *VarDeclarationStmt) TokenLiteral() string {
 	return v.Token.Literal
 }
 
+func (t *TupleDeclarationStmt) TokenLiteral() string {
+	return t.Token.Literal
+}
+
 func (r *ReturnStmt) TokenLiteral() string {
 	return r.Token.Literal
 }
@@ -516,6 +579,10 @@ func (a *ArrayLiteral) TokenLiteral() string {
 	return a.Token.Literal
 }
 
+func (t *TupleLiteral) TokenLiteral() string {
+	return t.Token.Literal
+}
+
 func (i *IndexExpr) TokenLiteral() string {
 	return i.Token.Literal
 }
@@ -556,6 +623,10 @@ func (s *StructDefinitionStmt) TokenLiteral() string {
 	return s.Token.Literal
 }
 
+func (e *EnumDefinitionStmt) TokenLiteral() string {
+	return e.Token.Literal
+}
+
 func (s *SelectorExpr) TokenLiteral() string {
 	return s.Token.Literal
 }
@@ -612,6 +683,10 @@ func (s *SpawnStmt) TokenLiteral() string {
 	return s.Token.Literal
 }
 
+func (d *DeferStmt) TokenLiteral() string {
+	return d.Token.Literal
+}
+
 func (s *SendStmt) TokenLiteral() string {
 	return s.Token.Literal
 }
@@ -628,6 +703,10 @@ func (m *MatchTypeExpr) TokenLiteral() string {
 	return m.Token.Literal
 }
 
+func (t *TryExpr) TokenLiteral() string {
+	return t.Token.Literal
+}
+
 // Statements
 func (p *Program) String() string {
 	var out bytes.Buffer
@@ -655,6 +734,27 @@ func (v *VarDeclarationStmt) String() string {
 	return out.String()
 }
 
+func (t *TupleDeclarationStmt) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(t.TokenLiteral() + " ")
+	out.WriteString(identifierList(t.Names))
+	out.WriteString(" = ")
+	out.WriteString(t.Value.String())
+	out.WriteString(";")
+
+	return out.String()
+}
+
+// identifierList writes names as a destructuring pattern, as in (q, r)
+func identifierList(names []*Identifier) string {
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = name.String()
+	}
+	return "(" + strings.Join(parts, ", ") + ")"
+}
+
 func (r *ReturnStmt) String() string {
 	var out bytes.Buffer
 
@@ -745,6 +845,28 @@ func (s *StructDefinitionStmt) String() string {
 	return out.String()
 }
 
+func (e *EnumDefinitionStmt) String() string {
+	var out bytes.Buffer
+	out.WriteString("define enum ")
+	out.WriteString(e.Name.String())
+	out.WriteString(" { ")
+	for i, v := range e.Type.Variants {
+		out.WriteString(v)
+		if len(e.Type.Payloads[i]) > 0 {
+			payloads := make([]string, 0, len(e.Type.Payloads[i]))
+			for _, t := range e.Type.Payloads[i] {
+				payloads = append(payloads, t.Signature())
+			}
+			out.WriteString("(" + strings.Join(payloads, ", ") + ")")
+		}
+		if i < len(e.Type.Variants)-1 {
+			out.WriteString(", ")
+		}
+	}
+	out.WriteString(" }")
+	return out.String()
+}
+
 func (s *SelectorAssignmentStmt) String() string {
 	var out bytes.Buffer
 	out.WriteString(s.Left.String())
@@ -892,6 +1014,14 @@ func (a *ArrayLiteral) String() string {
 	return out.String()
 }
 
+func (t *TupleLiteral) String() string {
+	elements := make([]string, len(t.Elements))
+	for i, el := range t.Elements {
+		elements[i] = el.String()
+	}
+	return "(" + strings.Join(elements, ", ") + ")"
+}
+
 func (n *NullLiteral) String() string {
 	return "null"
 }
@@ -1013,6 +1143,25 @@ func (m *MatchExpr) String() string {
 		out.WriteString(m.NoneArm.Body.String())
 		out.WriteString(",\n")
 	}
+	for _, arm := range m.Arms {
+		out.WriteString("\t")
+		out.WriteString(arm.Pattern.Variant.String())
+		if len(arm.Pattern.Bindings) > 0 {
+			bindings := make([]string, 0, len(arm.Pattern.Bindings))
+			for _, b := range arm.Pattern.Bindings {
+				bindings = append(bindings, b.String())
+			}
+			out.WriteString("(" + strings.Join(bindings, ", ") + ")")
+		}
+		out.WriteString(" -> ")
+		out.WriteString(arm.Body.String())
+		out.WriteString(",\n")
+	}
+	if m.Default != nil {
+		out.WriteString("\t_ -> ")
+		out.WriteString(m.Default.String())
+		out.WriteString(",\n")
+	}
 	out.WriteString("}")
 
 	return out.String()
@@ -1074,7 +1223,11 @@ func (f *ForInStmt) String() string {
 		out.WriteString(f.Key.String())
 		out.WriteString(", ")
 	}
-	out.WriteString(f.Value.String())
+	if f.Names != nil {
+		out.WriteString(identifierList(f.Names))
+	} else {
+		out.WriteString(f.Value.String())
+	}
 	out.WriteString(" in ")
 	out.WriteString(f.Iterable.String())
 	out.WriteString(" {\n")
@@ -1092,6 +1245,14 @@ func (s *SpawnStmt) String() string {
 	return out.String()
 }
 
+func (d *DeferStmt) String() string {
+	var out bytes.Buffer
+	out.WriteString("defer ")
+	out.WriteString(d.CallExpr.String())
+
+	return out.String()
+}
+
 func (s *SendStmt) String() string {
 	return s.Chan.String() + " <- " + s.Value.String()
 }
@@ -1100,6 +1261,10 @@ func (r *ReceiveExpr) String() string {
 	return "<- " + r.Chan.String()
 }
 
+func (t *TryExpr) String() string {
+	return t.Value.String() + "?"
+}
+
 func (c *ChannelConstructorExpr) String() string {
 	var out bytes.Buffer
 	out.WriteString("channel<")
@@ -1121,6 +1286,10 @@ func (v *VarDeclarationStmt) Pos() (int, int) {
 	return v.Token.Line, v.Token.Column
 }
 
+func (t *TupleDeclarationStmt) Pos() (int, int) {
+	return t.Token.Line, t.Token.Column
+}
+
 func (r *ReturnStmt) Pos() (int, int) {
 	return r.Token.Line, r.Token.Column
 }
@@ -1153,6 +1322,10 @@ func (s *StructDefinitionStmt) Pos() (int, int) {
 	return s.Token.Line, s.Token.Column
 }
 
+func (e *EnumDefinitionStmt) Pos() (int, int) {
+	return e.Token.Line, e.Token.Column
+}
+
 func (s *SelectorAssignmentStmt) Pos() (int, int) {
 	return s.Token.Line, s.Token.Column
 }
@@ -1189,6 +1362,10 @@ func (s *SpawnStmt) Pos() (int, int) {
 	return s.Token.Line, s.Token.Column
 }
 
+func (d *DeferStmt) Pos() (int, int) {
+	return d.Token.Line, d.Token.Column
+}
+
 func (s *SendStmt) Pos() (int, int) {
 	return s.Token.Line, s.Token.Column
 }
@@ -1233,6 +1410,10 @@ func (a *ArrayLiteral) Pos() (int, int) {
 	return a.Token.Line, a.Token.Column
 }
 
+func (t *TupleLiteral) Pos() (int, int) {
+	return t.Token.Line, t.Token.Column
+}
+
 func (i *IndexExpr) Pos() (int, int) {
 	return i.Token.Line, i.Token.Column
 }
@@ -1289,8 +1470,13 @@ func (m *MatchTypeExpr) Pos() (int, int) {
 	return m.Token.Line, m.Token.Column
 }
 
+func (t *TryExpr) Pos() (int, int) {
+	return t.Token.Line, t.Token.Column
+}
+
 // Statements
 func (v *VarDeclarationStmt) statementNode()      {}
+func (t *TupleDeclarationStmt) statementNode()    {}
 func (r *ReturnStmt) statementNode()              {}
 func (e *ExpressionStmt) statementNode()          {}
 func (b *BlockStmt) statementNode()               {}
@@ -1299,6 +1485,7 @@ func (f *ForStmt) statementNode()                 {}
 func (i *IndexAssignmentStmt) statementNode()     {}
 func (f *FunctionDeclarationStmt) statementNode() {}
 func (s *StructDefinitionStmt) statementNode()    {}
+func (e *EnumDefinitionStmt) statementNode()      {}
 func (s *SelectorAssignmentStmt) statementNode()  {}
 func (i *InterfaceDefinitionStmt) statementNode() {}
 func (m *ModuleDeclarationStmt) statementNode()   {}
@@ -1308,6 +1495,7 @@ func (c *ContinueStmt) statementNode()            {}
 func (b *BreakStmt) statementNode()               {}
 func (f *ForInStmt) statementNode()               {}
 func (s *SpawnStmt) statementNode()               {}
+func (d *DeferStmt) statementNode()               {}
 func (s *SendStmt) statementNode()                {}
 
 // Expressions
@@ -1321,6 +1509,7 @@ func (f *FunctionLiteral) expressionNode()        {}
 func (c *CallExpr) expressionNode()               {}
 func (s *StringLiteral) expressionNode()          {}
 func (a *ArrayLiteral) expressionNode()           {}
+func (t *TupleLiteral) expressionNode()           {}
 func (i *IndexExpr) expressionNode()              {}
 func (n *NullLiteral) expressionNode()            {}
 func (h *HashLiteral) expressionNode()            {}
@@ -1335,6 +1524,7 @@ func (s *SliceExpr) expressionNode()              {}
 func (r *ReceiveExpr) expressionNode()            {}
 func (c *ChannelConstructorExpr) expressionNode() {}
 func (m *MatchTypeExpr) expressionNode()          {}
+func (t *TryExpr) expressionNode()                {}
 
 func Dump(node Node, indent int) {
 	prefix := func(label string) {
@@ -1377,6 +1567,13 @@ func Dump(node Node, indent int) {
 			field("Type:", node.Type.Signature())
 		}
 		child("Value:", node.Value)
+	case *TupleDeclarationStmt:
+		prefix("TupleDeclarationStmt")
+		for _, name := range node.Names {
+			field("Name:", name.Value)
+		}
+		field("Constant:", fmt.Sprintf("%v", node.Constant))
+		child("Value:", node.Value)
 	case *VarAssignmentStmt:
 		prefix("VarAssignmentStmt")
 		field("Name:", node.Identifier.Value)
@@ -1413,6 +1610,16 @@ func Dump(node Node, indent int) {
 		for i, f := range node.Type.Fields {
 			field("Field:", f+" "+node.Type.Types[i].Signature())
 		}
+	case *EnumDefinitionStmt:
+		prefix("EnumDefinitionStmt")
+		field("Name:", node.Name.Value)
+		for i, v := range node.Type.Variants {
+			payloads := make([]string, 0, len(node.Type.Payloads[i]))
+			for _, t := range node.Type.Payloads[i] {
+				payloads = append(payloads, t.Signature())
+			}
+			field("Variant:", v+"("+strings.Join(payloads, ", ")+")")
+		}
 	case *SelectorAssignmentStmt:
 		prefix("SelectorAssignmentStmt")
 		child("Left:", node.Left)
@@ -1455,6 +1662,11 @@ func Dump(node Node, indent int) {
 		for i, el := range node.Elements {
 			child(fmt.Sprintf("[%d]:", i), el)
 		}
+	case *TupleLiteral:
+		prefix("TupleLiteral")
+		for i, el := range node.Elements {
+			child(fmt.Sprintf("[%d]:", i), el)
+		}
 	case *HashLiteral:
 		prefix("HashLiteral")
 		for k, v := range node.Pairs {
@@ -1544,6 +1756,16 @@ func Dump(node Node, indent int) {
 			}
 			Dump(node.ErrArm.Body, indent+4)
 		}
+		for _, arm := range node.Arms {
+			fmt.Println(withIdent("Arm: "+arm.Pattern.Variant.Value, indent+2))
+			for _, b := range arm.Pattern.Bindings {
+				fmt.Println(withIdent("Binding: "+b.Value, indent+4))
+			}
+			Dump(arm.Body, indent+4)
+		}
+		if node.Default != nil {
+			child("Default:", node.Default)
+		}
 	case *ByteLiteral:
 		prefix(fmt.Sprintf("ByteLiteral(%d)", node.Value))
 	case *SliceExpr:
@@ -1555,6 +1777,9 @@ func Dump(node Node, indent int) {
 		if node.End != nil {
 			child("End:", node.End)
 		}
+	case *DeferStmt:
+		prefix("DeferStmt")
+		child("Call: ", node.CallExpr)
 	case *SendStmt:
 		prefix("SendStmt")
 		child("Value: ", node.Value)
@@ -1562,6 +1787,12 @@ func Dump(node Node, indent int) {
 	case *ReceiveExpr:
 		prefix("ReceiveExpr")
 		child("Chan: ", node.Chan)
+	case *TryExpr:
+		prefix("TryExpr")
+		if node.ReturnType != nil {
+			field("ReturnType:", node.ReturnType.Signature())
+		}
+		child("Value:", node.Value)
 	case *MatchTypeExpr:
 		prefix("MatchTypeExpr")
 		for _, arm := range node.Arms {
@@ -1596,12 +1827,16 @@ func substituteInStmt(stmt Stmt, subs map[string]types.Type) {
 		if s.Value != nil {
 			substituteInExpr(s.Value, subs)
 		}
+	case *TupleDeclarationStmt:
+		substituteInExpr(s.Value, subs)
 	case *BlockStmt:
 		SubstituteTypeParams(s, subs)
 	case *ExpressionStmt:
 		substituteInExpr(s.Expr, subs)
 	case *ReturnStmt:
 		substituteInExpr(s.ReturnValue, subs)
+	case *DeferStmt:
+		substituteInExpr(s.CallExpr, subs)
 	case *ForStmt:
 		if s.Init != nil {
 			substituteInStmt(s.Init, subs)
@@ -1626,8 +1861,17 @@ func substituteInExpr(expr Expr, subs map[string]types.Type) {
 			SubstituteTypeParams(e.Alternative, subs)
 		}
 	case *MatchExpr:
-		SubstituteTypeParams(e.OkArm.Body, subs)
-		SubstituteTypeParams(e.ErrArm.Body, subs)
+		for _, arm := range []*MatchArm{e.OkArm, e.ErrArm, e.SomeArm, e.NoneArm} {
+			if arm != nil {
+				SubstituteTypeParams(arm.Body, subs)
+			}
+		}
+		for _, arm := range e.Arms {
+			SubstituteTypeParams(arm.Body, subs)
+		}
+		if e.Default != nil {
+			SubstituteTypeParams(e.Default, subs)
+		}
 	case *MatchTypeExpr:
 		for _, arm := range e.Arms {
 			SubstituteTypeParams(arm.Body, subs)
@@ -1646,5 +1890,11 @@ func substituteInExpr(expr Expr, subs map[string]types.Type) {
 		for _, arg := range e.Arguments {
 			substituteInExpr(arg, subs)
 		}
+	case *TupleLiteral:
+		for _, el := range e.Elements {
+			substituteInExpr(el, subs)
+		}
+	case *TryExpr:
+		substituteInExpr(e.Value, subs)
 	}
 }
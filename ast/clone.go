@@ -7,6 +7,7 @@ func Clone(n Node) *Program {
 
 	switch node := n.(type) {
 	case *Program:
+		nn.File = node.File
 		for _, stmt := range node.Stmts {
 			nn.Stmts = append(nn.Stmts, cloneStmt(stmt))
 		}
@@ -34,6 +35,14 @@ func cloneStmt(stmt Stmt) Stmt {
 		expr := cloneExpr(stmt.Value)
 		cloned.Value = expr
 		return &cloned
+	case *TupleDeclarationStmt:
+		cloned := *stmt
+		cloned.Names = make([]*Identifier, len(stmt.Names))
+		for i, name := range stmt.Names {
+			cloned.Names[i] = cloneIdentifier(name)
+		}
+		cloned.Value = cloneExpr(stmt.Value)
+		return &cloned
 	case *VarAssignmentStmt:
 		cloned := *stmt
 		cloned.Value = cloneExpr(stmt.Value)
@@ -42,6 +51,9 @@ func cloneStmt(stmt Stmt) Stmt {
 	case *StructDefinitionStmt:
 		cloned := *stmt
 		return &cloned
+	case *EnumDefinitionStmt:
+		cloned := *stmt
+		return &cloned
 	case *FunctionDeclarationStmt:
 		cloned := *stmt
 		cloned.Name = cloneIdentifier(stmt.Name)
@@ -72,6 +84,12 @@ func cloneStmt(stmt Stmt) Stmt {
 		cloned.Left = cloneSelectorExpr(stmt.Left)
 		cloned.Value = cloneExpr(stmt.Value)
 		return &cloned
+	case *DeferStmt:
+		cloned := *stmt
+		cloned.CallExpr = cloneExpr(stmt.CallExpr)
+		cloned.Bindings = nil
+		cloned.Closure = nil
+		return &cloned
 	case *BreakStmt:
 		cloned := *stmt
 		return &cloned
@@ -169,6 +187,10 @@ func cloneExpr(e Expr) Expr {
 		cloned.Left = cloneExpr(expr.Left)
 		cloned.Index = cloneExpr(expr.Index)
 		return &cloned
+	case *TryExpr:
+		cloned := *expr
+		cloned.Value = cloneExpr(expr.Value)
+		return &cloned
 	case *SelectorExpr:
 		return cloneSelectorExpr(expr)
 	case *FunctionLiteral:
@@ -186,6 +208,13 @@ func cloneExpr(e Expr) Expr {
 			cloned.Elements[i] = cloneExpr(e)
 		}
 		return &cloned
+	case *TupleLiteral:
+		cloned := *expr
+		cloned.Elements = make([]Expr, len(expr.Elements))
+		for i, e := range expr.Elements {
+			cloned.Elements[i] = cloneExpr(e)
+		}
+		return &cloned
 	case *HashLiteral:
 		cloned := *expr
 		cloned.Pairs = make(map[Expr]Expr)
@@ -259,6 +288,26 @@ func cloneExpr(e Expr) Expr {
 			cloned.NoneArm = &cnone
 		}
 
+		if expr.Arms != nil {
+			cloned.Arms = make([]*MatchArm, len(expr.Arms))
+			for i, arm := range expr.Arms {
+				carm := *arm
+				carmp := *arm.Pattern
+				carmp.Variant = cloneIdentifier(arm.Pattern.Variant)
+				carmp.Bindings = make([]*Identifier, len(arm.Pattern.Bindings))
+				for j, b := range arm.Pattern.Bindings {
+					carmp.Bindings[j] = cloneIdentifier(b)
+				}
+				carm.Pattern = &carmp
+				carm.Body = cloneBlockStmt(arm.Body)
+				cloned.Arms[i] = &carm
+			}
+		}
+
+		if expr.Default != nil {
+			cloned.Default = cloneBlockStmt(expr.Default)
+		}
+
 		return &cloned
 	}
 	return nil
@@ -1,6 +1,10 @@
 package ast
 
-import "log"
+import (
+	"log"
+
+	"sydney/token"
+)
 
 func FindAt(node Node, line, col int) (*Identifier, Scope) {
 	switch node := node.(type) {
@@ -35,6 +39,13 @@ func FindAt(node Node, line, col int) (*Identifier, Scope) {
 		if found, scope := FindAt(node.Value, line, col); found != nil {
 			return found, scope
 		}
+	case *TupleDeclarationStmt:
+		for _, name := range node.Names {
+			if matches(name, line, col) {
+				return name, nil
+			}
+		}
+		return FindAt(node.Value, line, col)
 	case *VarAssignmentStmt:
 		if matches(node.Identifier, line, col) {
 			return node.Identifier, nil
@@ -72,7 +83,70 @@ func FindAt(node Node, line, col int) (*Identifier, Scope) {
 		}
 		return FindAt(node.Body, line, col)
 	case *FunctionLiteral:
+		for _, param := range node.Parameters {
+			if found, _ := FindAt(param, line, col); found != nil {
+				return found, node.Body.Scope
+			}
+		}
 		return FindAt(node.Body, line, col)
+	case *StructLiteral:
+		// the struct name has no identifier of its own, so stand one in at the name token
+		name := &Identifier{Token: node.Token, Value: node.Name}
+		if matches(name, line, col) {
+			return name, nil
+		}
+		for _, field := range node.FieldNames {
+			if matches(field, line, col) {
+				return field, nil
+			}
+		}
+		for _, value := range node.Values {
+			if found, scope := FindAt(value, line, col); found != nil {
+				return found, scope
+			}
+		}
+	case *PrefixExpr:
+		return FindAt(node.Right, line, col)
+	case *TryExpr:
+		return FindAt(node.Value, line, col)
+	case *IndexExpr:
+		if found, scope := FindAt(node.Left, line, col); found != nil {
+			return found, scope
+		}
+		return FindAt(node.Index, line, col)
+	case *ArrayLiteral:
+		for _, elem := range node.Elements {
+			if found, scope := FindAt(elem, line, col); found != nil {
+				return found, scope
+			}
+		}
+	case *TupleLiteral:
+		for _, elem := range node.Elements {
+			if found, scope := FindAt(elem, line, col); found != nil {
+				return found, scope
+			}
+		}
+	case *ReceiveExpr:
+		return FindAt(node.Chan, line, col)
+	case *SpawnStmt:
+		return FindAt(node.CallExpr, line, col)
+	case *DeferStmt:
+		return FindAt(node.CallExpr, line, col)
+	case *SendStmt:
+		if found, scope := FindAt(node.Chan, line, col); found != nil {
+			return found, scope
+		}
+		return FindAt(node.Value, line, col)
+	case *SelectorAssignmentStmt:
+		if found, scope := FindAt(node.Left, line, col); found != nil {
+			return found, scope
+		}
+		return FindAt(node.Value, line, col)
+	case *IndexAssignmentStmt:
+		if found, scope := FindAt(node.Left, line, col); found != nil {
+			return found, scope
+		}
+		return FindAt(node.Value, line, col)
 	case *ReturnStmt:
 		return FindAt(node.ReturnValue, line, col)
 	case *ForStmt:
@@ -93,6 +167,17 @@ func FindAt(node Node, line, col int) (*Identifier, Scope) {
 			}
 		}
 	case *ForInStmt:
+		if node.Key != nil && matches(node.Key, line, col) {
+			return node.Key, node.Body.Scope
+		}
+		if node.Value != nil && matches(node.Value, line, col) {
+			return node.Value, node.Body.Scope
+		}
+		for _, name := range node.Names {
+			if matches(name, line, col) {
+				return name, node.Body.Scope
+			}
+		}
 		if found, _ := FindAt(node.Iterable, line, col); found != nil {
 			return found, node.Body.Scope
 		}
@@ -109,9 +194,41 @@ func FindAt(node Node, line, col int) (*Identifier, Scope) {
 			return FindAt(node.Alternative, line, col)
 		}
 	case *InterfaceDefinitionStmt:
+		for _, method := range node.MethodNames {
+			if matches(method, line, col) {
+				return method, nil
+			}
+		}
 		return FindAt(node.Name, line, col)
 	case *StructDefinitionStmt:
+		for _, field := range node.FieldNames {
+			if matches(field, line, col) {
+				return field, nil
+			}
+		}
+		return FindAt(node.Name, line, col)
+	case *EnumDefinitionStmt:
+		for _, variant := range node.VariantNames {
+			if matches(variant, line, col) {
+				return variant, nil
+			}
+		}
 		return FindAt(node.Name, line, col)
+	case *MatchTypeExpr:
+		if found, scope := FindAt(node.Subject, line, col); found != nil {
+			return found, scope
+		}
+		for _, arm := range node.Arms {
+			if found, _ := FindAt(arm.Binding, line, col); found != nil {
+				return found, arm.Body.Scope
+			}
+			if found, scope := FindAt(arm.Body, line, col); found != nil {
+				return found, scope
+			}
+		}
+		if node.Default != nil {
+			return FindAt(node.Default, line, col)
+		}
 	case *MatchExpr:
 		if found, scope := FindAt(node.Subject, line, col); found != nil {
 			return found, scope
@@ -149,6 +266,22 @@ func FindAt(node Node, line, col int) (*Identifier, Scope) {
 				return found, scope
 			}
 		}
+		for _, arm := range node.Arms {
+			if matches(arm.Pattern.Variant, line, col) {
+				return arm.Pattern.Variant, nil
+			}
+			for _, binding := range arm.Pattern.Bindings {
+				if found, _ := FindAt(binding, line, col); found != nil {
+					return found, arm.Body.Scope
+				}
+			}
+			if found, scope := FindAt(arm.Body, line, col); found != nil {
+				return found, scope
+			}
+		}
+		if node.Default != nil {
+			return FindAt(node.Default, line, col)
+		}
 	}
 
 	return nil, nil
@@ -183,6 +316,8 @@ func FindSelectorAt(node Node, line, col int) *SelectorExpr {
 		if node.Value != nil {
 			return FindSelectorAt(node.Value, line, col)
 		}
+	case *TupleDeclarationStmt:
+		return FindSelectorAt(node.Value, line, col)
 	case *VarAssignmentStmt:
 		return FindSelectorAt(node.Value, line, col)
 	case *ReturnStmt:
@@ -244,6 +379,14 @@ func FindSelectorAt(node Node, line, col int) *SelectorExpr {
 				return found
 			}
 		}
+		for _, arm := range node.Arms {
+			if found := FindSelectorAt(arm.Body, line, col); found != nil {
+				return found
+			}
+		}
+		if node.Default != nil {
+			return FindSelectorAt(node.Default, line, col)
+		}
 	}
 	return nil
 }
@@ -253,3 +396,94 @@ func matches(ident *Identifier, line, col int) bool {
 	log.Printf("hover: ast_line=%d ast_col=%d, ide_line=%d id_col=%d", l, c, line, col)
 	return l == line && col >= c && col < c+len(ident.Value)
 }
+
+// ScopeAt returns the innermost checked block scope enclosing line:col, or
+// nil at the top level
+func ScopeAt(node Node, line, col int) Scope {
+	switch node := node.(type) {
+	case *Program:
+		for _, stmt := range node.Stmts {
+			if scope := ScopeAt(stmt, line, col); scope != nil {
+				return scope
+			}
+		}
+	case *PubStatement:
+		return ScopeAt(node.Stmt, line, col)
+	case *BlockStmt:
+		if node == nil || !encloses(node, line, col) {
+			return nil
+		}
+		for _, stmt := range node.Stmts {
+			if scope := ScopeAt(stmt, line, col); scope != nil {
+				return scope
+			}
+		}
+		return node.Scope
+	case *ExpressionStmt:
+		return ScopeAt(node.Expr, line, col)
+	case *VarDeclarationStmt:
+		return ScopeAt(node.Value, line, col)
+	case *TupleDeclarationStmt:
+		return ScopeAt(node.Value, line, col)
+	case *VarAssignmentStmt:
+		return ScopeAt(node.Value, line, col)
+	case *ReturnStmt:
+		return ScopeAt(node.ReturnValue, line, col)
+	case *FunctionDeclarationStmt:
+		return ScopeAt(node.Body, line, col)
+	case *FunctionLiteral:
+		return ScopeAt(node.Body, line, col)
+	case *CallExpr:
+		for _, arg := range node.Arguments {
+			if scope := ScopeAt(arg, line, col); scope != nil {
+				return scope
+			}
+		}
+	case *IfExpr:
+		if scope := ScopeAt(node.Consequence, line, col); scope != nil {
+			return scope
+		}
+		if node.Alternative != nil {
+			return ScopeAt(node.Alternative, line, col)
+		}
+	case *ForStmt:
+		return ScopeAt(node.Body, line, col)
+	case *ForInStmt:
+		return ScopeAt(node.Body, line, col)
+	case *MatchExpr:
+		for _, arm := range append([]*MatchArm{node.OkArm, node.ErrArm, node.SomeArm, node.NoneArm}, node.Arms...) {
+			if arm != nil {
+				if scope := ScopeAt(arm.Body, line, col); scope != nil {
+					return scope
+				}
+			}
+		}
+		if node.Default != nil {
+			return ScopeAt(node.Default, line, col)
+		}
+	case *MatchTypeExpr:
+		for _, arm := range node.Arms {
+			if scope := ScopeAt(arm.Body, line, col); scope != nil {
+				return scope
+			}
+		}
+		if node.Default != nil {
+			return ScopeAt(node.Default, line, col)
+		}
+	}
+
+	return nil
+}
+
+func encloses(block *BlockStmt, line, col int) bool {
+	startLine, startCol := block.Token.Line, block.Token.Column
+	endLine, endCol := block.End.Line, block.End.Column
+	if line < startLine || (line == startLine && col <= startCol) {
+		return false
+	}
+	// a block cut short by the end of the file runs to the end of it
+	if block.End.Type != token.RightCurlyBracket {
+		return true
+	}
+	return line < endLine || (line == endLine && col <= endCol)
+}
@@ -0,0 +1,161 @@
+package ast
+
+// Inspect walks the tree rooted at node depth first, calling f on each node
+// it reaches. When f returns false the node's children are skipped
+func Inspect(node Node, f func(Node) bool) {
+	if node == nil || !f(node) {
+		return
+	}
+
+	switch node := node.(type) {
+	case *Program:
+		for _, stmt := range node.Stmts {
+			Inspect(stmt, f)
+		}
+	case *BlockStmt:
+		for _, stmt := range node.Stmts {
+			Inspect(stmt, f)
+		}
+	case *PubStatement:
+		Inspect(node.Stmt, f)
+	case *ExpressionStmt:
+		Inspect(node.Expr, f)
+	case *VarDeclarationStmt:
+		Inspect(node.Name, f)
+		Inspect(node.Value, f)
+	case *TupleDeclarationStmt:
+		for _, name := range node.Names {
+			Inspect(name, f)
+		}
+		Inspect(node.Value, f)
+	case *VarAssignmentStmt:
+		Inspect(node.Identifier, f)
+		Inspect(node.Value, f)
+	case *IndexAssignmentStmt:
+		Inspect(node.Left, f)
+		Inspect(node.Value, f)
+	case *SelectorAssignmentStmt:
+		Inspect(node.Left, f)
+		Inspect(node.Value, f)
+	case *ReturnStmt:
+		Inspect(node.ReturnValue, f)
+	case *ForStmt:
+		Inspect(node.Init, f)
+		Inspect(node.Condition, f)
+		Inspect(node.Post, f)
+		inspectBlock(node.Body, f)
+	case *ForInStmt:
+		if node.Key != nil {
+			Inspect(node.Key, f)
+		}
+		if node.Value != nil {
+			Inspect(node.Value, f)
+		}
+		for _, name := range node.Names {
+			Inspect(name, f)
+		}
+		Inspect(node.Iterable, f)
+		inspectBlock(node.Body, f)
+	case *FunctionDeclarationStmt:
+		Inspect(node.Name, f)
+		for _, param := range node.Params {
+			Inspect(param, f)
+		}
+		inspectBlock(node.Body, f)
+	case *SpawnStmt:
+		Inspect(node.CallExpr, f)
+	case *DeferStmt:
+		Inspect(node.CallExpr, f)
+		if node.Closure != nil {
+			Inspect(node.Closure, f)
+		}
+	case *SendStmt:
+		Inspect(node.Chan, f)
+		Inspect(node.Value, f)
+	case *FunctionLiteral:
+		for _, param := range node.Parameters {
+			Inspect(param, f)
+		}
+		inspectBlock(node.Body, f)
+	case *MacroLiteral:
+		for _, param := range node.Parameters {
+			Inspect(param, f)
+		}
+		inspectBlock(node.Body, f)
+	case *ArrayLiteral:
+		for _, el := range node.Elements {
+			Inspect(el, f)
+		}
+	case *TupleLiteral:
+		for _, el := range node.Elements {
+			Inspect(el, f)
+		}
+	case *HashLiteral:
+		for key, value := range node.Pairs {
+			Inspect(key, f)
+			Inspect(value, f)
+		}
+	case *StructLiteral:
+		for _, value := range node.Values {
+			Inspect(value, f)
+		}
+	case *PrefixExpr:
+		Inspect(node.Right, f)
+	case *InfixExpr:
+		Inspect(node.Left, f)
+		Inspect(node.Right, f)
+	case *IfExpr:
+		Inspect(node.Condition, f)
+		inspectBlock(node.Consequence, f)
+		inspectBlock(node.Alternative, f)
+	case *CallExpr:
+		Inspect(node.Function, f)
+		for _, arg := range node.Arguments {
+			Inspect(arg, f)
+		}
+	case *IndexExpr:
+		Inspect(node.Left, f)
+		Inspect(node.Index, f)
+	case *SelectorExpr:
+		Inspect(node.Left, f)
+		Inspect(node.Value, f)
+	case *ScopeAccessExpr:
+		Inspect(node.Module, f)
+		Inspect(node.Member, f)
+	case *MatchExpr:
+		Inspect(node.Subject, f)
+		for _, arm := range []*MatchArm{node.OkArm, node.ErrArm, node.SomeArm, node.NoneArm} {
+			if arm != nil {
+				inspectBlock(arm.Body, f)
+			}
+		}
+		for _, arm := range node.Arms {
+			inspectBlock(arm.Body, f)
+		}
+		inspectBlock(node.Default, f)
+	case *MatchTypeExpr:
+		Inspect(node.Subject, f)
+		for _, arm := range node.Arms {
+			inspectBlock(arm.Body, f)
+		}
+		inspectBlock(node.Default, f)
+	case *SliceExpr:
+		Inspect(node.Left, f)
+		Inspect(node.Start, f)
+		Inspect(node.End, f)
+	case *ReceiveExpr:
+		Inspect(node.Chan, f)
+	case *ChannelConstructorExpr:
+		Inspect(node.Capacity, f)
+	case *TryExpr:
+		Inspect(node.Value, f)
+	}
+}
+
+// inspectBlock skips the blocks a node leaves out, like a missing else, which
+// would otherwise reach Inspect as a non-nil Node holding a nil pointer
+func inspectBlock(block *BlockStmt, f func(Node) bool) {
+	if block != nil {
+		Inspect(block, f)
+	}
+}
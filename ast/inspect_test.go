@@ -0,0 +1,60 @@
+package ast
+
+import (
+	"testing"
+)
+
+func TestInspect(t *testing.T) {
+	one := func() Expr { return &IntegerLiteral{Value: 1} }
+
+	tests := []struct {
+		name     string
+		input    Node
+		expected int
+	}{
+		{
+			"nested expressions",
+			&ReturnStmt{ReturnValue: &InfixExpr{
+				Left:  &CallExpr{Function: &Identifier{Value: "f"}, Arguments: []Expr{one(), one()}},
+				Right: &ArrayLiteral{Elements: []Expr{one()}},
+			}},
+			3,
+		},
+		{
+			"missing else",
+			&IfExpr{Condition: one(), Consequence: &BlockStmt{Stmts: []Stmt{&ExpressionStmt{Expr: one()}}}},
+			2,
+		},
+		{
+			"match arms",
+			&MatchExpr{
+				Subject: one(),
+				SomeArm: &MatchArm{Body: &BlockStmt{Stmts: []Stmt{&ExpressionStmt{Expr: one()}}}},
+				Arms:    []*MatchArm{{Body: &BlockStmt{Stmts: []Stmt{&ExpressionStmt{Expr: one()}}}}},
+			},
+			3,
+		},
+		{
+			"function literals are skipped",
+			&ExpressionStmt{Expr: &CallExpr{
+				Function:  &FunctionLiteral{Body: &BlockStmt{Stmts: []Stmt{&ExpressionStmt{Expr: one()}}}},
+				Arguments: []Expr{one()},
+			}},
+			1,
+		},
+	}
+
+	for _, tt := range tests {
+		count := 0
+		Inspect(tt.input, func(node Node) bool {
+			if _, ok := node.(*IntegerLiteral); ok {
+				count++
+			}
+			_, isFunc := node.(*FunctionLiteral)
+			return !isFunc
+		})
+		if count != tt.expected {
+			t.Errorf("%s: expected %d integer literals, got %d", tt.name, tt.expected, count)
+		}
+	}
+}
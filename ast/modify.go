@@ -15,6 +15,8 @@ func Modify(node Node, modifier ModifierFunc) Node {
 		node.Right, _ = Modify(node.Right, modifier).(Expr)
 	case *PrefixExpr:
 		node.Right, _ = Modify(node.Right, modifier).(Expr)
+	case *TryExpr:
+		node.Value, _ = Modify(node.Value, modifier).(Expr)
 	case *IndexExpr:
 		node.Left, _ = Modify(node.Left, modifier).(Expr)
 		node.Index, _ = Modify(node.Index, modifier).(Expr)
@@ -30,8 +32,12 @@ func Modify(node Node, modifier ModifierFunc) Node {
 		}
 	case *ReturnStmt:
 		node.ReturnValue, _ = Modify(node.ReturnValue, modifier).(Expr)
+	case *DeferStmt:
+		node.CallExpr, _ = Modify(node.CallExpr, modifier).(Expr)
 	case *VarDeclarationStmt:
 		node.Value, _ = Modify(node.Value, modifier).(Expr)
+	case *TupleDeclarationStmt:
+		node.Value, _ = Modify(node.Value, modifier).(Expr)
 	case *VarAssignmentStmt:
 		node.Value, _ = Modify(node.Value, modifier).(Expr)
 	case *ForStmt:
@@ -46,6 +52,10 @@ func Modify(node Node, modifier ModifierFunc) Node {
 		for i, _ := range node.Elements {
 			node.Elements[i], _ = Modify(node.Elements[i], modifier).(Expr)
 		}
+	case *TupleLiteral:
+		for i, _ := range node.Elements {
+			node.Elements[i], _ = Modify(node.Elements[i], modifier).(Expr)
+		}
 	case *HashLiteral:
 		newPairs := make(map[Expr]Expr)
 		for key, val := range node.Pairs {
@@ -37,6 +37,10 @@ func assertStmt(stmt Stmt) {
 		if s.ReturnValue != nil {
 			assertExpr(s.ReturnValue)
 		}
+	case *DeferStmt:
+		assertExpr(s.CallExpr)
+	case *TupleDeclarationStmt:
+		assertExpr(s.Value)
 	case *VarAssignmentStmt:
 		assertExpr(s.Value)
 	case *ForStmt:
@@ -94,6 +98,8 @@ func assertExpr(expr Expr) {
 		assertExpr(e.Right)
 	case *PrefixExpr:
 		assertExpr(e.Right)
+	case *TryExpr:
+		assertExpr(e.Value)
 	case *IfExpr:
 		assertExpr(e.Condition)
 		assertBlock(e.Consequence)
@@ -111,6 +117,10 @@ func assertExpr(expr Expr) {
 		for _, el := range e.Elements {
 			assertExpr(el)
 		}
+	case *TupleLiteral:
+		for _, el := range e.Elements {
+			assertExpr(el)
+		}
 	case *HashLiteral:
 		for k, v := range e.Pairs {
 			assertExpr(k)
@@ -130,6 +140,12 @@ func assertExpr(expr Expr) {
 		if e.NoneArm != nil {
 			assertBlock(e.NoneArm.Body)
 		}
+		for _, arm := range e.Arms {
+			assertBlock(arm.Body)
+		}
+		if e.Default != nil {
+			assertBlock(e.Default)
+		}
 	case *MatchTypeExpr:
 		assertExpr(e.Subject)
 		for _, arm := range e.Arms {
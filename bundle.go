@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"sydney/compiler"
+	"sydney/object"
+	"sydney/vm"
+)
+
+// A bundled executable is a copy of the sydney binary with a serialized
+// bytecode payload appended, followed by a fixed-size trailer:
+//
+//	[sydney binary][payload][payload length u64][bundleMagic]
+//
+// On startup the binary checks its own tail for the trailer and, if present,
+// runs the payload on the VM instead of parsing the command line.
+const bundleMagic = "SYDNEYBUNDLE"
+
+const bundleTrailerSize = 8 + len(bundleMagic)
+
+func Bundle(args []string, flags map[Flag]bool) int {
+	if len(args) == 0 {
+		fmt.Println("Usage: sydney bundle [filename]")
+		return 1
+	}
+
+	// the executable is written next to the source without its extension, so
+	// anything else would be overwritten
+	filename := args[0]
+	if !strings.HasSuffix(filename, ".sy") {
+		fmt.Printf("cannot bundle %s: expected a .sy file\n", filename)
+		return 1
+	}
+
+	file, err := os.ReadFile(filename)
+	if err != nil {
+		fmt.Printf("cannot read file %s\n", filename)
+		return 1
+	}
+
+	bytecode, ok := compileBytecode(filename, string(file), flags)
+	if !ok {
+		return 1
+	}
+
+	var payload bytes.Buffer
+	err = bytecode.Serialize(&payload)
+	if err != nil {
+		fmt.Printf("cannot serialize bytecode: %s\n", err)
+		return 1
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		fmt.Printf("cannot locate sydney executable: %s\n", err)
+		return 1
+	}
+
+	self, err := os.Open(exe)
+	if err != nil {
+		fmt.Printf("cannot open sydney executable: %s\n", err)
+		return 1
+	}
+	defer self.Close()
+
+	// when bundling from an already bundled binary only copy the runtime part
+	runtimeSize, _, err := findBundlePayload(self)
+	if err != nil {
+		fmt.Printf("cannot read sydney executable: %s\n", err)
+		return 1
+	}
+
+	out := strings.TrimSuffix(filename, ".sy")
+	err = writeBundle(out, io.NewSectionReader(self, 0, runtimeSize), payload.Bytes())
+	if err != nil {
+		fmt.Printf("cannot write %s: %s\n", out, err)
+		return 1
+	}
+
+	return 0
+}
+
+// writeBundle writes the runtime followed by the payload and its trailer to
+// a temporary file next to out, then renames it into place, so a failed write
+// never leaves a truncated executable behind
+func writeBundle(out string, runtime io.Reader, payload []byte) (err error) {
+	dst, err := os.CreateTemp(filepath.Dir(out), "."+filepath.Base(out)+".*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			dst.Close()
+			os.Remove(dst.Name())
+		}
+	}()
+
+	// CreateTemp makes the file readable and writable by its owner only
+	err = dst.Chmod(0755)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(dst, runtime)
+	if err != nil {
+		return err
+	}
+
+	trailer := make([]byte, 8, bundleTrailerSize)
+	binary.BigEndian.PutUint64(trailer, uint64(len(payload)))
+	trailer = append(trailer, bundleMagic...)
+
+	_, err = dst.Write(append(payload, trailer...))
+	if err != nil {
+		return err
+	}
+
+	err = dst.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(dst.Name(), out)
+}
+
+// findBundlePayload returns the size of f without any bundled payload, and the
+// payload itself if one is present
+func findBundlePayload(f *os.File) (int64, []byte, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return 0, nil, err
+	}
+	size := info.Size()
+	if size < int64(bundleTrailerSize) {
+		return size, nil, nil
+	}
+
+	trailer := make([]byte, bundleTrailerSize)
+	_, err = f.ReadAt(trailer, size-int64(bundleTrailerSize))
+	if err != nil {
+		return 0, nil, err
+	}
+	if string(trailer[8:]) != bundleMagic {
+		return size, nil, nil
+	}
+
+	payloadLen := int64(binary.BigEndian.Uint64(trailer[:8]))
+	start := size - int64(bundleTrailerSize) - payloadLen
+	if payloadLen <= 0 || start < 0 {
+		return 0, nil, fmt.Errorf("corrupt bundle trailer")
+	}
+
+	payload := make([]byte, payloadLen)
+	_, err = f.ReadAt(payload, start)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return start, payload, nil
+}
+
+// embeddedBytecode loads the bytecode bundled into the running executable.
+// ok is false for a plain sydney binary.
+func embeddedBytecode() (*compiler.Bytecode, bool) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, false
+	}
+
+	f, err := os.Open(exe)
+	if err != nil {
+		return nil, false
+	}
+	defer f.Close()
+
+	_, payload, err := findBundlePayload(f)
+	if err != nil || payload == nil {
+		return nil, false
+	}
+
+	bytecode, err := compiler.Deserialize(bytes.NewReader(payload))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "cannot load bundled program: %s\n", err)
+		os.Exit(1)
+	}
+
+	return bytecode, true
+}
+
+// runEmbedded executes a bundled program. The whole command line belongs to
+// the program, so `args` sees os.Args unchanged.
+func runEmbedded(bytecode *compiler.Bytecode) int {
+	object.SetArgsOffset(0)
+
+	globals := make([]object.Object, vm.GlobalsSize)
+	machine := vm.NewWithGlobalStore(bytecode, globals)
+	err := machine.Run()
+	if err != nil {
+		printRuntimeError(os.Stdout, err, nil)
+		return 1
+	}
+
+	return 0
+}
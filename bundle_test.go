@@ -0,0 +1,113 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestBundle(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go toolchain not found, skipping bundle test")
+	}
+
+	dir := t.TempDir()
+	sydney := filepath.Join(dir, "sydney")
+	cmd := exec.Command("go", "build", "-o", sydney, ".")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("go build failed: %s\n%s", err, out)
+	}
+
+	source := filepath.Join(dir, "hello.sy")
+	err := os.WriteFile(source, []byte(`print("hello");
+print(args());
+`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmd = exec.Command(sydney, "bundle", source)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("sydney bundle failed: %s\n%s", err, out)
+	}
+
+	bundled := filepath.Join(dir, "hello")
+	out, err := exec.Command(bundled, "a", "b").CombinedOutput()
+	if err != nil {
+		t.Fatalf("running the bundle failed: %s\n%s", err, out)
+	}
+	expected := "hello\n[" + bundled + ", a, b]\n"
+	if string(out) != expected {
+		t.Errorf("expected output %q, got %q", expected, out)
+	}
+}
+
+func TestBundleRejectsOtherFiles(t *testing.T) {
+	source := filepath.Join(t.TempDir(), "hello")
+	contents := []byte("print(\"hello\");\n")
+	err := os.WriteFile(source, contents, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if code := Bundle([]string{source}, map[Flag]bool{}); code != 1 {
+		t.Errorf("expected exit code 1, got %d", code)
+	}
+
+	after, err := os.ReadFile(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(after) != string(contents) {
+		t.Errorf("expected %s to be left alone, got %q", source, after)
+	}
+}
+
+func TestWriteBundle(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "hello")
+	err := os.WriteFile(out, []byte("old"), 0o755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a failed write leaves the previous executable alone
+	failure := errors.New("read failed")
+	err = writeBundle(out, iotest.ErrReader(failure), []byte("payload"))
+	if !errors.Is(err, failure) {
+		t.Fatalf("expected %v, got %v", failure, err)
+	}
+	if contents, _ := os.ReadFile(out); string(contents) != "old" {
+		t.Errorf("expected %s to be left alone, got %q", out, contents)
+	}
+
+	err = writeBundle(out, strings.NewReader("runtime"), []byte("payload"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	size, payload, err := findBundlePayload(f)
+	if err != nil || size != int64(len("runtime")) || string(payload) != "payload" {
+		t.Errorf("expected the runtime and payload back, got %d, %q, %v", size, payload, err)
+	}
+	if info, _ := f.Stat(); info.Mode().Perm() != 0o755 {
+		t.Errorf("expected mode 0755, got %v", info.Mode().Perm())
+	}
+
+	// no temporary files are left behind either way
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("expected only %s in %s, got %v", out, dir, entries)
+	}
+}
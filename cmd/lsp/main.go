@@ -61,8 +61,35 @@ func main() {
 		case messages.DocumentChange:
 			lsp.HandleDocumentChange(&req)
 		case messages.DocumentClose:
+			lsp.HandleDocumentClose(&req)
+		case messages.DocumentSave:
+			lsp.HandleDocumentSave(&req)
 		case messages.Hover:
 			lsp.HandleHover(&req)
+		case messages.Definition:
+			lsp.HandleDefinition(&req)
+		case messages.References:
+			lsp.HandleReferences(&req)
+		case messages.PrepareRename:
+			lsp.HandlePrepareRename(&req)
+		case messages.Rename:
+			lsp.HandleRename(&req)
+		case messages.Completion:
+			lsp.HandleCompletion(&req)
+		case messages.SignatureHelp:
+			lsp.HandleSignatureHelp(&req)
+		case messages.InlayHint:
+			lsp.HandleInlayHint(&req)
+		case messages.DocumentSymbol:
+			lsp.HandleDocumentSymbol(&req)
+		case messages.WorkspaceSymbol:
+			lsp.HandleWorkspaceSymbol(&req)
+		case messages.SemanticTokensFull:
+			lsp.HandleSemanticTokens(&req)
+		case messages.SemanticTokensFullDelta:
+			lsp.HandleSemanticTokensDelta(&req)
+		case messages.CodeAction:
+			lsp.HandleCodeAction(&req)
 		case messages.Shutdown:
 			resp := &messages.Response{
 				Id:      req.Id,
@@ -62,6 +62,13 @@ const (
 	OpReceive
 	OpMatchType
 	OpUnboxInterface
+	OpEnum
+	OpEnumTag
+	OpEnumField
+	OpTuple
+	OpTupleField
+	OpUnpack
+	OpDefer
 )
 
 type (
@@ -123,6 +130,13 @@ var definitions = map[Opcode]*Definition{
 	OpReceive:            {"OpReceive", []int{}},
 	OpMatchType:          {"OpMatchType", []int{2}},
 	OpUnboxInterface:     {"OpUnboxInterface", []int{}},
+	OpEnum:               {"OpEnum", []int{2, 1, 1}}, // type idx, tag, num values
+	OpEnumTag:            {"OpEnumTag", []int{1}},    // tag to test for
+	OpEnumField:          {"OpEnumField", []int{1}},  // idx
+	OpTuple:              {"OpTuple", []int{1}},      // num values
+	OpTupleField:         {"OpTupleField", []int{1}}, // idx
+	OpUnpack:             {"OpUnpack", []int{1}},     // num values
+	OpDefer:              {"OpDefer", []int{}},
 }
 
 func Lookup(op byte) (*Definition, error) {
@@ -223,6 +237,8 @@ func (ins Instructions) fmtInstruction(def *Definition, operands []int) string {
 		return fmt.Sprintf("%s %d", def.Name, operands[0])
 	case 2:
 		return fmt.Sprintf("%s %d %d", def.Name, operands[0], operands[1])
+	case 3:
+		return fmt.Sprintf("%s %d %d %d", def.Name, operands[0], operands[1], operands[2])
 	}
 
 	return fmt.Sprintf("Error: unhandled operandCount for %s\n", def.Name)
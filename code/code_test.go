@@ -12,6 +12,9 @@ func TestMake(t *testing.T) {
 		{OpAdd, []int{}, []byte{byte(OpAdd)}},
 		{OpGetLocal, []int{255}, []byte{byte(OpGetLocal), 255}},
 		{OpClosure, []int{65534, 255}, []byte{byte(OpClosure), 255, 254, 255}},
+		{OpEnum, []int{65534, 2, 1}, []byte{byte(OpEnum), 255, 254, 2, 1}},
+		{OpUnpack, []int{3}, []byte{byte(OpUnpack), 3}},
+		{OpDefer, []int{}, []byte{byte(OpDefer)}},
 	}
 
 	for _, tt := range tests {
@@ -36,6 +39,8 @@ func TestInstructionString(t *testing.T) {
 		Make(OpConstant, 2),
 		Make(OpConstant, 65535),
 		Make(OpClosure, 65535, 255),
+		Make(OpEnum, 3, 1, 2),
+		Make(OpTuple, 2),
 	}
 
 	expected := `0000 OpAdd
@@ -43,6 +48,8 @@ func TestInstructionString(t *testing.T) {
 0003 OpConstant 2
 0006 OpConstant 65535
 0009 OpClosure 65535 255
+0013 OpEnum 3 1 2
+0018 OpTuple 2
 `
 
 	concatted := Instructions{}
@@ -87,3 +94,31 @@ func TestReadOperands(t *testing.T) {
 	}
 
 }
+
+func TestLocationForOffset(t *testing.T) {
+	sm := New()
+	sm.Mappings[0] = &SourceMapping{InstructionOffset: 0, Line: 1, Col: 1, File: "main.sy"}
+	sm.Mappings[5] = &SourceMapping{InstructionOffset: 5, Line: 3, Col: 4, File: "main.sy"}
+
+	tests := []struct {
+		offset int
+		line   int
+		col    int
+	}{
+		{0, 1, 1},
+		{4, 1, 1},
+		{5, 3, 4},
+		{9, 3, 4},
+	}
+
+	for _, tt := range tests {
+		line, col, file := sm.LocationForOffset(tt.offset)
+		if line != tt.line || col != tt.col || file != "main.sy" {
+			t.Errorf("offset %d: want=%d:%d, got=%d:%d (%q)", tt.offset, tt.line, tt.col, line, col, file)
+		}
+	}
+
+	if line, _, _ := New().LocationForOffset(3); line != 0 {
+		t.Errorf("expected no location in empty source map, got line %d", line)
+	}
+}
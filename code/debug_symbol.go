@@ -1,11 +1,17 @@
 package code
 
+import "sydney/types"
+
 type DebugSymbol struct {
 	Scope string
 	Name  string
 	Type  string
+	T     types.Type // nil when the compiler had no type for the symbol
 }
 
+// DebugSymbols names the slots of a compiled function: Locals by local slot
+// (globals for the main program) and Free by closure capture index
 type DebugSymbols struct {
 	Locals []*DebugSymbol
+	Free   []*DebugSymbol
 }
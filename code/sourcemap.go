@@ -24,3 +24,21 @@ func (sm *SourceMap) LineForOffset(offset int) (int, int, string) {
 
 	return 0, 0, ""
 }
+
+// LocationForOffset resolves an offset that may sit in the middle of an
+// instruction, or on an instruction emitted without a position, to the closest
+// mapping at or before it.
+func (sm *SourceMap) LocationForOffset(offset int) (int, int, string) {
+	best := -1
+	for off := range sm.Mappings {
+		if off <= offset && off > best {
+			best = off
+		}
+	}
+	if best == -1 {
+		return 0, 0, ""
+	}
+
+	mapping := sm.Mappings[best]
+	return mapping.Line, mapping.Col, mapping.File
+}
@@ -5,6 +5,7 @@ import (
 	"runtime/debug"
 	"slices"
 	"sort"
+	"strings"
 
 	"sydney/ast"
 	"sydney/code"
@@ -31,6 +32,7 @@ type Compiler struct {
 
 	currentModule string
 	fileName      string
+	stmtFiles     map[ast.Stmt]string // source file of each statement in a merged package
 
 	loopContexts []*LoopContext
 
@@ -86,6 +88,7 @@ func New() *Compiler {
 		interfaceTypes: make(map[string]types.InterfaceType),
 
 		itabMapping: make(map[ItabKey]int),
+		stmtFiles:   make(map[ast.Stmt]string),
 
 		loopContexts: make([]*LoopContext, 0),
 	}
@@ -154,6 +157,9 @@ func (c *Compiler) Compile(node ast.Node) error {
 		c.buildItabsFromTypes()
 
 		for _, s := range node.Stmts { // compile program
+			if file, ok := c.stmtFiles[s]; ok {
+				c.SetFileName(file)
+			}
 			err := c.Compile(s)
 			if err != nil {
 				return err
@@ -241,6 +247,12 @@ func (c *Compiler) Compile(node ast.Node) error {
 			c.emitAt(node, cde, symbol.Index)
 
 		}
+	case *ast.TupleDeclarationStmt:
+		err := c.Compile(node.Value)
+		if err != nil {
+			return err
+		}
+		return c.compileDestructure(node, node.Names, node.Constant)
 	case *ast.VarAssignmentStmt:
 		err := c.Compile(node.Value)
 		if err != nil {
@@ -462,6 +474,11 @@ func (c *Compiler) Compile(node ast.Node) error {
 			return err
 		}
 
+		if _, ok := node.ContainerType.(types.TupleType); ok {
+			c.emitAt(node, code.OpTupleField, int(node.Index.(*ast.IntegerLiteral).Value))
+			return nil
+		}
+
 		err = c.Compile(node.Index)
 		if err != nil {
 			return err
@@ -482,6 +499,9 @@ func (c *Compiler) Compile(node ast.Node) error {
 
 	case *ast.CallExpr:
 		if s, ok := node.Function.(*ast.SelectorExpr); ok {
+			if enum, ok := s.ContainerType.(types.EnumType); ok {
+				return c.compileEnumConstructor(node, enum, s, node.Arguments)
+			}
 			if _, ok := c.isInterfaceType(s.Left); ok {
 				return c.compileInterfaceMethodCall(node)
 			}
@@ -537,6 +557,14 @@ func (c *Compiler) Compile(node ast.Node) error {
 			}
 		}
 		c.emitAt(node, code.OpArray, len(node.Elements))
+	case *ast.TupleLiteral:
+		for _, el := range node.Elements {
+			err := c.Compile(el)
+			if err != nil {
+				return err
+			}
+		}
+		c.emitAt(node, code.OpTuple, len(node.Elements))
 	case *ast.HashLiteral:
 		keys := make([]ast.Expr, 0)
 		for k := range node.Pairs {
@@ -581,9 +609,12 @@ func (c *Compiler) Compile(node ast.Node) error {
 		c.enterScope()
 
 		c.symbolTable.DefineFunctionName(node.Name.Value)
-		for _, p := range node.Params {
+		ft, _ := node.Type.(types.FunctionType)
+		for i, p := range node.Params {
 			c.symbolTable.DefineMutable(p.Value)
-			c.symbolTable.AnnotateType(p.Value, node.Type)
+			if i < len(ft.Params) {
+				c.symbolTable.AnnotateType(p.Value, ft.Params[i])
+			}
 		}
 
 		err := c.Compile(node.Body)
@@ -601,6 +632,7 @@ func (c *Compiler) Compile(node ast.Node) error {
 
 		freeSymbols := c.symbolTable.FreeSymbols
 		numLocals := c.symbolTable.numDefinitions
+		dbgSyms := c.debugSymbols()
 		instructions, fnSourceMap := c.leaveScope()
 
 		// iterate over free symbols and load them onto stack
@@ -609,27 +641,12 @@ func (c *Compiler) Compile(node ast.Node) error {
 		}
 
 		compiledFn := &object.CompiledFunction{
+			Name:          c.displayName(name),
 			Instructions:  instructions,
 			NumLocals:     numLocals,
 			NumParameters: len(node.Params),
 			SourceMap:     fnSourceMap,
-		}
-
-		if c.shouldEmitDebug {
-			symbols := make([]*code.DebugSymbol, len(c.symbolTable.store))
-			for n, sym := range c.symbolTable.store {
-				if sym.Scope == BuiltinScope {
-					continue
-				}
-				dbg := &code.DebugSymbol{Name: n, Scope: string(sym.Scope)}
-				if sym.Type != nil {
-					dbg.Type = (*sym.Type).Signature()
-				}
-				symbols[sym.Index] = dbg
-			}
-			compiledFn.DebugSymbols = &code.DebugSymbols{
-				Locals: symbols,
-			}
+			DebugSymbols:  dbgSyms,
 		}
 
 		fnIdx := c.addConstant(compiledFn)
@@ -648,9 +665,13 @@ func (c *Compiler) Compile(node ast.Node) error {
 			c.symbolTable.DefineFunctionName(node.Name)
 		}
 
-		for _, p := range node.Parameters {
+		for i, p := range node.Parameters {
 			c.symbolTable.DefineImmutable(p.Value)
-			c.symbolTable.AnnotateType(p.Value, p.GetResolvedType())
+			pt := p.GetResolvedType()
+			if pt == nil && i < len(node.Type.Params) {
+				pt = node.Type.Params[i]
+			}
+			c.symbolTable.AnnotateType(p.Value, pt)
 		}
 
 		err := c.Compile(node.Body)
@@ -668,6 +689,7 @@ func (c *Compiler) Compile(node ast.Node) error {
 
 		freeSymbols := c.symbolTable.FreeSymbols
 		numLocals := c.symbolTable.numDefinitions
+		dbgSyms := c.debugSymbols()
 		// leave scope so we can load free symbols into enclosing scope
 		instructions, fnSourceMap := c.leaveScope()
 
@@ -676,28 +698,18 @@ func (c *Compiler) Compile(node ast.Node) error {
 			c.loadSymbol(s)
 		}
 
+		fnName := node.Name
+		if fnName == "" {
+			fnName = "<anonymous>"
+		}
+
 		compiledFn := &object.CompiledFunction{
+			Name:          c.displayName(fnName),
 			Instructions:  instructions,
 			NumLocals:     numLocals,
 			NumParameters: len(node.Parameters),
 			SourceMap:     fnSourceMap,
-		}
-
-		if c.shouldEmitDebug {
-			symbols := make([]*code.DebugSymbol, len(c.symbolTable.store))
-			for n, sym := range c.symbolTable.store {
-				if sym.Scope == BuiltinScope {
-					continue
-				}
-				dbg := &code.DebugSymbol{Name: n, Scope: string(sym.Scope)}
-				if sym.Type != nil {
-					dbg.Type = (*sym.Type).Signature()
-				}
-				symbols[sym.Index] = dbg
-			}
-			compiledFn.DebugSymbols = &code.DebugSymbols{
-				Locals: symbols,
-			}
+			DebugSymbols:  dbgSyms,
 		}
 
 		fnIdx := c.addConstant(compiledFn)
@@ -716,8 +728,11 @@ func (c *Compiler) Compile(node ast.Node) error {
 		typeObj := &object.TypeObject{T: t}
 		idx := c.addConstant(typeObj)
 
-		c.emit(code.OpStruct, idx, len(t.Fields))
+		c.emitAt(node, code.OpStruct, idx, len(t.Fields))
 	case *ast.SelectorExpr:
+		if enum, ok := node.ContainerType.(types.EnumType); ok {
+			return c.compileEnumConstructor(node, enum, node, nil)
+		}
 		t := node.ContainerType.(types.StructType)
 		err := c.Compile(node.Left)
 		if err != nil {
@@ -728,7 +743,7 @@ func (c *Compiler) Compile(node ast.Node) error {
 		// resolved type is appended in typechecker
 		idx := slices.Index(t.Fields, fieldIdent.Value)
 
-		c.emit(code.OpGetField, idx)
+		c.emitAt(node, code.OpGetField, idx)
 	case *ast.SelectorAssignmentStmt:
 		t := node.Left.ContainerType.(types.StructType)
 		err := c.Compile(node.Left.Left) // compile collection ident
@@ -743,7 +758,7 @@ func (c *Compiler) Compile(node ast.Node) error {
 		fieldIdent := node.Left.Value.(*ast.Identifier)
 		idx := slices.Index(t.Fields, fieldIdent.Value)
 
-		c.emit(code.OpSetField, idx)
+		c.emitAt(node, code.OpSetField, idx)
 	case *ast.ScopeAccessExpr:
 		mangled := c.mangleModule(node.Module.Value, node.Member.Value)
 		symbol, _, ok := c.symbolTable.Resolve(mangled)
@@ -756,7 +771,12 @@ func (c *Compiler) Compile(node ast.Node) error {
 		}
 		c.loadSymbol(symbol)
 	case *ast.MatchExpr:
-		if node.SomeArm != nil {
+		if enum, ok := node.SubjectType.(types.EnumType); ok {
+			err := c.compileEnumMatch(node, enum)
+			if err != nil {
+				return err
+			}
+		} else if node.SomeArm != nil {
 			err := c.compileOptionMatch(node)
 			if err != nil {
 				return err
@@ -813,7 +833,7 @@ func (c *Compiler) Compile(node ast.Node) error {
 		if err != nil {
 			return err
 		}
-		c.emit(code.OpSlice)
+		c.emitAt(node, code.OpSlice)
 	case *ast.SpawnStmt:
 		callExpr := node.CallExpr.(*ast.CallExpr)
 		err := c.Compile(callExpr.Function)
@@ -826,7 +846,19 @@ func (c *Compiler) Compile(node ast.Node) error {
 				return err
 			}
 		}
-		c.emit(code.OpSpawn, len(callExpr.Arguments))
+		c.emitAt(node, code.OpSpawn, len(callExpr.Arguments))
+	case *ast.DeferStmt:
+		for _, binding := range node.Bindings {
+			err := c.Compile(binding)
+			if err != nil {
+				return err
+			}
+		}
+		err := c.Compile(node.Closure)
+		if err != nil {
+			return err
+		}
+		c.emitAt(node, code.OpDefer)
 	case *ast.ChannelConstructorExpr:
 		if node.Capacity != nil {
 			err := c.Compile(node.Capacity)
@@ -836,7 +868,7 @@ func (c *Compiler) Compile(node ast.Node) error {
 		} else {
 			c.emit(code.OpConstant, c.addConstant(&object.Integer{Value: 0}))
 		}
-		c.emit(code.OpMakeChannel)
+		c.emitAt(node, code.OpMakeChannel)
 	case *ast.SendStmt:
 		err := c.Compile(node.Chan)
 		if err != nil {
@@ -846,18 +878,23 @@ func (c *Compiler) Compile(node ast.Node) error {
 		if err != nil {
 			return err
 		}
-		c.emit(code.OpSend)
+		c.emitAt(node, code.OpSend)
 	case *ast.ReceiveExpr:
 		err := c.Compile(node.Chan)
 		if err != nil {
 			return err
 		}
-		c.emit(code.OpReceive)
+		c.emitAt(node, code.OpReceive)
 	case *ast.MatchTypeExpr:
 		err := c.compileTypeMatch(node)
 		if err != nil {
 			return err
 		}
+	case *ast.TryExpr:
+		err := c.compileTryExpr(node)
+		if err != nil {
+			return err
+		}
 	}
 
 	if expr, ok := node.(ast.Expr); ok {
@@ -893,6 +930,11 @@ func (c *Compiler) CompilePackages(packages []*loader.Package) error {
 		merged := &ast.Program{}
 		for _, program := range pkg.Programs {
 			merged.Stmts = append(merged.Stmts, program.Stmts...)
+			if program.File != "" {
+				for _, stmt := range program.Stmts {
+					c.stmtFiles[stmt] = program.File
+				}
+			}
 		}
 		c.SetFileName(pkg.Name)
 		err := c.Compile(merged)
@@ -906,34 +948,44 @@ func (c *Compiler) CompilePackages(packages []*loader.Package) error {
 }
 
 func (c *Compiler) Bytecode() *Bytecode {
-	count := 0
-	for _, sym := range c.symbolTable.store {
-		if sym.Scope != BuiltinScope {
-			count++
-		}
-	}
-	var dbgs *code.DebugSymbols
-	if c.shouldEmitDebug {
-		symbols := make([]*code.DebugSymbol, count)
-		for n, sym := range c.symbolTable.store {
-			if sym.Scope == BuiltinScope {
-				continue
-			}
-			dbg := &code.DebugSymbol{Name: n, Scope: string(sym.Scope)}
-			if sym.Type != nil {
-				dbg.Type = (*sym.Type).Signature()
-			}
-			symbols[sym.Index] = dbg
-		}
-		dbgs = &code.DebugSymbols{Locals: symbols}
-	}
-
 	return &Bytecode{
 		Instructions: c.currentInstructions(),
 		Constants:    c.constants,
 		SourceMap:    c.scopes[c.scopeIndex].sourceMap,
-		DebugSymbols: dbgs,
+		DebugSymbols: c.debugSymbols(),
+	}
+}
+
+// debugSymbols describes the slots and captured variables of the function
+// scope being compiled, or the globals at the top level. nil unless debug
+// info was requested.
+func (c *Compiler) debugSymbols() *code.DebugSymbols {
+	if !c.shouldEmitDebug {
+		return nil
+	}
+
+	slots := c.symbolTable.Slots()
+	dbgs := &code.DebugSymbols{Locals: make([]*code.DebugSymbol, len(slots))}
+	for i, sym := range slots {
+		if sym.Name != "" {
+			dbgs.Locals[i] = debugSymbol(sym)
+		}
+	}
+
+	for _, sym := range c.symbolTable.owner().FreeSymbols {
+		dbgs.Free = append(dbgs.Free, debugSymbol(sym))
+	}
+
+	return dbgs
+}
+
+func debugSymbol(sym Symbol) *code.DebugSymbol {
+	dbg := &code.DebugSymbol{Name: sym.Name, Scope: string(sym.Scope)}
+	if sym.Type != nil && *sym.Type != nil {
+		dbg.Type = (*sym.Type).Signature()
+		dbg.T = *sym.Type
 	}
+	return dbg
 }
 
 func (c *Compiler) emitAt(node ast.Node, op code.Opcode, operands ...int) int {
@@ -942,7 +994,7 @@ func (c *Compiler) emitAt(node ast.Node, op code.Opcode, operands ...int) int {
 	line, col := node.Pos()
 
 	fileName := c.fileName
-	if c.currentModule != "" {
+	if fileName == "" {
 		fileName = c.currentModule
 	}
 
@@ -1098,7 +1150,7 @@ func (c *Compiler) compileResultMatch(node *ast.MatchExpr) error {
 	}
 	c.emit(code.OpResultValue)
 	sym = c.symbolTable.DefineImmutable(node.ErrArm.Pattern.Binding.Value)
-	c.symbolTable.AnnotateType(node.ErrArm.Pattern.Binding.Value, node.GetResolvedType())
+	c.symbolTable.AnnotateType(node.ErrArm.Pattern.Binding.Value, node.ErrArm.Pattern.Binding.GetResolvedType())
 	if sym.Scope == GlobalScope {
 		c.emit(code.OpSetImmutableGlobal, sym.Index)
 	} else {
@@ -1179,6 +1231,136 @@ func (c *Compiler) compileOptionMatch(node *ast.MatchExpr) error {
 	return nil
 }
 
+func (c *Compiler) compileEnumConstructor(node ast.Node, enum types.EnumType, selector *ast.SelectorExpr, args []ast.Expr) error {
+	for _, arg := range args {
+		err := c.Compile(arg)
+		if err != nil {
+			return err
+		}
+	}
+
+	tag := enum.VariantIndex(selector.Value.(*ast.Identifier).Value)
+	typeObj := &object.TypeObject{T: enum}
+	idx := c.addConstant(typeObj)
+
+	c.emitAt(node, code.OpEnum, idx, tag, len(args))
+	return nil
+}
+
+// compileTryExpr evaluates the value once into a hidden variable. An ok or
+// some leaves its value on the stack, and an err or none is returned as is,
+// since the VM does not distinguish result<T> from result<U>
+func (c *Compiler) compileTryExpr(node *ast.TryExpr) error {
+	c.pushBlockScope()
+	defer c.popBlockScope()
+
+	err := c.Compile(node.Value)
+	if err != nil {
+		return err
+	}
+	value := c.symbolTable.DefineMutable("__try_value__")
+	c.symbolTable.AnnotateType("__try_value__", node.Value.GetResolvedType())
+	c.emitSet(value)
+
+	c.emitGet(value)
+	c.emit(code.OpResultTag)
+	notTruthyPos := c.emit(code.OpJumpNotTruthy, 9999)
+
+	c.emitGet(value)
+	c.emit(code.OpResultValue)
+	jumpPos := c.emit(code.OpJump, 9999)
+
+	c.changeOperand(notTruthyPos, len(c.currentInstructions()))
+	c.emitGet(value)
+	c.emitAt(node, code.OpReturnValue)
+
+	c.changeOperand(jumpPos, len(c.currentInstructions()))
+	return nil
+}
+
+// compileEnumMatch tests the subject's tag against each arm in turn. The
+// subject is evaluated once into a hidden variable, and an exhaustive match
+// takes its last arm without testing it
+func (c *Compiler) compileEnumMatch(node *ast.MatchExpr, enum types.EnumType) error {
+	c.pushBlockScope()
+	defer c.popBlockScope()
+
+	err := c.Compile(node.Subject)
+	if err != nil {
+		return err
+	}
+	subject := c.symbolTable.DefineMutable("__match_subject__")
+	c.symbolTable.AnnotateType("__match_subject__", enum)
+	c.emitSet(subject)
+
+	prevJmp := -1
+	jmpEndPos := make([]int, 0)
+	for i, arm := range node.Arms {
+		if prevJmp != -1 {
+			c.changeOperand(prevJmp, len(c.currentInstructions()))
+			prevJmp = -1
+		}
+		tag := enum.VariantIndex(arm.Pattern.Variant.Value)
+		if i < len(node.Arms)-1 || node.Default != nil {
+			c.emitGet(subject)
+			c.emit(code.OpEnumTag, tag)
+			prevJmp = c.emit(code.OpJumpNotTruthy, 9999)
+		}
+
+		c.pushBlockScope()
+		for j, binding := range arm.Pattern.Bindings {
+			if binding.Value == "_" {
+				continue
+			}
+			c.emitGet(subject)
+			c.emit(code.OpEnumField, j)
+			sym := c.symbolTable.DefineImmutable(binding.Value)
+			c.symbolTable.AnnotateType(binding.Value, enum.Payloads[tag][j])
+			if sym.Scope == GlobalScope {
+				c.emit(code.OpSetImmutableGlobal, sym.Index)
+			} else {
+				c.emit(code.OpSetImmutableLocal, sym.Index)
+			}
+		}
+		err = c.Compile(arm.Body)
+		if err != nil {
+			return err
+		}
+		if c.lastInstructionIs(code.OpPop) {
+			c.removeLastPop()
+		}
+		if node.GetResolvedType() == types.Unit {
+			c.emit(code.OpNull)
+		}
+		c.popBlockScope()
+		jmpEndPos = append(jmpEndPos, c.emit(code.OpJump, 9999))
+	}
+
+	if prevJmp != -1 {
+		c.changeOperand(prevJmp, len(c.currentInstructions()))
+	}
+
+	if node.Default != nil {
+		err = c.Compile(node.Default)
+		if err != nil {
+			return err
+		}
+		if c.lastInstructionIs(code.OpPop) {
+			c.removeLastPop()
+		}
+		if node.GetResolvedType() == types.Unit {
+			c.emit(code.OpNull)
+		}
+	}
+
+	end := len(c.currentInstructions())
+	for _, jmp := range jmpEndPos {
+		c.changeOperand(jmp, end)
+	}
+
+	return nil
+}
+
 func (c *Compiler) compileTypeMatch(expr *ast.MatchTypeExpr) error {
 	prevJmp := -1
 	jmpEndPos := make([]int, 0)
@@ -1316,6 +1498,13 @@ func (c *Compiler) emitZeroValue(node ast.Node, t types.Type) {
 		b := &object.Byte{Value: 0}
 		c.emitAt(node, code.OpConstant, c.addConstant(b))
 	default:
+		if tt, ok := t.(types.TupleType); ok {
+			for _, et := range tt.Types {
+				c.emitZeroValue(node, et)
+			}
+			c.emitAt(node, code.OpTuple, len(tt.Types))
+			return
+		}
 		c.emitAt(node, code.OpNull)
 	}
 }
@@ -1409,6 +1598,8 @@ func getConcreteType(expr ast.Expr) (string, error) {
 		return node.ResolvedType.Signature(), nil
 	case *ast.ScopeAccessExpr:
 		return node.ResolvedType.Signature(), nil
+	case *ast.TryExpr:
+		return node.ResolvedType.Signature(), nil
 	}
 
 	return "", fmt.Errorf("expression of type %T has no concrete type", expr)
@@ -1425,6 +1616,8 @@ func (c *Compiler) isInterfaceType(expr ast.Expr) (*types.InterfaceType, bool) {
 		t = node.ResolvedType
 	case *ast.IndexExpr:
 		t = node.ResolvedType
+	case *ast.TryExpr:
+		t = node.ResolvedType
 	}
 
 	if t == nil {
@@ -1464,7 +1657,7 @@ func (c *Compiler) compileInterfaceMethodCall(node *ast.CallExpr) error {
 			return fmt.Errorf("method %s not found in interface type %s", s.Value.String(), it.Name)
 		}
 
-		c.emit(code.OpCallInterface, methodIdx, len(node.Arguments))
+		c.emitAt(node, code.OpCallInterface, methodIdx, len(node.Arguments))
 	} else if it := s.Left.GetCastTo(); it != nil {
 		// push args onto stack
 		for _, arg := range node.Arguments {
@@ -1484,7 +1677,7 @@ func (c *Compiler) compileInterfaceMethodCall(node *ast.CallExpr) error {
 		methodName := s.Value.(*ast.Identifier).Value
 		methodIdx := it.MethodIndices[methodName]
 
-		c.emit(code.OpCallInterface, methodIdx, len(node.Arguments))
+		c.emitAt(node, code.OpCallInterface, methodIdx, len(node.Arguments))
 	}
 
 	return nil
@@ -1494,6 +1687,15 @@ func (c *Compiler) mangleModule(module, name string) string {
 	return module + "__" + name
 }
 
+// displayName is the name a compiled function reports in stack traces,
+// using the same module:name form as scope access in source
+func (c *Compiler) displayName(name string) string {
+	if c.currentModule == "" {
+		return name
+	}
+	return c.currentModule + ":" + strings.TrimPrefix(name, c.currentModule+"__")
+}
+
 func (c *Compiler) compileForInStmt(node *ast.ForInStmt) error {
 	_, mok := node.Iterable.GetResolvedType().(types.MapType)
 	_, aok := node.Iterable.GetResolvedType().(types.ArrayType)
@@ -1558,9 +1760,10 @@ func (c *Compiler) compileForInStmtArr(node *ast.ForInStmt) error {
 	c.emitGet(iterSym)
 	c.emitGet(idxSym)
 	c.emit(code.OpIndex)
-	valSym := c.symbolTable.DefineMutable(node.Value.Value)
-	c.symbolTable.AnnotateType(node.Value.Value, node.Value.GetResolvedType())
-	c.emitSet(valSym)
+	err = c.bindForInValue(node)
+	if err != nil {
+		return err
+	}
 
 	// Compile body
 	err = c.Compile(node.Body)
@@ -1656,9 +1859,10 @@ func (c *Compiler) compileForInStmtMap(node *ast.ForInStmt) error {
 	c.emitGet(keySym)
 	c.emit(code.OpIndex)
 	c.emit(code.OpResultValue)
-	valSym := c.symbolTable.DefineMutable(node.Value.Value)
-	c.symbolTable.AnnotateType(node.Value.Value, node.Value.GetResolvedType())
-	c.emitSet(valSym)
+	err = c.bindForInValue(node)
+	if err != nil {
+		return err
+	}
 
 	// Compile body
 	err = c.Compile(node.Body)
@@ -1697,6 +1901,52 @@ func (c *Compiler) compileForInStmtMap(node *ast.ForInStmt) error {
 	return nil
 }
 
+// bindForInValue sets the loop's value variable, or the names it is
+// destructured into, from the element on the stack
+func (c *Compiler) bindForInValue(node *ast.ForInStmt) error {
+	if node.Names != nil {
+		return c.compileDestructure(node, node.Names, false)
+	}
+	valSym := c.symbolTable.DefineMutable(node.Value.Value)
+	c.symbolTable.AnnotateType(node.Value.Value, node.Value.GetResolvedType())
+	c.emitSet(valSym)
+	return nil
+}
+
+// compileDestructure sets one variable per element of the tuple on the
+// stack. OpUnpack leaves the first element on top, so names are set in order
+func (c *Compiler) compileDestructure(node ast.Node, names []*ast.Identifier, constant bool) error {
+	c.emitAt(node, code.OpUnpack, len(names))
+	for _, ident := range names {
+		if ident.Value == "_" {
+			c.emit(code.OpPop)
+			continue
+		}
+		name := ident.Value
+		if c.currentModule != "" && c.scopeIndex == 0 {
+			name = c.mangleModule(c.currentModule, name)
+		}
+		if sym, fromOuter, ok := c.symbolTable.Resolve(name); ok && !fromOuter && sym.Scope != FunctionScope {
+			return fmt.Errorf("variable %s already declared", name)
+		}
+
+		if constant {
+			symbol := c.symbolTable.DefineImmutable(name)
+			c.symbolTable.AnnotateType(name, ident.GetResolvedType())
+			cde := code.OpSetImmutableLocal
+			if symbol.Scope == GlobalScope {
+				cde = code.OpSetImmutableGlobal
+			}
+			c.emitAt(ident, cde, symbol.Index)
+		} else {
+			symbol := c.symbolTable.DefineMutable(name)
+			c.symbolTable.AnnotateType(name, ident.GetResolvedType())
+			c.emitSet(symbol)
+		}
+	}
+	return nil
+}
+
 func (c *Compiler) emitSet(sym Symbol) {
 	if sym.Scope == GlobalScope {
 		c.emit(code.OpSetMutableGlobal, sym.Index)
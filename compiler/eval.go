@@ -0,0 +1,47 @@
+package compiler
+
+import (
+	"sydney/ast"
+	"sydney/object"
+	"sydney/types"
+)
+
+// CompileExpression compiles a type checked expression as the body of a
+// function taking params, in order. The debugger uses it to run expressions
+// against values copied out of a paused frame: locals and captured variables
+// are passed as arguments, while globals and builtins resolve through the
+// compiler's symbol table as usual.
+func (c *Compiler) CompileExpression(expr ast.Expr, params []string, paramTypes []types.Type) (*object.CompiledFunction, error) {
+	c.enterScope()
+
+	for i, p := range params {
+		c.symbolTable.DefineImmutable(p)
+		if i < len(paramTypes) && paramTypes[i] != nil {
+			c.symbolTable.AnnotateType(p, paramTypes[i])
+		}
+	}
+
+	err := c.Compile(&ast.ExpressionStmt{Expr: expr})
+	if err != nil {
+		c.leaveScope()
+		return nil, err
+	}
+	c.replaceLastPopWithReturn()
+
+	numLocals := c.symbolTable.numDefinitions
+	instructions, sourceMap := c.leaveScope()
+
+	return &object.CompiledFunction{
+		Name:          "<eval>",
+		Instructions:  instructions,
+		NumLocals:     numLocals,
+		NumParameters: len(params),
+		SourceMap:     sourceMap,
+	}, nil
+}
+
+// Constants returns the constant pool, including anything added since the
+// last call to Bytecode
+func (c *Compiler) Constants() []object.Object {
+	return c.constants
+}
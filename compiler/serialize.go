@@ -0,0 +1,667 @@
+package compiler
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+
+	"sydney/code"
+	"sydney/object"
+	"sydney/types"
+)
+
+// BytecodeMagic prefixes every serialized bytecode file (.syc)
+const BytecodeMagic = "SYDC"
+
+// BytecodeVersion is bumped whenever the layout below changes. Files written
+// by a different version are rejected rather than misread.
+const BytecodeVersion uint16 = 8
+
+// maxSerializedLen bounds every count and length the decoder reads so a
+// corrupt file cannot make it allocate unbounded memory
+const maxSerializedLen = 1 << 30
+
+// maxPreallocLen caps how far a length prefix is trusted before its elements
+// have been read; longer lists grow as they decode
+const maxPreallocLen = 1 << 12
+
+// constant tags
+const (
+	tagInteger byte = iota + 1
+	tagFloat
+	tagString
+	tagByte
+	tagBoolean
+	tagNull
+	tagCompiledFunction
+	tagTypeObject
+	tagItab
+)
+
+// type tags
+const (
+	typeNil byte = iota
+	typeBasic
+	typeArray
+	typeFunction
+	typeMap
+	typeStruct
+	typeInterface
+	typeResult
+	typeOption
+	typeScope
+	typeTypeParam
+	typeTypeParamRef
+	typeChannel
+	typeEnum
+	typeTuple
+)
+
+// Serialize writes the bytecode to w in the versioned .syc format:
+//
+//	magic "SYDC" | version u16 | instructions | source map | debug symbols | constants
+//
+// Integers are varint encoded, strings and byte slices are length prefixed.
+func (b *Bytecode) Serialize(w io.Writer) error {
+	e := &encoder{w: bufio.NewWriter(w)}
+	e.raw([]byte(BytecodeMagic))
+	e.u16(BytecodeVersion)
+	e.bytes(b.Instructions)
+	e.sourceMap(b.SourceMap)
+	e.debugSymbols(b.DebugSymbols)
+	e.uint(len(b.Constants))
+	for _, c := range b.Constants {
+		e.constant(c)
+	}
+	if e.err != nil {
+		return e.err
+	}
+
+	return e.w.Flush()
+}
+
+// Deserialize reads bytecode previously written by Serialize
+func Deserialize(r io.Reader) (*Bytecode, error) {
+	d := &decoder{r: bufio.NewReader(r)}
+	magic := d.raw(len(BytecodeMagic))
+	if d.err != nil || string(magic) != BytecodeMagic {
+		return nil, fmt.Errorf("not a sydney bytecode file")
+	}
+	version := d.u16()
+	if d.err == nil && version != BytecodeVersion {
+		return nil, fmt.Errorf("unsupported bytecode version %d, expected %d", version, BytecodeVersion)
+	}
+
+	b := &Bytecode{}
+	b.Instructions = d.bytes()
+	b.SourceMap = d.sourceMap()
+	b.DebugSymbols = d.debugSymbols()
+	n := d.uint()
+	for i := 0; i < n && d.err == nil; i++ {
+		b.Constants = append(b.Constants, d.constant())
+	}
+	if d.err != nil {
+		return nil, fmt.Errorf("malformed bytecode: %w", d.err)
+	}
+
+	return b, nil
+}
+
+// IsSerializedBytecode reports whether data starts with the .syc magic
+func IsSerializedBytecode(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(BytecodeMagic))
+}
+
+type encoder struct {
+	w   *bufio.Writer
+	err error
+}
+
+func (e *encoder) raw(b []byte) {
+	if e.err != nil {
+		return
+	}
+	_, e.err = e.w.Write(b)
+}
+
+func (e *encoder) u16(v uint16) {
+	var buf [2]byte
+	binary.BigEndian.PutUint16(buf[:], v)
+	e.raw(buf[:])
+}
+
+func (e *encoder) uint(v int) {
+	e.raw(binary.AppendUvarint(nil, uint64(v)))
+}
+
+func (e *encoder) int(v int64) {
+	e.raw(binary.AppendVarint(nil, v))
+}
+
+func (e *encoder) bool(v bool) {
+	if v {
+		e.raw([]byte{1})
+	} else {
+		e.raw([]byte{0})
+	}
+}
+
+func (e *encoder) bytes(b []byte) {
+	e.uint(len(b))
+	e.raw(b)
+}
+
+func (e *encoder) string(s string) {
+	e.bytes([]byte(s))
+}
+
+func (e *encoder) strings(ss []string) {
+	e.uint(len(ss))
+	for _, s := range ss {
+		e.string(s)
+	}
+}
+
+func (e *encoder) sourceMap(sm *code.SourceMap) {
+	e.bool(sm != nil)
+	if sm == nil {
+		return
+	}
+
+	// sort offsets so the same program always serializes to the same bytes
+	offsets := make([]int, 0, len(sm.Mappings))
+	for off := range sm.Mappings {
+		offsets = append(offsets, off)
+	}
+	sort.Ints(offsets)
+
+	e.uint(len(offsets))
+	for _, off := range offsets {
+		m := sm.Mappings[off]
+		e.uint(m.InstructionOffset)
+		e.int(int64(m.Line))
+		e.int(int64(m.Col))
+		e.string(m.File)
+	}
+}
+
+func (e *encoder) debugSymbols(ds *code.DebugSymbols) {
+	e.bool(ds != nil)
+	if ds == nil {
+		return
+	}
+
+	e.debugSymbolList(ds.Locals)
+	e.debugSymbolList(ds.Free)
+}
+
+func (e *encoder) debugSymbolList(syms []*code.DebugSymbol) {
+	e.uint(len(syms))
+	for _, l := range syms {
+		e.bool(l != nil)
+		if l == nil {
+			continue
+		}
+		e.string(l.Scope)
+		e.string(l.Name)
+		e.string(l.Type)
+		e.typ(l.T)
+	}
+}
+
+func (e *encoder) constant(obj object.Object) {
+	switch o := obj.(type) {
+	case *object.Integer:
+		e.raw([]byte{tagInteger})
+		e.int(o.Value)
+	case *object.Float:
+		e.raw([]byte{tagFloat})
+		var buf [8]byte
+		binary.BigEndian.PutUint64(buf[:], math.Float64bits(o.Value))
+		e.raw(buf[:])
+	case *object.String:
+		e.raw([]byte{tagString})
+		e.string(o.Value)
+	case *object.Byte:
+		e.raw([]byte{tagByte, o.Value})
+	case *object.Boolean:
+		e.raw([]byte{tagBoolean})
+		e.bool(o.Value)
+	case *object.Null:
+		e.raw([]byte{tagNull})
+	case *object.CompiledFunction:
+		e.raw([]byte{tagCompiledFunction})
+		e.string(o.Name)
+		e.bytes(o.Instructions)
+		e.uint(o.NumLocals)
+		e.uint(o.NumParameters)
+		e.sourceMap(o.SourceMap)
+		e.debugSymbols(o.DebugSymbols)
+	case *object.TypeObject:
+		e.raw([]byte{tagTypeObject})
+		e.typ(o.T)
+	case *object.Itab:
+		e.raw([]byte{tagItab})
+		e.string(o.InterfaceName)
+		e.string(o.ConcreteName)
+		e.uint(len(o.MethodsIndices))
+		for _, idx := range o.MethodsIndices {
+			e.uint(idx)
+		}
+	default:
+		if e.err == nil {
+			e.err = fmt.Errorf("cannot serialize constant of type %T", obj)
+		}
+	}
+}
+
+func (e *encoder) types(ts []types.Type) {
+	e.uint(len(ts))
+	for _, t := range ts {
+		e.typ(t)
+	}
+}
+
+func (e *encoder) typeParam(tp types.TypeParam) {
+	e.string(tp.Name)
+	e.typ(tp.Constraint)
+}
+
+func (e *encoder) typ(t types.Type) {
+	switch tt := t.(type) {
+	case nil:
+		e.raw([]byte{typeNil})
+	case types.BasicType:
+		e.raw([]byte{typeBasic})
+		e.string(string(tt))
+	case types.ArrayType:
+		e.raw([]byte{typeArray})
+		e.typ(tt.ElemType)
+		e.bool(tt.IsEmpty)
+	case types.FunctionType:
+		e.raw([]byte{typeFunction})
+		e.types(tt.Params)
+		e.typ(tt.Return)
+		e.bool(tt.Variadic)
+		e.uint(len(tt.TypeParams))
+		for _, tp := range tt.TypeParams {
+			e.typeParam(tp)
+		}
+	case types.MapType:
+		e.raw([]byte{typeMap})
+		e.typ(tt.KeyType)
+		e.typ(tt.ValueType)
+		e.bool(tt.IsEmpty)
+	case types.StructType:
+		e.raw([]byte{typeStruct})
+		e.string(tt.Name)
+		e.string(tt.Module)
+		e.strings(tt.Fields)
+		e.types(tt.Types)
+		e.types(tt.Interfaces)
+		e.strings(tt.SatisfiedInterfaces)
+		e.uint(len(tt.TypeParams))
+		for _, tp := range tt.TypeParams {
+			e.typeParam(*tp)
+		}
+		e.types(tt.TypeArgs)
+	case types.InterfaceType:
+		e.raw([]byte{typeInterface})
+		e.string(tt.Name)
+		e.string(tt.Module)
+		e.strings(tt.Methods)
+		e.types(tt.Types)
+	case types.ResultType:
+		e.raw([]byte{typeResult})
+		e.typ(tt.T)
+		e.typ(tt.E)
+	case types.OptionType:
+		e.raw([]byte{typeOption})
+		e.typ(tt.T)
+	case types.ScopeType:
+		e.raw([]byte{typeScope})
+		e.string(tt.Module)
+		e.string(tt.Name)
+	case types.TypeParam:
+		e.raw([]byte{typeTypeParam})
+		e.typeParam(tt)
+	case *types.TypeParam:
+		e.raw([]byte{typeTypeParam})
+		e.typeParam(*tt)
+	case types.TypeParamRef:
+		e.raw([]byte{typeTypeParamRef})
+		e.string(tt.Name)
+	case *types.TypeParamRef:
+		e.raw([]byte{typeTypeParamRef})
+		e.string(tt.Name)
+	case types.ChannelType:
+		e.raw([]byte{typeChannel})
+		e.typ(tt.ElemType)
+	case types.EnumType:
+		e.raw([]byte{typeEnum})
+		e.string(tt.Name)
+		e.string(tt.Module)
+		e.strings(tt.Variants)
+		for _, payload := range tt.Payloads {
+			e.types(payload)
+		}
+		e.uint(len(tt.TypeParams))
+		for _, tp := range tt.TypeParams {
+			e.typeParam(*tp)
+		}
+		e.types(tt.TypeArgs)
+	case types.TupleType:
+		e.raw([]byte{typeTuple})
+		e.types(tt.Types)
+	default:
+		if e.err == nil {
+			e.err = fmt.Errorf("cannot serialize type %T", t)
+		}
+	}
+}
+
+type decoder struct {
+	r   *bufio.Reader
+	err error
+}
+
+func (d *decoder) raw(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n > maxPreallocLen {
+		buf, err := io.ReadAll(io.LimitReader(d.r, int64(n)))
+		if err == nil && len(buf) < n {
+			err = io.ErrUnexpectedEOF
+		}
+		d.err = err
+		return buf
+	}
+	buf := make([]byte, n)
+	_, d.err = io.ReadFull(d.r, buf)
+	return buf
+}
+
+func (d *decoder) byte() byte {
+	b := d.raw(1)
+	if d.err != nil {
+		return 0
+	}
+	return b[0]
+}
+
+func (d *decoder) u16() uint16 {
+	b := d.raw(2)
+	if d.err != nil {
+		return 0
+	}
+	return binary.BigEndian.Uint16(b)
+}
+
+func (d *decoder) uint() int {
+	if d.err != nil {
+		return 0
+	}
+	v, err := binary.ReadUvarint(d.r)
+	if err == nil && v > maxSerializedLen {
+		err = fmt.Errorf("length %d out of range", v)
+	}
+	d.err = err
+	if err != nil {
+		return 0
+	}
+	return int(v)
+}
+
+func (d *decoder) int() int64 {
+	if d.err != nil {
+		return 0
+	}
+	v, err := binary.ReadVarint(d.r)
+	d.err = err
+	return v
+}
+
+func (d *decoder) bool() bool {
+	return d.byte() == 1
+}
+
+func (d *decoder) bytes() []byte {
+	return d.raw(d.uint())
+}
+
+func (d *decoder) string() string {
+	return string(d.bytes())
+}
+
+func (d *decoder) strings() []string {
+	n := d.uint()
+	if n == 0 {
+		return nil
+	}
+	ss := make([]string, 0, min(n, maxPreallocLen))
+	for i := 0; i < n && d.err == nil; i++ {
+		ss = append(ss, d.string())
+	}
+	return ss
+}
+
+func (d *decoder) sourceMap() *code.SourceMap {
+	if !d.bool() {
+		return nil
+	}
+
+	sm := code.New()
+	n := d.uint()
+	for i := 0; i < n && d.err == nil; i++ {
+		m := &code.SourceMapping{
+			InstructionOffset: d.uint(),
+			Line:              int(d.int()),
+			Col:               int(d.int()),
+			File:              d.string(),
+		}
+		sm.Mappings[m.InstructionOffset] = m
+	}
+	return sm
+}
+
+func (d *decoder) debugSymbols() *code.DebugSymbols {
+	if !d.bool() {
+		return nil
+	}
+
+	return &code.DebugSymbols{
+		Locals: d.debugSymbolList(),
+		Free:   d.debugSymbolList(),
+	}
+}
+
+func (d *decoder) debugSymbolList() []*code.DebugSymbol {
+	n := d.uint()
+	syms := make([]*code.DebugSymbol, 0, min(n, maxPreallocLen))
+	for i := 0; i < n && d.err == nil; i++ {
+		if !d.bool() {
+			syms = append(syms, nil)
+			continue
+		}
+		syms = append(syms, &code.DebugSymbol{
+			Scope: d.string(),
+			Name:  d.string(),
+			Type:  d.string(),
+			T:     d.typ(),
+		})
+	}
+	if len(syms) == 0 {
+		return nil
+	}
+	return syms
+}
+
+func (d *decoder) constant() object.Object {
+	tag := d.byte()
+	if d.err != nil {
+		return nil
+	}
+
+	switch tag {
+	case tagInteger:
+		return &object.Integer{Value: d.int()}
+	case tagFloat:
+		b := d.raw(8)
+		if d.err != nil {
+			return nil
+		}
+		return &object.Float{Value: math.Float64frombits(binary.BigEndian.Uint64(b))}
+	case tagString:
+		return &object.String{Value: d.string()}
+	case tagByte:
+		return &object.Byte{Value: d.byte()}
+	case tagBoolean:
+		return &object.Boolean{Value: d.bool()}
+	case tagNull:
+		return &object.Null{}
+	case tagCompiledFunction:
+		return &object.CompiledFunction{
+			Name:          d.string(),
+			Instructions:  d.bytes(),
+			NumLocals:     d.uint(),
+			NumParameters: d.uint(),
+			SourceMap:     d.sourceMap(),
+			DebugSymbols:  d.debugSymbols(),
+		}
+	case tagTypeObject:
+		return &object.TypeObject{T: d.typ()}
+	case tagItab:
+		itab := &object.Itab{
+			InterfaceName: d.string(),
+			ConcreteName:  d.string(),
+		}
+		n := d.uint()
+		itab.MethodsIndices = make([]int, 0, min(n, maxPreallocLen))
+		for i := 0; i < n && d.err == nil; i++ {
+			itab.MethodsIndices = append(itab.MethodsIndices, d.uint())
+		}
+		return itab
+	}
+
+	d.err = fmt.Errorf("unknown constant tag %d", tag)
+	return nil
+}
+
+func (d *decoder) types() []types.Type {
+	n := d.uint()
+	if n == 0 {
+		return nil
+	}
+	ts := make([]types.Type, 0, min(n, maxPreallocLen))
+	for i := 0; i < n && d.err == nil; i++ {
+		ts = append(ts, d.typ())
+	}
+	return ts
+}
+
+func (d *decoder) typeParam() types.TypeParam {
+	return types.TypeParam{Name: d.string(), Constraint: d.typ()}
+}
+
+func (d *decoder) typ() types.Type {
+	tag := d.byte()
+	if d.err != nil {
+		return nil
+	}
+
+	switch tag {
+	case typeNil:
+		return nil
+	case typeBasic:
+		return types.BasicType(d.string())
+	case typeArray:
+		elem := d.typ()
+		return types.ArrayType{ElemType: elem, CollectionType: types.CollectionType{IsEmpty: d.bool()}}
+	case typeFunction:
+		ft := types.FunctionType{Params: d.types()}
+		if ft.Params == nil {
+			ft.Params = []types.Type{}
+		}
+		ft.Return = d.typ()
+		ft.Variadic = d.bool()
+		n := d.uint()
+		for i := 0; i < n && d.err == nil; i++ {
+			ft.TypeParams = append(ft.TypeParams, d.typeParam())
+		}
+		return ft
+	case typeMap:
+		key := d.typ()
+		val := d.typ()
+		return types.MapType{KeyType: key, ValueType: val, CollectionType: types.CollectionType{IsEmpty: d.bool()}}
+	case typeStruct:
+		st := types.StructType{
+			Name:       d.string(),
+			Module:     d.string(),
+			Fields:     d.strings(),
+			Types:      d.types(),
+			Interfaces: d.types(),
+		}
+		st.SatisfiedInterfaces = d.strings()
+		n := d.uint()
+		for i := 0; i < n && d.err == nil; i++ {
+			tp := d.typeParam()
+			st.TypeParams = append(st.TypeParams, &tp)
+		}
+		st.TypeArgs = d.types()
+		return st
+	case typeInterface:
+		it := types.InterfaceType{
+			Name:    d.string(),
+			Module:  d.string(),
+			Methods: d.strings(),
+			Types:   d.types(),
+		}
+		it.MethodIndices = make(map[string]int, len(it.Methods))
+		for i, mn := range it.Methods {
+			it.MethodIndices[mn] = i
+		}
+		return it
+	case typeResult:
+		return types.ResultType{T: d.typ(), E: d.typ()}
+	case typeOption:
+		return types.OptionType{T: d.typ()}
+	case typeScope:
+		return types.ScopeType{Module: d.string(), Name: d.string()}
+	case typeTypeParam:
+		tp := d.typeParam()
+		return &tp
+	case typeTypeParamRef:
+		return &types.TypeParamRef{Name: d.string()}
+	case typeChannel:
+		return types.ChannelType{ElemType: d.typ()}
+	case typeEnum:
+		et := types.EnumType{
+			Name:     d.string(),
+			Module:   d.string(),
+			Variants: d.strings(),
+		}
+		for range et.Variants {
+			if d.err != nil {
+				break
+			}
+			et.Payloads = append(et.Payloads, d.types())
+		}
+		n := d.uint()
+		for i := 0; i < n && d.err == nil; i++ {
+			tp := d.typeParam()
+			et.TypeParams = append(et.TypeParams, &tp)
+		}
+		et.TypeArgs = d.types()
+		return et
+	case typeTuple:
+		return types.TupleType{Types: d.types()}
+	}
+
+	d.err = fmt.Errorf("unknown type tag %d", tag)
+	return nil
+}
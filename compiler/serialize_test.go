@@ -0,0 +1,213 @@
+package compiler
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+
+	"sydney/code"
+	"sydney/object"
+)
+
+func TestSerializeRoundTrip(t *testing.T) {
+	source := `define struct Circle { radius float }
+		define interface Area { area() -> float }
+		define enum Shape { Round(Circle), Box((float, float)), Empty }
+
+		func area(Circle c) -> float {
+			const pi = 3.14;
+			return c.radius * c.radius * pi;
+		}
+
+		const Circle c = Circle { radius: 2.0 };
+		const msg = "area";
+		mut byte b = 'x';
+		const add = func(int x) -> fn<(int) -> int> {
+			func(int y) -> int { x + y; };
+		};
+		const Shape s = Shape.Round(c);
+		const result<int, Shape> r = err(s);
+		print(msg, c.area(), add(1)(2), b, s, r);`
+
+	comp := New()
+	comp.ShouldEmitDebug(true)
+	comp.SetFileName("main.sy")
+	err := comp.Compile(parse(source))
+	if err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	bytecode := comp.Bytecode()
+
+	var buf bytes.Buffer
+	err = bytecode.Serialize(&buf)
+	if err != nil {
+		t.Fatalf("serialize error: %s", err)
+	}
+
+	if !IsSerializedBytecode(buf.Bytes()) {
+		t.Fatalf("serialized bytecode is missing magic header")
+	}
+
+	loaded, err := Deserialize(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("deserialize error: %s", err)
+	}
+
+	err = testInstructions([]code.Instructions{bytecode.Instructions}, loaded.Instructions)
+	if err != nil {
+		t.Fatalf("instructions differ: %s", err)
+	}
+
+	if !reflect.DeepEqual(bytecode.SourceMap, loaded.SourceMap) {
+		t.Fatalf("source map differs. want=%+v, got=%+v", bytecode.SourceMap, loaded.SourceMap)
+	}
+
+	err = testDebugSymbols(bytecode.DebugSymbols, loaded.DebugSymbols)
+	if err != nil {
+		t.Fatalf("debug symbols differ: %s", err)
+	}
+
+	if len(loaded.Constants) != len(bytecode.Constants) {
+		t.Fatalf("wrong number of constants. want=%d, got=%d", len(bytecode.Constants), len(loaded.Constants))
+	}
+
+	for i, want := range bytecode.Constants {
+		got := loaded.Constants[i]
+		switch want := want.(type) {
+		case *object.CompiledFunction:
+			fn, ok := got.(*object.CompiledFunction)
+			if !ok {
+				t.Fatalf("constant %d: expected *object.CompiledFunction, got %T", i, got)
+			}
+			if want.Name != fn.Name || !bytes.Equal(want.Instructions, fn.Instructions) || want.NumLocals != fn.NumLocals || want.NumParameters != fn.NumParameters {
+				t.Fatalf("constant %d: compiled function differs. want=%+v, got=%+v", i, want, fn)
+			}
+			if !reflect.DeepEqual(want.SourceMap, fn.SourceMap) {
+				t.Fatalf("constant %d: source map differs", i)
+			}
+		case *object.TypeObject:
+			err := testTypeObject(want, got)
+			if err != nil {
+				t.Fatalf("constant %d: %s", i, err)
+			}
+		case *object.Itab:
+			err := testItab(want, got)
+			if err != nil {
+				t.Fatalf("constant %d: %s", i, err)
+			}
+		default:
+			if !reflect.DeepEqual(want, got) {
+				t.Fatalf("constant %d differs. want=%+v, got=%+v", i, want, got)
+			}
+		}
+	}
+}
+
+func TestDeserializeRejectsVersionMismatch(t *testing.T) {
+	var buf bytes.Buffer
+	err := New().Bytecode().Serialize(&buf)
+	if err != nil {
+		t.Fatalf("serialize error: %s", err)
+	}
+
+	data := buf.Bytes()
+	data[len(BytecodeMagic)+1]++ // bump low byte of version
+
+	_, err = Deserialize(bytes.NewReader(data))
+	if err == nil {
+		t.Fatalf("expected version mismatch error")
+	}
+
+	_, err = Deserialize(bytes.NewReader([]byte("not bytecode")))
+	if err == nil {
+		t.Fatalf("expected magic mismatch error")
+	}
+}
+
+func TestDeserializeRejectsTruncatedInput(t *testing.T) {
+	program := parse(`define struct Point { x int, y int }
+		func sum(Point p) -> int { return p.x + p.y; }
+		print(sum(Point { x: 1, y: 2 }), "done", 1.5);`)
+	c := New()
+	if err := c.Compile(program); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	var buf bytes.Buffer
+	if err := c.Bytecode().Serialize(&buf); err != nil {
+		t.Fatalf("serialize error: %s", err)
+	}
+
+	data := buf.Bytes()
+	for i := range data {
+		if _, err := Deserialize(bytes.NewReader(data[:i])); err == nil {
+			t.Fatalf("expected an error for the first %d of %d bytes", i, len(data))
+		}
+	}
+}
+
+func TestDeserializeRejectsCorruptCounts(t *testing.T) {
+	header := append([]byte(BytecodeMagic), byte(BytecodeVersion>>8), byte(BytecodeVersion))
+	huge := binary.AppendUvarint(nil, math.MaxUint64)
+	big := binary.AppendUvarint(nil, maxSerializedLen)
+
+	tests := []struct {
+		name string
+		body []byte
+	}{
+		{"instructions longer than the file", big},
+		{"instruction length past the limit", huge},
+		{"constant count past the limit", concat([]byte{0, 0, 0}, huge)},
+		{"source map count past the limit", concat([]byte{0, 1}, huge)},
+		{"debug symbol count past the limit", concat([]byte{0, 0, 1}, huge)},
+		{"debug symbol count longer than the file", concat([]byte{0, 0, 1}, big)},
+		{"itab method count past the limit", concat([]byte{0, 0, 0, 1, tagItab, 0, 0}, huge)},
+		{"itab method count longer than the file", concat([]byte{0, 0, 0, 1, tagItab, 0, 0}, big)},
+		{"struct field count longer than the file", concat([]byte{0, 0, 0, 1, tagTypeObject, typeStruct, 0, 0}, big)},
+		{"tuple type count longer than the file", concat([]byte{0, 0, 0, 1, tagTypeObject, typeTuple}, big)},
+	}
+
+	for _, tt := range tests {
+		if _, err := Deserialize(bytes.NewReader(concat(header, tt.body))); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+}
+
+func concat(parts ...[]byte) []byte {
+	var out []byte
+	for _, p := range parts {
+		out = append(out, p...)
+	}
+	return out
+}
+
+// testDebugSymbols compares types by signature: decoding does not preserve
+// the difference between nil and empty slices inside types
+func testDebugSymbols(want, got *code.DebugSymbols) error {
+	if len(want.Locals) != len(got.Locals) || len(want.Free) != len(got.Free) {
+		return fmt.Errorf("wrong number of symbols. want=%d/%d, got=%d/%d", len(want.Locals), len(want.Free), len(got.Locals), len(got.Free))
+	}
+
+	wantSyms := append(append([]*code.DebugSymbol{}, want.Locals...), want.Free...)
+	gotSyms := append(append([]*code.DebugSymbol{}, got.Locals...), got.Free...)
+	for i, w := range wantSyms {
+		g := gotSyms[i]
+		if (w == nil) != (g == nil) {
+			return fmt.Errorf("symbol %d: want=%+v, got=%+v", i, w, g)
+		}
+		if w == nil {
+			continue
+		}
+		if w.Scope != g.Scope || w.Name != g.Name || w.Type != g.Type || (w.T == nil) != (g.T == nil) {
+			return fmt.Errorf("symbol %d: want=%+v, got=%+v", i, w, g)
+		}
+		if w.T != nil && w.T.Signature() != g.T.Signature() {
+			return fmt.Errorf("symbol %d type: want=%s, got=%s", i, w.T.Signature(), g.T.Signature())
+		}
+	}
+
+	return nil
+}
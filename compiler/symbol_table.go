@@ -27,6 +27,10 @@ type SymbolTable struct {
 	numDefinitions int
 	FreeSymbols    []Symbol
 	isBlockScoped  bool
+
+	// every slot defined in this function scope, including the ones declared
+	// in nested blocks, indexed by slot. Only read for debug symbols.
+	slots []Symbol
 }
 
 func NewSymbolTable() *SymbolTable {
@@ -54,6 +58,7 @@ func (s *SymbolTable) DefineMutable(name string) Symbol {
 	}
 
 	s.store[name] = symbol
+	s.recordSlot(symbol)
 	s.numDefinitions++
 	for outer := s; outer.isBlockScoped && outer.Outer != nil; outer = outer.Outer {
 		outer.Outer.numDefinitions = outer.numDefinitions
@@ -70,6 +75,7 @@ func (s *SymbolTable) DefineImmutable(name string) Symbol {
 	}
 
 	s.store[name] = symbol
+	s.recordSlot(symbol)
 	s.numDefinitions++
 	for outer := s; outer.isBlockScoped && outer.Outer != nil; outer = outer.Outer {
 		outer.Outer.numDefinitions = outer.numDefinitions
@@ -97,6 +103,32 @@ func (s *SymbolTable) AnnotateType(name string, typ types.Type) {
 	sym := s.store[name]
 	sym.Type = &typ
 	s.store[name] = sym
+	if sym.Scope == LocalScope || sym.Scope == GlobalScope {
+		s.recordSlot(sym)
+	}
+}
+
+// owner returns the table whose slots s allocates into: the nearest enclosing
+// table that is not a block scope
+func (s *SymbolTable) owner() *SymbolTable {
+	for s.isBlockScoped && s.Outer != nil {
+		s = s.Outer
+	}
+	return s
+}
+
+func (s *SymbolTable) recordSlot(sym Symbol) {
+	owner := s.owner()
+	for len(owner.slots) <= sym.Index {
+		owner.slots = append(owner.slots, Symbol{})
+	}
+	owner.slots[sym.Index] = sym
+}
+
+// Slots returns the symbol of every local (or, at the top level, global) slot
+// of the enclosing function scope. Unused slots have an empty name.
+func (s *SymbolTable) Slots() []Symbol {
+	return s.owner().slots
 }
 
 // symbol, fromOuter, ok
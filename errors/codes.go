@@ -0,0 +1,420 @@
+package errors
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Code identifies a kind of diagnostic. Codes never change meaning, so tools
+// can filter on them and `sydney explain` can describe them. E00xx are syntax
+// errors, E01xx are type errors and W01xx are warnings from the type checker
+type Code string
+
+const (
+	UnexpectedToken    Code = "E0001"
+	ExpectedExpression Code = "E0002"
+	InvalidLiteral     Code = "E0003"
+	InvalidDeclaration Code = "E0004"
+
+	UnresolvedName          Code = "E0100"
+	UnknownType             Code = "E0101"
+	MismatchedTypes         Code = "E0102"
+	WrongArgumentCount      Code = "E0103"
+	InvalidArgument         Code = "E0104"
+	InvalidOperation        Code = "E0105"
+	AssignToConstant        Code = "E0106"
+	CannotInfer             Code = "E0107"
+	NotCallable             Code = "E0108"
+	InvalidIndex            Code = "E0109"
+	UnknownField            Code = "E0110"
+	InterfaceNotSatisfied   Code = "E0111"
+	MisplacedControlFlow    Code = "E0112"
+	MissingReturn           Code = "E0113"
+	UnknownModuleMember     Code = "E0114"
+	DuplicateDeclaration    Code = "E0115"
+	InvalidMatch            Code = "E0116"
+	NotIterable             Code = "E0117"
+	InvalidChannelOperation Code = "E0118"
+	NonExhaustiveMatch      Code = "E0119"
+
+	UnreachableCode Code = "W0100"
+)
+
+type explanation struct {
+	title string
+	text  string
+}
+
+var explanations = map[Code]explanation{
+	UnexpectedToken: {"unexpected token", `
+The parser found a token where the grammar does not allow it, usually because
+something before it is missing.
+
+Erroneous code example:
+
+    func add(int a, int b -> int { return a + b; }
+
+The parameter list is not closed before the return type. Add the missing
+token:
+
+    func add(int a, int b) -> int { return a + b; }
+`},
+	ExpectedExpression: {"expected expression", `
+An expression was expected, but the token found cannot start one.
+
+Erroneous code example:
+
+    const total = 1 +;
+
+Complete the expression:
+
+    const total = 1 + 2;
+`},
+	InvalidLiteral: {"invalid number literal", `
+A number literal could not be read as an int or a float, usually because it is
+too large.
+
+Erroneous code example:
+
+    const big = 99999999999999999999;
+
+Use a value that fits in 64 bits.
+`},
+	InvalidDeclaration: {"invalid declaration", `
+A declaration is written somewhere it is not allowed, or is missing a part it
+needs. Imports must be at the top level, annotations only apply to structs,
+constants must be initialized and variables without a value need a type.
+
+Erroneous code example:
+
+    const limit;
+    mut count;
+
+Give the constant a value and the variable a type:
+
+    const limit = 10;
+    mut int count;
+`},
+	UnresolvedName: {"unresolved name", `
+A name was used that is not declared in scope.
+
+Erroneous code example:
+
+    const count = 1;
+    print(cuont);
+
+Check the spelling, or declare the name before using it:
+
+    const count = 1;
+    print(count);
+`},
+	UnknownType: {"unknown type", `
+A type annotation names a type that is not declared, or a module type that the
+module does not declare.
+
+Erroneous code example:
+
+    func area(Shape s) -> float { return 0.0; }
+
+Declare the type, or import the module that declares it:
+
+    define struct Shape { width float }
+    func area(Shape s) -> float { return 0.0; }
+`},
+	MismatchedTypes: {"mismatched types", `
+A value has a different type than the place it is used requires: a variable's
+declared type, a parameter, a return type, a condition, or the other branch of
+an if or match.
+
+Erroneous code example:
+
+    mut int total = 0;
+    total = "ten";
+
+Assign a value of the declared type, or convert it:
+
+    mut int total = 0;
+    total = 10;
+`},
+	WrongArgumentCount: {"wrong number of arguments", `
+A function, builtin or generic was given more or fewer arguments or type
+arguments than it declares.
+
+Erroneous code example:
+
+    func add(int a, int b) -> int { return a + b; }
+    add(1);
+
+Pass one argument for each parameter:
+
+    add(1, 2);
+`},
+	InvalidArgument: {"invalid argument type", `
+A builtin was called with an argument of a type it does not accept.
+
+Erroneous code example:
+
+    len(10);
+
+len() takes a string, an array or a map:
+
+    len("ten");
+`},
+	InvalidOperation: {"invalid operation", `
+An operator was applied to a type that does not support it.
+
+Erroneous code example:
+
+    const x = true * false;
+
+Use an operator the operand's type defines:
+
+    const x = true && false;
+`},
+	AssignToConstant: {"assignment to a constant", `
+A variable declared with const cannot be assigned to after it is initialized.
+
+Erroneous code example:
+
+    const count = 1;
+    count = 2;
+
+Declare the variable with mut to allow assigning to it:
+
+    mut count = 1;
+    count = 2;
+`},
+	CannotInfer: {"type cannot be inferred", `
+The checker could not work out the type of a value, such as an empty array,
+none() or err() with nothing to say what they hold, or a variable initialized
+from an expression that has no type.
+
+Erroneous code example:
+
+    const items = [];
+
+Annotate the type:
+
+    const array<int> items = [];
+`},
+	NotCallable: {"not callable", `
+A value that is not a function was called, or spawn or defer was used on
+something that is not a function call.
+
+Erroneous code example:
+
+    const count = 1;
+    count();
+
+Only functions can be called:
+
+    func count() -> int { return 1; }
+    count();
+`},
+	InvalidIndex: {"invalid index", `
+An index or slice expression used a value that cannot be indexed, or an index
+of the wrong type. Arrays and strings are indexed by int, maps by their key
+type. Tuples cannot be map keys.
+
+Erroneous code example:
+
+    const xs = [1, 2, 3];
+    xs["first"];
+
+Use an int to index an array:
+
+    xs[0];
+`},
+	UnknownField: {"unknown field", `
+A field was accessed or assigned on a struct that does not declare it, on a
+value that is not a struct, or left out of a struct literal.
+
+Erroneous code example:
+
+    define struct Point { x int, y int }
+    const p = Point { x: 1, y: 2 };
+    p.z;
+
+Use one of the struct's fields:
+
+    p.x;
+`},
+	InterfaceNotSatisfied: {"interface not satisfied", `
+A struct or interface was used as an interface whose methods it does not all
+have with the right signatures. A struct implements an interface method with a
+function of the same name taking the struct first.
+
+Erroneous code example:
+
+    define interface Shape { area() -> float }
+    define struct Square { side float }
+    func measure(Shape s) -> float { return s.area(); }
+    measure(Square { side: 2.0 });
+
+Add the missing method:
+
+    func area(Square s) -> float { return s.side * s.side; }
+`},
+	MisplacedControlFlow: {"control flow outside its context", `
+return, ? or defer was used outside a function body, ? inside a deferred call,
+or break or continue outside a loop.
+
+Erroneous code example:
+
+    break;
+
+Only use break and continue inside for loops:
+
+    for (mut i = 0; i < 10; i = i + 1) {
+        if (i == 5) { break; }
+    }
+`},
+	MissingReturn: {"missing return", `
+A function with a return type can reach the end of its body without returning
+a value.
+
+Erroneous code example:
+
+    func sign(int x) -> int {
+        if (x < 0) { return -1; }
+    }
+
+Return a value on every path:
+
+    func sign(int x) -> int {
+        if (x < 0) { return -1; }
+        return 1;
+    }
+`},
+	UnknownModuleMember: {"unknown module member", `
+A module:member expression named a module that is not imported, or a member
+the module does not export with pub.
+
+Erroneous code example:
+
+    import "strings"
+    strings:splitt("a,b", ",");
+
+Check the spelling, and that the member is declared pub in its module:
+
+    strings:split("a,b", ",");
+`},
+	DuplicateDeclaration: {"duplicate declaration", `
+A name was declared twice in the same scope.
+
+Erroneous code example:
+
+    func twice(int x) -> int { return x * 2; }
+    func twice(int x) -> int { return x + x; }
+
+Rename or remove one of the declarations.
+`},
+	InvalidMatch: {"invalid match", `
+A match expression was used on a value it cannot match, or has an arm that
+does not fit it: value matches need a result, option or enum, enum arms must
+name each variant once and bind all of its values, and type matches need an
+any or an interface.
+
+Erroneous code example:
+
+    const n = 1;
+    const m = match n {
+        ok(v) -> { v; },
+        err(e) -> { 0; },
+    };
+
+Match on a result, option or enum:
+
+    const r = ok(1);
+    const m = match r {
+        ok(v) -> { v; },
+        err(e) -> { 0; },
+    };
+`},
+	NotIterable: {"not iterable", `
+A for-in loop was given a value that cannot be iterated over. Arrays, maps and
+strings can be iterated.
+
+Erroneous code example:
+
+    for (x in 10) { print(x); }
+
+Iterate over a collection:
+
+    for (x in [1, 2, 3]) { print(x); }
+`},
+	InvalidChannelOperation: {"invalid channel operation", `
+A channel operation was used on a value that is not a channel, or a channel
+was made with a capacity that is not an int.
+
+Erroneous code example:
+
+    const n = 1;
+    n <- 2;
+
+Send and receive on channels:
+
+    const c = chan<int>(1);
+    c <- 2;
+`},
+	NonExhaustiveMatch: {"non-exhaustive match", `
+A match on an enum has no arm for some of its variants and no _ arm, so there
+are values it cannot handle.
+
+Erroneous code example:
+
+    define enum Shape { Circle(float), Rect(float, float), Empty }
+    const s = Shape.Empty;
+    const area = match s {
+        Circle(r) -> { r * r; },
+        Rect(w, h) -> { w * h; },
+    };
+
+Add an arm for each missing variant, or a _ arm for the rest:
+
+    const area = match s {
+        Circle(r) -> { r * r; },
+        Rect(w, h) -> { w * h; },
+        _ -> { 0.0; },
+    };
+`},
+	UnreachableCode: {"unreachable code", `
+A statement follows a return, break or continue in the same block, so it never
+runs. This is a warning: the program still compiles.
+
+Erroneous code example:
+
+    func double(int x) -> int {
+        return x * 2;
+        print("doubled");
+    }
+
+Move the statement before the return, or remove it:
+
+    func double(int x) -> int {
+        print("doubled");
+        return x * 2;
+    }
+`},
+}
+
+// Explain is the long description of a code, with an example of code that
+// causes it and how to fix it
+func Explain(code Code) (string, bool) {
+	e, ok := explanations[Code(strings.ToUpper(string(code)))]
+	if !ok {
+		return "", false
+	}
+	return fmt.Sprintf("%s: %s\n%s", strings.ToUpper(string(code)), e.title, e.text), true
+}
+
+// Codes lists every code with its title, in order
+func Codes() []string {
+	lines := make([]string, 0, len(explanations))
+	for code, e := range explanations {
+		lines = append(lines, fmt.Sprintf("%s  %s", code, e.title))
+	}
+	sort.Strings(lines)
+	return lines
+}
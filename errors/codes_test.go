@@ -0,0 +1,79 @@
+package errors
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// declaredCodes reads the Code constants from codes.go, so a new code without
+// an explanation fails the test rather than being left out of it
+func declaredCodes(t *testing.T) map[string]Code {
+	t.Helper()
+	file, err := parser.ParseFile(token.NewFileSet(), "codes.go", nil, 0)
+	if err != nil {
+		t.Fatalf("parsing codes.go: %v", err)
+	}
+
+	codes := map[string]Code{}
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.CONST {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			value := spec.(*ast.ValueSpec)
+			if ident, ok := value.Type.(*ast.Ident); !ok || ident.Name != "Code" {
+				continue
+			}
+			for i, name := range value.Names {
+				lit, ok := value.Values[i].(*ast.BasicLit)
+				if !ok {
+					t.Fatalf("%s is not a string literal", name.Name)
+				}
+				code, err := strconv.Unquote(lit.Value)
+				if err != nil {
+					t.Fatalf("%s: %v", name.Name, err)
+				}
+				codes[name.Name] = Code(code)
+			}
+		}
+	}
+	if len(codes) == 0 {
+		t.Fatal("found no codes in codes.go")
+	}
+	return codes
+}
+
+func TestEveryCodeIsExplained(t *testing.T) {
+	listed := strings.Join(Codes(), "\n")
+	seen := map[Code]string{}
+	for name, code := range declaredCodes(t) {
+		t.Run(name, func(t *testing.T) {
+			if other, ok := seen[code]; ok {
+				t.Errorf("%s has the same code %s as %s", name, code, other)
+			}
+			seen[code] = name
+
+			text, ok := Explain(code)
+			if !ok {
+				t.Fatalf("%s (%s) has no explanation", name, code)
+			}
+			e := explanations[code]
+			if strings.TrimSpace(e.title) == "" || strings.TrimSpace(e.text) == "" {
+				t.Errorf("%s (%s) has an empty explanation: %q", name, code, text)
+			}
+			if !strings.Contains(listed, string(code)+"  "+e.title) {
+				t.Errorf("%s (%s) is missing from the list of codes", name, code)
+			}
+
+			// codes are looked up the way they are typed on the command line
+			if lower, _ := Explain(Code(strings.ToLower(string(code)))); lower != text {
+				t.Errorf("explain %s: expected the explanation of %s", strings.ToLower(string(code)), code)
+			}
+		})
+	}
+}
@@ -2,24 +2,74 @@ package errors
 
 import (
 	"fmt"
-	"io"
+	"strings"
 )
 
+// Severity says whether a diagnostic stops compilation
+type Severity int
+
+const (
+	Error Severity = iota
+	Warning
+)
+
+func (s Severity) String() string {
+	if s == Warning {
+		return "warning"
+	}
+	return "error"
+}
+
 type PositionError struct {
 	Line    int
 	Col     int
 	Message string
 	File    string
+
+	Code     Code
+	Severity Severity
+	Length   int     // columns to underline, or 0 for the token at Line:Col
+	Labels   []Label // other code the error refers to
+	Notes    []string
+	Help     string
+}
+
+// Label points at code related to an error, such as the declaration of a
+// variable that was assigned the wrong type
+type Label struct {
+	File    string
+	Line    int
+	Col     int
+	Length  int
+	Message string
 }
 
-func PrintPositionErrors(w io.Writer, errs []PositionError) {
-	for _, msg := range errs {
-		var str string
-		if msg.File != "" {
-			str = fmt.Sprintf("\t%s:%d:%d:%s\n", msg.File, msg.Line, msg.Col, msg.Message)
-		} else {
-			str = fmt.Sprintf("\t%d:%d:%s\n", msg.Line, msg.Col, msg.Message)
+// HasErrors reports whether any of errs stops compilation, rather than only
+// warning about the code
+func HasErrors(errs []PositionError) bool {
+	for _, err := range errs {
+		if err.Severity == Error {
+			return true
 		}
-		io.WriteString(w, str)
 	}
+	return false
+}
+
+func (e PositionError) Error() string {
+	if e.File != "" {
+		return fmt.Sprintf("%s:%d:%d: %s", e.File, e.Line, e.Col, e.Message)
+	}
+	return fmt.Sprintf("%d:%d: %s", e.Line, e.Col, e.Message)
+}
+
+// List is a set of errors reported together, such as the syntax errors of a
+// file
+type List []PositionError
+
+func (l List) Error() string {
+	msgs := make([]string, len(l))
+	for i, err := range l {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
 }
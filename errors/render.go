@@ -0,0 +1,293 @@
+package errors
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"sydney/lexer"
+	"sydney/token"
+)
+
+// Format is how a Renderer writes diagnostics
+type Format int
+
+const (
+	Text Format = iota // source snippets for people
+	JSON               // one JSON object per line for tools
+)
+
+const (
+	ansiReset  = "\x1b[0m"
+	ansiBold   = "\x1b[1m"
+	ansiRed    = "\x1b[1;31m"
+	ansiYellow = "\x1b[1;33m"
+	ansiBlue   = "\x1b[1;34m"
+	ansiCyan   = "\x1b[1;36m"
+)
+
+// Renderer writes diagnostics with the source they point at. Sources are read
+// from disk the first time a file is needed unless they were added already
+type Renderer struct {
+	w       io.Writer
+	format  Format
+	color   bool
+	sources map[string][]string
+}
+
+func NewRenderer(w io.Writer, format Format, color bool) *Renderer {
+	return &Renderer{w: w, format: format, color: color, sources: make(map[string][]string)}
+}
+
+// AddSource gives the text of file, for sources that are not on disk such as
+// a line typed into the REPL, whose errors have no file
+func (r *Renderer) AddSource(file, src string) {
+	r.sources[file] = strings.Split(src, "\n")
+}
+
+func (r *Renderer) Render(errs []PositionError) {
+	for _, err := range errs {
+		if r.format == JSON {
+			r.renderJSON(err)
+		} else {
+			r.renderText(err)
+		}
+	}
+}
+
+// annotation is a span to underline in a snippet
+type annotation struct {
+	line, col, length int
+	message           string
+	primary           bool
+}
+
+func (r *Renderer) renderText(err PositionError) {
+	severity := ansiRed
+	if err.Severity == Warning {
+		severity = ansiYellow
+	}
+	heading := err.Severity.String()
+	if err.Code != "" {
+		heading += "[" + string(err.Code) + "]"
+	}
+	fmt.Fprintf(r.w, "%s%s\n", r.paint(severity, heading), r.paint(ansiBold, ": "+err.Message))
+
+	// the primary span's file comes first, then the files of labels elsewhere
+	files := []string{err.File}
+	byFile := map[string][]annotation{
+		err.File: {{line: err.Line, col: err.Col, length: err.Length, primary: true}},
+	}
+	for _, label := range err.Labels {
+		if _, ok := byFile[label.File]; !ok {
+			files = append(files, label.File)
+		}
+		byFile[label.File] = append(byFile[label.File], annotation{line: label.Line, col: label.Col, length: label.Length, message: label.Message})
+	}
+
+	width := 1
+	for _, annotations := range byFile {
+		for _, a := range annotations {
+			width = max(width, len(strconv.Itoa(a.line)))
+		}
+	}
+	gutter := strings.Repeat(" ", width)
+
+	for i, file := range files {
+		annotations := byFile[file]
+		arrow := "-->"
+		if i > 0 {
+			arrow = ":::"
+		}
+		fmt.Fprintf(r.w, "%s%s %s\n", gutter, r.paint(ansiBlue, arrow), location(file, annotations[0].line, annotations[0].col))
+
+		lines := r.source(file)
+		if lines == nil || slices.IndexFunc(annotations, func(a annotation) bool { return a.line >= 1 }) < 0 {
+			continue
+		}
+		fmt.Fprintf(r.w, "%s %s\n", gutter, r.paint(ansiBlue, "|"))
+		r.renderSnippet(lines, annotations, width, severity)
+	}
+
+	for _, note := range err.Notes {
+		fmt.Fprintf(r.w, "%s %s %s\n", gutter, r.paint(ansiBlue, "="), r.paint(ansiBold, "note")+": "+note)
+	}
+	if err.Help != "" {
+		fmt.Fprintf(r.w, "%s %s %s\n", gutter, r.paint(ansiBlue, "="), r.paint(ansiCyan, "help")+": "+err.Help)
+	}
+	fmt.Fprintln(r.w)
+}
+
+// renderSnippet prints each line with an annotation once, in order, with the
+// annotations on it underlined below
+func (r *Renderer) renderSnippet(lines []string, annotations []annotation, width int, severity string) {
+	sort.SliceStable(annotations, func(i, j int) bool {
+		if annotations[i].line != annotations[j].line {
+			return annotations[i].line < annotations[j].line
+		}
+		return annotations[i].primary && !annotations[j].primary
+	})
+
+	last := 0
+	for _, a := range annotations {
+		if a.line < 1 || a.line > len(lines) {
+			continue
+		}
+		text := lines[a.line-1]
+		if a.line != last {
+			if last != 0 && a.line > last+1 {
+				fmt.Fprintf(r.w, "%s\n", r.paint(ansiBlue, "..."))
+			}
+			fmt.Fprintf(r.w, "%s %s %s\n", r.paint(ansiBlue, fmt.Sprintf("%*d", width, a.line)), r.paint(ansiBlue, "|"), text)
+			last = a.line
+		}
+
+		col := min(max(a.col, 1), len(text)+1)
+		length := a.length
+		if length <= 0 {
+			length = tokenLength(text, col)
+		}
+		length = max(min(length, len(text)-col+1), 1)
+
+		// keep tabs so the marks line up with the text above them
+		var pad strings.Builder
+		for _, ch := range text[:col-1] {
+			if ch == '\t' {
+				pad.WriteByte('\t')
+			} else {
+				pad.WriteByte(' ')
+			}
+		}
+		mark, color := "-", ansiBlue
+		if a.primary {
+			mark, color = "^", severity
+		}
+		underline := strings.Repeat(mark, length)
+		if a.message != "" {
+			underline += " " + a.message
+		}
+		fmt.Fprintf(r.w, "%s %s %s%s\n", strings.Repeat(" ", width), r.paint(ansiBlue, "|"), pad.String(), r.paint(color, underline))
+	}
+}
+
+// tokenLength is the width of the token starting at col of text, for errors
+// that only know where their node starts
+func tokenLength(text string, col int) int {
+	rest := text[col-1:]
+	lx := lexer.New(rest)
+	first := lx.NextToken()
+	if first.Type == token.EOF || first.Column != 1 {
+		return 1
+	}
+	// the next token's column covers literals whose text the lexer unquotes
+	if next := lx.NextToken(); next.Type != token.EOF && next.Line == 1 && next.Column-1 <= len(rest) {
+		if span := strings.TrimRight(rest[:next.Column-1], " \t"); span != "" {
+			return len(span)
+		}
+	}
+	return max(len(first.Literal), 1)
+}
+
+func (r *Renderer) source(file string) []string {
+	if lines, ok := r.sources[file]; ok {
+		return lines
+	}
+	var lines []string
+	if data, err := os.ReadFile(file); err == nil {
+		lines = strings.Split(string(data), "\n")
+	}
+	r.sources[file] = lines
+	return lines
+}
+
+func (r *Renderer) paint(color, s string) string {
+	if !r.color {
+		return s
+	}
+	return color + s + ansiReset
+}
+
+func location(file string, line, col int) string {
+	if line < 1 {
+		// errors about code the checker made up have no position
+		return file
+	}
+	if file == "" {
+		return fmt.Sprintf("%d:%d", line, col)
+	}
+	return fmt.Sprintf("%s:%d:%d", file, line, col)
+}
+
+type jsonLabel struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Length  int    `json:"length"`
+	Message string `json:"message"`
+}
+
+type jsonDiagnostic struct {
+	Severity string      `json:"severity"`
+	Code     string      `json:"code,omitempty"`
+	Message  string      `json:"message"`
+	File     string      `json:"file"`
+	Line     int         `json:"line"`
+	Column   int         `json:"column"`
+	Length   int         `json:"length"`
+	Labels   []jsonLabel `json:"labels"`
+	Notes    []string    `json:"notes"`
+	Help     string      `json:"help,omitempty"`
+}
+
+func (r *Renderer) renderJSON(err PositionError) {
+	d := jsonDiagnostic{
+		Severity: err.Severity.String(),
+		Code:     string(err.Code),
+		Message:  err.Message,
+		File:     err.File,
+		Line:     err.Line,
+		Column:   err.Col,
+		Length:   r.spanLength(err.File, err.Line, err.Col, err.Length),
+		Labels:   []jsonLabel{},
+		Notes:    []string{},
+		Help:     err.Help,
+	}
+	for _, label := range err.Labels {
+		d.Labels = append(d.Labels, jsonLabel{
+			File:    label.File,
+			Line:    label.Line,
+			Column:  label.Col,
+			Length:  r.spanLength(label.File, label.Line, label.Col, label.Length),
+			Message: label.Message,
+		})
+	}
+	d.Notes = append(d.Notes, err.Notes...)
+
+	// keep signatures such as func<(int) -> int> readable
+	enc := json.NewEncoder(r.w)
+	enc.SetEscapeHTML(false)
+	enc.Encode(d)
+}
+
+// spanLength measures spans without a length from the source when it can
+// be found
+func (r *Renderer) spanLength(file string, line, col, length int) int {
+	return SpanLength(r.source(file), line, col, length)
+}
+
+// SpanLength is the number of columns a span at line:col of lines covers: its
+// length when it has one, and otherwise the width of the token there
+func SpanLength(lines []string, line, col, length int) int {
+	if length > 0 {
+		return length
+	}
+	if line < 1 || line > len(lines) || col < 1 || col > len(lines[line-1]) {
+		return 1
+	}
+	return tokenLength(lines[line-1], col)
+}
@@ -2,7 +2,6 @@ module sydney
 
 go 1.25.0
 
-require (
-	golang.org/x/sys v0.42.0 // indirect
-	golang.org/x/term v0.41.0 // indirect
-)
+require golang.org/x/term v0.41.0
+
+require golang.org/x/sys v0.42.0 // indirect
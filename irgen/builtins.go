@@ -146,7 +146,7 @@ func (e *Emitter) wrapIntoResult(val string, typ IrType) (string, IrType) {
 	e.emit(line)
 
 	// heap-allocate so the result survives function returns
-	rt := GetResultTaggedUnion(typ)
+	rt := GetResultTaggedUnion(typ, IrPtr)
 	resultAddr := e.tmp()
 	line = fmt.Sprintf("%s = call ptr @sydney_gc_alloc(i64 24)", resultAddr)
 	e.emit(line)
@@ -235,8 +235,12 @@ func (e *Emitter) emitAppendCall(expr *ast.CallExpr) (string, IrType) {
 	line = fmt.Sprintf("%s = load ptr, ptr %s", data, dataPtr)
 	e.emit(line)
 
+	stride := "8"
+	if isTupleIrType(valType) {
+		stride = e.emitSizeOf(valType)
+	}
 	newBytes := e.tmp()
-	line = fmt.Sprintf("%s = mul i64 %s, 8", newBytes, newLen)
+	line = fmt.Sprintf("%s = mul i64 %s, %s", newBytes, newLen, stride)
 	e.emit(line)
 	newData := e.tmp()
 	line = fmt.Sprintf("%s = call ptr @sydney_gc_alloc(i64 %s)", newData, newBytes)
@@ -244,7 +248,7 @@ func (e *Emitter) emitAppendCall(expr *ast.CallExpr) (string, IrType) {
 
 	// copy memory
 	oldBytes := e.tmp()
-	line = fmt.Sprintf("%s = mul i64 %s, 8", oldBytes, l)
+	line = fmt.Sprintf("%s = mul i64 %s, %s", oldBytes, l, stride)
 	e.emit(line)
 	line = fmt.Sprintf("call void @llvm.memcpy.p0.p0.i64(ptr %s, ptr %s, i64 %s, i1 false)", newData, data, oldBytes)
 	e.emit(line)
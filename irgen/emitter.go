@@ -43,6 +43,9 @@ type funcState struct {
 	depth     int
 	allocaBuf *bytes.Buffer
 	bodyBuf   *bytes.Buffer
+	defers    string
+	block     string
+	inFunc    bool
 }
 
 type LoopLabels struct {
@@ -109,6 +112,10 @@ type Emitter struct {
 	loopStack       []*LoopLabels
 	blockTerminated bool
 	currentBlock    string
+
+	// defers is the alloca heading the current function's list of deferred
+	// closures, or "" when the function has no defer statement
+	defers string
 }
 
 func generateIndents() map[int]string {
@@ -235,6 +242,21 @@ func (e *Emitter) collect(n ast.Node) *ast.Program {
 			name: globalName,
 			typ:  SydneyTypeToIrType(node.Type),
 		}
+	case *ast.TupleDeclarationStmt:
+		for _, ident := range node.Names {
+			if ident.Value == "_" {
+				continue
+			}
+			name := ident.Value
+			if e.currentModule != "" {
+				name = e.moduleMangle(e.currentModule, name)
+			}
+			globalName := e.global(name)
+			e.globals[globalName] = irGlobal{
+				name: globalName,
+				typ:  SydneyTypeToIrType(ident.GetResolvedType()),
+			}
+		}
 	case *ast.FunctionDeclarationStmt:
 		fType, _ := node.Type.(types.FunctionType)
 		name := node.Name.Value
@@ -309,6 +331,8 @@ func (e *Emitter) collectStrings(n ast.Node) {
 		}
 	case *ast.VarDeclarationStmt:
 		e.collectStrings(node.Value)
+	case *ast.TupleDeclarationStmt:
+		e.collectStrings(node.Value)
 	case *ast.VarAssignmentStmt:
 		e.collectStrings(node.Value)
 	case *ast.IndexAssignmentStmt:
@@ -354,6 +378,10 @@ func (e *Emitter) collectStrings(n ast.Node) {
 		for _, elem := range node.Elements {
 			e.collectStrings(elem)
 		}
+	case *ast.TupleLiteral:
+		for _, elem := range node.Elements {
+			e.collectStrings(elem)
+		}
 	case *ast.HashLiteral:
 		keys := make([]ast.Expr, 0, len(node.Pairs))
 		for key := range node.Pairs {
@@ -386,6 +414,12 @@ func (e *Emitter) collectStrings(n ast.Node) {
 		if node.NoneArm != nil {
 			e.collectStrings(node.NoneArm.Body)
 		}
+		for _, arm := range node.Arms {
+			e.collectStrings(arm.Body)
+		}
+		if node.Default != nil {
+			e.collectStrings(node.Default)
+		}
 	case *ast.MatchTypeExpr:
 		e.collectStrings(node.Subject)
 		for _, arm := range node.Arms {
@@ -396,11 +430,15 @@ func (e *Emitter) collectStrings(n ast.Node) {
 		}
 	case *ast.SpawnStmt:
 		e.collectStrings(node.CallExpr)
+	case *ast.DeferStmt:
+		e.collectStrings(node.CallExpr)
 	case *ast.SendStmt:
 		e.collectStrings(node.Chan)
 		e.collectStrings(node.Value)
 	case *ast.ReceiveExpr:
 		e.collectStrings(node.Chan)
+	case *ast.TryExpr:
+		e.collectStrings(node.Value)
 	case *ast.ChannelConstructorExpr:
 		if node.Capacity != nil {
 			e.collectStrings(node.Capacity)
@@ -736,7 +774,6 @@ func (e *Emitter) emitJmp(label string) {
 }
 
 func (e *Emitter) beginFunction() funcState {
-	e.inFunc = true
 	state := funcState{
 		scope:     e.scope,
 		tmpIdx:    e.tmpIdx,
@@ -744,14 +781,19 @@ func (e *Emitter) beginFunction() funcState {
 		depth:     e.depth,
 		allocaBuf: e.allocaBuf,
 		bodyBuf:   e.bodyBuf,
+		defers:    e.defers,
+		block:     e.currentBlock,
+		inFunc:    e.inFunc,
 	}
 
+	e.inFunc = true
 	e.scope = newScope(e.scope)
 	e.tmpIdx = 0
 	e.lblIdx = 0
 	e.currentBlock = "entry"
 	e.allocaBuf = &bytes.Buffer{}
 	e.bodyBuf = &bytes.Buffer{}
+	e.defers = ""
 	return state
 }
 
@@ -762,7 +804,9 @@ func (e *Emitter) endFunction(state funcState) {
 	e.depth = state.depth
 	e.allocaBuf = state.allocaBuf
 	e.bodyBuf = state.bodyBuf
-	e.inFunc = false
+	e.defers = state.defers
+	e.currentBlock = state.block
+	e.inFunc = state.inFunc
 	e.blockTerminated = false
 }
 
@@ -781,6 +825,8 @@ func (e *Emitter) emitStmt(stmt ast.Node) (string, IrType, bool) {
 		val, valType = e.emitExpr(s.Expr)
 	case *ast.VarDeclarationStmt:
 		val, valType = e.emitVarDecl(s)
+	case *ast.TupleDeclarationStmt:
+		val, valType = e.emitTupleDecl(s)
 	case *ast.VarAssignmentStmt:
 		val, valType = e.emitVariableAssignment(s)
 	case *ast.ReturnStmt:
@@ -820,6 +866,8 @@ func (e *Emitter) emitStmt(stmt ast.Node) (string, IrType, bool) {
 		return "", IrUnit, false
 	case *ast.SpawnStmt:
 		e.emitSpawn(s)
+	case *ast.DeferStmt:
+		e.emitDefer(s)
 	case *ast.SendStmt:
 		e.emitSend(s)
 	}
@@ -893,6 +941,8 @@ func (e *Emitter) emitExprInner(expr ast.Expr) (string, IrType) {
 		return e.emitStructLiteral(expr)
 	case *ast.ArrayLiteral:
 		return e.emitArrayLiteral(expr)
+	case *ast.TupleLiteral:
+		return e.emitTupleLiteral(expr)
 	case *ast.FunctionLiteral:
 		return e.emitClosure(expr)
 	case *ast.HashLiteral:
@@ -940,6 +990,10 @@ func (e *Emitter) emitExprInner(expr ast.Expr) (string, IrType) {
 		if _, ok := lt.(types.MapType); ok {
 			return e.emitMapIndexExpr(expr)
 		}
+
+		if _, ok := lt.(types.TupleType); ok {
+			return e.emitTupleIndexExpr(expr)
+		}
 	case *ast.ScopeAccessExpr:
 		return e.emitScopeAccessExpr(expr)
 	case *ast.MatchExpr:
@@ -950,6 +1004,8 @@ func (e *Emitter) emitExprInner(expr ast.Expr) (string, IrType) {
 		return e.emitSliceExpr(expr)
 	case *ast.ReceiveExpr:
 		return e.emitReceive(expr)
+	case *ast.TryExpr:
+		return e.emitTryExpr(expr)
 	case *ast.ChannelConstructorExpr:
 		return e.emitChannelConstructor(expr)
 	}
@@ -1143,6 +1199,12 @@ func (e *Emitter) emitPrefixExpr(expr *ast.PrefixExpr) (string, IrType) {
 }
 
 func (e *Emitter) emitCallExpr(expr *ast.CallExpr) (string, IrType) {
+	if sel, ok := expr.Function.(*ast.SelectorExpr); ok {
+		if enum, ok := sel.ContainerType.(types.EnumType); ok {
+			return e.emitEnumConstructor(enum, sel, expr.Arguments)
+		}
+	}
+
 	if ident, ok := expr.Function.(*ast.Identifier); ok {
 		name := ident.Value
 
@@ -1238,10 +1300,8 @@ func (e *Emitter) emitCallExpr(expr *ast.CallExpr) (string, IrType) {
 			return e.emitInterfaceMethodCall(expr, sel, castTo)
 		}
 
-		if ident, ok := sel.Left.(*ast.Identifier); ok {
-			if iface, ok := ident.ResolvedType.(types.InterfaceType); ok {
-				return e.emitInterfaceMethodCall(expr, sel, &iface)
-			}
+		if iface, ok := sel.Left.GetResolvedType().(types.InterfaceType); ok {
+			return e.emitInterfaceMethodCall(expr, sel, &iface)
 		}
 	}
 
@@ -1318,11 +1378,6 @@ func (e *Emitter) emitInterfaceType(sname, iname string, methods []string) {
 }
 
 func (e *Emitter) emitVarDecl(stmt *ast.VarDeclarationStmt) (string, IrType) {
-	global, isGlobal := e.globals[e.global(stmt.Name.Value)]
-	if e.inFunc {
-		isGlobal = false
-	}
-
 	var val string
 	var valType IrType
 	if stmt.Value != nil {
@@ -1337,15 +1392,26 @@ func (e *Emitter) emitVarDecl(stmt *ast.VarDeclarationStmt) (string, IrType) {
 		valType = IrPtr
 	}
 
-	name := stmt.Name.Value
+	e.declareVar(stmt.Name.Value, stmt.Type, val, valType)
+
+	return val, valType
+}
+
+// declareVar stores the initial value of a variable: in its global at the
+// top level, or in a new alloca inside functions
+func (e *Emitter) declareVar(name string, t types.Type, val string, valType IrType) {
+	global, isGlobal := e.globals[e.global(name)]
+	if e.inFunc {
+		isGlobal = false
+	}
+
 	if isGlobal {
-		name = global.name
-		e.emitStore(valType.String(), val, name)
-		e.emitCall("", "", "@sydney_gc_add_global_root", []string{getCallArg("ptr", name)})
+		e.emitStore(valType.String(), val, global.name)
+		e.emitGlobalRoot(global.name, t)
 	} else if e.currentModule != "" && !e.inFunc {
 		name = e.global(e.moduleMangle(e.currentModule, name))
 		e.emitStore(valType.String(), val, name)
-		e.emitCall("", "", "@sydney_gc_add_global_root", []string{getCallArg("ptr", name)})
+		e.emitGlobalRoot(name, t)
 	} else {
 		allocaName := e.alloca(name)
 		e.emitAlloca(allocaName, valType)
@@ -1353,8 +1419,67 @@ func (e *Emitter) emitVarDecl(stmt *ast.VarDeclarationStmt) (string, IrType) {
 		e.emitStore(valType.String(), val, allocaName)
 		e.scope.set(name, irLocal{alloca: allocaName, typ: valType})
 	}
+}
 
-	return val, valType
+// emitTupleDecl declares one variable per value of a tuple
+func (e *Emitter) emitTupleDecl(stmt *ast.TupleDeclarationStmt) (string, IrType) {
+	tuple, tupleType := e.emitExpr(stmt.Value)
+	for i, ident := range stmt.Names {
+		if ident.Value == "_" {
+			continue
+		}
+		val := e.tmp()
+		e.emit(fmt.Sprintf("%s = extractvalue %s %s, %d", val, tupleType, tuple, i))
+		e.declareVar(ident.Value, ident.GetResolvedType(), val, SydneyTypeToIrType(ident.GetResolvedType()))
+	}
+
+	return "", IrUnit
+}
+
+// emitTupleLiteral builds a tuple as a struct value, one insertvalue per
+// element
+func (e *Emitter) emitTupleLiteral(lit *ast.TupleLiteral) (string, IrType) {
+	tt := lit.GetResolvedType().(types.TupleType)
+	tupleType := TupleIrType(tt)
+
+	tuple := "undef"
+	for i, element := range lit.Elements {
+		val, valType := e.emitExpr(element)
+		if tt.Types[i] == types.Any && element.GetResolvedType() != types.Any {
+			val = e.emitBoxAny(val, valType)
+			valType = IrPtr
+		}
+		next := e.tmp()
+		e.emit(fmt.Sprintf("%s = insertvalue %s %s, %s %s, %d", next, tupleType, tuple, valType, val, i))
+		tuple = next
+	}
+
+	return tuple, tupleType
+}
+
+func (e *Emitter) emitTupleIndexExpr(expr *ast.IndexExpr) (string, IrType) {
+	tuple, tupleType := e.emitExpr(expr.Left)
+	idx := expr.Index.(*ast.IntegerLiteral).Value
+
+	result := e.tmp()
+	e.emit(fmt.Sprintf("%s = extractvalue %s %s, %d", result, tupleType, tuple, idx))
+
+	return result, SydneyTypeToIrType(expr.ResolvedType)
+}
+
+// emitGlobalRoot registers a global with the collector. A tuple registers
+// each of its values, as the collector reads a root as a single pointer
+func (e *Emitter) emitGlobalRoot(name string, t types.Type) {
+	tuple, ok := t.(types.TupleType)
+	if !ok {
+		e.emitCall("", "", "@sydney_gc_add_global_root", []string{getCallArg("ptr", name)})
+		return
+	}
+	for i := range tuple.Types {
+		field := e.tmp()
+		e.emit(fmt.Sprintf("%s = getelementptr %s, ptr %s, i32 0, i32 %d", field, TupleIrType(tuple), name, i))
+		e.emitCall("", "", "@sydney_gc_add_global_root", []string{getCallArg("ptr", field)})
+	}
 }
 
 func (e *Emitter) emitVariableAssignment(stmt *ast.VarAssignmentStmt) (string, IrType) {
@@ -1382,6 +1507,9 @@ func (e *Emitter) getZeroValue(t types.Type) (string, IrType) {
 	case types.Bool:
 		return "0", IrBool
 	}
+	if tt, ok := t.(types.TupleType); ok {
+		return "zeroinitializer", TupleIrType(tt)
+	}
 
 	return "null", IrPtr
 }
@@ -1397,6 +1525,9 @@ func (e *Emitter) getZeroValueFromIrType(t IrType) string {
 	case IrInt8:
 		return "0"
 	}
+	if isTupleIrType(t) {
+		return "zeroinitializer"
+	}
 
 	return "null"
 }
@@ -1439,10 +1570,12 @@ func (e *Emitter) emitSelectorAssignment(stmt *ast.SelectorAssignmentStmt) (stri
 
 func (e *Emitter) emitReturnStmt(stmt *ast.ReturnStmt) (string, IrType) {
 	if stmt.ReturnValue == nil {
+		e.emitDeferredCalls()
 		e.emit("ret void")
 		return "", IrUnit
 	}
 	val, typ := e.emitExpr(stmt.ReturnValue)
+	e.emitDeferredCalls()
 	line := fmt.Sprintf("ret %s %s", typ, val)
 	e.emit(line)
 	return val, typ
@@ -1551,7 +1684,7 @@ func (e *Emitter) emitForInStmtArr(stmt *ast.ForInStmt) (string, IrType) {
 	}
 
 	// bind value: v = arr[idx]
-	elemType := SydneyTypeToIrType(stmt.Value.GetResolvedType())
+	elemType := SydneyTypeToIrType(stmt.Iterable.GetResolvedType().(types.ArrayType).ElemType)
 	iter2 := e.tmp()
 	e.emitLoad(iter2, iterType.String(), iterAlloca)
 	dataPtr := e.tmp()
@@ -1563,10 +1696,14 @@ func (e *Emitter) emitForInStmtArr(stmt *ast.ForInStmt) (string, IrType) {
 	elemVal := e.tmp()
 	e.emitLoad(elemVal, elemType.String(), elemPtr)
 
-	valAlloca := e.alloca(stmt.Value.Value)
-	e.emitAlloca(valAlloca, elemType)
-	e.emitStore(elemType.String(), elemVal, valAlloca)
-	e.scope.set(stmt.Value.Value, irLocal{alloca: valAlloca, typ: elemType})
+	if stmt.Names != nil {
+		e.bindTupleLocals(stmt.Names, elemVal, elemType)
+	} else {
+		valAlloca := e.alloca(stmt.Value.Value)
+		e.emitAlloca(valAlloca, elemType)
+		e.emitStore(elemType.String(), elemVal, valAlloca)
+		e.scope.set(stmt.Value.Value, irLocal{alloca: valAlloca, typ: elemType})
+	}
 
 	// compile body
 	e.emitBlock(stmt.Body)
@@ -1677,15 +1814,21 @@ func (e *Emitter) emitForInStmtMap(stmt *ast.ForInStmt) (string, IrType) {
 	finalType := valType
 	if valType == IrPtr {
 		finalVal = e.emitIntToPtr(rawVal)
+	} else if isTupleIrType(valType) {
+		finalVal, _ = e.fromI64(rawVal, valType)
 	} else if valType == IrFloat {
 		finalVal = e.tmp()
 		e.emit(fmt.Sprintf("%s = bitcast i64 %s to double", finalVal, rawVal))
 	}
 
-	valAlloca := e.alloca(stmt.Value.Value)
-	e.emitAlloca(valAlloca, finalType)
-	e.emitStore(finalType.String(), finalVal, valAlloca)
-	e.scope.set(stmt.Value.Value, irLocal{alloca: valAlloca, typ: finalType})
+	if stmt.Names != nil {
+		e.bindTupleLocals(stmt.Names, finalVal, finalType)
+	} else {
+		valAlloca := e.alloca(stmt.Value.Value)
+		e.emitAlloca(valAlloca, finalType)
+		e.emitStore(finalType.String(), finalVal, valAlloca)
+		e.scope.set(stmt.Value.Value, irLocal{alloca: valAlloca, typ: finalType})
+	}
 
 	// compile body
 	e.emitBlock(stmt.Body)
@@ -1708,6 +1851,22 @@ func (e *Emitter) emitForInStmtMap(stmt *ast.ForInStmt) (string, IrType) {
 	return "", IrUnit
 }
 
+// bindTupleLocals destructures a loop's tuple element into locals
+func (e *Emitter) bindTupleLocals(names []*ast.Identifier, tuple string, tupleType IrType) {
+	for i, ident := range names {
+		if ident.Value == "_" {
+			continue
+		}
+		typ := SydneyTypeToIrType(ident.GetResolvedType())
+		val := e.tmp()
+		e.emit(fmt.Sprintf("%s = extractvalue %s %s, %d", val, tupleType, tuple, i))
+		valAlloca := e.alloca(ident.Value)
+		e.emitAlloca(valAlloca, typ)
+		e.emitStore(typ.String(), val, valAlloca)
+		e.scope.set(ident.Value, irLocal{alloca: valAlloca, typ: typ})
+	}
+}
+
 func (e *Emitter) emitIfExpr(expr *ast.IfExpr) (string, IrType) {
 	cond, _ := e.emitExpr(expr.Condition) // emit condition, typechecker enforces this is bool
 
@@ -1807,6 +1966,7 @@ func (e *Emitter) emitFunction(decl *ast.FunctionDeclarationStmt) (string, IrTyp
 		e.emitStore(paramIrTypes[i].String(), "%"+pName, allocaName)
 		e.scope.set(pName, irLocal{alloca: allocaName, typ: SydneyTypeToIrType(fType.Params[i])})
 	}
+	e.beginDefers(decl.Body)
 
 	e.depth = 0
 	e.pushScope()
@@ -1814,6 +1974,7 @@ func (e *Emitter) emitFunction(decl *ast.FunctionDeclarationStmt) (string, IrTyp
 	e.depth = 1
 	e.popScope()
 	if !hasReturn && !e.blockTerminated {
+		e.emitDeferredCalls()
 		if ret == IrUnit {
 			e.emit("ret void")
 		} else {
@@ -1901,6 +2062,7 @@ func (e *Emitter) emitClosure(expr *ast.FunctionLiteral) (string, IrType) {
 		e.emitStore(paramIrTypes[i].String(), "%"+pName, allocaName)
 		e.scope.set(pName, irLocal{alloca: allocaName, typ: SydneyTypeToIrType(expr.Type.Params[i])})
 	}
+	e.beginDefers(expr.Body)
 	e.depth = 0
 
 	// self-reference for recursive closures
@@ -1924,6 +2086,7 @@ func (e *Emitter) emitClosure(expr *ast.FunctionLiteral) (string, IrType) {
 
 	e.depth = 1
 	if !hasReturn && !e.blockTerminated {
+		e.emitDeferredCalls()
 		if retType == IrUnit {
 			e.emit("ret void")
 		} else {
@@ -1989,6 +2152,10 @@ func (e *Emitter) emitSelectorExpr(expr *ast.SelectorExpr) (string, IrType) {
 	//  %t2 = load i64, ptr %t1
 	//  ; %t2 is the result
 
+	if enum, ok := expr.ContainerType.(types.EnumType); ok {
+		return e.emitEnumConstructor(enum, expr, nil)
+	}
+
 	val := expr.Value.(*ast.Identifier).Value // this needs to be changed since we might have Circle.Point.x
 
 	t := expr.ContainerType.(types.StructType)
@@ -2014,6 +2181,50 @@ func (e *Emitter) emitSelectorExpr(expr *ast.SelectorExpr) (string, IrType) {
 	return result, retType
 }
 
+// enumIrType is the layout of one variant: its tag followed by its values
+func enumIrType(payload []types.Type) IrType {
+	fields := []string{IrInt.String()}
+	for _, t := range payload {
+		fields = append(fields, SydneyTypeToIrType(t).String())
+	}
+	return BasicIrType("{ " + strings.Join(fields, ", ") + " }")
+}
+
+func (e *Emitter) emitEnumConstructor(enum types.EnumType, sel *ast.SelectorExpr, args []ast.Expr) (string, IrType) {
+	//; Shape.Circle(1.5)
+	//%t0 = getelementptr { i64, double }, ptr null, i32 1
+	//%t1 = ptrtoint ptr %t0 to i64
+	//%t2 = call ptr @sydney_gc_alloc(i64 %t1)
+	//%t3 = getelementptr { i64, double }, ptr %t2, i32 0, i32 0
+	//store i64 0, ptr %t3
+	//%t4 = getelementptr { i64, double }, ptr %t2, i32 0, i32 1
+	//store double 1.5, ptr %t4
+	tag := enum.VariantIndex(sel.Value.(*ast.Identifier).Value)
+	lt := enumIrType(enum.Payloads[tag])
+
+	vals := make([]string, len(args))
+	valTypes := make([]IrType, len(args))
+	for i, arg := range args {
+		vals[i], valTypes[i] = e.emitExpr(arg)
+	}
+
+	// tuple payloads are stored inline, so fields are not all 8 bytes
+	size := e.emitSizeOf(lt)
+	result := e.tmp()
+	e.emitGCAlloc(result, size)
+
+	tagPtr := e.tmp()
+	e.emit(fmt.Sprintf("%s = getelementptr %s, ptr %s, i32 0, i32 0", tagPtr, lt, result))
+	e.emitStore(IrInt.String(), strconv.Itoa(tag), tagPtr)
+	for i := range args {
+		valPtr := e.tmp()
+		e.emit(fmt.Sprintf("%s = getelementptr %s, ptr %s, i32 0, i32 %d", valPtr, lt, result, i+1))
+		e.emitStore(valTypes[i].String(), vals[i], valPtr)
+	}
+
+	return result, IrPtr
+}
+
 func (e *Emitter) getConcreteType(expr ast.Expr) string {
 	switch node := expr.(type) {
 	case *ast.StructLiteral:
@@ -2022,6 +2233,8 @@ func (e *Emitter) getConcreteType(expr ast.Expr) string {
 		return node.ResolvedType.Signature()
 	case *ast.CallExpr:
 		return node.ResolvedType.Signature()
+	case *ast.TryExpr:
+		return node.ResolvedType.Signature()
 	}
 
 	return ""
@@ -2211,6 +2424,11 @@ func (e *Emitter) findFreeVars(stmt *ast.BlockStmt, params []*ast.Identifier) []
 		case *ast.VarDeclarationStmt:
 			paramSet[n.Name.Value] = true
 			walk(n.Value)
+		case *ast.TupleDeclarationStmt:
+			for _, name := range n.Names {
+				paramSet[name.Value] = true
+			}
+			walk(n.Value)
 		case *ast.VarAssignmentStmt:
 			walk(n.Value)
 		case *ast.IndexAssignmentStmt:
@@ -2253,15 +2471,23 @@ func (e *Emitter) findFreeVars(stmt *ast.BlockStmt, params []*ast.Identifier) []
 			for _, elem := range n.Elements {
 				walk(elem)
 			}
+		case *ast.TupleLiteral:
+			for _, elem := range n.Elements {
+				walk(elem)
+			}
 		case *ast.FunctionLiteral:
 			walk(n.Body)
 		case *ast.SpawnStmt:
 			walk(n.CallExpr)
+		case *ast.DeferStmt:
+			walk(n.CallExpr)
 		case *ast.SendStmt:
 			walk(n.Chan)
 			walk(n.Value)
 		case *ast.ReceiveExpr:
 			walk(n.Chan)
+		case *ast.TryExpr:
+			walk(n.Value)
 		case *ast.ChannelConstructorExpr:
 			if n.Capacity != nil {
 				walk(n.Capacity)
@@ -2292,6 +2518,8 @@ func (e *Emitter) emitMapIndexAssignment(stmt *ast.IndexAssignmentStmt) (string,
 	val, valType := e.emitExpr(stmt.Value)
 	if valType == IrPtr {
 		val = e.emitPtrToInt(val)
+	} else if isTupleIrType(valType) {
+		val = e.toI64(val, valType)
 	}
 
 	if stmt.Left.Index.GetResolvedType() == types.String {
@@ -2412,12 +2640,18 @@ func (e *Emitter) emitMapIndexExpr(expr *ast.IndexExpr) (string, IrType) {
 	innerVal := rawVal
 	if innerIrType == IrPtr {
 		innerVal = e.emitIntToPtr(rawVal)
+	} else if isTupleIrType(innerIrType) {
+		innerVal, _ = e.fromI64(rawVal, innerIrType)
 	}
 
 	// allocate option tagged union { i1, innerType }
 	ut := GetOptionTaggedUnion(innerIrType)
+	size := "16"
+	if isTupleIrType(innerIrType) {
+		size = e.emitSizeOf(ut)
+	}
 	result := e.tmp()
-	e.emitGCAlloc(result, "16")
+	e.emitGCAlloc(result, size)
 
 	tagPtr := e.tmp()
 	e.emit(fmt.Sprintf("%s = getelementptr %s, ptr %s, i32 0, i32 0", tagPtr, ut, result))
@@ -2451,9 +2685,15 @@ func (e *Emitter) emitArrayLiteral(arr *ast.ArrayLiteral) (string, IrType) {
 	//
 	//  ; Allocate data buffer (3 elements * 8 bytes)
 	//  %t0 = call ptr @sydney_gc_alloc(i64 24)
-	l := len(arr.Elements) * 8 // 8 bytes since we use 64bit sizes
+	l := strconv.Itoa(len(arr.Elements) * 8) // 8 bytes since we use 64bit sizes
+	if at, ok := arr.GetResolvedType().(types.ArrayType); ok {
+		if tt, ok := at.ElemType.(types.TupleType); ok {
+			l = e.tmp()
+			e.emit(fmt.Sprintf("%s = mul i64 %s, %d", l, e.emitSizeOf(TupleIrType(tt)), len(arr.Elements)))
+		}
+	}
 	buf := e.tmp()
-	e.emitGCAlloc(buf, strconv.Itoa(l))
+	e.emitGCAlloc(buf, l)
 
 	//  Store each element
 	//  %t1 = getelementptr i64, ptr %t0, i32 0
@@ -2529,6 +2769,8 @@ func (e *Emitter) emitHashLiteral(lit *ast.HashLiteral) (string, IrType) {
 		}
 		if valType == IrPtr {
 			val = e.emitPtrToInt(val)
+		} else if isTupleIrType(valType) {
+			val = e.toI64(val, valType)
 		}
 
 		if t.KeyType == types.String {
@@ -2619,6 +2861,8 @@ func (e *Emitter) containsIdentifier(node ast.Node, name string) bool {
 		return e.containsIdentifier(node.Body, name) || e.containsIdentifier(node.Iterable, name)
 	case *ast.VarDeclarationStmt:
 		return e.containsIdentifier(node.Value, name)
+	case *ast.TupleDeclarationStmt:
+		return e.containsIdentifier(node.Value, name)
 	case *ast.VarAssignmentStmt:
 		return e.containsIdentifier(node.Value, name)
 	case *ast.IndexExpr:
@@ -2629,10 +2873,14 @@ func (e *Emitter) containsIdentifier(node ast.Node, name string) bool {
 		return e.containsIdentifier(node.Body, name)
 	case *ast.SpawnStmt:
 		return e.containsIdentifier(node.CallExpr, name)
+	case *ast.DeferStmt:
+		return e.containsIdentifier(node.CallExpr, name)
 	case *ast.SendStmt:
 		return e.containsIdentifier(node.Chan, name) || e.containsIdentifier(node.Value, name)
 	case *ast.ReceiveExpr:
 		return e.containsIdentifier(node.Chan, name)
+	case *ast.TryExpr:
+		return e.containsIdentifier(node.Value, name)
 	case *ast.ChannelConstructorExpr:
 		if node.Capacity != nil {
 			return e.containsIdentifier(node.Capacity, name)
@@ -2706,16 +2954,25 @@ func (e *Emitter) emitResultConstructorCall(isOk bool, expr *ast.CallExpr) (stri
 	arg, argTyp := e.emitExpr(expr.Arguments[0])
 
 	var typ IrType
+	rt := expr.ResolvedType.(*types.ResultType)
+	errTyp := SydneyTypeToIrType(rt.Err())
 	if !isOk {
-		rt := expr.ResolvedType.(*types.ResultType)
 		argTyp = SydneyTypeToIrType(rt.T)
+		if _, ok := rt.Err().(types.InterfaceType); ok {
+			// the boxed error lives on the stack, and the result outlives it
+			arg = e.emitHeapCopy(arg, IrFatPtr)
+		}
 	}
 
-	typ = GetResultTaggedUnion(argTyp)
+	typ = GetResultTaggedUnion(argTyp, errTyp)
+	size := "24"
+	if isTupleIrType(argTyp) || isTupleIrType(errTyp) {
+		size = e.emitSizeOf(typ)
+	}
 
 	result := e.tmp()
 
-	e.emitGCAlloc(result, "24")
+	e.emitGCAlloc(result, size)
 	okPtr := e.tmp()
 	line := fmt.Sprintf("%s = getelementptr %s, ptr %s, i32 0, i32 0", okPtr, typ, result)
 	e.emit(line)
@@ -2733,7 +2990,7 @@ func (e *Emitter) emitResultConstructorCall(isOk bool, expr *ast.CallExpr) (stri
 		line = fmt.Sprintf("%s = getelementptr %s, ptr %s, i32 0, i32 2", errPtr, typ, result)
 		e.emit(line)
 
-		e.emitStore("ptr", "null", errPtr)
+		e.emitStore(errTyp.String(), e.getZeroValueFromIrType(errTyp), errPtr)
 	} else {
 		e.emitStore("i1", "0", okPtr)
 
@@ -2747,7 +3004,7 @@ func (e *Emitter) emitResultConstructorCall(isOk bool, expr *ast.CallExpr) (stri
 		line = fmt.Sprintf("%s = getelementptr %s, ptr %s, i32 0, i32 2", errPtr, typ, result)
 		e.emit(line)
 
-		e.emitStore("ptr", arg, errPtr)
+		e.emitStore(errTyp.String(), arg, errPtr)
 	}
 
 	return result, IrPtr
@@ -2765,8 +3022,12 @@ func (e *Emitter) emitOptionConstructorCall(isSome bool, expr *ast.CallExpr) (st
 	}
 
 	ut := GetOptionTaggedUnion(innerType)
+	size := "16"
+	if isTupleIrType(innerType) {
+		size = e.emitSizeOf(ut)
+	}
 	result := e.tmp()
-	e.emitGCAlloc(result, "16")
+	e.emitGCAlloc(result, size)
 
 	tagPtr := e.tmp()
 	line := fmt.Sprintf("%s = getelementptr %s, ptr %s, i32 0, i32 0", tagPtr, ut, result)
@@ -2788,6 +3049,9 @@ func (e *Emitter) emitOptionConstructorCall(isSome bool, expr *ast.CallExpr) (st
 }
 
 func (e *Emitter) emitMatchExpr(expr *ast.MatchExpr) (string, IrType) {
+	if enum, ok := expr.SubjectType.(types.EnumType); ok {
+		return e.emitEnumMatchExpr(expr, enum)
+	}
 	if expr.SomeArm != nil {
 		return e.emitOptionMatchExpr(expr)
 	}
@@ -2798,7 +3062,11 @@ func (e *Emitter) emitResultMatchExpr(expr *ast.MatchExpr) (string, IrType) {
 	subj, _ := e.emitExpr(expr.Subject)
 	rt := SydneyTypeToIrType(expr.ResolvedType)
 	innerType := SydneyTypeToIrType(expr.SubjectType)
-	ut := GetResultTaggedUnion(innerType)
+	errType := IrType(IrPtr)
+	if t := expr.ErrArm.Pattern.Binding.GetResolvedType(); t != nil {
+		errType = SydneyTypeToIrType(t)
+	}
+	ut := GetResultTaggedUnion(innerType, errType)
 
 	// load tag
 	tagPtr := e.tmp()
@@ -2852,12 +3120,12 @@ func (e *Emitter) emitResultMatchExpr(expr *ast.MatchExpr) (string, IrType) {
 	line = fmt.Sprintf("%s = getelementptr %s, ptr %s, i32 0, i32 2", errPtr, ut, subj)
 	e.emit(line)
 	errVal := e.tmp()
-	e.emitLoad(errVal, "ptr", errPtr)
+	e.emitLoad(errVal, errType.String(), errPtr)
 	bindAlloca = e.tmp() + ".addr"
-	e.emitAlloca(bindAlloca, IrPtr)
-	e.emitStore("ptr", errVal, bindAlloca)
+	e.emitAlloca(bindAlloca, errType)
+	e.emitStore(errType.String(), errVal, bindAlloca)
 	e.pushScope()
-	e.scope.set(expr.ErrArm.Pattern.Binding.Value, irLocal{alloca: bindAlloca, typ: IrPtr})
+	e.scope.set(expr.ErrArm.Pattern.Binding.Value, irLocal{alloca: bindAlloca, typ: errType})
 	errResult, _, _ := e.emitBlock(expr.ErrArm.Body)
 	if errResult == "" {
 		errResult = irZeroValueFromType(rt)
@@ -2887,6 +3155,83 @@ func (e *Emitter) emitResultMatchExpr(expr *ast.MatchExpr) (string, IrType) {
 	return finalVal, rt
 }
 
+// emitTryExpr branches on the tag of value?, loading the value on ok or some.
+// An err or none is returned as is when the enclosing function's return type
+// has the same layout, and otherwise rebuilt with that function's T
+func (e *Emitter) emitTryExpr(expr *ast.TryExpr) (string, IrType) {
+	subj, _ := e.emitExpr(expr.Value)
+	innerType := SydneyTypeToIrType(expr.ResolvedType)
+
+	// ? only passes on errors of the type the function returns
+	var ut, retUt, retInner IrType
+	errType := ResultErrIrType(expr.ReturnType)
+	size := "16"
+	switch rt := expr.ReturnType.(type) {
+	case types.ResultType:
+		ut = GetResultTaggedUnion(innerType, errType)
+		retInner = SydneyTypeToIrType(rt.T)
+		retUt = GetResultTaggedUnion(retInner, errType)
+		size = "24"
+	case types.OptionType:
+		ut = GetOptionTaggedUnion(innerType)
+		retInner = SydneyTypeToIrType(rt.T)
+		retUt = GetOptionTaggedUnion(retInner)
+	}
+
+	tagPtr := e.tmp()
+	line := fmt.Sprintf("%s = getelementptr %s, ptr %s, i32 0, i32 0", tagPtr, ut, subj)
+	e.emit(line)
+	tag := e.tmp()
+	e.emitLoad(tag, "i1", tagPtr)
+
+	okLab := e.label("try.ok")
+	failLab := e.label("try.fail")
+	e.emitBranch(tag, okLab, failLab)
+
+	e.emitLabel(failLab)
+	if retUt.String() == ut.String() {
+		e.emitDeferredCalls()
+		e.emit(fmt.Sprintf("ret ptr %s", subj))
+	} else {
+		if isTupleIrType(retInner) || isTupleIrType(errType) {
+			size = e.emitSizeOf(retUt)
+		}
+		result := e.tmp()
+		e.emitGCAlloc(result, size)
+		retTagPtr := e.tmp()
+		line = fmt.Sprintf("%s = getelementptr %s, ptr %s, i32 0, i32 0", retTagPtr, retUt, result)
+		e.emit(line)
+		e.emitStore("i1", "0", retTagPtr)
+		retValPtr := e.tmp()
+		line = fmt.Sprintf("%s = getelementptr %s, ptr %s, i32 0, i32 1", retValPtr, retUt, result)
+		e.emit(line)
+		e.emitStore(retInner.String(), e.getZeroValueFromIrType(retInner), retValPtr)
+
+		if _, ok := expr.ReturnType.(types.ResultType); ok {
+			errPtr := e.tmp()
+			line = fmt.Sprintf("%s = getelementptr %s, ptr %s, i32 0, i32 2", errPtr, ut, subj)
+			e.emit(line)
+			errVal := e.tmp()
+			e.emitLoad(errVal, errType.String(), errPtr)
+			retErrPtr := e.tmp()
+			line = fmt.Sprintf("%s = getelementptr %s, ptr %s, i32 0, i32 2", retErrPtr, retUt, result)
+			e.emit(line)
+			e.emitStore(errType.String(), errVal, retErrPtr)
+		}
+		e.emitDeferredCalls()
+		e.emit(fmt.Sprintf("ret ptr %s", result))
+	}
+
+	e.emitLabel(okLab)
+	valPtr := e.tmp()
+	line = fmt.Sprintf("%s = getelementptr %s, ptr %s, i32 0, i32 1", valPtr, ut, subj)
+	e.emit(line)
+	val := e.tmp()
+	e.emitLoad(val, innerType.String(), valPtr)
+
+	return val, innerType
+}
+
 func (e *Emitter) emitOptionMatchExpr(expr *ast.MatchExpr) (string, IrType) {
 	subj, _ := e.emitExpr(expr.Subject)
 	rt := SydneyTypeToIrType(expr.ResolvedType)
@@ -2956,6 +3301,100 @@ func (e *Emitter) emitOptionMatchExpr(expr *ast.MatchExpr) (string, IrType) {
 	return finalVal, rt
 }
 
+// emitEnumMatchExpr compares the subject's tag against each arm in turn. An
+// exhaustive match without a default takes its last arm without comparing
+func (e *Emitter) emitEnumMatchExpr(expr *ast.MatchExpr, enum types.EnumType) (string, IrType) {
+	subj, _ := e.emitExpr(expr.Subject)
+	rt := SydneyTypeToIrType(expr.ResolvedType)
+
+	tagPtr := e.tmp()
+	e.emit(fmt.Sprintf("%s = getelementptr { i64 }, ptr %s, i32 0, i32 0", tagPtr, subj))
+	tag := e.tmp()
+	e.emitLoad(tag, IrInt.String(), tagPtr)
+
+	endLab := e.label("match.end")
+
+	result := ""
+	if rt != IrUnit {
+		result = e.tmp()
+		e.emitAlloca(result, rt)
+	}
+
+	allTerminated := true
+	emitArm := func(body *ast.BlockStmt) {
+		armResult, _, _ := e.emitBlock(body)
+		if armResult == "" {
+			armResult = irZeroValueFromType(rt)
+		}
+		terminated := e.blockTerminated
+		e.blockTerminated = false
+		e.popScope()
+		if !terminated {
+			allTerminated = false
+			if rt != IrUnit {
+				e.emitStore(rt.String(), armResult, result)
+			}
+			e.emitJmp(endLab)
+		}
+	}
+
+	for i, arm := range expr.Arms {
+		variant := enum.VariantIndex(arm.Pattern.Variant.Value)
+		armLab := e.label("match.arm")
+		nextLab := ""
+		if i < len(expr.Arms)-1 || expr.Default != nil {
+			nextLab = e.label("match.next")
+			cond := e.tmp()
+			e.emit(fmt.Sprintf("%s = icmp eq i64 %s, %d", cond, tag, variant))
+			e.emitBranch(cond, armLab, nextLab)
+		} else {
+			e.emitJmp(armLab)
+		}
+		e.emitLabel(armLab)
+
+		payload := enum.Payloads[variant]
+		lt := enumIrType(payload)
+		e.pushScope()
+		for j, binding := range arm.Pattern.Bindings {
+			if binding.Value == "_" {
+				continue
+			}
+			valType := SydneyTypeToIrType(payload[j])
+			valPtr := e.tmp()
+			e.emit(fmt.Sprintf("%s = getelementptr %s, ptr %s, i32 0, i32 %d", valPtr, lt, subj, j+1))
+			val := e.tmp()
+			e.emitLoad(val, valType.String(), valPtr)
+			bindAlloca := e.tmp() + ".addr"
+			e.emitAlloca(bindAlloca, valType)
+			e.emitStore(valType.String(), val, bindAlloca)
+			e.scope.set(binding.Value, irLocal{alloca: bindAlloca, typ: valType})
+		}
+		emitArm(arm.Body)
+
+		if nextLab != "" {
+			e.emitLabel(nextLab)
+		}
+	}
+	if expr.Default != nil {
+		e.pushScope()
+		emitArm(expr.Default)
+	}
+
+	e.emitLabel(endLab)
+	if allTerminated {
+		e.emit("unreachable")
+		e.blockTerminated = true
+		return "", rt
+	}
+	finalVal := ""
+	if rt != IrUnit {
+		finalVal = e.tmp()
+		e.emitLoad(finalVal, rt.String(), result)
+	}
+
+	return finalVal, rt
+}
+
 func (e *Emitter) emitTypeMatchExpr(expr *ast.MatchTypeExpr) (string, IrType) {
 	subjType := expr.Subject.GetResolvedType()
 	if subjType == types.Any {
@@ -3197,6 +3636,9 @@ func (e *Emitter) emitSliceExpr(expr *ast.SliceExpr) (string, IrType) {
 		var line string
 		if isArray {
 			size = irTypeSize(SydneyTypeToIrType(at.ElemType))
+			if tt, ok := at.ElemType.(types.TupleType); ok {
+				size = e.emitSizeOf(TupleIrType(tt))
+			}
 			lenPtr := e.tmp()
 			line = fmt.Sprintf("%s = getelementptr { i64, ptr }, ptr %s, i32 0, i32 0", lenPtr, left)
 			e.emit(line)
@@ -3226,7 +3668,11 @@ func (e *Emitter) emitSliceExpr(expr *ast.SliceExpr) (string, IrType) {
 		srcData := e.tmp()
 		e.emitLoad(srcData, "ptr", srcDataPtr)
 		srcOffset := e.tmp()
-		line = fmt.Sprintf("%s = getelementptr i64, ptr %s, i64 %s", srcOffset, srcData, start)
+		elemType := IrType(IrInt)
+		if _, ok := at.ElemType.(types.TupleType); ok {
+			elemType = SydneyTypeToIrType(at.ElemType)
+		}
+		line = fmt.Sprintf("%s = getelementptr %s, ptr %s, i64 %s", srcOffset, elemType, srcData, start)
 		e.emit(line)
 		e.emitCall("", "", "@llvm.memcpy.p0.p0.i64", []string{
 			getCallArg("ptr", dataPtr),
@@ -3275,6 +3721,27 @@ func (e *Emitter) emitSliceExpr(expr *ast.SliceExpr) (string, IrType) {
 	return result, IrPtr
 }
 
+// emitSizeOf asks LLVM for the size of t, for tuples, whose size depends on
+// the layout of their values
+func (e *Emitter) emitSizeOf(t IrType) string {
+	end := e.tmp()
+	e.emit(fmt.Sprintf("%s = getelementptr %s, ptr null, i32 1", end, t))
+	size := e.tmp()
+	e.emit(fmt.Sprintf("%s = ptrtoint ptr %s to i64", size, end))
+	return size
+}
+
+// emitHeapCopy copies the typ that ptr points at into gc memory, for values
+// built on the stack that outlive the function
+func (e *Emitter) emitHeapCopy(ptr string, typ IrType) string {
+	copied := e.tmp()
+	e.emitGCAlloc(copied, e.emitSizeOf(typ))
+	val := e.tmp()
+	e.emitLoad(val, typ.String(), ptr)
+	e.emitStore(typ.String(), val, copied)
+	return copied
+}
+
 func irTypeSize(t IrType) string {
 	switch t {
 	case IrInt, IrFloat, IrPtr, IrFatPtr:
@@ -3431,7 +3898,105 @@ func (e *Emitter) resolveSpawnTarget(callExpr *ast.CallExpr) (string, funcSig) {
 	return "", funcSig{}
 }
 
+// beginDefers gives a function whose body defers calls an empty list of
+// deferred closures. Every return then runs the list first
+func (e *Emitter) beginDefers(body *ast.BlockStmt) {
+	if !e.containsDefer(body) {
+		return
+	}
+	e.defers = e.alloca("defers")
+	e.emitAlloca(e.defers, IrPtr)
+	e.emitStore("ptr", "null", e.defers)
+}
+
+// containsDefer reports whether a function body has a defer statement of its
+// own, rather than one inside a function literal
+func (e *Emitter) containsDefer(body *ast.BlockStmt) bool {
+	found := false
+	ast.Inspect(body, func(node ast.Node) bool {
+		switch node.(type) {
+		case *ast.DeferStmt:
+			found = true
+		case *ast.FunctionLiteral:
+			return false
+		}
+		return !found
+	})
+	return found
+}
+
+// emitDefer evaluates the deferred call's arguments into the locals its
+// closure captures, then pushes the closure onto the function's list, a chain
+// of { closure, next } nodes
+func (e *Emitter) emitDefer(stmt *ast.DeferStmt) {
+	for _, binding := range stmt.Bindings {
+		e.emitVarDecl(binding)
+	}
+	closure, _ := e.emitClosure(stmt.Closure)
+
+	node := e.tmp()
+	e.emitGCAlloc(node, "16")
+	e.emitStore("ptr", closure, node)
+	head := e.tmp()
+	e.emitLoad(head, "ptr", e.defers)
+	nextSlot := e.tmp()
+	line := fmt.Sprintf("%s = getelementptr { ptr, ptr }, ptr %s, i32 0, i32 1", nextSlot, node)
+	e.emit(line)
+	e.emitStore("ptr", head, nextSlot)
+	e.emitStore("ptr", node, e.defers)
+}
+
+// emitDeferredCalls calls the closures deferred so far, last first, ahead of
+// a return
+func (e *Emitter) emitDeferredCalls() {
+	if e.defers == "" || e.blockTerminated {
+		return
+	}
+
+	loopLab := e.label("defer.loop")
+	callLab := e.label("defer.call")
+	doneLab := e.label("defer.done")
+	e.emitJmp(loopLab)
+
+	e.emitLabel(loopLab)
+	node := e.tmp()
+	e.emitLoad(node, "ptr", e.defers)
+	empty := e.tmp()
+	e.emit(fmt.Sprintf("%s = icmp eq ptr %s, null", empty, node))
+	e.emitBranch(empty, doneLab, callLab)
+
+	e.emitLabel(callLab)
+	nextSlot := e.tmp()
+	line := fmt.Sprintf("%s = getelementptr { ptr, ptr }, ptr %s, i32 0, i32 1", nextSlot, node)
+	e.emit(line)
+	next := e.tmp()
+	e.emitLoad(next, "ptr", nextSlot)
+	e.emitStore("ptr", next, e.defers)
+
+	closure := e.tmp()
+	e.emitLoad(closure, "ptr", node)
+	fnPtr := e.tmp()
+	e.emitLoad(fnPtr, "ptr", closure)
+	envSlot := e.tmp()
+	line = fmt.Sprintf("%s = getelementptr { ptr, ptr }, ptr %s, i32 0, i32 1", envSlot, closure)
+	e.emit(line)
+	envPtr := e.tmp()
+	e.emitLoad(envPtr, "ptr", envSlot)
+	e.emitCall("", "", fnPtr, []string{getCallArg("ptr", envPtr)})
+	e.emitJmp(loopLab)
+
+	e.emitLabel(doneLab)
+}
+
 func (e *Emitter) toI64(reg string, typ IrType) string {
+	if isTupleIrType(typ) {
+		// tuples do not fit in a word, so they cross as a pointer to a copy
+		box := e.tmp()
+		e.emitGCAlloc(box, e.emitSizeOf(typ))
+		e.emitStore(typ.String(), reg, box)
+		return e.emitPtrToInt(box)
+	}
+
 	switch typ {
 	case IrInt:
 		return reg
@@ -3455,6 +4020,12 @@ func (e *Emitter) toI64(reg string, typ IrType) string {
 }
 
 func (e *Emitter) fromI64(reg string, typ IrType) (string, IrType) {
+	if isTupleIrType(typ) {
+		r := e.tmp()
+		e.emitLoad(r, typ.String(), e.emitIntToPtr(reg))
+		return r, typ
+	}
+
 	switch typ {
 	case IrInt:
 		return reg, IrInt
@@ -3514,6 +4085,9 @@ func irZeroValueFromType(t IrType) string {
 	case IrFloat:
 		return "0.0"
 	}
+	if isTupleIrType(t) {
+		return "zeroinitializer"
+	}
 
 	return "null"
 }
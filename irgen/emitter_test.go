@@ -624,6 +624,236 @@ func TestE2EOptionMatch(t *testing.T) {
 	runE2ETests(t, tests)
 }
 
+func TestE2EEnumMatch(t *testing.T) {
+	tests := []e2eTestCase{
+		{ // payload bindings and default arm
+			source: `define enum Shape { Circle(int), Rect(int, int), Empty }
+			func area(Shape s) -> int {
+				return match s {
+					Circle(r) -> { r * r; },
+					Rect(w, h) -> { w * h; },
+					_ -> { 0; },
+				};
+			}
+			print(area(Shape.Rect(2, 3)));
+			print(area(Shape.Empty));`,
+			expected: "60",
+		},
+		{ // recursive generic enum
+			source: `define enum Tree<T> { Leaf(T), Node(Tree<T>, Tree<T>) }
+			func sum(Tree<int> t) -> int {
+				return match t {
+					Leaf(v) -> { v; },
+					Node(l, r) -> { sum(l) + sum(r); },
+				};
+			}
+			print(sum(Tree.Node(Tree.Leaf(1), Tree.Leaf(2))));`,
+			expected: "3",
+		},
+		{ // tuple payloads are wider than one word
+			source: `define enum E { A((int, int)), B }
+			func total(E e) -> int {
+				return match e {
+					A(p) -> { p[0] + p[1]; },
+					_ -> { 0; },
+				};
+			}
+			print(total(E.A((1, 2))));
+			print(total(E.B));`,
+			expected: "30",
+		},
+	}
+	runE2ETests(t, tests)
+}
+
+func TestE2ETuples(t *testing.T) {
+	tests := []e2eTestCase{
+		{ // multiple returns and indexing
+			source: `func divmod(int a, int b) -> (int, int) {
+				return (a / b, a % b);
+			}
+			const (q, r) = divmod(17, 5);
+			const t = (q, "x");
+			print(q);
+			print(r);
+			print(t[1]);`,
+			expected: "32x",
+		},
+		{ // arrays of tuples and for-in destructuring
+			source: `mut pairs = [(1, 2), (3, 4)];
+			pairs = append(pairs, (5, 6));
+			mut total = 0;
+			for (i, (a, b) in pairs) {
+				total = total + i + a * b;
+			}
+			print(total);`,
+			expected: "47",
+		},
+	}
+	runE2ETests(t, tests)
+}
+
+func TestE2ETryExpr(t *testing.T) {
+	tests := []e2eTestCase{
+		{ // ok unwraps and err returns early
+			source: `func half(int n) -> result<int> {
+				if (n % 2 != 0) { return err("odd"); }
+				return ok(n / 2);
+			}
+			func quarter(int n) -> result<string> {
+				const q = half(half(n)?)?;
+				return ok("quarter");
+			}
+			match quarter(12) { ok(v) -> { print(v); }, err(e) -> { print(e); }, };
+			match quarter(6) { ok(v) -> { print(v); }, err(e) -> { print(e); }, };`,
+			expected: "quarterodd",
+		},
+		{ // some unwraps and none returns early
+			source: `func find(array<int> xs, int x) -> option<int> {
+				for (i, v in xs) {
+					if (v == x) { return some(i); }
+				}
+				return none();
+			}
+			func both(array<int> xs, int x, int y) -> option<(int, int)> {
+				return some((find(xs, x)?, find(xs, y)?));
+			}
+			match both([4, 5, 6], 6, 4) { some(p) -> { print(p[0] * 10 + p[1]); }, none -> { print(-1); }, };
+			match both([4, 5, 6], 7, 4) { some(p) -> { print(p[0]); }, none -> { print(-1); }, };`,
+			expected: "20-1",
+		},
+	}
+	runE2ETests(t, tests)
+}
+
+func TestE2ETypedErrors(t *testing.T) {
+	tests := []e2eTestCase{
+		{ // enum errors and ?
+			source: `define enum NetError { Timeout(int), Refused }
+			func dial(int n) -> result<int, NetError> {
+				if (n == 0) { return err(NetError.Refused); }
+				if (n > 10) { return err(NetError.Timeout(n)); }
+				return ok(n);
+			}
+			func fetch(int n) -> result<string, NetError> {
+				const fd = dial(n)?;
+				return ok("fetched");
+			}
+			func show(result<string, NetError> r) {
+				match r {
+					ok(v) -> { print(v); },
+					err(e) -> {
+						match e {
+							Timeout(s) -> { print(s); },
+							Refused -> { print("refused"); },
+						};
+					},
+				};
+			}
+			show(fetch(3));
+			show(fetch(0));
+			show(fetch(30));`,
+			expected: "fetchedrefused30",
+		},
+		{ // struct errors
+			source: `define struct ParseError { line int, msg string }
+			func parse(int n) -> result<int, ParseError> {
+				if (n < 0) { return err(ParseError { line: 3, msg: "negative" }); }
+				return ok(n);
+			}
+			match parse(-1) { ok(v) -> { print(v); }, err(e) -> { print(e.msg); print(e.line); }, };`,
+			expected: "negative3",
+		},
+		{ // interface errors
+			source: `define struct ParseError { line int }
+			define interface Describe { describe() -> int }
+			func describe(ParseError p) -> int { return p.line * 2; }
+			func parse(int n) -> result<int, Describe> {
+				return err(ParseError { line: n });
+			}
+			match parse(4) { ok(v) -> { print(v); }, err(d) -> { print(d.describe()); }, };`,
+			expected: "8",
+		},
+	}
+	runE2ETests(t, tests)
+}
+
+func TestE2EDefer(t *testing.T) {
+	tests := []e2eTestCase{
+		{ // last deferred runs first, on every return
+			source: `func work(int n) -> int {
+				defer print("a");
+				defer print("b");
+				if (n == 0) { return 0; }
+				print("c");
+				return n;
+			}
+			print(work(0));
+			print(work(7));`,
+			expected: "ba0cba7",
+		},
+		{ // once per iteration, inside a closure
+			source: `const f = func(int x) {
+				for (mut i = 0; i < x; i = i + 1) {
+					defer print(i);
+				}
+				print("-");
+			};
+			f(3);`,
+			expected: "-210",
+		},
+		{ // interface methods and ? returning early
+			source: `define interface Closer { close() -> int }
+			define struct Sock { fd int }
+			func close(Sock s) -> int { print(s.fd); return 0; }
+			func half(int n) -> result<int> {
+				if (n % 2 != 0) { return err("odd"); }
+				return ok(n / 2);
+			}
+			func read(Closer c, int n) -> result<int> {
+				defer c.close();
+				return ok(half(n)?);
+			}
+			match read(Sock { fd: 3 }, 5) { ok(v) -> { print(v); }, err(e) -> { print(e); }, };
+			match read(Sock { fd: 4 }, 8) { ok(v) -> { print(v); }, err(e) -> { print(e); }, };`,
+			expected: "3odd44",
+		},
+		{ // inside an expression, with arguments as they were at the defer
+			source: `mut g = 1;
+			func work(bool c) -> int {
+				mut local = 1;
+				const x = if (c) {
+					defer print(local + g);
+					1
+				} else {
+					2
+				};
+				local = 5;
+				g = 10;
+				return x;
+			}
+			print(work(true));`,
+			expected: "21",
+		},
+		{ // arguments and receivers are evaluated at the defer
+			source: `define interface Closer { close() -> int }
+			define struct Sock { fd int }
+			func close(Sock s) -> int { print(s.fd); return 0; }
+			mut g = 1;
+			func tick(int n) -> int { print(n); return n; }
+			func work(Closer c) {
+				defer print(tick(1) + g);
+				defer c.close();
+				g = 10;
+				print(5);
+			}
+			work(Sock { fd: 4 });`,
+			expected: "1542",
+		},
+	}
+	runE2ETests(t, tests)
+}
+
 func TestE2ETypeMatch(t *testing.T) {
 	tests := []e2eTestCase{
 		{ // match first arm
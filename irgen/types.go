@@ -2,6 +2,7 @@ package irgen
 
 import (
 	"fmt"
+	"strings"
 	"sydney/types"
 )
 
@@ -64,14 +65,31 @@ func SydneyTypeToIrType(t types.Type) IrType {
 		return IrPtr // this is indicative of an issue where type structs are not pointers consistently
 	case types.ChannelType:
 		return IrInt
+	case types.EnumType:
+		return IrPtr // ptr to { i64 tag, payload... }
 	case types.OptionType:
 		return IrPtr
 	case *types.OptionType:
 		return IrPtr
+	case types.TupleType:
+		return TupleIrType(t.(types.TupleType))
 	}
 	return IrUnit
 }
 
+// TupleIrType is the struct a tuple is passed around as by value
+func TupleIrType(t types.TupleType) IrType {
+	elems := make([]string, len(t.Types))
+	for i, et := range t.Types {
+		elems[i] = SydneyTypeToIrType(et).String()
+	}
+	return BasicIrType(fmt.Sprintf("{ %s }", strings.Join(elems, ", ")))
+}
+
+func isTupleIrType(t IrType) bool {
+	return strings.HasPrefix(t.String(), "{") && t != IrFatPtr
+}
+
 // Tag values for the any tagged union
 const (
 	AnyTagInt    = 0
@@ -97,8 +115,22 @@ func AnyTagForType(t IrType) int {
 	return -1
 }
 
-func GetResultTaggedUnion(t IrType) IrType {
-	return BasicIrType(fmt.Sprintf("{ i1 , %s, ptr }", t))
+// GetResultTaggedUnion is the layout of a result<T, E>: whether it is ok,
+// then the value and the error
+func GetResultTaggedUnion(t IrType, e IrType) IrType {
+	return BasicIrType(fmt.Sprintf("{ i1 , %s, %s }", t, e))
+}
+
+// ResultErrIrType is the IR type of the error held by a result, which the
+// checker leaves as a value or a pointer
+func ResultErrIrType(t types.Type) IrType {
+	switch rt := t.(type) {
+	case types.ResultType:
+		return SydneyTypeToIrType(rt.Err())
+	case *types.ResultType:
+		return SydneyTypeToIrType(rt.Err())
+	}
+	return IrPtr
 }
 
 func GetOptionTaggedUnion(t IrType) IrType {
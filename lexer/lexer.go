@@ -1,6 +1,7 @@
 package lexer
 
 import (
+	"sort"
 	"strconv"
 
 	"sydney/token"
@@ -34,6 +35,7 @@ const (
 	greaterThan = '>'
 	lessThan    = '<'
 	bang        = '!'
+	question    = '?'
 	ampersand   = '&'
 	pipe        = '|'
 
@@ -283,6 +285,8 @@ func (l *Lexer) NextToken() token.Token {
 		} else {
 			tok = l.makeToken(token.Bang, l.char)
 		}
+	case question:
+		tok = l.makeToken(token.Question, l.char)
 	case ampersand:
 		if l.peekChar() == ampersand {
 			char := l.char
@@ -332,8 +336,8 @@ func (l *Lexer) NextToken() token.Token {
 				tok.Type = token.Integer
 			}
 			tok.Literal = literal
-			tok.Line = l.line
-			tok.Column = l.column
+			tok.Line = line
+			tok.Column = col
 			return tok // This is to avoid the l.readChar() call before this functions return
 		} else {
 			tok = l.makeToken(token.Illegal, l.char)
@@ -363,6 +367,7 @@ var keywords = map[string]token.TokenType{
 	"struct":    token.Struct,
 	"define":    token.Define,
 	"interface": token.Interface,
+	"enum":      token.Enum,
 	"pub":       token.Public,
 	"module":    token.Module,
 	"import":    token.Import,
@@ -372,6 +377,7 @@ var keywords = map[string]token.TokenType{
 	"continue":  token.Continue,
 	"in":        token.In,
 	"spawn":     token.Spawn,
+	"defer":     token.Defer,
 	"typeof":    token.TypeOf,
 }
 
@@ -390,6 +396,28 @@ var types = map[string]token.TokenType{
 	"any":    token.AnyType,
 }
 
+// Keywords returns the reserved words of the language, including the names
+// of built in types
+func Keywords() []string {
+	words := make([]string, 0, len(keywords)+len(types))
+	for word := range keywords {
+		words = append(words, word)
+	}
+	for word := range types {
+		if _, ok := keywords[word]; !ok {
+			words = append(words, word)
+		}
+	}
+	sort.Strings(words)
+	return words
+}
+
+// IsTypeName reports whether word names a built in type
+func IsTypeName(word string) bool {
+	_, ok := types[word]
+	return ok
+}
+
 func LookupIdent(ident string) token.TokenType {
 	if tok, ok := keywords[ident]; ok {
 		return tok
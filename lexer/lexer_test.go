@@ -34,6 +34,7 @@ func TestNextToken(t *testing.T) {
 	macro(x, y) { x + y; };
 	define struct Person { age int, name string }
 	define interface Pointer { getX() -> int, setX(int x) }
+	define enum Shape { Circle(float), Empty }
 
 	import "math"
 	pub mut int x
@@ -234,6 +235,18 @@ func TestNextToken(t *testing.T) {
 		{token.RightParen, ")"},
 		{token.RightCurlyBracket, "}"},
 
+		{token.Define, "define"},
+		{token.Enum, "enum"},
+		{token.Identifier, "Shape"},
+		{token.LeftCurlyBracket, "{"},
+		{token.Identifier, "Circle"},
+		{token.LeftParen, "("},
+		{token.FloatType, "float"},
+		{token.RightParen, ")"},
+		{token.Comma, ","},
+		{token.Identifier, "Empty"},
+		{token.RightCurlyBracket, "}"},
+
 		{token.Import, "import"},
 		{token.String, "math"},
 
@@ -543,3 +556,93 @@ func TestSpawnToken(t *testing.T) {
 		}
 	}
 }
+
+func TestDeferToken(t *testing.T) {
+	source := `defer f.close();`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.Defer, "defer"},
+		{token.Identifier, "f"},
+		{token.Dot, "."},
+		{token.Identifier, "close"},
+		{token.LeftParen, "("},
+		{token.RightParen, ")"},
+		{token.Semicolon, ";"},
+		{token.EOF, ""},
+	}
+
+	lexer := New(source)
+	for i, tt := range tests {
+		tok := lexer.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestQuestionToken(t *testing.T) {
+	source := `const f = open(path)?;`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.Const, "const"},
+		{token.Identifier, "f"},
+		{token.Assign, "="},
+		{token.Identifier, "open"},
+		{token.LeftParen, "("},
+		{token.Identifier, "path"},
+		{token.RightParen, ")"},
+		{token.Question, "?"},
+		{token.Semicolon, ";"},
+		{token.EOF, ""},
+	}
+
+	lexer := New(source)
+	for i, tt := range tests {
+		tok := lexer.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestTokenPositions(t *testing.T) {
+	source := `foo(12, "a")
+  3.5;`
+
+	tests := []struct {
+		expectedLiteral string
+		line, column    int
+	}{
+		{"foo", 1, 1},
+		{"(", 1, 4},
+		{"12", 1, 5},
+		{",", 1, 7},
+		{"a", 1, 9},
+		{")", 1, 12},
+		{"3.5", 2, 3},
+		{";", 2, 6},
+	}
+
+	lexer := New(source)
+	for i, tt := range tests {
+		tok := lexer.NextToken()
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+		if tok.Line != tt.line || tok.Column != tt.column {
+			t.Fatalf("tests[%d] - position of %q wrong. expected=%d:%d, got=%d:%d", i, tok.Literal, tt.line, tt.column, tok.Line, tok.Column)
+		}
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"strings"
 	"sydney/ast"
 	"sydney/codegen"
+	"sydney/errors"
 	"sydney/lexer"
 	"sydney/parser"
 	"sydney/token"
@@ -19,6 +20,7 @@ type Loader struct {
 	loaded    *Package
 	imports   []*ast.ImportStatement
 	loading   map[string]bool
+	overlay   map[string]string
 }
 
 type Package struct {
@@ -58,6 +60,9 @@ func NewFromImports(imports []string) *Loader {
 }
 
 func (l *Loader) Read(filename string) (string, error) {
+	if source, ok := l.overlay[filename]; ok {
+		return source, nil
+	}
 	file, err := os.ReadFile(filename)
 	if err != nil {
 		return "", fmt.Errorf("cannot read file %s", filename)
@@ -66,7 +71,7 @@ func (l *Loader) Read(filename string) (string, error) {
 	return string(file), nil
 }
 
-func (l *Loader) Parse(source string) (*ast.Program, []string) {
+func (l *Loader) Parse(source string) (*ast.Program, []errors.PositionError) {
 	lx := lexer.New(source)
 	p := parser.New(lx)
 	program := p.ParseProgram()
@@ -83,7 +88,7 @@ func (l *Loader) LoadPackage(dir string) (*Package, error) {
 		return nil, err
 	}
 
-	var sources []string
+	var sources, files []string
 	for _, entry := range entries {
 		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sy") || strings.HasSuffix(entry.Name(), "_test.sy") {
 			continue
@@ -93,10 +98,12 @@ func (l *Loader) LoadPackage(dir string) (*Package, error) {
 			return nil, err
 		}
 		sources = append(sources, source)
+		files = append(files, filepath.Join(dir, entry.Name()))
 	}
 
 	allStructs := map[string]types.Type{}
 	allInterfaces := map[string]types.Type{}
+	allEnums := map[string]types.Type{}
 	for _, source := range sources {
 		scan := parser.New(lexer.New(source))
 		scan.ParseDefinitions()
@@ -106,16 +113,21 @@ func (l *Loader) LoadPackage(dir string) (*Package, error) {
 		for k, v := range scan.DefinedInterfaces() {
 			allInterfaces[k] = v
 		}
+		for k, v := range scan.DefinedEnums() {
+			allEnums[k] = v
+		}
 	}
 
 	pkg := &Package{}
-	for _, source := range sources {
+	for i, source := range sources {
 		p := parser.New(lexer.New(source))
-		p.SetDefinedTypes(allStructs, allInterfaces)
+		p.SetFile(files[i])
+		p.SetDefinedTypes(allStructs, allInterfaces, allEnums)
 		program := p.ParseProgram()
 		if len(p.Errors()) > 0 {
-			return nil, fmt.Errorf("%s", strings.Join(p.Errors(), "\n"))
+			return nil, errors.List(p.Errors())
 		}
+		program.File = files[i]
 
 		deriveImports := codegen.ScanDeriveImports(source)
 		for _, di := range deriveImports {
@@ -181,6 +193,7 @@ func (l *Loader) Load(visited map[string]bool) ([]*Package, map[string]map[strin
 			child := New(program)
 			child.stdLib = l.stdLib
 			child.sourceDir = l.sourceDir
+			child.overlay = l.overlay
 			childPkgs, childPkgTypes, childGenericNames, err := child.Load(visited)
 			if err != nil {
 				return nil, nil, nil, err
@@ -202,11 +215,17 @@ func (l *Loader) Load(visited map[string]bool) ([]*Package, map[string]map[strin
 }
 
 func (l *Loader) resolveDir(name string) (string, error) {
+	return ResolveImport(l.stdLib, l.sourceDir, name), nil
+}
+
+// ResolveImport is the directory an import path names: relative to the
+// importing source for ./ paths, and in the standard library otherwise
+func ResolveImport(stdlib, sourceDir, name string) string {
 	if strings.HasPrefix(name, "./") {
-		return filepath.Join(l.sourceDir, name), nil
+		return filepath.Join(sourceDir, name)
 	}
 	// stdlib lookup
-	return filepath.Join(l.stdLib, name), nil
+	return filepath.Join(stdlib, name)
 }
 
 func (l *Loader) SetPaths(stdlib, sourceDir string) {
@@ -214,6 +233,12 @@ func (l *Loader) SetPaths(stdlib, sourceDir string) {
 	l.sourceDir = sourceDir
 }
 
+// SetOverlay makes the loader read the given files, keyed by path, from
+// memory instead of disk, as the language server does for unsaved edits
+func (l *Loader) SetOverlay(files map[string]string) {
+	l.overlay = files
+}
+
 func ResolveStdlib(sourceDir string) string {
 	if root := os.Getenv("SYDNEY_PATH"); root != "" {
 		return filepath.Join(root, "stdlib")
@@ -229,6 +254,9 @@ func (l *Loader) ExtractTypes(pkg *Package) map[string]types.Type {
 				if sd, ok := pub.Stmt.(*ast.StructDefinitionStmt); ok {
 					tt[sd.Name.Value] = sd.Type
 				}
+				if ed, ok := pub.Stmt.(*ast.EnumDefinitionStmt); ok {
+					tt[ed.Name.Value] = ed.Type
+				}
 				if id, ok := pub.Stmt.(*ast.InterfaceDefinitionStmt); ok {
 					id.Type.MethodIndices = make(map[string]int)
 					for i, mn := range id.Type.Methods {
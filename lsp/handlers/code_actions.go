@@ -0,0 +1,291 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"sydney/ast"
+	"sydney/lexer"
+	"sydney/loader"
+	"sydney/lsp/messages"
+	"sydney/token"
+	"sydney/types"
+)
+
+const QuickFix = "quickfix"
+
+type CodeActionParams struct {
+	TextDocument messages.TextDocumentIdentifier `json:"textDocument"`
+	Range        messages.Range                  `json:"range"`
+	Context      CodeActionContext               `json:"context"`
+}
+
+type CodeActionContext struct {
+	Diagnostics []Diagnostic `json:"diagnostics"`
+}
+
+type CodeAction struct {
+	Title string                  `json:"title"`
+	Kind  string                  `json:"kind"`
+	Edit  *messages.WorkspaceEdit `json:"edit"`
+}
+
+type CodeActions []CodeAction
+
+func (c CodeActions) Result() {}
+
+func (l *LSP) HandleCodeAction(req *messages.Request) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("CodeAction panicked: %v", r)
+		}
+	}()
+
+	actions := CodeActions{}
+	resp := &messages.Response{
+		Id:      req.Id,
+		Version: messages.Version,
+		Result:  actions,
+	}
+	defer func() {
+		if err := l.WriteResponse(resp); err != nil {
+			log.Printf("%s: Error writing response: %v", messages.CodeAction, err)
+		}
+	}()
+
+	var params CodeActionParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		log.Printf("%s Error unmarshalling params: %v", messages.CodeAction, err)
+		return
+	}
+	a := l.checked(params.TextDocument.URI)
+	if a == nil {
+		return
+	}
+
+	actions = append(actions, importActions(a, params.Range)...)
+	actions = append(actions, implementActions(a, params)...)
+	resp.Result = actions
+}
+
+// importActions offers to import the module of each module:member in range
+// that names a package the file does not import
+func importActions(a *analysis, r messages.Range) []CodeAction {
+	var tokens []token.Token
+	lx := lexer.New(a.src)
+	for tok := lx.NextToken(); tok.Type != token.EOF; tok = lx.NextToken() {
+		tokens = append(tokens, tok)
+	}
+
+	var actions []CodeAction
+	offered := map[string]bool{}
+	for i := 0; i+2 < len(tokens); i++ {
+		mod := tokens[i]
+		if mod.Type != token.Identifier || tokens[i+1].Type != token.Colon || tokens[i+2].Type != token.Identifier {
+			continue
+		}
+		if mod.Line-1 < r.Start.Line || mod.Line-1 > r.End.Line || offered[mod.Literal] || isModule(a, mod.Literal) {
+			continue
+		}
+		// a struct literal field or anything else the checker resolved
+		if _, ok := a.checker.Definition(a.file, mod.Line, mod.Column); ok {
+			continue
+		}
+		path, ok := findPackage(filepath.Dir(a.file), mod.Literal)
+		if !ok {
+			continue
+		}
+		offered[mod.Literal] = true
+
+		uri := url.URL{Path: a.file, Scheme: "file"}
+		at := importPosition(a.document)
+		actions = append(actions, CodeAction{
+			Title: fmt.Sprintf("Add import %q", path),
+			Kind:  QuickFix,
+			Edit: &messages.WorkspaceEdit{Changes: map[string][]messages.TextEdit{
+				uri.String(): {{
+					Range:   messages.Range{Start: at, End: at},
+					NewText: fmt.Sprintf("import %q\n", path),
+				}},
+			}},
+		})
+	}
+	return actions
+}
+
+// findPackage finds the import path of a module named name, looking in the
+// standard library and then beside the file
+func findPackage(dir, name string) (string, bool) {
+	if isPackageDir(filepath.Join(loader.ResolveStdlib(dir), name)) {
+		return name, true
+	}
+	if isPackageDir(filepath.Join(dir, name)) {
+		return "./" + name, true
+	}
+	return "", false
+}
+
+func isPackageDir(dir string) bool {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return false
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".sy") {
+			return true
+		}
+	}
+	return false
+}
+
+// importPosition is the start of the line after the last import, or after
+// the module declaration when there are none
+func importPosition(program *ast.Program) messages.Position {
+	line := 0
+	for _, stmt := range program.Stmts {
+		switch s := stmt.(type) {
+		case *ast.ImportStatement, *ast.ModuleDeclarationStmt:
+			if stmtLine, _ := s.Pos(); stmtLine > line {
+				line = stmtLine
+			}
+		}
+	}
+	return messages.Position{Line: line, Character: 0}
+}
+
+// implementActions offers stubs for the methods a struct declared in this
+// file is missing from an interface it was used as, when the range touches
+// the struct or a diagnostic about it
+func implementActions(a *analysis, params CodeActionParams) []CodeAction {
+	var actions []CodeAction
+	for _, u := range a.checker.Unimplemented() {
+		def := structDefinition(a.document, u.Struct.Name)
+		if def == nil {
+			continue
+		}
+		line, _ := def.Name.Pos()
+		touches := line-1 >= params.Range.Start.Line && line-1 <= params.Range.End.Line
+		message := fmt.Sprintf("struct %s does not satisfy interface %s", u.Struct.Name, u.Interface.Name)
+		for _, d := range params.Context.Diagnostics {
+			if strings.HasPrefix(d.Message, message) {
+				touches = true
+			}
+		}
+		if !touches {
+			continue
+		}
+
+		at, ok := structEnd(a.src, def)
+		if !ok {
+			continue
+		}
+		var stubs strings.Builder
+		for _, method := range u.Methods {
+			idx := slices.Index(u.Interface.Methods, method)
+			if fn, ok := u.Interface.Types[idx].(types.FunctionType); ok {
+				stubs.WriteString("\n\n" + methodStub(u.Struct.Name, method, fn))
+			}
+		}
+
+		uri := url.URL{Path: a.file, Scheme: "file"}
+		actions = append(actions, CodeAction{
+			Title: fmt.Sprintf("Implement %s for %s", u.Interface.Name, u.Struct.Name),
+			Kind:  QuickFix,
+			Edit: &messages.WorkspaceEdit{Changes: map[string][]messages.TextEdit{
+				uri.String(): {{
+					Range:   messages.Range{Start: at, End: at},
+					NewText: stubs.String(),
+				}},
+			}},
+		})
+	}
+	return actions
+}
+
+func structDefinition(program *ast.Program, name string) *ast.StructDefinitionStmt {
+	for _, stmt := range program.Stmts {
+		if pub, ok := stmt.(*ast.PubStatement); ok {
+			stmt = pub.Stmt
+		}
+		if def, ok := stmt.(*ast.StructDefinitionStmt); ok && def.Name.Value == name {
+			return def
+		}
+	}
+	return nil
+}
+
+// structEnd is the position just after the } closing a struct definition
+func structEnd(src string, def *ast.StructDefinitionStmt) (messages.Position, bool) {
+	line, col := def.Name.Pos()
+	if n := len(def.FieldNames); n > 0 {
+		line, col = def.FieldNames[n-1].Pos()
+	}
+	lines := strings.Split(src, "\n")
+	for i := line - 1; i < len(lines); i++ {
+		start := 0
+		if i == line-1 {
+			start = col - 1
+		}
+		if start > len(lines[i]) {
+			continue
+		}
+		if j := strings.IndexByte(lines[i][start:], '}'); j >= 0 {
+			return messages.Position{Line: i, Character: start + j + 1}, true
+		}
+	}
+	return messages.Position{}, false
+}
+
+// methodStub declares method on structName with the signature fn, which
+// lists the parameters after the receiver
+func methodStub(structName, method string, fn types.FunctionType) string {
+	receiver := strings.ToLower(structName[:1])
+	params := []string{structName + " " + receiver}
+	for i, p := range fn.Params {
+		params = append(params, fmt.Sprintf("%s arg%d", p.Signature(), i+1))
+	}
+
+	var out strings.Builder
+	out.WriteString(fmt.Sprintf("func %s(%s)", method, strings.Join(params, ", ")))
+	if fn.Return != nil && fn.Return != types.Unit {
+		out.WriteString(" -> " + fn.Return.Signature())
+	}
+	out.WriteString(" {\n    panic(\"not implemented\");\n")
+	if zero, ok := zeroValue(fn.Return); ok {
+		out.WriteString("    return " + zero + ";\n")
+	}
+	out.WriteString("}")
+	return out.String()
+}
+
+// zeroValue is a literal of type t, for stubs that must return something
+func zeroValue(t types.Type) (string, bool) {
+	switch t := t.(type) {
+	case types.BasicType:
+		switch t {
+		case types.Int:
+			return "0", true
+		case types.Float:
+			return "0.0", true
+		case types.String:
+			return `""`, true
+		case types.Bool:
+			return "false", true
+		}
+	case types.ArrayType:
+		return "[]", true
+	case types.OptionType:
+		return "none()", true
+	case types.ResultType:
+		if t.Err() == types.String {
+			return `err("not implemented")`, true
+		}
+	}
+	return "", false
+}
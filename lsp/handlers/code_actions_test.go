@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sydney/lsp/messages"
+	"testing"
+)
+
+func codeActions(t *testing.T, l *LSP, out *bytes.Buffer, path string, r *messages.Range, diagnostics ...Diagnostic) CodeActions {
+	t.Helper()
+	var actions CodeActions
+	call(t, out, l.HandleCodeAction, messages.CodeAction, CodeActionParams{
+		TextDocument: messages.TextDocumentIdentifier{URI: fileURI(path)},
+		Range:        *r,
+		Context:      CodeActionContext{Diagnostics: diagnostics},
+	}, &actions)
+	return actions
+}
+
+// checkEdit expects action to make a single edit to the file at path
+func checkEdit(t *testing.T, action CodeAction, path string, expected messages.TextEdit) {
+	t.Helper()
+	if action.Kind != QuickFix || action.Edit == nil || len(action.Edit.Changes) != 1 {
+		t.Fatalf("expected a quick fix editing one file, got %+v", action)
+	}
+	edits := action.Edit.Changes[fileURI(path)]
+	if len(edits) != 1 {
+		t.Fatalf("expected one edit to %s, got %+v", path, action.Edit.Changes)
+	}
+	if edits[0] != expected {
+		t.Errorf("expected edit %+v, got %+v", expected, edits[0])
+	}
+}
+
+func TestImportActions(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		lines  *messages.Range
+		title  string
+		edit   messages.TextEdit
+	}{
+		{
+			"first import",
+			"const x = lib:one();\nconst y = lib:one();",
+			span(0, 0, 1, 0),
+			`Add import "./lib"`,
+			messages.TextEdit{Range: *span(0, 0, 0, 0), NewText: "import \"./lib\"\n"},
+		},
+		{
+			"after the other imports",
+			"import \"./lib\"\n\nconst x = geo:two() + lib:one();",
+			span(2, 0, 2, 0),
+			`Add import "./geo"`,
+			messages.TextEdit{Range: *span(1, 0, 1, 0), NewText: "import \"./geo\"\n"},
+		},
+	}
+
+	for _, tt := range tests {
+		l, out, path, _ := openWorkspace(t, tt.source)
+		geo := filepath.Join(filepath.Dir(path), "geo", "geo.sy")
+		if err := os.MkdirAll(filepath.Dir(geo), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(geo, []byte("module \"geo\"\n\npub func two() -> int { return 2; }\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		actions := codeActions(t, l, out, path, tt.lines)
+		if len(actions) != 1 {
+			t.Errorf("%s: expected one action, got %+v", tt.name, actions)
+			continue
+		}
+		if actions[0].Title != tt.title {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.title, actions[0].Title)
+		}
+		checkEdit(t, actions[0], path, tt.edit)
+
+		// nothing to import outside the range
+		if actions := codeActions(t, l, out, path, span(9, 0, 9, 0)); len(actions) != 0 {
+			t.Errorf("%s: expected no actions past the end of the file, got %+v", tt.name, actions)
+		}
+	}
+}
+
+func TestImplementActions(t *testing.T) {
+	source := `define interface Shape { area() -> int, name() -> string }
+define struct Square {
+    side int
+}
+func measure(Shape s) -> int { return s.area(); }
+const m = measure(Square { side: 2 });`
+	l, out, path, _ := openWorkspace(t, source)
+
+	stubs := "\n\nfunc area(Square s) -> int {\n    panic(\"not implemented\");\n    return 0;\n}" +
+		"\n\nfunc name(Square s) -> string {\n    panic(\"not implemented\");\n    return \"\";\n}"
+	expected := messages.TextEdit{Range: *span(3, 1, 3, 1), NewText: stubs}
+
+	tests := []struct {
+		name        string
+		lines       *messages.Range
+		diagnostics []Diagnostic
+		offered     bool
+	}{
+		{"on the struct", span(1, 0, 1, 0), nil, true},
+		{"on the diagnostic", span(5, 0, 5, 0), []Diagnostic{{Message: "struct Square does not satisfy interface Shape: missing area"}}, true},
+		{"elsewhere", span(5, 0, 5, 0), nil, false},
+	}
+
+	for _, tt := range tests {
+		actions := codeActions(t, l, out, path, tt.lines, tt.diagnostics...)
+		if !tt.offered {
+			if len(actions) != 0 {
+				t.Errorf("%s: expected no actions, got %+v", tt.name, actions)
+			}
+			continue
+		}
+		if len(actions) != 1 || actions[0].Title != "Implement Shape for Square" {
+			t.Errorf("%s: expected to implement Shape, got %+v", tt.name, actions)
+			continue
+		}
+		checkEdit(t, actions[0], path, expected)
+	}
+}
@@ -0,0 +1,264 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"sydney/ast"
+	"sydney/lexer"
+	"sydney/lsp/messages"
+	"sydney/object"
+	"sydney/typechecker"
+	"sydney/types"
+)
+
+type completionContext int
+
+const (
+	completeName   completionContext = iota
+	completeMember                   // after receiver.
+	completeModule                   // after module:
+)
+
+func (l *LSP) HandleCompletion(req *messages.Request) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Completion panicked: %v", r)
+		}
+	}()
+
+	list := &messages.CompletionList{Items: []messages.CompletionItem{}}
+	resp := &messages.Response{
+		Id:      req.Id,
+		Version: messages.Version,
+		Result:  list,
+	}
+	defer func() {
+		if err := l.WriteResponse(resp); err != nil {
+			log.Printf("%s: Error writing response: %v", messages.Completion, err)
+		}
+	}()
+
+	var params messages.CompletionParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		log.Printf("%s Error unmarshalling params: %v", messages.Completion, err)
+		return
+	}
+	doc := l.document(params.TextDocument.URI)
+	if doc == nil {
+		return
+	}
+
+	lines := strings.Split(doc.text, "\n")
+	if params.Position.Line >= len(lines) {
+		return
+	}
+	text := lines[params.Position.Line]
+	cursor := min(params.Position.Character, len(text))
+
+	// the word being typed, and what comes before it
+	start := cursor
+	for start > 0 && isIdentChar(text[start-1]) {
+		start--
+	}
+	partial := text[start:cursor]
+	context := completeName
+	if start > 0 && text[start-1] == '.' {
+		context = completeMember
+	} else if start > 0 && text[start-1] == ':' {
+		context = completeModule
+	}
+
+	var qualifier string
+	qualifierStart := start
+	if context != completeName {
+		qualifierStart = start - 1
+		for qualifierStart > 0 && isIdentChar(text[qualifierStart-1]) {
+			qualifierStart--
+		}
+		qualifier = text[qualifierStart : start-1]
+		// drop the unfinished member so the rest of the file still checks
+		lines[params.Position.Line] = text[:start-1] + text[cursor:]
+	}
+
+	a := l.analyze(messages.Completion, doc.path, strings.Join(lines, "\n"), nil)
+	if a == nil {
+		// fall back to the last version of the file that checked
+		a = doc.analysis
+	}
+	if a == nil {
+		return
+	}
+
+	line := params.Position.Line + 1
+	var items []messages.CompletionItem
+	switch context {
+	case completeMember:
+		items = l.memberCompletions(a, line, qualifierStart+1)
+	case completeModule:
+		items = moduleCompletions(a, qualifier)
+		if items == nil {
+			// not a module, e.g. a struct literal field
+			items = nameCompletions(a, line, cursor+1)
+		}
+	default:
+		items = nameCompletions(a, line, cursor+1)
+	}
+
+	for _, item := range items {
+		if strings.HasPrefix(item.Label, partial) {
+			list.Items = append(list.Items, item)
+		}
+	}
+	sort.Slice(list.Items, func(i, j int) bool {
+		return list.Items[i].Label < list.Items[j].Label
+	})
+}
+
+// memberCompletions offers the fields of the receiver at line:col, the
+// functions taking it as their first parameter, or its interface methods
+func (l *LSP) memberCompletions(a *analysis, line, col int) []messages.CompletionItem {
+	ident, _ := ast.FindAt(a.document, line, col)
+	if ident == nil {
+		return nil
+	}
+	receiver := ident.ResolvedType
+	if sel := ast.FindSelectorAt(a.document, line, col); sel != nil {
+		receiver = sel.ResolvedType
+	}
+	if st, ok := receiver.(types.ScopeType); ok {
+		receiver = resolveScopeType(a, st)
+	}
+
+	var items []messages.CompletionItem
+	switch t := receiver.(type) {
+	case types.StructType:
+		for i, field := range t.Fields {
+			items = append(items, messages.CompletionItem{
+				Label:  field,
+				Kind:   messages.FieldCompletion,
+				Detail: t.Types[i].Signature(),
+			})
+		}
+		env := a.checker.Env()
+		seen := map[string]bool{}
+		for _, method := range env.MethodsOf(t.Name) {
+			if seen[method] {
+				continue
+			}
+			seen[method] = true
+			item := messages.CompletionItem{Label: method, Kind: messages.MethodCompletion}
+			if mt, _, ok := env.Get(t.Name + "." + method); ok {
+				item.Detail = mt.Signature()
+			}
+			items = append(items, item)
+		}
+	case types.InterfaceType:
+		for i, method := range t.Methods {
+			items = append(items, messages.CompletionItem{
+				Label:  method,
+				Kind:   messages.MethodCompletion,
+				Detail: t.Types[i].Signature(),
+			})
+		}
+	}
+	return items
+}
+
+// moduleCompletions offers the exports of an imported module, or nil when
+// name is not one
+func moduleCompletions(a *analysis, name string) []messages.CompletionItem {
+	items := map[string]messages.CompletionItem{}
+	// the module's type table says which exports are types rather than values
+	for path, tt := range a.modules {
+		if path != name && filepath.Base(path) != name {
+			continue
+		}
+		for symbol, t := range tt {
+			items[symbol] = typeCompletion(symbol, t)
+		}
+	}
+	if env, ok := a.checker.Package(name); ok {
+		for symbol, t := range env.Symbols() {
+			if _, ok := items[symbol]; !ok {
+				items[symbol] = symbolCompletion(symbol, t)
+			}
+		}
+	}
+
+	if len(items) == 0 {
+		return nil
+	}
+	result := make([]messages.CompletionItem, 0, len(items))
+	for _, item := range items {
+		result = append(result, item)
+	}
+	return result
+}
+
+// nameCompletions offers everything in scope at line:col, the builtins and
+// the keywords
+func nameCompletions(a *analysis, line, col int) []messages.CompletionItem {
+	symbols := a.checker.Env().Symbols()
+	if scope, ok := ast.ScopeAt(a.document, line, col).(*typechecker.TypeEnv); ok {
+		symbols = scope.Symbols()
+	}
+
+	var items []messages.CompletionItem
+	for name, t := range symbols {
+		if object.GetBuiltInByName(name) != nil {
+			continue
+		}
+		items = append(items, symbolCompletion(name, t))
+	}
+	for _, builtin := range object.Builtins {
+		items = append(items, messages.CompletionItem{
+			Label:  builtin.Name,
+			Kind:   messages.FunctionCompletion,
+			Detail: builtin.BuiltIn.T.Signature(),
+		})
+	}
+	for _, keyword := range lexer.Keywords() {
+		items = append(items, messages.CompletionItem{Label: keyword, Kind: messages.KeywordCompletion})
+	}
+	return items
+}
+
+func symbolCompletion(name string, t types.Type) messages.CompletionItem {
+	switch t.(type) {
+	case nil:
+		// names declared without a value are struct types
+		return messages.CompletionItem{Label: name, Kind: messages.StructCompletion}
+	case types.InterfaceType:
+		return typeCompletion(name, t)
+	case types.FunctionType:
+		return messages.CompletionItem{Label: name, Kind: messages.FunctionCompletion, Detail: t.Signature()}
+	}
+	return messages.CompletionItem{Label: name, Kind: messages.VariableCompletion, Detail: t.Signature()}
+}
+
+func typeCompletion(name string, t types.Type) messages.CompletionItem {
+	if _, ok := t.(types.InterfaceType); ok {
+		return messages.CompletionItem{Label: name, Kind: messages.InterfaceCompletion}
+	}
+	return messages.CompletionItem{Label: name, Kind: messages.StructCompletion}
+}
+
+func resolveScopeType(a *analysis, st types.ScopeType) types.Type {
+	for path, tt := range a.modules {
+		if path != st.Module && filepath.Base(path) != st.Module {
+			continue
+		}
+		if t, ok := tt[st.Name]; ok {
+			return t
+		}
+	}
+	return st
+}
+
+func isIdentChar(ch byte) bool {
+	return ch == '_' || ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || ch >= '0' && ch <= '9'
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"strings"
+	"sydney/lsp/messages"
+	"testing"
+)
+
+func TestCompletion(t *testing.T) {
+	source := `import "./lib"
+
+define struct Point { x int, y int }
+define interface Shape { area() -> int }
+func norm(Point p) -> int {
+    return p.x + p.y;
+}
+func measure(Shape s) -> int {
+    return s.area();
+}
+const origin = Point { x: 0, y: 0 };
+`
+
+	type item struct {
+		label string
+		kind  messages.CompletionItemKind
+	}
+	tests := []struct {
+		name     string
+		typed    string // the last line, with the cursor at its end
+		expected []item
+	}{
+		{"struct members after a dot", "const a = origin.", []item{
+			{"norm", messages.MethodCompletion},
+			{"x", messages.FieldCompletion},
+			{"y", messages.FieldCompletion},
+		}},
+		{"members starting with what is typed", "const a = origin.n", []item{{"norm", messages.MethodCompletion}}},
+		{"module exports", "const a = lib:", []item{{"one", messages.FunctionCompletion}}},
+		{"names in scope", "const a = nor", []item{{"norm", messages.FunctionCompletion}}},
+		{"struct types", "const a = Poi", []item{{"Point", messages.StructCompletion}}},
+		{"interfaces", "const a = Sha", []item{{"Shape", messages.InterfaceCompletion}}},
+	}
+
+	for _, tt := range tests {
+		l, out, path, _ := openWorkspace(t, source+tt.typed)
+		lines := strings.Split(source+tt.typed, "\n")
+
+		var list messages.CompletionList
+		call(t, out, l.HandleCompletion, messages.Completion, messages.CompletionParams{
+			TextDocument: messages.TextDocumentIdentifier{URI: fileURI(path)},
+			Position:     messages.Position{Line: len(lines) - 1, Character: len(tt.typed)},
+		}, &list)
+
+		if len(list.Items) != len(tt.expected) {
+			t.Errorf("%s: expected %d items, got %+v", tt.name, len(tt.expected), list.Items)
+			continue
+		}
+		for i, want := range tt.expected {
+			got := list.Items[i]
+			if got.Label != want.label || got.Kind != want.kind {
+				t.Errorf("%s: expected item %d to be %s of kind %d, got %s of kind %d", tt.name, i, want.label, want.kind, got.Label, got.Kind)
+			}
+		}
+	}
+}
+
+func TestCompletionInterfaceMembers(t *testing.T) {
+	source := `define interface Shape { area() -> int, name() -> string }
+func describe(Shape s) -> string {
+    const n = s.
+    return s.name();
+}`
+	l, out, path, _ := openWorkspace(t, source)
+
+	var list messages.CompletionList
+	call(t, out, l.HandleCompletion, messages.Completion, messages.CompletionParams{
+		TextDocument: messages.TextDocumentIdentifier{URI: fileURI(path)},
+		Position:     messages.Position{Line: 2, Character: 16},
+	}, &list)
+
+	expected := []messages.CompletionItem{
+		{Label: "area", Kind: messages.MethodCompletion, Detail: "func<() -> int>"},
+		{Label: "name", Kind: messages.MethodCompletion, Detail: "func<() -> string>"},
+	}
+	if len(list.Items) != len(expected) {
+		t.Fatalf("expected %d items, got %+v", len(expected), list.Items)
+	}
+	for i, want := range expected {
+		if list.Items[i] != want {
+			t.Errorf("expected item %d to be %+v, got %+v", i, want, list.Items[i])
+		}
+	}
+}
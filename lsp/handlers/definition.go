@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/url"
+	"sydney/ast"
+	"sydney/lsp/messages"
+	"sydney/typechecker"
+)
+
+func (l *LSP) HandleDefinition(req *messages.Request) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Definition panicked: %v", r)
+		}
+	}()
+
+	resp := &messages.Response{
+		Id:      req.Id,
+		Version: messages.Version,
+	}
+	defer func() {
+		if err := l.WriteResponse(resp); err != nil {
+			log.Printf("%s: Error writing response: %v", messages.Definition, err)
+		}
+	}()
+
+	var params messages.DefinitionParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		log.Printf("%s Error unmarshalling params: %v", messages.Definition, err)
+		return
+	}
+	a := l.checked(params.TextDocument.URI)
+	if a == nil {
+		return
+	}
+
+	ident, decl, ok := a.resolve(params.Position)
+	if !ok {
+		log.Printf("definition: cannot resolve symbol at %d:%d", params.Position.Line+1, params.Position.Character+1)
+		return
+	}
+	log.Printf("definition: %s", ident.Value)
+
+	file := decl.File
+	if file == "" {
+		file = a.file
+	}
+	line, col := decl.Name.Pos()
+	resp.Result = location(file, line, col, len(decl.Name.Value))
+}
+
+// resolve finds the identifier at a position of the checked file and the
+// declaration it refers to
+func (a *analysis) resolve(pos messages.Position) (*ast.Identifier, typechecker.Declaration, bool) {
+	line, col := pos.Line+1, pos.Character+1
+	ident, scope := ast.FindAt(a.document, line, col)
+	if ident == nil {
+		return nil, typechecker.Declaration{}, false
+	}
+
+	decl, ok := a.findDefinition(ident, scope, line, col)
+	return ident, decl, ok
+}
+
+// findDefinition prefers what the checker resolved the identifier to, and
+// falls back to looking the name up in its scope for identifiers the checker
+// only saw a copy of, such as those in generic function bodies
+func (a *analysis) findDefinition(ident *ast.Identifier, scope ast.Scope, line, col int) (typechecker.Declaration, bool) {
+	identLine, identCol := ident.Pos()
+	if decl, ok := a.checker.Definition(a.file, identLine, identCol); ok {
+		return decl, true
+	}
+
+	// a field or method name means nothing on its own
+	if sel := ast.FindSelectorAt(a.document, line, col); sel != nil {
+		return typechecker.Declaration{}, false
+	}
+
+	if env, ok := scope.(*typechecker.TypeEnv); ok && env != nil {
+		if decl, ok := env.Declaration(ident.Value); ok {
+			return decl, true
+		}
+	}
+	return a.checker.Env().Declaration(ident.Value)
+}
+
+func location(file string, line, col, length int) *messages.Location {
+	uri := url.URL{Path: file, Scheme: "file"}
+	return &messages.Location{
+		URI:   uri.String(),
+		Range: nameRange(line, col, length),
+	}
+}
+
+// nameRange converts a 1-based line and column to the range of a name
+func nameRange(line, col, length int) messages.Range {
+	return messages.Range{
+		Start: messages.Position{Line: line - 1, Character: col - 1},
+		End:   messages.Position{Line: line - 1, Character: col - 1 + length},
+	}
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"sydney/lsp/messages"
+	"testing"
+)
+
+func TestDefinition(t *testing.T) {
+	source := `import "./lib"
+
+define struct Point { x int, y int }
+func double(int n) -> int {
+    const twice = n * 2;
+    return twice;
+}
+const p = Point { x: 1, y: 2 };
+const d = double(p.x) + lib:one();`
+	l, out, path, libPath := openWorkspace(t, source)
+
+	tests := []struct {
+		name     string
+		pos      messages.Position
+		file     string // "" when there is no definition
+		expected messages.Range
+	}{
+		{"local", messages.Position{Line: 5, Character: 12}, path, *span(4, 10, 4, 15)},
+		{"parameter", messages.Position{Line: 4, Character: 18}, path, *span(3, 16, 3, 17)},
+		{"function", messages.Position{Line: 8, Character: 11}, path, *span(3, 5, 3, 11)},
+		{"struct", messages.Position{Line: 7, Character: 11}, path, *span(2, 14, 2, 19)},
+		{"global", messages.Position{Line: 8, Character: 17}, path, *span(7, 6, 7, 7)},
+		{"imported function", messages.Position{Line: 8, Character: 29}, libPath, *span(2, 9, 2, 12)},
+		{"keyword", messages.Position{Line: 4, Character: 5}, "", messages.Range{}},
+	}
+
+	for _, tt := range tests {
+		var loc *messages.Location
+		call(t, out, l.HandleDefinition, messages.Definition, messages.DefinitionParams{
+			TextDocument: messages.TextDocumentIdentifier{URI: fileURI(path)},
+			Position:     tt.pos,
+		}, &loc)
+
+		if tt.file == "" {
+			if loc != nil {
+				t.Errorf("%s: expected no definition, got %+v", tt.name, loc)
+			}
+			continue
+		}
+		if loc == nil {
+			t.Errorf("%s: expected a definition in %s", tt.name, tt.file)
+			continue
+		}
+		if loc.URI != fileURI(tt.file) || loc.Range != tt.expected {
+			t.Errorf("%s: expected %s at %v, got %s at %v", tt.name, tt.file, tt.expected, loc.URI, loc.Range)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package handlers
 import (
 	"log"
 	"net/url"
+	"strings"
 	"sydney/errors"
 	"sydney/lsp/messages"
 )
@@ -32,35 +33,24 @@ type Diagnostic struct {
 	Source   string             `json:"source"`
 	Message  string             `json:"message"`
 	Severity DiagnosticSeverity `json:"severity"`
+	Code     string             `json:"code,omitempty"`
 }
 
-func (l *LSP) SendTypecheckerDiagnostics(errors []errors.PositionError, filePath string) {
+// SendDiagnostics publishes the syntax and type errors of a file, replacing
+// those published for it before
+func (l *LSP) SendDiagnostics(filePath string, syntaxErrs, typeErrs []errors.PositionError) {
 	uri := url.URL{Path: filePath, Scheme: "file"}
 
 	params := &PublishDiagnosticsParams{
-		Uri: uri.String(),
+		Uri:         uri.String(),
+		Diagnostics: make([]Diagnostic, 0, len(syntaxErrs)+len(typeErrs)),
 	}
-
-	diagnostics := make([]Diagnostic, len(errors))
-	for i, err := range errors {
-		diagnostics[i] = Diagnostic{
-			Range: messages.Range{
-				Start: messages.Position{
-					Line:      err.Line,
-					Character: err.Col,
-				},
-				End: messages.Position{
-					Line:      err.Line,
-					Character: err.Col + 99, // im hoping this just does the whole line
-				},
-			},
-			Source:   TypecheckerSource,
-			Severity: ErrorSeverity,
-			Message:  err.Message,
-		}
+	var lines []string
+	if src, err := l.source(filePath); err == nil {
+		lines = strings.Split(src, "\n")
 	}
-
-	params.Diagnostics = diagnostics
+	params.Diagnostics = appendDiagnostics(params.Diagnostics, lines, syntaxErrs, ParserSource)
+	params.Diagnostics = appendDiagnostics(params.Diagnostics, lines, typeErrs, TypecheckerSource)
 
 	notif := &messages.Notification{
 		Method: messages.PublishDiagnostics,
@@ -69,6 +59,37 @@ func (l *LSP) SendTypecheckerDiagnostics(errors []errors.PositionError, filePath
 
 	err := l.WriteNotification(notif)
 	if err != nil {
-		log.Printf("%s: Error writing notification: %v", messages.Hover, err)
+		log.Printf("%s: Error writing notification: %v", messages.PublishDiagnostics, err)
+	}
+}
+
+// appendDiagnostics converts errs in the file made of lines, which measures
+// the spans of errors that only know where they start
+func appendDiagnostics(diagnostics []Diagnostic, lines []string, errs []errors.PositionError, source string) []Diagnostic {
+	for _, err := range errs {
+		// errors count lines and columns from 1, the protocol from 0
+		line, col := max(err.Line-1, 0), max(err.Col-1, 0)
+		length := errors.SpanLength(lines, err.Line, err.Col, err.Length)
+		severity := ErrorSeverity
+		if err.Severity == errors.Warning {
+			severity = WarningSeverity
+		}
+		diagnostics = append(diagnostics, Diagnostic{
+			Range: messages.Range{
+				Start: messages.Position{
+					Line:      line,
+					Character: col,
+				},
+				End: messages.Position{
+					Line:      line,
+					Character: col + length,
+				},
+			},
+			Source:   source,
+			Severity: severity,
+			Message:  err.Message,
+			Code:     string(err.Code),
+		})
 	}
+	return diagnostics
 }
@@ -3,7 +3,6 @@ package handlers
 import (
 	"encoding/json"
 	"log"
-	"net/url"
 	"sydney/lsp/messages"
 )
 
@@ -11,19 +10,22 @@ func (l *LSP) HandleDocumentChange(req *messages.Request) {
 	var params messages.DocumentChangeParams
 	err := json.Unmarshal(req.Params, &params)
 	if err != nil {
-		log.Printf("%s: Error unmarshalling params: %v", messages.DocumentOpen, err)
+		log.Printf("%s: Error unmarshalling params: %v", messages.DocumentChange, err)
 		return
 	}
 
-	u, err := url.Parse(params.TextDocument.URI)
-	if err != nil {
-		log.Printf("%s: Error parsing URI: %v", messages.DocumentOpen, err)
+	doc := l.document(params.TextDocument.URI)
+	if doc == nil {
+		log.Printf("%s: %s is not open", messages.DocumentChange, params.TextDocument.URI)
 		return
 	}
-	log.Printf("parsed %s uri", u.String())
 
-	filePath := u.Path
-	src := params.ContentChanges[0].Text
+	// changes apply in order, each to the text the last one left
+	for _, change := range params.ContentChanges {
+		doc.text = applyChange(doc.text, change)
+	}
+	doc.version = params.TextDocument.Version
+	l.forgetSymbols(doc.path)
 
-	l.parse(messages.DocumentChange, filePath, src)
+	l.update(messages.DocumentChange, doc)
 }
@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"sydney/lsp/messages"
+)
+
+func (l *LSP) HandleDocumentClose(req *messages.Request) {
+	var params messages.DocumentCloseParams
+	err := json.Unmarshal(req.Params, &params)
+	if err != nil {
+		log.Printf("%s: Error unmarshalling params: %v", messages.DocumentClose, err)
+		return
+	}
+
+	doc := l.document(params.TextDocument.URI)
+	if doc == nil {
+		return
+	}
+	dependents := l.dependents(doc.path)
+	delete(l.documents, doc.path)
+
+	// the file's diagnostics belong to the editor's copy, which is gone
+	l.SendDiagnostics(doc.path, nil, nil)
+
+	// dependents now read the file as it is on disk, without unsaved edits
+	for _, dependent := range dependents {
+		l.check(messages.DocumentClose, dependent)
+	}
+}
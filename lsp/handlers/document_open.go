@@ -20,8 +20,12 @@ func (l *LSP) HandleDocumentOpen(req *messages.Request) {
 		log.Printf("%s: Error parsing URI: %v", messages.DocumentOpen, err)
 		return
 	}
-	filePath := u.Path
-	src := params.TextDocument.Text
+	doc := &document{
+		path:    u.Path,
+		text:    params.TextDocument.Text,
+		version: params.TextDocument.Version,
+	}
+	l.documents[doc.path] = doc
 
-	l.parse(messages.DocumentOpen, filePath, src)
+	l.update(messages.DocumentOpen, doc)
 }
@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"sydney/lsp/messages"
+)
+
+func (l *LSP) HandleDocumentSave(req *messages.Request) {
+	var params messages.DocumentSaveParams
+	err := json.Unmarshal(req.Params, &params)
+	if err != nil {
+		log.Printf("%s: Error unmarshalling params: %v", messages.DocumentSave, err)
+		return
+	}
+
+	doc := l.document(params.TextDocument.URI)
+	if doc == nil {
+		log.Printf("%s: %s is not open", messages.DocumentSave, params.TextDocument.URI)
+		return
+	}
+	if params.Text != nil {
+		doc.text = *params.Text
+	}
+	l.forgetSymbols(doc.path)
+
+	l.update(messages.DocumentSave, doc)
+}
@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"sydney/errors"
+	"sydney/loader"
+	"sydney/lsp/messages"
+)
+
+// document is a file open in the editor. text is what the client last sent,
+// which may not parse, and analysis is the last check of it that succeeded
+type document struct {
+	path     string
+	text     string
+	version  int
+	analysis *analysis
+
+	// the last semantic tokens sent, which delta requests are relative to
+	tokensID string
+	tokens   []int
+}
+
+// document finds the open document a request is about
+func (l *LSP) document(uri string) *document {
+	u, err := url.Parse(uri)
+	if err != nil {
+		log.Printf("Error parsing URI %s: %v", uri, err)
+		return nil
+	}
+	return l.documents[u.Path]
+}
+
+// checked finds the open document a request is about, if it has been
+// checked successfully
+func (l *LSP) checked(uri string) *analysis {
+	if doc := l.document(uri); doc != nil {
+		return doc.analysis
+	}
+	return nil
+}
+
+// source is the text of the file at path, as the editor has it when it is
+// open and as it is on disk otherwise
+func (l *LSP) source(path string) (string, error) {
+	if doc, ok := l.documents[path]; ok {
+		return doc.text, nil
+	}
+	data, err := os.ReadFile(path)
+	return string(data), err
+}
+
+// overlay is the text of the open documents, for the loader to read instead
+// of what is saved
+func (l *LSP) overlay() map[string]string {
+	files := make(map[string]string, len(l.documents))
+	for path, doc := range l.documents {
+		files[path] = doc.text
+	}
+	return files
+}
+
+// update checks doc and the open documents that depend on it, publishing
+// diagnostics for each
+func (l *LSP) update(method messages.Method, doc *document) {
+	l.check(method, doc)
+	for _, dependent := range l.dependents(doc.path) {
+		log.Printf("%s: rechecking %s, which depends on %s", method, dependent.path, doc.path)
+		l.check(method, dependent)
+	}
+}
+
+func (l *LSP) check(method messages.Method, doc *document) {
+	// the syntax errors go out before checking types, so they are still
+	// published when the check fails
+	a := l.analyze(method, doc.path, doc.text, func(syntaxErrs []errors.PositionError) {
+		l.SendDiagnostics(doc.path, errorsIn(doc.path, syntaxErrs), nil)
+	})
+	if a == nil {
+		return
+	}
+	doc.analysis = a
+
+	log.Printf("%s: Sending diagnostics for file %s", method, doc.path)
+	l.SendDiagnostics(doc.path, errorsIn(doc.path, a.syntaxErrs), errorsIn(doc.path, a.errs))
+	log.Printf("%s: parsed %d statements", method, len(a.program.Stmts))
+}
+
+// errorsIn keeps the errors in the file at path, as a module is checked as a
+// whole
+func errorsIn(path string, errs []errors.PositionError) []errors.PositionError {
+	var kept []errors.PositionError
+	for _, err := range errs {
+		if err.File == "" || err.File == path {
+			kept = append(kept, err)
+		}
+	}
+	return kept
+}
+
+// dependents lists the open documents other than the one at path whose
+// checks read it: the other files of its module, and those importing its
+// package directly or through other packages
+func (l *LSP) dependents(path string) []*document {
+	dir := filepath.Dir(path)
+	var docs []*document
+	for _, doc := range l.documents {
+		if doc.path != path && slices.Contains(doc.dependencies(), dir) {
+			docs = append(docs, doc)
+		}
+	}
+	sort.Slice(docs, func(i, j int) bool { return docs[i].path < docs[j].path })
+	return docs
+}
+
+// dependencies lists the directories of the packages a document is checked
+// with, including its own when it is part of a module
+func (d *document) dependencies() []string {
+	sourceDir := filepath.Dir(d.path)
+	var dirs []string
+	if strings.HasPrefix(d.text, "module ") {
+		dirs = append(dirs, sourceDir)
+	}
+	if d.analysis != nil {
+		for _, pkg := range d.analysis.packages {
+			for _, program := range pkg.Programs {
+				dirs = append(dirs, filepath.Dir(program.File))
+			}
+		}
+	}
+	// imports added since the last check that succeeded
+	stdlib := loader.ResolveStdlib(sourceDir)
+	for _, imp := range loader.ScanImports(d.text) {
+		dirs = append(dirs, loader.ResolveImport(stdlib, sourceDir, imp))
+	}
+	return dirs
+}
+
+// applyChange edits text as a content change describes
+func applyChange(text string, change messages.ContentChanges) string {
+	if change.Range == nil {
+		return change.Text
+	}
+	start := byteOffset(text, change.Range.Start)
+	end := max(byteOffset(text, change.Range.End), start)
+	return text[:start] + change.Text + text[end:]
+}
+
+// byteOffset converts a position, whose character counts UTF-16 code units,
+// to a byte offset in text. Positions past the end of a line or the text
+// are clamped to it
+func byteOffset(text string, pos messages.Position) int {
+	i := 0
+	for line := 0; line < pos.Line; line++ {
+		next := strings.IndexByte(text[i:], '\n')
+		if next < 0 {
+			return len(text)
+		}
+		i += next + 1
+	}
+	for units := 0; units < pos.Character && i < len(text) && text[i] != '\n'; {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if r >= 0x10000 {
+			units += 2
+		} else {
+			units++
+		}
+		i += size
+	}
+	return i
+}
@@ -0,0 +1,337 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sydney/lsp/messages"
+	"testing"
+)
+
+type publishedDiagnostics struct {
+	Method messages.Method          `json:"method"`
+	Params PublishDiagnosticsParams `json:"params"`
+}
+
+// readFrames splits what the server wrote into its Content-Length framed
+// messages
+func readFrames(t *testing.T, out *bytes.Buffer) [][]byte {
+	t.Helper()
+	r := textproto.NewReader(bufio.NewReader(out))
+	var frames [][]byte
+	for {
+		header, err := r.ReadMIMEHeader()
+		if err == io.EOF {
+			return frames
+		}
+		if err != nil {
+			t.Fatalf("reading header: %v", err)
+		}
+		length, err := strconv.Atoi(header.Get("Content-Length"))
+		if err != nil {
+			t.Fatalf("bad Content-Length %q: %v", header.Get("Content-Length"), err)
+		}
+		body := make([]byte, length)
+		if _, err := io.ReadFull(r.R, body); err != nil {
+			t.Fatalf("reading body: %v", err)
+		}
+		frames = append(frames, body)
+	}
+}
+
+func readNotifications(t *testing.T, out *bytes.Buffer) []publishedDiagnostics {
+	t.Helper()
+	var notifs []publishedDiagnostics
+	for _, body := range readFrames(t, out) {
+		var n publishedDiagnostics
+		if err := json.Unmarshal(body, &n); err != nil {
+			t.Fatalf("unmarshalling %s: %v", body, err)
+		}
+		notifs = append(notifs, n)
+	}
+	return notifs
+}
+
+// call sends a request to handle and decodes the result it responds with
+// into result. Anything the server wrote before is discarded
+func call(t *testing.T, out *bytes.Buffer, handle func(*messages.Request), method messages.Method, params, result any) {
+	t.Helper()
+	out.Reset()
+	raw, err := json.Marshal(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handle(&messages.Request{Version: "2.0", Method: method, Params: raw, Id: 1})
+
+	frames := readFrames(t, out)
+	if len(frames) != 1 {
+		t.Fatalf("%s: expected one response, got %d messages", method, len(frames))
+	}
+	var resp struct {
+		Result json.RawMessage `json:"result"`
+	}
+	if err := json.Unmarshal(frames[0], &resp); err != nil {
+		t.Fatalf("unmarshalling %s: %v", frames[0], err)
+	}
+	if err := json.Unmarshal(resp.Result, result); err != nil {
+		t.Fatalf("%s: unmarshalling result %s: %v", method, resp.Result, err)
+	}
+}
+
+// fileURI is the URI the client names the file at path by
+func fileURI(path string) string {
+	u := url.URL{Scheme: "file", Path: path}
+	return u.String()
+}
+
+func openDocument(t *testing.T, l *LSP, path, text string) {
+	t.Helper()
+	params, err := json.Marshal(messages.DocumentOpenParams{
+		TextDocument: messages.TextDocumentItem{URI: fileURI(path), LanguageID: "sydney", Version: 1, Text: text},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.HandleDocumentOpen(&messages.Request{Version: "2.0", Method: messages.DocumentOpen, Params: params})
+}
+
+// openWorkspace writes a library module beside main.sy and opens main.sy
+func openWorkspace(t *testing.T, source string) (l *LSP, out *bytes.Buffer, mainPath, libPath string) {
+	t.Helper()
+	dir := t.TempDir()
+	libPath = filepath.Join(dir, "lib", "lib.sy")
+	lib := "module \"lib\"\n\npub func one() -> int {\n    return 1;\n}\n"
+	if err := os.MkdirAll(filepath.Dir(libPath), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(libPath, []byte(lib), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	mainPath = filepath.Join(dir, "main.sy")
+	if err := os.WriteFile(mainPath, []byte(source), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	out = &bytes.Buffer{}
+	l = New(out)
+	l.root = dir
+	openDocument(t, l, mainPath, source)
+	return l, out, mainPath, libPath
+}
+
+func changeDocument(t *testing.T, l *LSP, path string, version int, changes ...messages.ContentChanges) {
+	t.Helper()
+	params, err := json.Marshal(messages.DocumentChangeParams{
+		TextDocument:   messages.TextDocumentItem{URI: fileURI(path), Version: version},
+		ContentChanges: changes,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.HandleDocumentChange(&messages.Request{Version: "2.0", Method: messages.DocumentChange, Params: params})
+}
+
+func span(startLine, startChar, endLine, endChar int) *messages.Range {
+	return &messages.Range{
+		Start: messages.Position{Line: startLine, Character: startChar},
+		End:   messages.Position{Line: endLine, Character: endChar},
+	}
+}
+
+func TestApplyChange(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		change   messages.ContentChanges
+		expected string
+	}{
+		{"insert", "const a = 1;", messages.ContentChanges{Range: span(0, 10, 0, 10), Text: "4"}, "const a = 41;"},
+		{"replace on a line", "const a = 1;\nconst b = 2;", messages.ContentChanges{Range: span(1, 6, 1, 7), Text: "c"}, "const a = 1;\nconst c = 2;"},
+		{"across lines", "one\ntwo\nthree", messages.ContentChanges{Range: span(0, 2, 2, 3), Text: "ly\nf"}, "only\nfee"},
+		{"delete lines", "one\ntwo\nthree\n", messages.ContentChanges{Range: span(1, 0, 2, 0), Text: ""}, "one\nthree\n"},
+		{"insert lines", "ab", messages.ContentChanges{Range: span(0, 1, 0, 1), Text: "\n\n"}, "a\n\nb"},
+		{"after a surrogate pair", "\"😀\" + x", messages.ContentChanges{Range: span(0, 7, 0, 8), Text: "y"}, "\"😀\" + y"},
+		{"replace a surrogate pair", "a😀b", messages.ContentChanges{Range: span(0, 1, 0, 3), Text: "é"}, "aéb"},
+		{"after two byte runes", "é😀é\nx", messages.ContentChanges{Range: span(0, 3, 1, 0), Text: ""}, "é😀x"},
+		{"past the end of a line", "ab\ncd", messages.ContentChanges{Range: span(0, 9, 1, 1), Text: "-"}, "ab-d"},
+		{"past the end of the text", "ab", messages.ContentChanges{Range: span(0, 1, 5, 0), Text: "c"}, "ac"},
+		{"full replacement", "const a = 1;", messages.ContentChanges{Text: "const b = 2;"}, "const b = 2;"},
+	}
+
+	for _, tt := range tests {
+		if got := applyChange(tt.text, tt.change); got != tt.expected {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.expected, got)
+		}
+	}
+}
+
+func TestByteOffset(t *testing.T) {
+	tests := []struct {
+		text     string
+		pos      messages.Position
+		expected int
+	}{
+		{"abc", messages.Position{Line: 0, Character: 2}, 2},
+		{"ab\ncd", messages.Position{Line: 1, Character: 1}, 4},
+		{"ab\ncd", messages.Position{Line: 0, Character: 5}, 2},
+		{"ab\ncd", messages.Position{Line: 3, Character: 0}, 5},
+		{"é", messages.Position{Line: 0, Character: 1}, 2},
+		{"😀a", messages.Position{Line: 0, Character: 2}, 4},
+		{"😀a", messages.Position{Line: 0, Character: 3}, 5},
+		{"\n😀\n😀b", messages.Position{Line: 2, Character: 2}, 10},
+	}
+
+	for _, tt := range tests {
+		if got := byteOffset(tt.text, tt.pos); got != tt.expected {
+			t.Errorf("byteOffset(%q, %d:%d): expected %d, got %d", tt.text, tt.pos.Line, tt.pos.Character, tt.expected, got)
+		}
+	}
+}
+
+func TestDocumentChange(t *testing.T) {
+	var out bytes.Buffer
+	l := New(&out)
+	path := filepath.Join(t.TempDir(), "main.sy")
+	openDocument(t, l, path, "const a = 1;\nconst b = a;\n")
+	out.Reset()
+
+	// changes apply in order, the second to the text the first left
+	changeDocument(t, l, path, 2,
+		messages.ContentChanges{Range: span(0, 6, 0, 7), Text: "😀"},
+		messages.ContentChanges{Range: span(0, 6, 0, 8), Text: "c"},
+	)
+	if text := l.documents[path].text; text != "const c = 1;\nconst b = a;\n" {
+		t.Fatalf("unexpected text after incremental changes: %q", text)
+	}
+	if version := l.documents[path].version; version != 2 {
+		t.Errorf("expected version 2, got %d", version)
+	}
+	notifs := readNotifications(t, &out)
+	if len(notifs) == 0 || len(notifs[len(notifs)-1].Params.Diagnostics) == 0 {
+		t.Fatalf("expected the undefined a to be diagnosed, got %+v", notifs)
+	}
+
+	out.Reset()
+	changeDocument(t, l, path, 3, messages.ContentChanges{Text: "const a = 1;\nconst b = a;\n"})
+	notifs = readNotifications(t, &out)
+	if len(notifs) == 0 || len(notifs[len(notifs)-1].Params.Diagnostics) != 0 {
+		t.Errorf("expected no diagnostics after replacing the text, got %+v", notifs)
+	}
+}
+
+func TestDependentsRechecked(t *testing.T) {
+	dir := t.TempDir()
+	libPath := filepath.Join(dir, "lib", "lib.sy")
+	lib := "module \"lib\"\n\npub func one() -> int {\n    return 1;\n}\n"
+	if err := os.MkdirAll(filepath.Dir(libPath), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(libPath, []byte(lib), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	mainPath := filepath.Join(dir, "main.sy")
+
+	var out bytes.Buffer
+	l := New(&out)
+	openDocument(t, l, libPath, lib)
+	openDocument(t, l, mainPath, "import \"./lib\"\n\nconst x = lib:one();\n")
+	if deps := l.dependents(libPath); len(deps) != 1 || deps[0].path != mainPath {
+		t.Fatalf("expected main.sy to depend on lib.sy, got %v", deps)
+	}
+	out.Reset()
+
+	// renaming the function breaks main.sy without it being edited
+	changeDocument(t, l, libPath, 2, messages.ContentChanges{Range: span(2, 9, 2, 12), Text: "two"})
+
+	published := map[string][]Diagnostic{}
+	for _, n := range readNotifications(t, &out) {
+		u, err := url.Parse(n.Params.Uri)
+		if err != nil {
+			t.Fatal(err)
+		}
+		published[u.Path] = n.Params.Diagnostics
+	}
+	diags, ok := published[mainPath]
+	if !ok {
+		t.Fatalf("expected main.sy to be rechecked, got diagnostics for %v", published)
+	}
+	found := false
+	for _, d := range diags {
+		found = found || d.Range.Start.Line == 2 && strings.Contains(d.Message, "lib:one")
+	}
+	if !found {
+		t.Errorf("expected the call to the renamed function to be diagnosed, got %+v", diags)
+	}
+	if len(published[libPath]) != 0 {
+		t.Errorf("expected lib.sy to stay clean, got %+v", published[libPath])
+	}
+}
+
+func TestSyntaxErrorDiagnostics(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		line  int
+		col   int
+		end   int // the column the diagnostic's range ends at
+	}{
+		{
+			"missing comma in interface",
+			"define interface Shape { area() -> int perimeter() -> int }\nfunc f(Shape s) -> int {\n    return s.area();\n}\n",
+			0, 39, 48,
+		},
+		{
+			"missing expression",
+			"const a = 1 +;\nconst b = a;\n",
+			0, 13, 14,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			l := New(&out)
+			path := filepath.Join(t.TempDir(), "bad.sy")
+			openDocument(t, l, path, tt.input)
+
+			notifs := readNotifications(t, &out)
+			if len(notifs) == 0 {
+				t.Fatalf("expected diagnostics to be published")
+			}
+			// the parser errors are published on their own before checking types
+			first := notifs[0].Params.Diagnostics
+			if len(first) != 1 || first[0].Source != ParserSource {
+				t.Errorf("expected only the parser diagnostic first, got %+v", first)
+			}
+
+			last := notifs[len(notifs)-1]
+			if last.Method != messages.PublishDiagnostics {
+				t.Fatalf("expected method %s, got %s", messages.PublishDiagnostics, last.Method)
+			}
+
+			var syntax []Diagnostic
+			for _, d := range last.Params.Diagnostics {
+				if d.Source == ParserSource {
+					syntax = append(syntax, d)
+				}
+			}
+			if len(syntax) != 1 {
+				t.Fatalf("expected 1 parser diagnostic, got %d: %+v", len(syntax), last.Params.Diagnostics)
+			}
+			start, end := syntax[0].Range.Start, syntax[0].Range.End
+			if start.Line != tt.line || start.Character != tt.col {
+				t.Errorf("expected diagnostic at %d:%d, got %d:%d", tt.line, tt.col, start.Line, start.Character)
+			}
+			if end.Line != tt.line || end.Character != tt.end {
+				t.Errorf("expected diagnostic to end at %d:%d, got %d:%d", tt.line, tt.end, end.Line, end.Character)
+			}
+		})
+	}
+}
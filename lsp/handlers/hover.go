@@ -6,6 +6,7 @@ import (
 	"log"
 	"sydney/ast"
 	"sydney/lsp/messages"
+	"sydney/typechecker"
 	"sydney/types"
 )
 
@@ -26,18 +27,20 @@ func (l *LSP) HandleHover(req *messages.Request) {
 			log.Printf("Hover panicked: %v", r)
 		}
 	}()
-	if l.program == nil || l.env == nil {
-		return
-	}
 	var params messages.HoverParams
 	err := json.Unmarshal(req.Params, &params)
 	if err != nil {
 		log.Printf("%s Error unmarshalling params: %v", messages.Hover, err)
 		return
 	}
+	a := l.checked(params.TextDocument.URI)
+	if a == nil {
+		return
+	}
+	env := a.checker.Env()
 
 	log.Printf("hover: line=%d col=%d", params.Position.Line+1, params.Position.Character+1)
-	ident, foundScope := ast.FindAt(l.program, params.Position.Line+1, params.Position.Character+1)
+	ident, foundScope := ast.FindAt(a.program, params.Position.Line+1, params.Position.Character+1)
 	if ident == nil {
 		log.Printf("hover: no ident found")
 		return
@@ -50,13 +53,13 @@ func (l *LSP) HandleHover(req *messages.Request) {
 		typ, _, ok = foundScope.Get(ident.Value)
 		log.Printf("hover: found block scoped type %s", typ)
 	} else {
-		typ, _, ok = l.env.Get(ident.Value)
+		typ, _, ok = env.Get(ident.Value)
 		log.Printf("hover: found globally scoped type %s", typ)
 	}
 	if !ok {
-		sel := ast.FindSelectorAt(l.program, params.Position.Line+1, params.Position.Character+1)
+		sel := ast.FindSelectorAt(a.program, params.Position.Line+1, params.Position.Character+1)
 		if sel != nil {
-			typ, ok = l.resolveMethodType(sel, ident, foundScope)
+			typ, ok = resolveMethodType(env, sel, ident, foundScope)
 		}
 	}
 	if !ok {
@@ -80,7 +83,7 @@ func (l *LSP) HandleHover(req *messages.Request) {
 	}
 }
 
-func (l *LSP) resolveMethodType(sel *ast.SelectorExpr, method *ast.Identifier, scope ast.Scope) (types.Type, bool) {
+func resolveMethodType(env *typechecker.TypeEnv, sel *ast.SelectorExpr, method *ast.Identifier, scope ast.Scope) (types.Type, bool) {
 	if receiver, ok := sel.Left.(*ast.Identifier); ok {
 		var receiverType types.Type
 		var found bool
@@ -88,7 +91,7 @@ func (l *LSP) resolveMethodType(sel *ast.SelectorExpr, method *ast.Identifier, s
 			receiverType, _, found = scope.Get(receiver.Value)
 		}
 		if !found {
-			receiverType, _, found = l.env.Get(receiver.Value)
+			receiverType, _, found = env.Get(receiver.Value)
 		}
 		if !found || receiverType == nil {
 			return nil, false
@@ -112,7 +115,7 @@ func (l *LSP) resolveMethodType(sel *ast.SelectorExpr, method *ast.Identifier, s
 				return typ, true
 			}
 		}
-		if typ, _, ok := l.env.Get(mangled); ok {
+		if typ, _, ok := env.Get(mangled); ok {
 			return typ, true
 		}
 	}
@@ -1,13 +1,67 @@
 package handlers
 
 import (
+	"encoding/json"
 	"log"
+	"net/url"
 	"sydney/lsp/messages"
 )
 
 type ServerCapabilities struct {
-	TextDocumentSync int  `json:"textDocumentSync"`
-	HoverProvider    bool `json:"hoverProvider"`
+	TextDocumentSync        TextDocumentSyncOptions `json:"textDocumentSync"`
+	HoverProvider           bool                    `json:"hoverProvider"`
+	DefinitionProvider      bool                    `json:"definitionProvider"`
+	ReferencesProvider      bool                    `json:"referencesProvider"`
+	RenameProvider          RenameOptions           `json:"renameProvider"`
+	CompletionProvider      CompletionOptions       `json:"completionProvider"`
+	SignatureHelpProvider   SignatureHelpOptions    `json:"signatureHelpProvider"`
+	InlayHintProvider       bool                    `json:"inlayHintProvider"`
+	DocumentSymbolProvider  bool                    `json:"documentSymbolProvider"`
+	WorkspaceSymbolProvider bool                    `json:"workspaceSymbolProvider"`
+	SemanticTokensProvider  SemanticTokensOptions   `json:"semanticTokensProvider"`
+	CodeActionProvider      bool                    `json:"codeActionProvider"`
+}
+
+// the kinds of TextDocumentSyncOptions.Change
+const (
+	FullSync        = 1
+	IncrementalSync = 2
+)
+
+type TextDocumentSyncOptions struct {
+	OpenClose bool        `json:"openClose"`
+	Change    int         `json:"change"`
+	Save      SaveOptions `json:"save"`
+}
+
+type SaveOptions struct {
+	IncludeText bool `json:"includeText"`
+}
+
+type CompletionOptions struct {
+	TriggerCharacters []string `json:"triggerCharacters"`
+}
+
+type SignatureHelpOptions struct {
+	TriggerCharacters []string `json:"triggerCharacters"`
+}
+
+type SemanticTokensOptions struct {
+	Legend SemanticTokensLegend `json:"legend"`
+	Full   SemanticTokensFull   `json:"full"`
+}
+
+type SemanticTokensLegend struct {
+	TokenTypes     []string `json:"tokenTypes"`
+	TokenModifiers []string `json:"tokenModifiers"`
+}
+
+type SemanticTokensFull struct {
+	Delta bool `json:"delta"`
+}
+
+type RenameOptions struct {
+	PrepareProvider bool `json:"prepareProvider"`
 }
 
 type InitializeResult struct {
@@ -23,14 +77,43 @@ type ServerInfo struct {
 }
 
 func (l *LSP) HandleInitialize(r *messages.Request) {
+	var params messages.InitializeParams
+	if err := json.Unmarshal(r.Params, &params); err != nil {
+		log.Printf("%s: Error unmarshalling params: %v", messages.Initialize, err)
+	}
+	root := params.RootURI
+	if len(params.WorkspaceFolders) > 0 {
+		root = params.WorkspaceFolders[0].URI
+	}
+	if u, err := url.Parse(root); err == nil {
+		l.root = u.Path
+	}
+
 	res := &InitializeResult{
 		ServerInfo: ServerInfo{
 			Name:    "sydney-lsp",
 			Version: "0.1.0",
 		},
 		Capabilities: ServerCapabilities{
-			HoverProvider:    true,
-			TextDocumentSync: 1,
+			HoverProvider:           true,
+			DefinitionProvider:      true,
+			ReferencesProvider:      true,
+			RenameProvider:          RenameOptions{PrepareProvider: true},
+			CompletionProvider:      CompletionOptions{TriggerCharacters: []string{".", ":"}},
+			SignatureHelpProvider:   SignatureHelpOptions{TriggerCharacters: []string{"(", ","}},
+			InlayHintProvider:       true,
+			DocumentSymbolProvider:  true,
+			WorkspaceSymbolProvider: true,
+			SemanticTokensProvider: SemanticTokensOptions{
+				Legend: SemanticTokensLegend{TokenTypes: tokenTypes, TokenModifiers: tokenModifiers},
+				Full:   SemanticTokensFull{Delta: true},
+			},
+			CodeActionProvider: true,
+			TextDocumentSync: TextDocumentSyncOptions{
+				OpenClose: true,
+				Change:    IncrementalSync,
+				Save:      SaveOptions{IncludeText: true},
+			},
 		},
 	}
 
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"strings"
+
+	"sydney/lsp/messages"
+)
+
+func (l *LSP) HandleInlayHint(req *messages.Request) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("InlayHint panicked: %v", r)
+		}
+	}()
+
+	hints := messages.InlayHints{}
+	resp := &messages.Response{
+		Id:      req.Id,
+		Version: messages.Version,
+		Result:  hints,
+	}
+	defer func() {
+		if err := l.WriteResponse(resp); err != nil {
+			log.Printf("%s: Error writing response: %v", messages.InlayHint, err)
+		}
+	}()
+
+	var params messages.InlayHintParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		log.Printf("%s Error unmarshalling params: %v", messages.InlayHint, err)
+		return
+	}
+	a := l.checked(params.TextDocument.URI)
+	if a == nil {
+		return
+	}
+
+	for _, inf := range a.checker.Inferences(a.file) {
+		line := inf.Line - 1
+		if line < params.Range.Start.Line || line > params.Range.End.Line {
+			continue
+		}
+		if inf.Type != nil {
+			// types are written before the name, as in const int x
+			hints = append(hints, messages.InlayHintItem{
+				Position:     messages.Position{Line: line, Character: inf.Col - 1},
+				Label:        inf.Type.Signature(),
+				Kind:         messages.TypeHint,
+				PaddingRight: true,
+			})
+		}
+		if len(inf.TypeArgs) > 0 {
+			args := make([]string, len(inf.TypeArgs))
+			for i, arg := range inf.TypeArgs {
+				args[i] = arg.Signature()
+			}
+			hints = append(hints, messages.InlayHintItem{
+				Position: messages.Position{Line: line, Character: inf.Col - 1 + len(inf.Name)},
+				Label:    "<" + strings.Join(args, ", ") + ">",
+				Kind:     messages.TypeHint,
+			})
+		}
+	}
+	resp.Result = hints
+}
@@ -12,6 +12,7 @@ import (
 
 	"sydney/ast"
 	"sydney/codegen"
+	"sydney/errors"
 	"sydney/lexer"
 	"sydney/loader"
 	"sydney/lsp/messages"
@@ -21,15 +22,39 @@ import (
 )
 
 type LSP struct {
-	program *ast.Program
-	env     *typechecker.TypeEnv
+	documents map[string]*document // the open files, by path
+	root      string
+
+	// the outlines of the files searched for workspace symbols, by path
+	symbols map[string]*fileSymbols
+
+	// numbers the semantic token results sent
+	tokensVersion int
 
 	w io.Writer
 }
 
+// analysis is the result of checking one file: the program it was checked
+// as (all of a module's files merged), the file's own statements, and the
+// checker that resolved them
+type analysis struct {
+	file     string
+	src      string
+	program  *ast.Program
+	document *ast.Program
+	checker  *typechecker.Checker
+	modules  map[string]map[string]types.Type
+	packages []*loader.Package
+	// syntax errors, which leave out the statements they are in
+	syntaxErrs []errors.PositionError
+	errs       []errors.PositionError
+}
+
 func New(w io.Writer) *LSP {
 	return &LSP{
-		w: w,
+		documents: map[string]*document{},
+		symbols:   map[string]*fileSymbols{},
+		w:         w,
 	}
 }
 
@@ -57,23 +82,25 @@ func (l *LSP) WriteNotification(r *messages.Notification) error {
 	return nil
 }
 
-func (l *LSP) parse(method messages.Method, filePath string, src string) {
+// analyze parses and checks src, calling parsed, when given, with the syntax
+// errors before checking types
+func (l *LSP) analyze(method messages.Method, filePath string, src string, parsed func([]errors.PositionError)) (a *analysis) {
 	defer func() {
 		if r := recover(); r != nil {
 			buf := make([]byte, 4096)
 			n := runtime.Stack(buf, false)
 			log.Printf("%s: PANIC: %v\n%s", method, r, buf[:n])
+			a = nil
 		}
 	}()
 
 	if strings.HasPrefix(src, "module ") {
-		l.parseModule(method, filePath, src)
-	} else {
-		l.parseProgram(method, filePath, src)
+		return l.parseModule(method, filePath, src, parsed)
 	}
+	return l.parseProgram(method, filePath, src, parsed)
 }
 
-func (l *LSP) parseModule(method messages.Method, filePath string, src string) {
+func (l *LSP) parseModule(method messages.Method, filePath string, src string, parsed func([]errors.PositionError)) *analysis {
 	sourceDir := filepath.Dir(filePath)
 	base := filepath.Base(filePath)
 
@@ -81,6 +108,7 @@ func (l *LSP) parseModule(method messages.Method, filePath string, src string) {
 
 	allStructs := map[string]types.Type{}
 	allInterfaces := map[string]types.Type{}
+	allEnums := map[string]types.Type{}
 	for _, source := range allSources {
 		scan := parser.New(lexer.New(source))
 		scan.ParseDefinitions()
@@ -90,18 +118,24 @@ func (l *LSP) parseModule(method messages.Method, filePath string, src string) {
 		for k, v := range scan.DefinedInterfaces() {
 			allInterfaces[k] = v
 		}
+		for k, v := range scan.DefinedEnums() {
+			allEnums[k] = v
+		}
 	}
 
 	var allImports []string
 	var programs []*ast.Program
 	var currentProgram *ast.Program
+	var syntaxErrs []errors.PositionError
 	for i, source := range allSources {
 		p := parser.New(lexer.New(source))
-		p.SetDefinedTypes(allStructs, allInterfaces)
+		p.SetFile(filepath.Join(sourceDir, allNames[i]))
+		p.SetDefinedTypes(allStructs, allInterfaces, allEnums)
 		prog := p.ParseProgram()
 		if len(p.Errors()) > 0 {
+			// check what parsed, so the rest of the file still works
 			log.Printf("%s: parse errors in %s: %v", method, allNames[i], p.Errors())
-			continue
+			syntaxErrs = append(syntaxErrs, p.Errors()...)
 		}
 		codegen.ExpandDerives(prog)
 		for _, imp := range loader.ScanImports(source) {
@@ -110,24 +144,30 @@ func (l *LSP) parseModule(method messages.Method, filePath string, src string) {
 		for _, imp := range codegen.ScanDeriveImports(source) {
 			allImports = append(allImports, imp)
 		}
+		prog.File = filepath.Join(sourceDir, allNames[i])
 		programs = append(programs, prog)
 		if allNames[i] == base {
 			currentProgram = prog
 		}
 	}
 
+	if parsed != nil {
+		parsed(syntaxErrs)
+	}
+
 	if currentProgram == nil {
 		log.Printf("%s: could not find current file in parsed programs", method)
-		return
+		return nil
 	}
 
 	ld := loader.NewFromImports(allImports)
 	stdLib := loader.ResolveStdlib(sourceDir)
 	ld.SetPaths(stdLib, sourceDir)
+	ld.SetOverlay(l.overlay())
 	packages, tt, _, err := ld.Load(make(map[string]bool))
 	if err != nil {
 		log.Printf("%s: Error loading packages: %v", method, err)
-		return
+		return nil
 	}
 
 	for _, pkg := range packages {
@@ -148,19 +188,16 @@ func (l *LSP) parseModule(method messages.Method, filePath string, src string) {
 	typeEnv := typechecker.NewTypeEnv(nil)
 	c := typechecker.NewWithModuleTypes(typeEnv, tt)
 	c.SetCurrentModule(moduleName)
+	c.SetFiles(programs)
 	errs := c.CheckAsPackage(merged, packages)
 	if len(errs) > 0 {
 		log.Printf("%s: Errors found: %v", method, errs)
 	}
-	log.Printf("%s: Sending diagnostics for file %s", method, filePath)
-	l.SendTypecheckerDiagnostics(errs, filePath)
 
-	l.program = merged
-	l.env = c.Env()
-	log.Printf("%s: parsed %d statements", method, len(merged.Stmts))
+	return &analysis{file: currentProgram.File, src: src, program: merged, document: currentProgram, checker: c, modules: tt, packages: packages, syntaxErrs: syntaxErrs, errs: errs}
 }
 
-func (l *LSP) parseProgram(method messages.Method, filePath string, src string) {
+func (l *LSP) parseProgram(method messages.Method, filePath string, src string, parsed func([]errors.PositionError)) *analysis {
 	sourceDir := filepath.Dir(filePath)
 
 	typeEnv := typechecker.NewTypeEnv(nil)
@@ -171,15 +208,23 @@ func (l *LSP) parseProgram(method messages.Method, filePath string, src string)
 	ld := loader.NewFromImports(imports)
 	stdLib := loader.ResolveStdlib(sourceDir)
 	ld.SetPaths(stdLib, sourceDir)
+	ld.SetOverlay(l.overlay())
 	packages, tt, gns, err := ld.Load(make(map[string]bool))
 	if err != nil {
 		log.Printf("%s: Error loading packages: %v", method, err)
-		return
+		return nil
 	}
 
 	lx := lexer.New(src)
 	p := parser.NewWithGenericNames(lx, gns)
+	p.SetFile(filePath)
 	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		log.Printf("%s: parse errors in %s: %v", method, filePath, p.Errors())
+	}
+	if parsed != nil {
+		parsed(p.Errors())
+	}
 
 	for _, pkg := range packages {
 		for _, pr := range pkg.Programs {
@@ -188,18 +233,16 @@ func (l *LSP) parseProgram(method messages.Method, filePath string, src string)
 	}
 
 	codegen.ExpandDerives(program)
+	program.File = filePath
 
 	c := typechecker.NewWithModuleTypes(typeEnv, tt)
+	c.SetFiles([]*ast.Program{program})
 	errs := c.Check(program, packages)
 	if len(errs) > 0 {
 		log.Printf("%s: Errors found: %v", method, errs)
 	}
-	log.Printf("%s: Sending diagnostics for file %s", method, filePath)
-	l.SendTypecheckerDiagnostics(errs, filePath)
 
-	l.program = program
-	l.env = c.Env()
-	log.Printf("%s: parsed %d statements", method, len(program.Stmts))
+	return &analysis{file: filePath, src: src, program: program, document: program, checker: c, modules: tt, packages: packages, syntaxErrs: p.Errors(), errs: errs}
 }
 
 func (l *LSP) readDirSources(dir, currentBase, currentSrc string) ([]string, []string) {
@@ -219,11 +262,11 @@ func (l *LSP) readDirSources(dir, currentBase, currentSrc string) ([]string, []s
 			names = append(names, entry.Name())
 			continue
 		}
-		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		source, err := l.source(filepath.Join(dir, entry.Name()))
 		if err != nil {
 			continue
 		}
-		sources = append(sources, string(data))
+		sources = append(sources, source)
 		names = append(names, entry.Name())
 	}
 
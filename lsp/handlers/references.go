@@ -0,0 +1,267 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io/fs"
+	"log"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"sydney/lexer"
+	"sydney/lsp/messages"
+	"sydney/token"
+	"sydney/typechecker"
+)
+
+func (l *LSP) HandleReferences(req *messages.Request) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("References panicked: %v", r)
+		}
+	}()
+
+	resp := &messages.Response{
+		Id:      req.Id,
+		Version: messages.Version,
+	}
+	defer func() {
+		if err := l.WriteResponse(resp); err != nil {
+			log.Printf("%s: Error writing response: %v", messages.References, err)
+		}
+	}()
+
+	var params messages.ReferenceParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		log.Printf("%s Error unmarshalling params: %v", messages.References, err)
+		return
+	}
+	a := l.checked(params.TextDocument.URI)
+	if a == nil {
+		return
+	}
+
+	_, decl, ok := a.resolve(params.Position)
+	if !ok {
+		log.Printf("references: cannot resolve symbol at %d:%d", params.Position.Line+1, params.Position.Character+1)
+		return
+	}
+
+	locations := messages.Locations{}
+	for _, ref := range l.references(messages.References, a, decl, params.Context.IncludeDeclaration) {
+		locations = append(locations, *location(ref.File, ref.Line, ref.Col, len(decl.Name.Value)))
+	}
+	resp.Result = locations
+}
+
+func (l *LSP) HandlePrepareRename(req *messages.Request) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("PrepareRename panicked: %v", r)
+		}
+	}()
+
+	resp := &messages.Response{
+		Id:      req.Id,
+		Version: messages.Version,
+	}
+	defer func() {
+		if err := l.WriteResponse(resp); err != nil {
+			log.Printf("%s: Error writing response: %v", messages.PrepareRename, err)
+		}
+	}()
+
+	var params messages.DefinitionParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		log.Printf("%s Error unmarshalling params: %v", messages.PrepareRename, err)
+		return
+	}
+	a := l.checked(params.TextDocument.URI)
+	if a == nil {
+		return
+	}
+
+	// only names the checker can resolve can be renamed, which rules out
+	// builtins, keywords and types
+	ident, _, ok := a.resolve(params.Position)
+	if !ok {
+		return
+	}
+	line, col := ident.Pos()
+	resp.Result = &messages.PrepareRenameResult{
+		Range:       nameRange(line, col, len(ident.Value)),
+		Placeholder: ident.Value,
+	}
+}
+
+func (l *LSP) HandleRename(req *messages.Request) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Rename panicked: %v", r)
+		}
+	}()
+
+	resp := &messages.Response{
+		Id:      req.Id,
+		Version: messages.Version,
+	}
+	defer func() {
+		if err := l.WriteResponse(resp); err != nil {
+			log.Printf("%s: Error writing response: %v", messages.Rename, err)
+		}
+	}()
+
+	var params messages.RenameParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		log.Printf("%s Error unmarshalling params: %v", messages.Rename, err)
+		return
+	}
+	a := l.checked(params.TextDocument.URI)
+	if a == nil {
+		return
+	}
+
+	if !isIdentifier(params.NewName) {
+		resp.Error = &messages.ResponseError{Code: messages.RequestFailed, Message: params.NewName + " is not a valid identifier"}
+		return
+	}
+	_, decl, ok := a.resolve(params.Position)
+	if !ok {
+		resp.Error = &messages.ResponseError{Code: messages.RequestFailed, Message: "no symbol to rename here"}
+		return
+	}
+
+	edit := &messages.WorkspaceEdit{Changes: map[string][]messages.TextEdit{}}
+	for _, ref := range l.references(messages.Rename, a, decl, true) {
+		uri := url.URL{Path: ref.File, Scheme: "file"}
+		edit.Changes[uri.String()] = append(edit.Changes[uri.String()], messages.TextEdit{
+			Range:   nameRange(ref.Line, ref.Col, len(decl.Name.Value)),
+			NewText: params.NewName,
+		})
+	}
+	resp.Result = edit
+}
+
+// references finds every use of decl across the checked file, the packages
+// it loads and the rest of the workspace. Interface methods and the struct
+// methods implementing them are treated as one symbol
+func (l *LSP) references(method messages.Method, current *analysis, decl typechecker.Declaration, includeDeclaration bool) []typechecker.Reference {
+	analyses := append([]*analysis{current}, l.workspace(method, current, decl.Name.Value)...)
+
+	decls := []typechecker.Declaration{decl}
+	for i := 0; i < len(decls); i++ {
+		for _, a := range analyses {
+			for _, impl := range a.checker.Implementations(decls[i]) {
+				if !containsDeclaration(decls, impl) {
+					decls = append(decls, impl)
+				}
+			}
+		}
+	}
+
+	seen := map[typechecker.Reference]bool{}
+	var refs []typechecker.Reference
+	add := func(ref typechecker.Reference) {
+		if ref.File == "" {
+			ref.File = current.file
+		}
+		// generated code has no position to point at
+		if ref.Line <= 0 || seen[ref] {
+			return
+		}
+		seen[ref] = true
+		refs = append(refs, ref)
+	}
+
+	for _, d := range decls {
+		if includeDeclaration {
+			line, col := d.Name.Pos()
+			add(typechecker.Reference{File: d.File, Line: line, Col: col})
+		}
+		for _, a := range analyses {
+			for _, ref := range a.checker.References(d) {
+				add(ref)
+			}
+		}
+	}
+
+	sort.Slice(refs, func(i, j int) bool {
+		a, b := refs[i], refs[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Col < b.Col
+	})
+	return refs
+}
+
+// workspace checks the files of the workspace other than the current one
+// that mention name, reading open files as the editor has them. A module
+// directory is checked once, as a whole
+func (l *LSP) workspace(method messages.Method, current *analysis, name string) []*analysis {
+	root := l.root
+	if root == "" {
+		root = filepath.Dir(current.file)
+	}
+
+	currentDir := filepath.Dir(current.file)
+	currentIsModule := current.program != current.document
+
+	var analyses []*analysis
+	checkedDirs := map[string]bool{}
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if path != root && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, ".sy") || strings.HasSuffix(path, "_test.sy") || path == current.file {
+			return nil
+		}
+
+		dir := filepath.Dir(path)
+		if checkedDirs[dir] || (currentIsModule && dir == currentDir) {
+			return nil
+		}
+		src, err := l.source(path)
+		if err != nil {
+			return nil
+		}
+		if !strings.Contains(src, name) {
+			return nil
+		}
+
+		if strings.HasPrefix(src, "module ") {
+			checkedDirs[dir] = true
+		}
+		if a := l.analyze(method, path, src, nil); a != nil {
+			analyses = append(analyses, a)
+		}
+		return nil
+	})
+
+	return analyses
+}
+
+func containsDeclaration(decls []typechecker.Declaration, decl typechecker.Declaration) bool {
+	for _, d := range decls {
+		if d.Same(decl) {
+			return true
+		}
+	}
+	return false
+}
+
+func isIdentifier(name string) bool {
+	l := lexer.New(name)
+	tok := l.NextToken()
+	return tok.Type == token.Identifier && tok.Literal == name && l.NextToken().Type == token.EOF
+}
@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"sydney/lsp/messages"
+	"testing"
+)
+
+const shapesSource = `import "./lib"
+
+define interface Shape { area() -> int }
+define struct Square { side int }
+func area(Square s) -> int {
+    return s.side * s.side;
+}
+func total(Shape s) -> int {
+    return s.area() + lib:one();
+}
+const sq = Square { side: 2 };
+const t = total(sq) + sq.area() + lib:one();
+print(t);`
+
+func TestReferences(t *testing.T) {
+	l, out, mainPath, libPath := openWorkspace(t, shapesSource)
+
+	tests := []struct {
+		name               string
+		pos                messages.Position
+		includeDeclaration bool
+		expected           []messages.Location
+	}{
+		{
+			"interface method and its implementation",
+			messages.Position{Line: 2, Character: 26},
+			true,
+			[]messages.Location{
+				{URI: fileURI(mainPath), Range: *span(2, 25, 2, 29)},
+				{URI: fileURI(mainPath), Range: *span(4, 5, 4, 9)},
+				{URI: fileURI(mainPath), Range: *span(8, 13, 8, 17)},
+				{URI: fileURI(mainPath), Range: *span(11, 25, 11, 29)},
+			},
+		},
+		{
+			"implementation without declarations",
+			messages.Position{Line: 4, Character: 6},
+			false,
+			[]messages.Location{
+				{URI: fileURI(mainPath), Range: *span(8, 13, 8, 17)},
+				{URI: fileURI(mainPath), Range: *span(11, 25, 11, 29)},
+			},
+		},
+		{
+			"function in another file",
+			messages.Position{Line: 11, Character: 39},
+			true,
+			[]messages.Location{
+				{URI: fileURI(libPath), Range: *span(2, 9, 2, 12)},
+				{URI: fileURI(mainPath), Range: *span(8, 26, 8, 29)},
+				{URI: fileURI(mainPath), Range: *span(11, 38, 11, 41)},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		var locations []messages.Location
+		call(t, out, l.HandleReferences, messages.References, messages.ReferenceParams{
+			TextDocument: messages.TextDocumentIdentifier{URI: fileURI(mainPath)},
+			Position:     tt.pos,
+			Context:      messages.ReferenceContext{IncludeDeclaration: tt.includeDeclaration},
+		}, &locations)
+
+		if len(locations) != len(tt.expected) {
+			t.Errorf("%s: expected %d locations, got %+v", tt.name, len(tt.expected), locations)
+			continue
+		}
+		for i, want := range tt.expected {
+			if locations[i] != want {
+				t.Errorf("%s: expected location %d to be %+v, got %+v", tt.name, i, want, locations[i])
+			}
+		}
+	}
+}
+
+func TestRename(t *testing.T) {
+	l, out, mainPath, libPath := openWorkspace(t, shapesSource)
+
+	tests := []struct {
+		name     string
+		pos      messages.Position
+		expected map[string][]messages.Range
+	}{
+		{
+			"interface method and its implementation",
+			messages.Position{Line: 8, Character: 14},
+			map[string][]messages.Range{
+				fileURI(mainPath): {*span(2, 25, 2, 29), *span(4, 5, 4, 9), *span(8, 13, 8, 17), *span(11, 25, 11, 29)},
+			},
+		},
+		{
+			"function in another file",
+			messages.Position{Line: 8, Character: 27},
+			map[string][]messages.Range{
+				fileURI(libPath):  {*span(2, 9, 2, 12)},
+				fileURI(mainPath): {*span(8, 26, 8, 29), *span(11, 38, 11, 41)},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		var edit messages.WorkspaceEdit
+		call(t, out, l.HandleRename, messages.Rename, messages.RenameParams{
+			TextDocument: messages.TextDocumentIdentifier{URI: fileURI(mainPath)},
+			Position:     tt.pos,
+			NewName:      "renamed",
+		}, &edit)
+
+		if len(edit.Changes) != len(tt.expected) {
+			t.Errorf("%s: expected edits to %d files, got %+v", tt.name, len(tt.expected), edit.Changes)
+			continue
+		}
+		for uri, ranges := range tt.expected {
+			edits := edit.Changes[uri]
+			if len(edits) != len(ranges) {
+				t.Errorf("%s: expected %d edits to %s, got %+v", tt.name, len(ranges), uri, edits)
+				continue
+			}
+			for i, r := range ranges {
+				if edits[i].Range != r || edits[i].NewText != "renamed" {
+					t.Errorf("%s: expected edit %d of %s to rename %v, got %+v", tt.name, i, uri, r, edits[i])
+				}
+			}
+		}
+	}
+}
+
+func TestPrepareRename(t *testing.T) {
+	l, out, mainPath, _ := openWorkspace(t, shapesSource)
+
+	tests := []struct {
+		name     string
+		pos      messages.Position
+		expected *messages.PrepareRenameResult
+	}{
+		{"function", messages.Position{Line: 11, Character: 12}, &messages.PrepareRenameResult{Range: *span(11, 10, 11, 15), Placeholder: "total"}},
+		{"constant", messages.Position{Line: 12, Character: 6}, &messages.PrepareRenameResult{Range: *span(12, 6, 12, 7), Placeholder: "t"}},
+		{"builtin", messages.Position{Line: 12, Character: 2}, nil},
+		{"keyword", messages.Position{Line: 10, Character: 2}, nil},
+	}
+
+	for _, tt := range tests {
+		var result *messages.PrepareRenameResult
+		call(t, out, l.HandlePrepareRename, messages.PrepareRename, messages.DefinitionParams{
+			TextDocument: messages.TextDocumentIdentifier{URI: fileURI(mainPath)},
+			Position:     tt.pos,
+		}, &result)
+
+		if (result == nil) != (tt.expected == nil) || result != nil && *result != *tt.expected {
+			t.Errorf("%s: expected %+v, got %+v", tt.name, tt.expected, result)
+		}
+	}
+}
@@ -0,0 +1,387 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"path/filepath"
+	"strconv"
+
+	"sydney/ast"
+	"sydney/lexer"
+	"sydney/lsp/messages"
+	"sydney/object"
+	"sydney/token"
+	"sydney/typechecker"
+	"sydney/types"
+)
+
+// token types, in the order of tokenTypes
+const (
+	namespaceToken = iota
+	typeToken
+	structToken
+	interfaceToken
+	typeParameterToken
+	parameterToken
+	variableToken
+	propertyToken
+	functionToken
+	methodToken
+	macroToken
+	keywordToken
+	stringToken
+	numberToken
+	enumToken
+	enumMemberToken
+)
+
+var tokenTypes = []string{
+	"namespace", "type", "struct", "interface", "typeParameter", "parameter", "variable",
+	"property", "function", "method", "macro", "keyword", "string", "number",
+	"enum", "enumMember",
+}
+
+// token modifier bits, in the order of tokenModifiers
+const (
+	declarationModifier = 1 << iota
+	readonlyModifier
+	defaultLibraryModifier
+)
+
+var tokenModifiers = []string{"declaration", "readonly", "defaultLibrary"}
+
+type semanticToken struct {
+	line, col, length int // 1-based, as the lexer reports them
+	typ, modifiers    int
+}
+
+func (l *LSP) HandleSemanticTokens(req *messages.Request) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("SemanticTokens panicked: %v", r)
+		}
+	}()
+
+	result := &messages.SemanticTokens{Data: []int{}}
+	resp := &messages.Response{
+		Id:      req.Id,
+		Version: messages.Version,
+		Result:  result,
+	}
+	defer func() {
+		if err := l.WriteResponse(resp); err != nil {
+			log.Printf("%s: Error writing response: %v", messages.SemanticTokensFull, err)
+		}
+	}()
+
+	var params messages.SemanticTokensParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		log.Printf("%s Error unmarshalling params: %v", messages.SemanticTokensFull, err)
+		return
+	}
+	doc := l.document(params.TextDocument.URI)
+	if doc == nil || doc.analysis == nil {
+		return
+	}
+
+	result.Data = encodeTokens(semanticTokens(doc.analysis))
+	result.ResultID = l.rememberTokens(doc, result.Data)
+}
+
+func (l *LSP) HandleSemanticTokensDelta(req *messages.Request) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("SemanticTokensDelta panicked: %v", r)
+		}
+	}()
+
+	resp := &messages.Response{
+		Id:      req.Id,
+		Version: messages.Version,
+		Result:  &messages.SemanticTokens{Data: []int{}},
+	}
+	defer func() {
+		if err := l.WriteResponse(resp); err != nil {
+			log.Printf("%s: Error writing response: %v", messages.SemanticTokensFullDelta, err)
+		}
+	}()
+
+	var params messages.SemanticTokensDeltaParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		log.Printf("%s Error unmarshalling params: %v", messages.SemanticTokensFullDelta, err)
+		return
+	}
+	doc := l.document(params.TextDocument.URI)
+	if doc == nil || doc.analysis == nil {
+		return
+	}
+
+	previous, known := doc.tokens, params.PreviousResultID == doc.tokensID
+	data := encodeTokens(semanticTokens(doc.analysis))
+	id := l.rememberTokens(doc, data)
+	if !known {
+		// the client's copy is not the one we kept, so send everything
+		resp.Result = &messages.SemanticTokens{ResultID: id, Data: data}
+		return
+	}
+	resp.Result = &messages.SemanticTokensDelta{ResultID: id, Edits: diffTokens(previous, data)}
+}
+
+// rememberTokens keeps the latest result for a document's next delta
+// request
+func (l *LSP) rememberTokens(doc *document, data []int) string {
+	l.tokensVersion++
+	doc.tokensID = strconv.Itoa(l.tokensVersion)
+	doc.tokens = data
+	return doc.tokensID
+}
+
+// diffTokens describes cur as a single edit of prev, replacing whatever lies
+// between their common prefix and suffix
+func diffTokens(prev, cur []int) []messages.SemanticTokensEdit {
+	start := 0
+	for start < len(prev) && start < len(cur) && prev[start] == cur[start] {
+		start++
+	}
+	end := 0
+	for end < len(prev)-start && end < len(cur)-start && prev[len(prev)-1-end] == cur[len(cur)-1-end] {
+		end++
+	}
+	if start == len(prev) && start == len(cur) {
+		return []messages.SemanticTokensEdit{}
+	}
+	return []messages.SemanticTokensEdit{{
+		Start:       start,
+		DeleteCount: len(prev) - start - end,
+		Data:        append([]int{}, cur[start:len(cur)-end]...),
+	}}
+}
+
+func encodeTokens(tokens []semanticToken) []int {
+	data := make([]int, 0, len(tokens)*5)
+	prevLine, prevCol := 1, 1
+	for _, tok := range tokens {
+		deltaLine := tok.line - prevLine
+		deltaCol := tok.col - 1
+		if deltaLine == 0 {
+			deltaCol = tok.col - prevCol
+		}
+		data = append(data, deltaLine, deltaCol, tok.length, tok.typ, tok.modifiers)
+		prevLine, prevCol = tok.line, tok.col
+	}
+	return data
+}
+
+// semanticTokens classifies the tokens of the checked source, using what the
+// checker resolved each identifier to where the lexer alone cannot tell
+func semanticTokens(a *analysis) []semanticToken {
+	var tokens []token.Token
+	lx := lexer.New(a.src)
+	for tok := lx.NextToken(); tok.Type != token.EOF; tok = lx.NextToken() {
+		tokens = append(tokens, tok)
+	}
+
+	lineStarts := []int{0}
+	for i, ch := range a.src {
+		if ch == '\n' {
+			lineStarts = append(lineStarts, i+1)
+		}
+	}
+	typeParams := typeParameterNames(a.document)
+
+	var result []semanticToken
+	emit := func(tok token.Token, length, typ, modifiers int) {
+		result = append(result, semanticToken{tok.Line, tok.Column, length, typ, modifiers})
+	}
+
+	inAnnotation, depth := false, 0
+	for i, tok := range tokens {
+		switch tok.Type {
+		case token.AnnotationStart:
+			inAnnotation, depth = true, 0
+			emit(tok, len(tok.Literal), macroToken, 0)
+		case token.LeftSquareBracket:
+			if inAnnotation {
+				depth++
+			}
+		case token.RightSquareBracket:
+			if inAnnotation {
+				if depth == 0 {
+					inAnnotation = false
+				} else {
+					depth--
+				}
+			}
+		case token.String, token.Byte:
+			if tok.Line <= len(lineStarts) {
+				offset := lineStarts[tok.Line-1] + tok.Column - 1
+				if length, ok := quotedLength(a.src, offset); ok {
+					emit(tok, length, stringToken, 0)
+				}
+			}
+		case token.Integer, token.Float:
+			emit(tok, len(tok.Literal), numberToken, 0)
+		case token.Identifier:
+			if inAnnotation {
+				emit(tok, len(tok.Literal), macroToken, 0)
+				continue
+			}
+			if typ, modifiers, ok := classifyIdentifier(a, tokens, i, typeParams); ok {
+				emit(tok, len(tok.Literal), typ, modifiers)
+			}
+		default:
+			if tok.Literal == "" || !isIdentChar(tok.Literal[0]) {
+				continue
+			}
+			if lexer.IsTypeName(tok.Literal) {
+				emit(tok, len(tok.Literal), typeToken, defaultLibraryModifier)
+			} else {
+				emit(tok, len(tok.Literal), keywordToken, 0)
+			}
+		}
+	}
+	return result
+}
+
+func classifyIdentifier(a *analysis, tokens []token.Token, i int, typeParams map[string]bool) (int, int, bool) {
+	tok := tokens[i]
+	if decl, ok := a.checker.Definition(a.file, tok.Line, tok.Column); ok {
+		typ, modifiers := declarationToken(decl.Kind)
+		line, col := decl.Name.Pos()
+		if line == tok.Line && col == tok.Column && (decl.File == a.file || decl.File == "") {
+			modifiers |= declarationModifier
+		}
+		return typ, modifiers, true
+	}
+
+	tokenAt := func(j int) token.TokenType {
+		if j < 0 || j >= len(tokens) {
+			return token.EOF
+		}
+		return tokens[j].Type
+	}
+
+	// module:member
+	if tokenAt(i+1) == token.Colon && tokenAt(i+2) == token.Identifier && isModule(a, tok.Literal) {
+		return namespaceToken, 0, true
+	}
+	if tokenAt(i-1) == token.Colon && tokenAt(i-2) == token.Identifier && isModule(a, tokens[i-2].Literal) {
+		switch resolveScopeType(a, types.ScopeType{Module: tokens[i-2].Literal, Name: tok.Literal}).(type) {
+		case types.StructType:
+			return structToken, 0, true
+		case types.InterfaceType:
+			return interfaceToken, 0, true
+		case types.EnumType:
+			return enumToken, 0, true
+		}
+		return 0, 0, false
+	}
+
+	// type names outside struct literals are not recorded as uses
+	if decl, ok := a.checker.Env().Declaration(tok.Literal); ok {
+		switch decl.Kind {
+		case typechecker.StructDeclaration:
+			return structToken, 0, true
+		case typechecker.InterfaceDeclaration:
+			return interfaceToken, 0, true
+		case typechecker.EnumDeclaration:
+			return enumToken, 0, true
+		}
+	}
+	if typeParams[tok.Literal] {
+		return typeParameterToken, 0, true
+	}
+	if object.GetBuiltInByName(tok.Literal) != nil && tokenAt(i-1) != token.Dot {
+		return functionToken, defaultLibraryModifier, true
+	}
+	// match arm patterns
+	switch tok.Literal {
+	case "ok", "err", "some", "none":
+		if next := tokenAt(i + 1); next == token.LeftParen || next == token.Arrow {
+			return keywordToken, 0, true
+		}
+	}
+	return 0, 0, false
+}
+
+func declarationToken(kind typechecker.DeclarationKind) (int, int) {
+	switch kind {
+	case typechecker.ConstantDeclaration:
+		return variableToken, readonlyModifier
+	case typechecker.ParameterDeclaration:
+		return parameterToken, 0
+	case typechecker.FunctionDeclaration:
+		return functionToken, 0
+	case typechecker.MethodDeclaration:
+		return methodToken, 0
+	case typechecker.StructDeclaration:
+		return structToken, 0
+	case typechecker.InterfaceDeclaration:
+		return interfaceToken, 0
+	case typechecker.FieldDeclaration:
+		return propertyToken, 0
+	case typechecker.EnumDeclaration:
+		return enumToken, 0
+	case typechecker.VariantDeclaration:
+		return enumMemberToken, 0
+	}
+	return variableToken, 0
+}
+
+func isModule(a *analysis, name string) bool {
+	if _, ok := a.checker.Package(name); ok {
+		return true
+	}
+	for path := range a.modules {
+		if path == name || filepath.Base(path) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// typeParameterNames collects the type parameters of the generic functions,
+// structs and enums declared in program
+func typeParameterNames(program *ast.Program) map[string]bool {
+	names := map[string]bool{}
+	for _, stmt := range program.Stmts {
+		if pub, ok := stmt.(*ast.PubStatement); ok {
+			stmt = pub.Stmt
+		}
+		switch s := stmt.(type) {
+		case *ast.FunctionDeclarationStmt:
+			for _, tp := range s.TypeParams {
+				names[tp.Name] = true
+			}
+		case *ast.StructDefinitionStmt:
+			for _, tp := range s.Type.TypeParams {
+				names[tp.Name] = true
+			}
+		case *ast.EnumDefinitionStmt:
+			for _, tp := range s.Type.TypeParams {
+				names[tp.Name] = true
+			}
+		}
+	}
+	return names
+}
+
+// quotedLength measures the string or byte literal starting with the quote
+// at src[offset], or fails when it spans lines
+func quotedLength(src string, offset int) (int, bool) {
+	if offset >= len(src) {
+		return 0, false
+	}
+	quote := src[offset]
+	for i := offset + 1; i < len(src); i++ {
+		switch src[i] {
+		case '\\':
+			i++
+		case '\n':
+			return 0, false
+		case quote:
+			return i - offset + 1, true
+		}
+	}
+	return 0, false
+}
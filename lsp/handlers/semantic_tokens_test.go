@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"bytes"
+	"slices"
+	"sydney/lsp/messages"
+	"testing"
+)
+
+// tokensResult decodes both full and delta semantic token responses
+type tokensResult struct {
+	ResultID string                        `json:"resultId"`
+	Data     []int                         `json:"data"`
+	Edits    []messages.SemanticTokensEdit `json:"edits"`
+}
+
+func requestTokens(t *testing.T, l *LSP, out *bytes.Buffer, path, previous string) tokensResult {
+	t.Helper()
+	var result tokensResult
+	doc := messages.TextDocumentIdentifier{URI: fileURI(path)}
+	if previous == "" {
+		call(t, out, l.HandleSemanticTokens, messages.SemanticTokensFull, messages.SemanticTokensParams{TextDocument: doc}, &result)
+	} else {
+		call(t, out, l.HandleSemanticTokensDelta, messages.SemanticTokensFullDelta, messages.SemanticTokensDeltaParams{
+			TextDocument:     doc,
+			PreviousResultID: previous,
+		}, &result)
+	}
+	if result.ResultID == "" {
+		t.Fatalf("expected a result id, got %+v", result)
+	}
+	return result
+}
+
+// applyEdits edits the previous result's data as a client does
+func applyEdits(data []int, edits []messages.SemanticTokensEdit) []int {
+	data = slices.Clone(data)
+	for _, edit := range edits {
+		data = slices.Replace(data, edit.Start, edit.Start+edit.DeleteCount, edit.Data...)
+	}
+	return data
+}
+
+func TestSemanticTokens(t *testing.T) {
+	source := `const limit = 10;
+func twice(int n) -> int {
+    return n * limit;
+}
+print(twice(2));`
+	l, out, path, _ := openWorkspace(t, source)
+
+	// each token is relative to the one before: the line, then the
+	// character when on the same line
+	expected := []int{
+		0, 0, 5, keywordToken, 0,
+		0, 6, 5, variableToken, declarationModifier | readonlyModifier,
+		0, 8, 2, numberToken, 0,
+		1, 0, 4, keywordToken, 0,
+		0, 5, 5, functionToken, declarationModifier,
+		0, 6, 3, typeToken, defaultLibraryModifier,
+		0, 4, 1, parameterToken, declarationModifier,
+		0, 6, 3, typeToken, defaultLibraryModifier,
+		1, 4, 6, keywordToken, 0,
+		0, 7, 1, parameterToken, 0,
+		0, 4, 5, variableToken, readonlyModifier,
+		2, 0, 5, functionToken, defaultLibraryModifier,
+		0, 6, 5, functionToken, 0,
+		0, 6, 1, numberToken, 0,
+	}
+
+	result := requestTokens(t, l, out, path, "")
+	if !slices.Equal(result.Data, expected) {
+		t.Errorf("expected tokens\n%v\ngot\n%v", expected, result.Data)
+	}
+}
+
+func TestSemanticTokensDelta(t *testing.T) {
+	source := "const limit = 10;\nfunc twice(int n) -> int {\n    return n * limit;\n}\n"
+	l, out, path, _ := openWorkspace(t, source)
+	first := requestTokens(t, l, out, path, "")
+
+	// nothing changed
+	delta := requestTokens(t, l, out, path, first.ResultID)
+	if delta.Data != nil || len(delta.Edits) != 0 {
+		t.Errorf("expected no edits for an unchanged file, got %+v", delta)
+	}
+	previous := delta.ResultID
+
+	changeDocument(t, l, path, 2, messages.ContentChanges{Range: span(2, 15, 2, 20), Text: "2"})
+	delta = requestTokens(t, l, out, path, previous)
+	if delta.Data != nil || len(delta.Edits) != 1 {
+		t.Fatalf("expected a single edit, got %+v", delta)
+	}
+	// only the last token changed, from the variable limit to a number
+	expected := messages.SemanticTokensEdit{Start: 52, DeleteCount: 3, Data: []int{1, numberToken, 0}}
+	if edit := delta.Edits[0]; edit.Start != expected.Start || edit.DeleteCount != expected.DeleteCount || !slices.Equal(edit.Data, expected.Data) {
+		t.Errorf("expected edit %+v, got %+v", expected, edit)
+	}
+	edited := applyEdits(first.Data, delta.Edits)
+
+	// a result the server no longer has gets every token
+	full := requestTokens(t, l, out, path, first.ResultID)
+	if full.Edits != nil {
+		t.Fatalf("expected all tokens for an unknown result id, got %+v", full)
+	}
+	if !slices.Equal(edited, full.Data) {
+		t.Errorf("applying the edits gave\n%v\nbut the tokens are\n%v", edited, full.Data)
+	}
+}
+
+func TestDiffTokens(t *testing.T) {
+	tests := []struct {
+		name      string
+		prev, cur []int
+	}{
+		{"unchanged", []int{1, 2, 3}, []int{1, 2, 3}},
+		{"insert in the middle", []int{1, 2, 3}, []int{1, 2, 9, 9, 3}},
+		{"delete at the end", []int{1, 2, 3, 4}, []int{1, 2}},
+		{"replace at the start", []int{1, 2, 3}, []int{7, 2, 3}},
+		{"from nothing", nil, []int{1, 2}},
+		{"to nothing", []int{1, 2}, nil},
+		{"repeated values", []int{1, 1, 1}, []int{1, 1, 1, 1}},
+	}
+
+	for _, tt := range tests {
+		edits := diffTokens(tt.prev, tt.cur)
+		if slices.Equal(tt.prev, tt.cur) {
+			if len(edits) != 0 {
+				t.Errorf("%s: expected no edits, got %+v", tt.name, edits)
+			}
+			continue
+		}
+		if len(edits) != 1 {
+			t.Errorf("%s: expected one edit, got %+v", tt.name, edits)
+			continue
+		}
+		if got := applyEdits(tt.prev, edits); !slices.Equal(got, tt.cur) {
+			t.Errorf("%s: applying %+v gave %v, expected %v", tt.name, edits, got, tt.cur)
+		}
+	}
+}
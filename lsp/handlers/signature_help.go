@@ -0,0 +1,292 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"strings"
+
+	"sydney/ast"
+	"sydney/lexer"
+	"sydney/lsp/messages"
+	"sydney/object"
+	"sydney/token"
+	"sydney/typechecker"
+	"sydney/types"
+)
+
+// callSite is a call the cursor is in the argument list of
+type callSite struct {
+	name      token.Token     // the function or method name
+	qualifier token.TokenType // token.Dot for method calls, token.Colon for module functions
+	argument  int             // the argument the cursor is in
+}
+
+func (l *LSP) HandleSignatureHelp(req *messages.Request) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("SignatureHelp panicked: %v", r)
+		}
+	}()
+
+	resp := &messages.Response{
+		Id:      req.Id,
+		Version: messages.Version,
+	}
+	defer func() {
+		if err := l.WriteResponse(resp); err != nil {
+			log.Printf("%s: Error writing response: %v", messages.SignatureHelp, err)
+		}
+	}()
+
+	var params messages.SignatureHelpParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		log.Printf("%s Error unmarshalling params: %v", messages.SignatureHelp, err)
+		return
+	}
+	doc := l.document(params.TextDocument.URI)
+	if doc == nil {
+		return
+	}
+
+	call, ok := callAt(doc.text, params.Position)
+	if !ok {
+		return
+	}
+
+	a := l.analyze(messages.SignatureHelp, doc.path, doc.text, nil)
+	if a == nil {
+		// fall back to the last version of the file that checked
+		a = doc.analysis
+	}
+	if a == nil {
+		return
+	}
+
+	sig, ok := signature(a, call)
+	if !ok {
+		log.Printf("signatureHelp: cannot resolve %s", call.name.Literal)
+		return
+	}
+	active := call.argument
+	if active >= len(sig.Parameters) {
+		active = len(sig.Parameters) - 1
+	}
+	resp.Result = &messages.SignatureHelpResult{
+		Signatures:      []messages.SignatureInformation{sig},
+		ActiveParameter: max(active, 0),
+	}
+}
+
+// callAt finds the innermost call whose argument list contains pos, by
+// lexing the text before it and matching brackets
+func callAt(text string, pos messages.Position) (*callSite, bool) {
+	lines := strings.Split(text, "\n")
+	if pos.Line >= len(lines) {
+		return nil, false
+	}
+	offset := 0
+	for _, line := range lines[:pos.Line] {
+		offset += len(line) + 1
+	}
+	offset += min(pos.Character, len(lines[pos.Line]))
+
+	var tokens []token.Token
+	lx := lexer.New(text[:offset])
+	for tok := lx.NextToken(); tok.Type != token.EOF; tok = lx.NextToken() {
+		tokens = append(tokens, tok)
+	}
+
+	// one entry per open bracket; nil for those that do not start a call
+	type bracket struct {
+		tok  token.TokenType
+		call *callSite
+	}
+	var open []bracket
+	for i, tok := range tokens {
+		switch tok.Type {
+		case token.LeftParen:
+			open = append(open, bracket{tok.Type, calleeBefore(tokens, i)})
+		case token.LeftSquareBracket, token.LeftCurlyBracket:
+			open = append(open, bracket{tok.Type, nil})
+		case token.RightParen, token.RightSquareBracket, token.RightCurlyBracket:
+			if len(open) > 0 {
+				open = open[:len(open)-1]
+			}
+		case token.Comma:
+			if len(open) > 0 && open[len(open)-1].call != nil {
+				open[len(open)-1].call.argument++
+			}
+		}
+	}
+
+	for i := len(open) - 1; i >= 0; i-- {
+		if open[i].call != nil {
+			return open[i].call, true
+		}
+		// a block inside the arguments, e.g. a function literal's body
+		if open[i].tok == token.LeftCurlyBracket {
+			break
+		}
+	}
+	return nil, false
+}
+
+// calleeBefore returns the call whose ( is tokens[paren], or nil when the
+// parenthesis groups an expression or opens a declaration's parameters
+func calleeBefore(tokens []token.Token, paren int) *callSite {
+	i := paren - 1
+	// skip explicit type arguments
+	if i >= 0 && tokens[i].Type == token.GreaterThan {
+		depth := 0
+		for ; i >= 0; i-- {
+			if tokens[i].Type == token.GreaterThan {
+				depth++
+			} else if tokens[i].Type == token.LessThan {
+				depth--
+				if depth == 0 {
+					break
+				}
+			}
+		}
+		i--
+	}
+	if i < 0 || tokens[i].Type != token.Identifier {
+		return nil
+	}
+
+	call := &callSite{name: tokens[i]}
+	if i > 0 {
+		switch tokens[i-1].Type {
+		case token.Func:
+			return nil
+		case token.Dot, token.Colon:
+			call.qualifier = tokens[i-1].Type
+		}
+	}
+	return call
+}
+
+// signature describes the function called at call, with its type parameters
+// replaced by the type arguments of the call when the checker knows them
+func signature(a *analysis, call *callSite) (messages.SignatureInformation, bool) {
+	name := call.name.Literal
+	var fnType types.FunctionType
+	var paramNames []*ast.Identifier
+	var typeParams []*types.TypeParam
+	receiver := false
+
+	ok := false
+	decl, found := a.checker.Definition(a.file, call.name.Line, call.name.Column)
+	if !found && call.qualifier != token.Colon {
+		// a call still being typed does not parse, so the checker never
+		// saw it; look the name up instead
+		decl, _ = a.checker.Env().Declaration(name)
+	}
+	switch node := a.declaration(decl).(type) {
+	case *ast.FunctionDeclarationStmt:
+		fnType, ok = node.Type.(types.FunctionType)
+		paramNames = node.Params
+		typeParams = node.TypeParams
+		// the receiver of a method call is written before the name
+		receiver = call.qualifier == token.Dot && len(node.Params) > 0
+	case *ast.InterfaceDefinitionStmt:
+		for i, method := range node.MethodNames {
+			if method == decl.Name {
+				fnType, ok = node.Type.Types[i].(types.FunctionType)
+			}
+		}
+	default:
+		if builtin := object.GetBuiltInByName(name); builtin != nil && call.qualifier == "" {
+			fnType, ok = builtin.T, true
+		} else if t, _, found := a.checker.Env().Get(name); found {
+			fnType, ok = t.(types.FunctionType)
+		}
+	}
+	if !ok {
+		return messages.SignatureInformation{}, false
+	}
+
+	var typeArgs []types.Type
+	if len(typeParams) > 0 {
+		typeArgs, _ = a.checker.TypeArguments(a.file, call.name.Line, call.name.Column)
+	}
+	if len(typeArgs) == len(typeParams) && len(typeArgs) > 0 {
+		subs := make(map[string]types.Type)
+		for i, tp := range typeParams {
+			subs[tp.Name] = typeArgs[i]
+		}
+		fnType = types.SubstituteTypeParams(fnType, subs).(types.FunctionType)
+	}
+
+	var label strings.Builder
+	label.WriteString("func " + name)
+	if len(typeParams) > 0 {
+		label.WriteString("<")
+		for i, tp := range typeParams {
+			if i > 0 {
+				label.WriteString(", ")
+			}
+			if len(typeArgs) == len(typeParams) {
+				label.WriteString(typeArgs[i].Signature())
+			} else {
+				label.WriteString(tp.Signature())
+			}
+		}
+		label.WriteString(">")
+	}
+	label.WriteString("(")
+
+	var params []messages.ParameterInformation
+	for i, p := range fnType.Params {
+		if receiver && i == 0 {
+			continue
+		}
+		if len(params) > 0 {
+			label.WriteString(", ")
+		}
+		start := label.Len()
+		label.WriteString(p.Signature())
+		if i < len(paramNames) {
+			label.WriteString(" " + paramNames[i].Value)
+		}
+		params = append(params, messages.ParameterInformation{Label: [2]int{start, label.Len()}})
+	}
+	label.WriteString(")")
+	if fnType.Return != nil && fnType.Return != types.Unit {
+		label.WriteString(" -> " + fnType.Return.Signature())
+	}
+
+	return messages.SignatureInformation{Label: label.String(), Parameters: params}, true
+}
+
+// declaration finds the statement declaring decl among the checked program
+// and the packages it loaded
+func (a *analysis) declaration(decl typechecker.Declaration) ast.Stmt {
+	if decl.Name == nil {
+		return nil
+	}
+	programs := []*ast.Program{a.program}
+	for _, pkg := range a.packages {
+		programs = append(programs, pkg.Programs...)
+	}
+	for _, program := range programs {
+		for _, stmt := range program.Stmts {
+			if pub, ok := stmt.(*ast.PubStatement); ok {
+				stmt = pub.Stmt
+			}
+			switch s := stmt.(type) {
+			case *ast.FunctionDeclarationStmt:
+				if s.Name == decl.Name {
+					return s
+				}
+			case *ast.InterfaceDefinitionStmt:
+				for _, method := range s.MethodNames {
+					if method == decl.Name {
+						return s
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"strings"
+	"sydney/lsp/messages"
+	"testing"
+)
+
+func TestSignatureHelp(t *testing.T) {
+	source := `func scale(int factor, float x) -> float { return x; }
+define struct Point { x int, y int }
+func move(Point p, int dx, int dy) -> Point { return p; }
+func first<T>(T a, T b) -> T { return a; }
+const p = Point { x: 1, y: 2 };
+`
+
+	tests := []struct {
+		name     string
+		typed    string // the last line, with | at the cursor
+		label    string
+		params   [][2]int
+		expected int // the active parameter
+	}{
+		{"first argument", "const a = scale(|", "func scale(int factor, float x) -> float", [][2]int{{11, 21}, {23, 30}}, 0},
+		{"second argument", "const a = scale(2, |", "func scale(int factor, float x) -> float", [][2]int{{11, 21}, {23, 30}}, 1},
+		{"inside a finished call", "const a = scale(2, 1.|5);", "func scale(int factor, float x) -> float", [][2]int{{11, 21}, {23, 30}}, 1},
+		{"method without its receiver", "const a = p.move(1, |", "func move(int dx, int dy) -> Point", [][2]int{{10, 16}, {18, 24}}, 1},
+		{"innermost call", "const a = scale(first(1|, 2), 1.5);", "func first<int>(int a, int b) -> int", [][2]int{{16, 21}, {23, 28}}, 0},
+		{"type arguments", "const a = first(1, |2);", "func first<int>(int a, int b) -> int", [][2]int{{16, 21}, {23, 28}}, 1},
+	}
+
+	for _, tt := range tests {
+		cursor := strings.Index(tt.typed, "|")
+		text := source + strings.Replace(tt.typed, "|", "", 1)
+		l, out, path, _ := openWorkspace(t, text)
+
+		var result *messages.SignatureHelpResult
+		call(t, out, l.HandleSignatureHelp, messages.SignatureHelp, messages.SignatureHelpParams{
+			TextDocument: messages.TextDocumentIdentifier{URI: fileURI(path)},
+			Position:     messages.Position{Line: strings.Count(source, "\n"), Character: cursor},
+		}, &result)
+
+		if result == nil || len(result.Signatures) != 1 {
+			t.Errorf("%s: expected one signature, got %+v", tt.name, result)
+			continue
+		}
+		sig := result.Signatures[0]
+		if sig.Label != tt.label {
+			t.Errorf("%s: expected label %q, got %q", tt.name, tt.label, sig.Label)
+		}
+		if tt.params != nil {
+			if len(sig.Parameters) != len(tt.params) {
+				t.Errorf("%s: expected %d parameters, got %+v", tt.name, len(tt.params), sig.Parameters)
+			}
+			for i, p := range sig.Parameters {
+				if i < len(tt.params) && p.Label != tt.params[i] {
+					t.Errorf("%s: expected parameter %d at %v, got %v", tt.name, i, tt.params[i], p.Label)
+				}
+			}
+		}
+		if result.ActiveParameter != tt.expected {
+			t.Errorf("%s: expected active parameter %d, got %d", tt.name, tt.expected, result.ActiveParameter)
+		}
+	}
+}
+
+func TestSignatureHelpOutsideCalls(t *testing.T) {
+	source := "func scale(int factor) -> int { return factor; }\nconst a = scale(2);\nconst b = (a + 1);"
+	l, out, path, _ := openWorkspace(t, source)
+
+	positions := []messages.Position{
+		{Line: 1, Character: 18}, // after the closing parenthesis
+		{Line: 2, Character: 12}, // in a parenthesized expression
+		{Line: 0, Character: 13}, // in the declaration's parameters
+	}
+	for _, pos := range positions {
+		var result *messages.SignatureHelpResult
+		call(t, out, l.HandleSignatureHelp, messages.SignatureHelp, messages.SignatureHelpParams{
+			TextDocument: messages.TextDocumentIdentifier{URI: fileURI(path)},
+			Position:     pos,
+		}, &result)
+		if result != nil {
+			t.Errorf("%d:%d: expected no signature, got %+v", pos.Line, pos.Character, result)
+		}
+	}
+}
+
+func TestInlayHints(t *testing.T) {
+	source := `const a = 1;
+const b = "s";
+func id<T>(T v) -> T { return v; }
+const c = id(2.5);
+const int d = 3;`
+	l, out, path, _ := openWorkspace(t, source)
+
+	tests := []struct {
+		name     string
+		lines    *messages.Range
+		expected []messages.InlayHintItem
+	}{
+		{"whole file", span(0, 0, 4, 0), []messages.InlayHintItem{
+			{Position: messages.Position{Line: 0, Character: 6}, Label: "int", Kind: messages.TypeHint, PaddingRight: true},
+			{Position: messages.Position{Line: 1, Character: 6}, Label: "string", Kind: messages.TypeHint, PaddingRight: true},
+			{Position: messages.Position{Line: 3, Character: 6}, Label: "float", Kind: messages.TypeHint, PaddingRight: true},
+			{Position: messages.Position{Line: 3, Character: 12}, Label: "<float>", Kind: messages.TypeHint},
+		}},
+		{"requested lines only", span(1, 0, 2, 0), []messages.InlayHintItem{
+			{Position: messages.Position{Line: 1, Character: 6}, Label: "string", Kind: messages.TypeHint, PaddingRight: true},
+		}},
+	}
+
+	for _, tt := range tests {
+		var hints []messages.InlayHintItem
+		call(t, out, l.HandleInlayHint, messages.InlayHint, messages.InlayHintParams{
+			TextDocument: messages.TextDocumentIdentifier{URI: fileURI(path)},
+			Range:        *tt.lines,
+		}, &hints)
+
+		if len(hints) != len(tt.expected) {
+			t.Errorf("%s: expected %d hints, got %+v", tt.name, len(tt.expected), hints)
+			continue
+		}
+		for i, want := range tt.expected {
+			if hints[i] != want {
+				t.Errorf("%s: expected hint %d to be %+v, got %+v", tt.name, i, want, hints[i])
+			}
+		}
+	}
+}
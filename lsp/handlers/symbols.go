@@ -0,0 +1,324 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"sydney/ast"
+	"sydney/lexer"
+	"sydney/loader"
+	"sydney/lsp/messages"
+	"sydney/parser"
+	"sydney/types"
+)
+
+func (l *LSP) HandleDocumentSymbol(req *messages.Request) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("DocumentSymbol panicked: %v", r)
+		}
+	}()
+
+	resp := &messages.Response{
+		Id:      req.Id,
+		Version: messages.Version,
+		Result:  messages.DocumentSymbols{},
+	}
+	defer func() {
+		if err := l.WriteResponse(resp); err != nil {
+			log.Printf("%s: Error writing response: %v", messages.DocumentSymbol, err)
+		}
+	}()
+
+	var params messages.DocumentSymbolParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		log.Printf("%s Error unmarshalling params: %v", messages.DocumentSymbol, err)
+		return
+	}
+	doc := l.document(params.TextDocument.URI)
+	if doc == nil {
+		return
+	}
+
+	// the checked program knows the types of variables declared without one
+	var program *ast.Program
+	if doc.analysis != nil && doc.analysis.src == doc.text {
+		program = doc.analysis.document
+	} else {
+		program = parser.New(lexer.New(doc.text)).ParseProgram()
+	}
+	resp.Result = messages.DocumentSymbols(outline(program))
+}
+
+func (l *LSP) HandleWorkspaceSymbol(req *messages.Request) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("WorkspaceSymbol panicked: %v", r)
+		}
+	}()
+
+	symbols := messages.WorkspaceSymbols{}
+	resp := &messages.Response{
+		Id:      req.Id,
+		Version: messages.Version,
+		Result:  symbols,
+	}
+	defer func() {
+		if err := l.WriteResponse(resp); err != nil {
+			log.Printf("%s: Error writing response: %v", messages.WorkspaceSymbol, err)
+		}
+	}()
+
+	var params messages.WorkspaceSymbolParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		log.Printf("%s Error unmarshalling params: %v", messages.WorkspaceSymbol, err)
+		return
+	}
+
+	roots := []string{l.root}
+	if l.root == "" {
+		// without a workspace, search beside each open file
+		roots = nil
+		for path := range l.documents {
+			roots = append(roots, filepath.Dir(path))
+		}
+		sort.Strings(roots)
+	}
+	if len(roots) == 0 {
+		return
+	}
+
+	for _, path := range sourceFiles(append(roots, loader.ResolveStdlib(roots[0]))...) {
+		file := l.fileSymbols(path)
+		if file == nil {
+			continue
+		}
+
+		for _, symbol := range file.symbols {
+			symbols = appendMatching(symbols, params.Query, path, file.module, symbol)
+			for _, child := range symbol.Children {
+				symbols = appendMatching(symbols, params.Query, path, symbol.Name, child)
+			}
+		}
+	}
+	resp.Result = symbols
+}
+
+// fileSymbols is the outline of a file, kept until the file changes
+type fileSymbols struct {
+	module  string
+	symbols []messages.DocumentSymbolItem
+	modTime time.Time // of the file on disk, or zero while it is open
+}
+
+// fileSymbols outlines the file at path, reusing the last outline unless the
+// file has changed since. Edits to open files drop it, see forgetSymbols
+func (l *LSP) fileSymbols(path string) *fileSymbols {
+	var modTime time.Time
+	if _, open := l.documents[path]; !open {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil
+		}
+		modTime = info.ModTime()
+	}
+	if cached, ok := l.symbols[path]; ok && cached.modTime.Equal(modTime) {
+		return cached
+	}
+
+	src, err := l.source(path)
+	if err != nil {
+		return nil
+	}
+	program := parser.New(lexer.New(src)).ParseProgram()
+	file := &fileSymbols{symbols: outline(program), modTime: modTime}
+	for _, stmt := range program.Stmts {
+		if m, ok := stmt.(*ast.ModuleDeclarationStmt); ok {
+			file.module = m.Name.Value
+		}
+	}
+	l.symbols[path] = file
+	return file
+}
+
+// forgetSymbols drops the outline of a file whose text has changed
+func (l *LSP) forgetSymbols(path string) {
+	delete(l.symbols, path)
+}
+
+// outline lists the top level declarations of a program, with the fields of
+// structs, the methods of interfaces and the variants of enums beneath them
+func outline(program *ast.Program) []messages.DocumentSymbolItem {
+	var symbols []messages.DocumentSymbolItem
+	for _, stmt := range program.Stmts {
+		if pub, ok := stmt.(*ast.PubStatement); ok {
+			stmt = pub.Stmt
+		}
+
+		switch s := stmt.(type) {
+		case *ast.FunctionDeclarationStmt:
+			line, col := s.Name.Pos()
+			// instantiations of generic functions and derived methods are
+			// not written in the file
+			if line <= 0 || s.Name.Value != s.Name.Token.Literal {
+				continue
+			}
+			symbol := messages.DocumentSymbolItem{
+				Name:           s.Name.Value,
+				Kind:           messages.FunctionSymbol,
+				Detail:         detail(s.Type),
+				SelectionRange: nameRange(line, col, len(s.Name.Value)),
+			}
+			symbol.Range = symbol.SelectionRange
+			if start, _ := s.Pos(); start > 0 {
+				symbol.Range.Start = messages.Position{Line: start - 1, Character: s.Token.Column - 1}
+			}
+			if s.Body != nil && s.Body.End.Line > 0 {
+				symbol.Range.End = messages.Position{Line: s.Body.End.Line - 1, Character: s.Body.End.Column}
+			}
+			symbols = append(symbols, symbol)
+		case *ast.StructDefinitionStmt:
+			symbols = append(symbols, container(s.Name, messages.StructSymbol, s.FieldNames, messages.FieldSymbol, s.Type.Types))
+		case *ast.InterfaceDefinitionStmt:
+			symbols = append(symbols, container(s.Name, messages.InterfaceSymbol, s.MethodNames, messages.MethodSymbol, s.Type.Types))
+		case *ast.EnumDefinitionStmt:
+			symbols = append(symbols, container(s.Name, messages.EnumSymbol, s.VariantNames, messages.EnumMemberSymbol, nil))
+		case *ast.VarDeclarationStmt:
+			kind := messages.VariableSymbol
+			if s.Constant {
+				kind = messages.ConstantSymbol
+			}
+			line, col := s.Name.Pos()
+			symbol := messages.DocumentSymbolItem{
+				Name:           s.Name.Value,
+				Kind:           kind,
+				Detail:         detail(s.Type),
+				SelectionRange: nameRange(line, col, len(s.Name.Value)),
+			}
+			symbol.Range = symbol.SelectionRange
+			symbol.Range.Start = messages.Position{Line: s.Token.Line - 1, Character: s.Token.Column - 1}
+			symbols = append(symbols, symbol)
+		case *ast.TupleDeclarationStmt:
+			kind := messages.VariableSymbol
+			if s.Constant {
+				kind = messages.ConstantSymbol
+			}
+			for _, name := range s.Names {
+				if name.Value == "_" {
+					continue
+				}
+				line, col := name.Pos()
+				symbol := messages.DocumentSymbolItem{
+					Name:           name.Value,
+					Kind:           kind,
+					Detail:         detail(name.GetResolvedType()),
+					SelectionRange: nameRange(line, col, len(name.Value)),
+				}
+				symbol.Range = symbol.SelectionRange
+				symbols = append(symbols, symbol)
+			}
+		}
+	}
+	return symbols
+}
+
+// container is the symbol of a struct, interface or enum, spanning from its name
+// to its last member
+func container(name *ast.Identifier, kind messages.SymbolKind, members []*ast.Identifier, memberKind messages.SymbolKind, memberTypes []types.Type) messages.DocumentSymbolItem {
+	line, col := name.Pos()
+	symbol := messages.DocumentSymbolItem{
+		Name:           name.Value,
+		Kind:           kind,
+		Range:          nameRange(line, col, len(name.Value)),
+		SelectionRange: nameRange(line, col, len(name.Value)),
+	}
+	for i, member := range members {
+		line, col := member.Pos()
+		child := messages.DocumentSymbolItem{
+			Name:           member.Value,
+			Kind:           memberKind,
+			Range:          nameRange(line, col, len(member.Value)),
+			SelectionRange: nameRange(line, col, len(member.Value)),
+		}
+		if i < len(memberTypes) {
+			child.Detail = detail(memberTypes[i])
+		}
+		symbol.Children = append(symbol.Children, child)
+		symbol.Range.End = child.Range.End
+	}
+	return symbol
+}
+
+// detail is the signature of t, or "" when the parser left part of it
+// unresolved, as it does for types from other files
+func detail(t types.Type) (signature string) {
+	defer func() {
+		if recover() != nil {
+			signature = ""
+		}
+	}()
+	if t == nil {
+		return ""
+	}
+	return t.Signature()
+}
+
+func appendMatching(symbols messages.WorkspaceSymbols, query, path, containerName string, symbol messages.DocumentSymbolItem) messages.WorkspaceSymbols {
+	if !fuzzyMatch(query, symbol.Name) {
+		return symbols
+	}
+	return append(symbols, messages.SymbolInformation{
+		Name: symbol.Name,
+		Kind: symbol.Kind,
+		Location: *location(path, symbol.SelectionRange.Start.Line+1, symbol.SelectionRange.Start.Character+1,
+			symbol.SelectionRange.End.Character-symbol.SelectionRange.Start.Character),
+		ContainerName: containerName,
+	})
+}
+
+// fuzzyMatch reports whether the letters of query appear in name in order,
+// ignoring case
+func fuzzyMatch(query, name string) bool {
+	name = strings.ToLower(name)
+	for _, ch := range strings.ToLower(query) {
+		i := strings.IndexRune(name, ch)
+		if i < 0 {
+			return false
+		}
+		name = name[i+1:]
+	}
+	return true
+}
+
+// sourceFiles lists the .sy files under roots, other than tests and those in
+// hidden directories, each once
+func sourceFiles(roots ...string) []string {
+	seen := map[string]bool{}
+	var files []string
+	for _, root := range roots {
+		filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if d.IsDir() {
+				if path != root && strings.HasPrefix(d.Name(), ".") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !strings.HasSuffix(path, ".sy") || strings.HasSuffix(path, "_test.sy") || seen[path] {
+				return nil
+			}
+			seen[path] = true
+			files = append(files, path)
+			return nil
+		})
+	}
+	return files
+}
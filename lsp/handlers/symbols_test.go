@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"sydney/lsp/messages"
+	"testing"
+	"time"
+)
+
+func TestDocumentSymbols(t *testing.T) {
+	source := `define struct Point {
+    x int,
+    y int
+}
+define interface Shape {
+    area() -> float,
+    name() -> string
+}
+define enum Color { Red, Green(int) }
+func origin() -> Point {
+    return Point { x: 0, y: 0 };
+}
+const limit = 10;`
+
+	var out bytes.Buffer
+	l := New(&out)
+	path := filepath.Join(t.TempDir(), "main.sy")
+	openDocument(t, l, path, source)
+
+	var symbols []messages.DocumentSymbolItem
+	call(t, &out, l.HandleDocumentSymbol, messages.DocumentSymbol,
+		messages.DocumentSymbolParams{TextDocument: messages.TextDocumentIdentifier{URI: fileURI(path)}}, &symbols)
+
+	type child struct {
+		name   string
+		kind   messages.SymbolKind
+		detail string
+	}
+	tests := []struct {
+		name     string
+		kind     messages.SymbolKind
+		start    messages.Position // of the whole declaration
+		end      messages.Position
+		children []child
+	}{
+		{"Point", messages.StructSymbol, messages.Position{Line: 0, Character: 14}, messages.Position{Line: 2, Character: 5},
+			[]child{{"x", messages.FieldSymbol, "int"}, {"y", messages.FieldSymbol, "int"}}},
+		{"Shape", messages.InterfaceSymbol, messages.Position{Line: 4, Character: 17}, messages.Position{Line: 6, Character: 8},
+			[]child{{"area", messages.MethodSymbol, "func<() -> float>"}, {"name", messages.MethodSymbol, "func<() -> string>"}}},
+		{"Color", messages.EnumSymbol, messages.Position{Line: 8, Character: 12}, messages.Position{Line: 8, Character: 30},
+			[]child{{"Red", messages.EnumMemberSymbol, ""}, {"Green", messages.EnumMemberSymbol, ""}}},
+		{"origin", messages.FunctionSymbol, messages.Position{Line: 9, Character: 0}, messages.Position{Line: 11, Character: 1}, nil},
+		{"limit", messages.ConstantSymbol, messages.Position{Line: 12, Character: 0}, messages.Position{Line: 12, Character: 11}, nil},
+	}
+
+	if len(symbols) != len(tests) {
+		t.Fatalf("expected %d symbols, got %d: %+v", len(tests), len(symbols), symbols)
+	}
+	for i, tt := range tests {
+		got := symbols[i]
+		if got.Name != tt.name || got.Kind != tt.kind {
+			t.Errorf("symbol %d: expected %s of kind %d, got %s of kind %d", i, tt.name, tt.kind, got.Name, got.Kind)
+			continue
+		}
+		if got.Range.Start != tt.start || got.Range.End != tt.end {
+			t.Errorf("%s: expected range %v-%v, got %v-%v", tt.name, tt.start, tt.end, got.Range.Start, got.Range.End)
+		}
+		if len(got.Children) != len(tt.children) {
+			t.Errorf("%s: expected %d children, got %+v", tt.name, len(tt.children), got.Children)
+			continue
+		}
+		for j, want := range tt.children {
+			c := got.Children[j]
+			if c.Name != want.name || c.Kind != want.kind || c.Detail != want.detail {
+				t.Errorf("%s child %d: expected %s of kind %d (%q), got %s of kind %d (%q)",
+					tt.name, j, want.name, want.kind, want.detail, c.Name, c.Kind, c.Detail)
+			}
+			if c.SelectionRange.End.Character-c.SelectionRange.Start.Character != len(want.name) {
+				t.Errorf("%s child %s: expected its selection to cover the name, got %v", tt.name, want.name, c.SelectionRange)
+			}
+		}
+	}
+}
+
+// workspaceSymbols names the symbols matching query as container.name
+func workspaceSymbols(t *testing.T, l *LSP, out *bytes.Buffer, query string) []string {
+	t.Helper()
+	var symbols []messages.SymbolInformation
+	call(t, out, l.HandleWorkspaceSymbol, messages.WorkspaceSymbol, messages.WorkspaceSymbolParams{Query: query}, &symbols)
+
+	var names []string
+	for _, s := range symbols {
+		names = append(names, s.ContainerName+"."+s.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestWorkspaceSymbols(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("SYDNEY_PATH", t.TempDir())
+	shapes := filepath.Join(dir, "shapes", "shapes.sy")
+	if err := os.MkdirAll(filepath.Dir(shapes), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	err := os.WriteFile(shapes, []byte("module \"shapes\"\n\npub define struct Square { side int }\npub func square_area(Square s) -> int { return s.side * s.side; }\n"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	l := New(&out)
+	l.root = dir
+	mainPath := filepath.Join(dir, "main.sy")
+	main := "const total_area = 1;\nfunc side_length() -> int { return 2; }\n"
+	if err := os.WriteFile(mainPath, []byte(main), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	openDocument(t, l, mainPath, main)
+
+	tests := []struct {
+		query    string
+		expected []string
+	}{
+		{"area", []string{".total_area", "shapes.square_area"}},
+		{"SQAREA", []string{"shapes.square_area"}},
+		{"side", []string{".side_length", "Square.side"}},
+		{"sqr", []string{"shapes.Square", "shapes.square_area"}},
+		{"zzz", nil},
+	}
+	for _, tt := range tests {
+		got := workspaceSymbols(t, l, &out, tt.query)
+		if !slices.Equal(got, tt.expected) {
+			t.Errorf("query %q: expected %v, got %v", tt.query, tt.expected, got)
+		}
+	}
+
+	// the outlines are reused until their files change
+	cached := l.symbols[shapes]
+	if cached == nil || l.symbols[mainPath] == nil {
+		t.Fatalf("expected the outlines to be cached, got %v", l.symbols)
+	}
+	workspaceSymbols(t, l, &out, "area")
+	if l.symbols[shapes] != cached {
+		t.Errorf("expected the outline of an unchanged file to be reused")
+	}
+
+	changeDocument(t, l, mainPath, 2, messages.ContentChanges{Range: span(0, 6, 0, 16), Text: "sum_of_areas"})
+	if got := workspaceSymbols(t, l, &out, "area"); !slices.Equal(got, []string{".sum_of_areas", "shapes.square_area"}) {
+		t.Errorf("expected the edited file to be outlined again, got %v", got)
+	}
+
+	err = os.WriteFile(shapes, []byte("module \"shapes\"\n\npub func circle_area(int r) -> int { return 3 * r * r; }\n"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Second)
+	if err := os.Chtimes(shapes, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if got := workspaceSymbols(t, l, &out, "area"); !slices.Equal(got, []string{".sum_of_areas", "shapes.circle_area"}) {
+		t.Errorf("expected the file changed on disk to be outlined again, got %v", got)
+	}
+}
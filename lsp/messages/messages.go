@@ -5,18 +5,35 @@ import "encoding/json"
 type Method string
 
 const (
-	Initialize         Method = "initialize"
-	Initialized               = "initialized"
-	DocumentOpen              = "textDocument/didOpen"
-	DocumentChange            = "textDocument/didChange"
-	Hover                     = "textDocument/hover"
-	DocumentClose             = "textDocument/didClose"
-	Shutdown                  = "shutdown"
-	PublishDiagnostics        = "textDocument/publishDiagnostics"
+	Initialize              Method = "initialize"
+	Initialized                    = "initialized"
+	DocumentOpen                   = "textDocument/didOpen"
+	DocumentChange                 = "textDocument/didChange"
+	Hover                          = "textDocument/hover"
+	Definition                     = "textDocument/definition"
+	References                     = "textDocument/references"
+	Rename                         = "textDocument/rename"
+	PrepareRename                  = "textDocument/prepareRename"
+	Completion                     = "textDocument/completion"
+	SignatureHelp                  = "textDocument/signatureHelp"
+	InlayHint                      = "textDocument/inlayHint"
+	DocumentSymbol                 = "textDocument/documentSymbol"
+	WorkspaceSymbol                = "workspace/symbol"
+	SemanticTokensFull             = "textDocument/semanticTokens/full"
+	SemanticTokensFullDelta        = "textDocument/semanticTokens/full/delta"
+	CodeAction                     = "textDocument/codeAction"
+	DocumentClose                  = "textDocument/didClose"
+	DocumentSave                   = "textDocument/didSave"
+	Shutdown                       = "shutdown"
+	PublishDiagnostics             = "textDocument/publishDiagnostics"
 )
 
 const Version = "2.0"
 
+// RequestFailed is the error code for a well formed request the server
+// cannot carry out
+const RequestFailed = -32803
+
 type Request struct {
 	Version string          `json:"jsonrpc"`
 	Method  Method          `json:"method"`
@@ -24,6 +41,16 @@ type Request struct {
 	Id      interface{}     `json:"id"`
 }
 
+type InitializeParams struct {
+	RootURI          string            `json:"rootUri"`
+	WorkspaceFolders []WorkspaceFolder `json:"workspaceFolders"`
+}
+
+type WorkspaceFolder struct {
+	URI  string `json:"uri"`
+	Name string `json:"name"`
+}
+
 type TextDocumentIdentifier struct {
 	URI string `json:"uri"`
 }
@@ -54,8 +81,221 @@ type HoverParams struct {
 	Position     Position               `json:"position"`
 }
 
+type DefinitionParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Position     Position               `json:"position"`
+}
+
+type Location struct {
+	URI   string `json:"uri"`
+	Range Range  `json:"range"`
+}
+
+func (l *Location) Result() {}
+
+type Locations []Location
+
+func (l Locations) Result() {}
+
+type ReferenceContext struct {
+	IncludeDeclaration bool `json:"includeDeclaration"`
+}
+
+type ReferenceParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Position     Position               `json:"position"`
+	Context      ReferenceContext       `json:"context"`
+}
+
+type RenameParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Position     Position               `json:"position"`
+	NewName      string                 `json:"newName"`
+}
+
+type PrepareRenameResult struct {
+	Range       Range  `json:"range"`
+	Placeholder string `json:"placeholder"`
+}
+
+func (p *PrepareRenameResult) Result() {}
+
+type TextEdit struct {
+	Range   Range  `json:"range"`
+	NewText string `json:"newText"`
+}
+
+type WorkspaceEdit struct {
+	Changes map[string][]TextEdit `json:"changes"`
+}
+
+func (w *WorkspaceEdit) Result() {}
+
+type CompletionParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Position     Position               `json:"position"`
+}
+
+type CompletionItemKind int
+
+const (
+	MethodCompletion    CompletionItemKind = 2
+	FunctionCompletion  CompletionItemKind = 3
+	FieldCompletion     CompletionItemKind = 5
+	VariableCompletion  CompletionItemKind = 6
+	InterfaceCompletion CompletionItemKind = 8
+	KeywordCompletion   CompletionItemKind = 14
+	StructCompletion    CompletionItemKind = 22
+)
+
+type CompletionItem struct {
+	Label  string             `json:"label"`
+	Kind   CompletionItemKind `json:"kind"`
+	Detail string             `json:"detail,omitempty"`
+}
+
+type CompletionList struct {
+	IsIncomplete bool             `json:"isIncomplete"`
+	Items        []CompletionItem `json:"items"`
+}
+
+func (c *CompletionList) Result() {}
+
+type SignatureHelpParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Position     Position               `json:"position"`
+}
+
+// ParameterInformation labels a parameter by its start and end offsets in
+// the signature's label
+type ParameterInformation struct {
+	Label [2]int `json:"label"`
+}
+
+type SignatureInformation struct {
+	Label      string                 `json:"label"`
+	Parameters []ParameterInformation `json:"parameters"`
+}
+
+type SignatureHelpResult struct {
+	Signatures      []SignatureInformation `json:"signatures"`
+	ActiveSignature int                    `json:"activeSignature"`
+	ActiveParameter int                    `json:"activeParameter"`
+}
+
+func (s *SignatureHelpResult) Result() {}
+
+type InlayHintParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Range        Range                  `json:"range"`
+}
+
+type InlayHintKind int
+
+const (
+	TypeHint      InlayHintKind = 1
+	ParameterHint InlayHintKind = 2
+)
+
+type InlayHintItem struct {
+	Position     Position      `json:"position"`
+	Label        string        `json:"label"`
+	Kind         InlayHintKind `json:"kind"`
+	PaddingLeft  bool          `json:"paddingLeft,omitempty"`
+	PaddingRight bool          `json:"paddingRight,omitempty"`
+}
+
+type InlayHints []InlayHintItem
+
+func (i InlayHints) Result() {}
+
+type DocumentSymbolParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+type WorkspaceSymbolParams struct {
+	Query string `json:"query"`
+}
+
+type SymbolKind int
+
+const (
+	MethodSymbol     SymbolKind = 6
+	FieldSymbol      SymbolKind = 8
+	EnumSymbol       SymbolKind = 10
+	InterfaceSymbol  SymbolKind = 11
+	FunctionSymbol   SymbolKind = 12
+	VariableSymbol   SymbolKind = 13
+	ConstantSymbol   SymbolKind = 14
+	EnumMemberSymbol SymbolKind = 22
+	StructSymbol     SymbolKind = 23
+)
+
+// DocumentSymbolItem is an entry of a file's outline. Range covers the whole
+// declaration and SelectionRange its name
+type DocumentSymbolItem struct {
+	Name           string               `json:"name"`
+	Detail         string               `json:"detail,omitempty"`
+	Kind           SymbolKind           `json:"kind"`
+	Range          Range                `json:"range"`
+	SelectionRange Range                `json:"selectionRange"`
+	Children       []DocumentSymbolItem `json:"children,omitempty"`
+}
+
+type DocumentSymbols []DocumentSymbolItem
+
+func (d DocumentSymbols) Result() {}
+
+type SymbolInformation struct {
+	Name          string     `json:"name"`
+	Kind          SymbolKind `json:"kind"`
+	Location      Location   `json:"location"`
+	ContainerName string     `json:"containerName,omitempty"`
+}
+
+type WorkspaceSymbols []SymbolInformation
+
+func (w WorkspaceSymbols) Result() {}
+
+type SemanticTokensParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+type SemanticTokensDeltaParams struct {
+	TextDocument     TextDocumentIdentifier `json:"textDocument"`
+	PreviousResultID string                 `json:"previousResultId"`
+}
+
+// SemanticTokens holds five integers per token: the line relative to the
+// previous token, the start character (relative to the previous token when
+// on the same line), the length, the type and the modifier bits
+type SemanticTokens struct {
+	ResultID string `json:"resultId"`
+	Data     []int  `json:"data"`
+}
+
+func (s *SemanticTokens) Result() {}
+
+// SemanticTokensEdit replaces DeleteCount integers of the previous result's
+// data, starting at Start, with Data
+type SemanticTokensEdit struct {
+	Start       int   `json:"start"`
+	DeleteCount int   `json:"deleteCount"`
+	Data        []int `json:"data"`
+}
+
+type SemanticTokensDelta struct {
+	ResultID string               `json:"resultId"`
+	Edits    []SemanticTokensEdit `json:"edits"`
+}
+
+func (s *SemanticTokensDelta) Result() {}
+
+// ContentChanges replaces the text in Range, or the whole document when
+// there is no range
 type ContentChanges struct {
-	Text string `json:"text"`
+	Range *Range `json:"range"`
+	Text  string `json:"text"`
 }
 
 type DocumentChangeParams struct {
@@ -63,6 +303,15 @@ type DocumentChangeParams struct {
 	ContentChanges []ContentChanges `json:"contentChanges"`
 }
 
+type DocumentCloseParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+type DocumentSaveParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Text         *string                `json:"text"`
+}
+
 type Response struct {
 	Version string      `json:"jsonrpc"`
 	Result  Result      `json:"result"`
@@ -70,6 +319,11 @@ type Response struct {
 	Id      interface{} `json:"id"`
 }
 
+type ResponseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
 type Notification struct {
 	Method Method `json:"method"`
 	Params Params `json:"params"`
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"os"
@@ -28,12 +29,26 @@ const (
 	dumpAst   Flag = "dump-ast"
 	dumpTypes Flag = "dump-types"
 	profile   Flag = "profile"
+
+	targetBytecode Flag = "target=bytecode"
+	dap            Flag = "dap"
+
+	errorFormatJSON Flag = "error-format=json"
+	color           Flag = "color"
+	noColor         Flag = "no-color"
 )
 
 var allowedFlags = map[Flag]bool{
 	dumpTypes: true,
 	dumpAst:   true,
 	profile:   true,
+
+	targetBytecode: true,
+	dap:            true,
+
+	errorFormatJSON: true,
+	color:           true,
+	noColor:         true,
 }
 
 type CommandFunc func(args []string, flags map[Flag]bool) int
@@ -45,9 +60,15 @@ var commands = map[string]CommandFunc{
 	"run":     Run,
 	"test":    Test,
 	"debug":   Debug,
+	"bundle":  Bundle,
+	"explain": Explain,
 }
 
 func main() {
+	if bytecode, ok := embeddedBytecode(); ok {
+		os.Exit(runEmbedded(bytecode))
+	}
+
 	args := os.Args
 	status := 0
 	flags := parseFlags(args)
@@ -61,13 +82,18 @@ func main() {
 			fmt.Fprintf(os.Stderr, "unknown command: %s\n", args[1])
 			os.Exit(1)
 		}
-		status = command(args[2:], flags)
+		status = command(positionalArgs(args[2:]), flags)
 	}
 	os.Exit(status)
 }
 
 func Help(args []string, flags map[Flag]bool) int {
-	fmt.Println("Usage: sydney [version|run|compile|help] [filename]")
+	fmt.Println("Usage: sydney [version|run|compile|bundle|help] [filename]")
+	fmt.Println("       sydney explain [code]                        describe an error code such as E0102, or list them all")
+	fmt.Println("       sydney compile --target=bytecode [filename]  emit a .syc file for `sydney run`")
+	fmt.Println("       sydney debug --dap [filename] [host:port]    serve the Debug Adapter Protocol on stdio or TCP")
+	fmt.Println("       --error-format=json                          report errors as JSON lines for tools")
+	fmt.Println("       --color, --no-color                          force colored errors on or off")
 	return 0
 }
 
@@ -76,15 +102,27 @@ func Version(args []string, flags map[Flag]bool) int {
 	return 0
 }
 
+// Explain describes the error code given, or lists the codes with none
+func Explain(args []string, flags map[Flag]bool) int {
+	if len(args) == 0 {
+		for _, line := range errors.Codes() {
+			fmt.Println(line)
+		}
+		return 0
+	}
+
+	text, ok := errors.Explain(errors.Code(args[0]))
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown error code: %s\n", args[0])
+		return 1
+	}
+	fmt.Print(text)
+	return 0
+}
+
 func Run(args []string, flags map[Flag]bool) int {
 	filename := args[0]
-	constants := []object.Object{}
 	globals := make([]object.Object, vm.GlobalsSize)
-	symbolTable := compiler.NewSymbolTable()
-	typeEnv := typechecker.NewTypeEnv(nil)
-	for i, v := range object.Builtins {
-		symbolTable.DefineBuiltin(i, v.Name)
-	}
 
 	file, err := os.ReadFile(filename)
 	if err != nil {
@@ -92,7 +130,42 @@ func Run(args []string, flags map[Flag]bool) int {
 		return 1
 	}
 
-	src := string(file)
+	var bytecode *compiler.Bytecode
+	if compiler.IsSerializedBytecode(file) {
+		bytecode, err = compiler.Deserialize(bytes.NewReader(file))
+		if err != nil {
+			fmt.Printf("cannot load bytecode %s: %s\n", filename, err)
+			return 1
+		}
+	} else {
+		var ok bool
+		bytecode, ok = compileBytecode(filename, string(file), flags)
+		if !ok {
+			return 1
+		}
+	}
+
+	machine := vm.NewWithGlobalStore(bytecode, globals)
+	err = machine.Run()
+	if err != nil {
+		printRuntimeError(os.Stdout, err, flags)
+		return 1
+	}
+
+	return 0
+}
+
+// compileBytecode runs the full frontend (loader, parser, derives, typechecker)
+// over src and compiles it, together with its imported packages, for the VM.
+// Diagnostics are printed to stdout; ok is false if any stage failed.
+func compileBytecode(filename string, src string, flags map[Flag]bool) (bytecode *compiler.Bytecode, ok bool) {
+	r := newRenderer(flags)
+	constants := []object.Object{}
+	symbolTable := compiler.NewSymbolTable()
+	typeEnv := typechecker.NewTypeEnv(nil)
+	for i, v := range object.Builtins {
+		symbolTable.DefineBuiltin(i, v.Name)
+	}
 
 	imports := loader.ScanImports(src)
 	deriveImports := codegen.ScanDeriveImports(src)
@@ -103,16 +176,17 @@ func Run(args []string, flags map[Flag]bool) int {
 	ld.SetPaths(stdLib, sourceDir)
 	packages, tt, gns, err := ld.Load(make(map[string]bool))
 	if err != nil {
-		fmt.Printf("loader error: %s\n", err)
-		return 1
+		reportLoadError(r, err)
+		return nil, false
 	}
 
 	l := lexer.New(src)
 	p := parser.NewWithGenericNames(l, gns)
+	p.SetFile(filename)
 	program := p.ParseProgram()
 	if len(p.Errors()) != 0 {
-		printParserErrors(os.Stdout, p.Errors())
-		return 1
+		r.Render(p.Errors())
+		return nil, false
 	}
 
 	for _, pkg := range packages {
@@ -142,15 +216,17 @@ func Run(args []string, flags map[Flag]bool) int {
 			}
 		}
 	}()
+	program.File = filename
+	c.SetFiles([]*ast.Program{program})
 	typeErrs := c.Check(program, packages)
 
 	if flags[dumpTypes] {
 		ast.Dump(program, 0)
 	}
 
-	if len(typeErrs) != 0 {
-		errors.PrintPositionErrors(os.Stdout, typeErrs)
-		return 1
+	r.Render(typeErrs)
+	if errors.HasErrors(typeErrs) {
+		return nil, false
 	}
 
 	ast.FilterGenericTemplates(program)
@@ -164,22 +240,16 @@ func Run(args []string, flags map[Flag]bool) int {
 	err = comp.CompilePackages(packages)
 	if err != nil {
 		fmt.Printf("compiler error: %s\n", err)
-		return 1
+		return nil, false
 	}
+	comp.SetFileName(filename)
 	err = comp.Compile(program)
 	if err != nil {
 		fmt.Printf("compiler error: %s\n", err)
-		return 1
+		return nil, false
 	}
 
-	machine := vm.NewWithGlobalStore(comp.Bytecode(), globals)
-	err = machine.Run()
-	if err != nil {
-		fmt.Printf("Runtime error: %s\n", err)
-		return 1
-	}
-
-	return 0
+	return comp.Bytecode(), true
 }
 
 func Compile(args []string, flags map[Flag]bool) int {
@@ -202,6 +272,12 @@ func Compile(args []string, flags map[Flag]bool) int {
 
 	src := string(file)
 
+	if flags[targetBytecode] {
+		return compileToBytecodeFile(filename, src, flags)
+	}
+
+	r := newRenderer(flags)
+
 	imports := loader.ScanImports(src)
 	deriveImports := codegen.ScanDeriveImports(src)
 	imports = append(imports, deriveImports...)
@@ -211,12 +287,13 @@ func Compile(args []string, flags map[Flag]bool) int {
 	ld.SetPaths(stdLib, sourceDir)
 	packages, tt, gns, err := ld.Load(make(map[string]bool))
 	if err != nil {
-		fmt.Printf("loader error: %s\n", err)
+		reportLoadError(r, err)
 		return 1
 	}
 
 	l := lexer.New(src)
 	p := parser.NewWithGenericNames(l, gns)
+	p.SetFile(filename)
 	program := p.ParseProgram()
 
 	if flags[dumpAst] {
@@ -224,7 +301,7 @@ func Compile(args []string, flags map[Flag]bool) int {
 	}
 
 	if len(p.Errors()) != 0 {
-		printParserErrors(os.Stdout, p.Errors())
+		r.Render(p.Errors())
 		return 1
 	}
 
@@ -245,14 +322,16 @@ func Compile(args []string, flags map[Flag]bool) int {
 			}
 		}
 	}()
+	program.File = filename
+	c.SetFiles([]*ast.Program{program})
 	errs := c.Check(program, packages)
 
 	if flags[dumpTypes] {
 		ast.Dump(program, 0)
 	}
 
-	if len(errs) != 0 {
-		errors.PrintPositionErrors(os.Stdout, errs)
+	r.Render(errs)
+	if errors.HasErrors(errs) {
 		return 1
 	}
 
@@ -274,8 +353,39 @@ func Compile(args []string, flags map[Flag]bool) int {
 	return 0
 }
 
+// compileToBytecodeFile compiles src for the VM and writes the result next to
+// the source as a .syc file that `sydney run` can load without recompiling
+func compileToBytecodeFile(filename string, src string, flags map[Flag]bool) int {
+	bytecode, ok := compileBytecode(filename, src, flags)
+	if !ok {
+		return 1
+	}
+
+	out := strings.TrimSuffix(filename, ".sy") + ".syc"
+	f, err := os.Create(out)
+	if err != nil {
+		fmt.Printf("cannot create file %s\n", out)
+		return 1
+	}
+	defer f.Close()
+
+	err = bytecode.Serialize(f)
+	if err != nil {
+		fmt.Printf("cannot write bytecode: %s\n", err)
+		return 1
+	}
+
+	return 0
+}
+
 func Debug(args []string, flags map[Flag]bool) int {
-	filename := args[0]
+	// breakpoints from editors arrive as absolute paths, so compile against one
+	filename, err := filepath.Abs(args[0])
+	if err != nil {
+		fmt.Printf("Honk! Cannot read file %s\n", args[0])
+		return 1
+	}
+	r := newRenderer(flags)
 	constants := []object.Object{}
 	globals := make([]object.Object, vm.GlobalsSize)
 	symbolTable := compiler.NewSymbolTable()
@@ -301,15 +411,16 @@ func Debug(args []string, flags map[Flag]bool) int {
 	ld.SetPaths(stdLib, sourceDir)
 	packages, tt, gns, err := ld.Load(make(map[string]bool))
 	if err != nil {
-		fmt.Printf("loader error: %s\n", err)
+		reportLoadError(r, err)
 		return 1
 	}
 
 	l := lexer.New(src)
 	p := parser.NewWithGenericNames(l, gns)
+	p.SetFile(filename)
 	program := p.ParseProgram()
 	if len(p.Errors()) != 0 {
-		printParserErrors(os.Stdout, p.Errors())
+		r.Render(p.Errors())
 		return 1
 	}
 
@@ -340,14 +451,16 @@ func Debug(args []string, flags map[Flag]bool) int {
 			}
 		}
 	}()
+	program.File = filename
+	c.SetFiles([]*ast.Program{program})
 	typeErrs := c.Check(program, packages)
 
 	if flags[dumpTypes] {
 		ast.Dump(program, 0)
 	}
 
-	if len(typeErrs) != 0 {
-		errors.PrintPositionErrors(os.Stdout, typeErrs)
+	r.Render(typeErrs)
+	if errors.HasErrors(typeErrs) {
 		return 1
 	}
 
@@ -377,11 +490,28 @@ func Debug(args []string, flags map[Flag]bool) int {
 	dbg := vm.NewDebugger(symbolTable)
 	dbg.AddSource(filename, src)
 	machine.AttachDebugger(dbg)
-	dbg.WaitForClient()
+	if flags[dap] {
+		addr := ""
+		if len(args) > 1 {
+			addr = args[1]
+		}
+		err = dbg.ServeDAP(addr)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "debug adapter error: %s\n", err)
+			return 1
+		}
+	} else {
+		dbg.WaitForClient()
+	}
 
 	err = machine.Run()
+	dbg.Terminate(err)
+	if dbg.Detached() {
+		// the client ended the session, which is not the program failing
+		return 0
+	}
 	if err != nil {
-		fmt.Printf("Runtime error: %s\n", err)
+		printRuntimeError(os.Stdout, err, flags)
 		return 1
 	}
 
@@ -412,10 +542,11 @@ func Test(args []string, flags map[Flag]bool) int {
 		return 0
 	}
 
+	r := newRenderer(flags)
 	totalPassed, totalFailed := 0, 0
 	for _, filename := range testFiles {
 		fmt.Printf("--- %s\n", filepath.Base(filename))
-		p, f := runTestFile(filename)
+		p, f := runTestFile(filename, r)
 		totalPassed += p
 		totalFailed += f
 	}
@@ -427,7 +558,7 @@ func Test(args []string, flags map[Flag]bool) int {
 	return 0
 }
 
-func runTestFile(filename string) (passed, failed int) {
+func runTestFile(filename string, r *errors.Renderer) (passed, failed int) {
 	file, err := os.ReadFile(filename)
 	if err != nil {
 		fmt.Printf("  cannot read file %s\n", filename)
@@ -452,27 +583,32 @@ func runTestFile(filename string) (passed, failed int) {
 	ld.SetPaths(stdLib, sourceDir)
 	packages, tt, gns, err := ld.Load(make(map[string]bool))
 	if err != nil {
-		fmt.Printf("  loader error: %s\n", err)
+		reportLoadError(r, err)
 		return 0, 1
 	}
 
 	l := lexer.New(src)
 	p := parser.NewWithGenericNames(l, gns)
+	p.SetFile(filename)
 	program := p.ParseProgram()
 	if len(p.Errors()) != 0 {
-		printParserErrors(os.Stdout, p.Errors())
+		r.Render(p.Errors())
 		return 0, 1
 	}
 
 	codegen.ExpandDerives(program)
 
+	// only the test file's own statements are from filename
+	program.File = filename
+	c := typechecker.NewWithModuleTypes(nil, tt)
+	c.SetFiles([]*ast.Program{program})
+
 	// Prepend sibling module declarations into the test program
 	program.Stmts = append(siblingStmts, program.Stmts...)
 
-	c := typechecker.NewWithModuleTypes(nil, tt)
 	typeErrs := c.Check(program, packages)
-	if len(typeErrs) != 0 {
-		errors.PrintPositionErrors(os.Stdout, typeErrs)
+	r.Render(typeErrs)
+	if errors.HasErrors(typeErrs) {
 		return 0, 1
 	}
 
@@ -533,6 +669,9 @@ func runTestFile(filename string) (passed, failed int) {
 
 		if err != nil {
 			fmt.Printf("  FAIL  %s: %s\n", name, err)
+			if rerr, ok := err.(*vm.RuntimeError); ok {
+				io.WriteString(os.Stdout, indent(rerr.StackTrace(), "        "))
+			}
 			failed++
 		} else {
 			fmt.Printf("  PASS  %s\n", name)
@@ -600,10 +739,82 @@ func appendUnique(slice []string, item string) []string {
 	return append(slice, item)
 }
 
-func printParserErrors(out io.Writer, errors []string) {
-	for _, msg := range errors {
-		io.WriteString(out, "\t"+msg+"\n")
+// newRenderer reports diagnostics on stdout in the format the flags ask for,
+// coloring them when stdout is a terminal and NO_COLOR is not set
+func newRenderer(flags map[Flag]bool) *errors.Renderer {
+	if flags[errorFormatJSON] {
+		return errors.NewRenderer(os.Stdout, errors.JSON, false)
 	}
+	colored := flags[color]
+	if !colored && !flags[noColor] && os.Getenv("NO_COLOR") == "" {
+		if info, err := os.Stdout.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+			colored = true
+		}
+	}
+	return errors.NewRenderer(os.Stdout, errors.Text, colored)
+}
+
+// reportLoadError renders the syntax errors of an imported package, and
+// describes any other failure to load one
+func reportLoadError(r *errors.Renderer, err error) {
+	if list, ok := err.(errors.List); ok {
+		r.Render(list)
+		return
+	}
+	fmt.Printf("loader error: %s\n", err)
+}
+
+// printRuntimeError reports a VM failure, followed by the Sydney stack trace
+// when the error came from a fiber
+func printRuntimeError(out io.Writer, err error, flags map[Flag]bool) {
+	if flags[errorFormatJSON] {
+		errors.NewRenderer(out, errors.JSON, false).Render([]errors.PositionError{runtimeDiagnostic(err)})
+		return
+	}
+
+	fmt.Fprintf(out, "Runtime error: %s\n", err)
+	if rerr, ok := err.(*vm.RuntimeError); ok {
+		io.WriteString(out, rerr.StackTrace())
+	}
+}
+
+// runtimeDiagnostic places a VM failure at the innermost frame of its stack
+// trace, with one note per frame
+func runtimeDiagnostic(err error) errors.PositionError {
+	d := errors.PositionError{Message: err.Error()}
+	rerr, ok := err.(*vm.RuntimeError)
+	if !ok {
+		return d
+	}
+	if len(rerr.Frames) > 0 {
+		d.File, d.Line, d.Col = rerr.Frames[0].File, rerr.Frames[0].Line, rerr.Frames[0].Col
+	}
+	for _, f := range rerr.Frames {
+		d.Notes = append(d.Notes, "at "+f.String())
+	}
+	return d
+}
+
+func indent(s string, prefix string) string {
+	lines := strings.SplitAfter(s, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = prefix + line
+		}
+	}
+	return strings.Join(lines, "")
+}
+
+// positionalArgs drops --flags so commands can index their operands directly
+func positionalArgs(args []string) []string {
+	positional := make([]string, 0, len(args))
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "--") {
+			positional = append(positional, arg)
+		}
+	}
+
+	return positional
 }
 
 func parseFlags(args []string) map[Flag]bool {
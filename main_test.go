@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"testing"
+
+	"sydney/vm"
+)
+
+func TestRuntimeErrorJSON(t *testing.T) {
+	err := &vm.RuntimeError{
+		Err: fmt.Errorf("division by zero"),
+		Frames: []vm.StackFrame{
+			{Function: "f", File: "main.sy", Line: 1, Col: 34},
+			{Function: "<main>", File: "main.sy", Line: 2, Col: 8},
+		},
+	}
+
+	var out bytes.Buffer
+	printRuntimeError(&out, err, map[Flag]bool{errorFormatJSON: true})
+
+	var d struct {
+		Severity string   `json:"severity"`
+		Message  string   `json:"message"`
+		File     string   `json:"file"`
+		Line     int      `json:"line"`
+		Column   int      `json:"column"`
+		Notes    []string `json:"notes"`
+	}
+	if jsonErr := json.Unmarshal(out.Bytes(), &d); jsonErr != nil {
+		t.Fatalf("expected one JSON diagnostic, got %q: %v", out.String(), jsonErr)
+	}
+
+	if d.Severity != "error" || d.Message != "division by zero" {
+		t.Errorf("expected error %q, got %s %q", "division by zero", d.Severity, d.Message)
+	}
+	if d.File != "main.sy" || d.Line != 1 || d.Column != 34 {
+		t.Errorf("expected the innermost frame main.sy:1:34, got %s:%d:%d", d.File, d.Line, d.Column)
+	}
+	notes := []string{"at f (main.sy:1:34)", "at <main> (main.sy:2:8)"}
+	if !slices.Equal(d.Notes, notes) {
+		t.Errorf("expected notes %v, got %v", notes, d.Notes)
+	}
+}
@@ -53,6 +53,17 @@ func slabInsertListener(l gonet.Listener) int64 {
 	return int64(len(tcpListeners) - 1)
 }
 
+// argsOffset is the number of leading os.Args hidden from the `args` builtin.
+// Under `sydney run file.sy a b` it skips the sydney binary and the command,
+// so programs see [file.sy, a, b] just like argv in a native binary.
+var argsOffset = 2
+
+// SetArgsOffset changes how many leading os.Args the `args` builtin drops.
+// Bundled executables use 0 since the program is the binary itself.
+func SetArgsOffset(n int) {
+	argsOffset = n
+}
+
 var Builtins = []struct {
 	Name    string
 	BuiltIn *BuiltIn
@@ -187,7 +198,7 @@ var Builtins = []struct {
 		"err",
 		&BuiltIn{
 			Fn: func(args ...Object) Object {
-				return &Result{Value: nil, Error: args[0].(*String), IsOk: false}
+				return &Result{Value: nil, Error: args[0], IsOk: false}
 			},
 			T: types.FunctionType{Params: []types.Type{types.Infer}, Return: types.ResultType{T: types.Infer}},
 		},
@@ -389,7 +400,7 @@ var Builtins = []struct {
 					return newError("`panic` expects one argument")
 				}
 				msg := args[0].(*String).Value
-				return &Error{Message: "panic: " + msg}
+				return &Error{Message: "panic: " + msg, Panic: true}
 			},
 			T: types.FunctionType{Params: []types.Type{types.String}, Return: types.Unit},
 		},
@@ -689,7 +700,7 @@ var Builtins = []struct {
 		"args",
 		&BuiltIn{
 			Fn: func(args ...Object) Object {
-				stripped := os.Args[2:]
+				stripped := os.Args[argsOffset:]
 				res := &Array{Elements: make([]Object, len(stripped))}
 				for i, a := range stripped {
 					res.Elements[i] = &String{Value: a}
@@ -710,3 +721,13 @@ func GetBuiltInByName(name string) *BuiltIn {
 
 	return nil
 }
+
+func GetBuiltInName(builtin *BuiltIn) string {
+	for _, bi := range Builtins {
+		if bi.BuiltIn == builtin {
+			return bi.Name
+		}
+	}
+
+	return ""
+}
@@ -44,6 +44,8 @@ const (
 	OptionObj           ObjectType = "Option"
 	ByteObj             ObjectType = "Byte"
 	ChannelObj          ObjectType = "Channel"
+	EnumObj             ObjectType = "Enum"
+	TupleObj            ObjectType = "Tuple"
 )
 
 type (
@@ -78,6 +80,7 @@ type (
 
 	Error struct {
 		Message string
+		Panic   bool // raised by the panic builtin rather than a failed operation
 	}
 
 	Variable struct {
@@ -135,6 +138,7 @@ type (
 	}
 
 	CompiledFunction struct {
+		Name          string
 		Instructions  code.Instructions
 		NumLocals     int
 		NumParameters int
@@ -170,7 +174,7 @@ type (
 	Result struct {
 		IsOk  bool
 		Value Object
-		Error *String
+		Error Object // a *String unless the result has another error type
 	}
 
 	Option struct {
@@ -181,6 +185,18 @@ type (
 	Channel struct {
 		Id int
 	}
+
+	// Enum is a value of an enum type: the index of its variant and the
+	// values of that variant's payload
+	Enum struct {
+		T      *TypeObject
+		Tag    int
+		Values []Object
+	}
+
+	Tuple struct {
+		Values []Object
+	}
 )
 
 func (i *Integer) Type() ObjectType {
@@ -279,6 +295,14 @@ func (c *Channel) Type() ObjectType {
 	return ChannelObj
 }
 
+func (e *Enum) Type() ObjectType {
+	return EnumObj
+}
+
+func (t *Tuple) Type() ObjectType {
+	return TupleObj
+}
+
 func (i *Integer) Inspect() string {
 	return fmt.Sprintf("%d", i.Value)
 }
@@ -481,6 +505,42 @@ func (c *Channel) Inspect() string {
 	return out.String()
 }
 
+func (e *Enum) Inspect() string {
+	var out bytes.Buffer
+	t := e.T.T.(types.EnumType)
+
+	out.WriteString(t.Name)
+	out.WriteString(".")
+	out.WriteString(t.Variants[e.Tag])
+	if len(e.Values) > 0 {
+		out.WriteString("(")
+		for i, v := range e.Values {
+			out.WriteString(v.Inspect())
+			if i != len(e.Values)-1 {
+				out.WriteString(", ")
+			}
+		}
+		out.WriteString(")")
+	}
+
+	return out.String()
+}
+
+func (t *Tuple) Inspect() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	for i, v := range t.Values {
+		out.WriteString(v.Inspect())
+		if i != len(t.Values)-1 {
+			out.WriteString(", ")
+		}
+	}
+	out.WriteString(")")
+
+	return out.String()
+}
+
 // HashKey functions
 func (b *Boolean) HashKey() HashKey {
 	var val uint64
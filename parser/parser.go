@@ -5,6 +5,7 @@ import (
 	"strconv"
 
 	"sydney/ast"
+	"sydney/errors"
 	"sydney/lexer"
 	"sydney/token"
 	"sydney/types"
@@ -48,6 +49,7 @@ var precedences = map[token.TokenType]Precedence{
 	token.Modulo:             PRODUCT,
 	token.LeftParen:          CALL,
 	token.LeftSquareBracket:  INDEX,
+	token.Question:           INDEX,
 	token.Dot:                SELECTOR,
 	token.Colon:              SCOPEACCESS,
 }
@@ -59,13 +61,16 @@ type Parser struct {
 	peekToken     token.Token
 	peekPeekToken token.Token
 
-	errors []string
+	file   string
+	errors []errors.PositionError
+	failed bool // whether the statement being parsed has a syntax error
 
 	prefixParseFns map[token.TokenType]prefixParseFn
 	infixParseFns  map[token.TokenType]infixParseFn
 
 	definedStructs    map[string]types.Type
 	definedInterfaces map[string]types.Type
+	definedEnums      map[string]types.Type
 	doneImports       bool
 
 	genericNames   map[string]bool
@@ -75,7 +80,7 @@ type Parser struct {
 }
 
 func New(l *lexer.Lexer) *Parser {
-	p := &Parser{lexer: l, errors: make([]string, 0)}
+	p := &Parser{lexer: l, errors: make([]errors.PositionError, 0)}
 
 	// peekToken and currToken are initialized to the zero value of token.Token, so we advance twice
 	p.nextToken() // set peek
@@ -120,6 +125,7 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfix(token.Or, p.parseInfixExpr)
 	p.registerInfix(token.LeftParen, p.parseCallExpr)
 	p.registerInfix(token.LeftSquareBracket, p.parseIndexExpr)
+	p.registerInfix(token.Question, p.parseTryExpr)
 	p.registerInfix(token.Dot, p.parseSelectorExpr)
 	p.registerInfix(token.Colon, p.parseScopeAccessExpr)
 	p.registerPrefix(token.IntType, p.parseTypeCast)
@@ -128,6 +134,7 @@ func New(l *lexer.Lexer) *Parser {
 
 	p.definedStructs = make(map[string]types.Type)
 	p.definedInterfaces = make(map[string]types.Type)
+	p.definedEnums = make(map[string]types.Type)
 
 	p.genericNames = make(map[string]bool)
 	p.typeParameters = make(map[string]bool)
@@ -140,10 +147,15 @@ func NewWithGenericNames(l *lexer.Lexer, genericNames map[string]bool) *Parser {
 	return p
 }
 
-func (p *Parser) Errors() []string {
+func (p *Parser) Errors() []errors.PositionError {
 	return p.errors
 }
 
+// SetFile names the file being parsed in the errors the parser reports
+func (p *Parser) SetFile(file string) {
+	p.file = file
+}
+
 func (p *Parser) ParseDefinitions() {
 	for !p.currTokenIs(token.EOF) {
 		if p.currTokenIs(token.Public) && p.peekTokenIs(token.Define) {
@@ -160,6 +172,11 @@ func (p *Parser) ParseDefinitions() {
 				p.parseInterfaceDefinitionStmt()
 				continue
 			}
+			if p.peekTokenIs(token.Enum) {
+				p.nextToken()
+				p.parseEnumDefinitionStmt()
+				continue
+			}
 		}
 		p.nextToken()
 	}
@@ -173,13 +190,23 @@ func (p *Parser) DefinedInterfaces() map[string]types.Type {
 	return p.definedInterfaces
 }
 
-func (p *Parser) SetDefinedTypes(structs map[string]types.Type, interfaces map[string]types.Type) {
+func (p *Parser) DefinedEnums() map[string]types.Type {
+	return p.definedEnums
+}
+
+func (p *Parser) SetDefinedTypes(structs map[string]types.Type, interfaces map[string]types.Type, enums map[string]types.Type) {
 	for k, v := range structs {
 		p.definedStructs[k] = v
 	}
 	for k, v := range interfaces {
 		p.definedInterfaces[k] = v
 	}
+	for k, v := range enums {
+		p.definedEnums[k] = v
+		if len(v.(types.EnumType).TypeParams) > 0 {
+			p.genericNames[k] = true
+		}
+	}
 }
 
 func (p *Parser) registerPrefix(tokenType token.TokenType, fn prefixParseFn) {
@@ -191,8 +218,29 @@ func (p *Parser) registerInfix(tokenType token.TokenType, fn infixParseFn) {
 }
 
 func (p *Parser) noPrefixParseFnError(t token.Token) {
-	msg := fmt.Sprintf("%d:%d no prefix parse function for %s found", t.Line, t.Column, t.Type)
-	p.errors = append(p.errors, msg)
+	p.codedErrorAt(errors.ExpectedExpression, t, "no prefix parse function for %s found", t.Type)
+}
+
+// errorAt records a syntax error at tok, marking the statement being parsed
+// as broken so that parsing can resume after it
+func (p *Parser) errorAt(tok token.Token, format string, args ...any) {
+	p.report(*p.positionError(tok, format, args...))
+}
+
+// codedErrorAt is errorAt for the errors that are not an unexpected token
+func (p *Parser) codedErrorAt(code errors.Code, tok token.Token, format string, args ...any) {
+	err := p.positionError(tok, format, args...)
+	err.Code = code
+	p.report(*err)
+}
+
+func (p *Parser) report(err errors.PositionError) {
+	p.errors = append(p.errors, err)
+	p.failed = true
+}
+
+func (p *Parser) positionError(tok token.Token, format string, args ...any) *errors.PositionError {
+	return &errors.PositionError{Code: errors.UnexpectedToken, Line: tok.Line, Col: tok.Column, Message: fmt.Sprintf(format, args...), File: p.file}
 }
 
 // advances current and peek by one
@@ -227,9 +275,21 @@ func (p *Parser) expectPeek(t token.TokenType) bool {
 }
 
 func (p *Parser) peekError(t token.TokenType) {
-	msg := fmt.Sprintf("%d:%d expected next token to be %s, got %s instead",
-		p.peekToken.Line, p.peekToken.Column, t, p.peekToken.Type)
-	p.errors = append(p.errors, msg)
+	p.errorAt(p.peekToken, "expected next token to be %s, got %s instead", t, p.peekToken.Type)
+}
+
+// expectSeparator moves past the comma between the items of a list closed by
+// closing, with one error when neither comes next
+func (p *Parser) expectSeparator(closing token.TokenType, literal string) bool {
+	if p.peekTokenIs(closing) {
+		return true
+	}
+	if p.peekTokenIs(token.Comma) {
+		p.nextToken()
+		return true
+	}
+	p.errorAt(p.peekToken, "expected , or %s got %s", literal, p.peekToken.Literal)
+	return false
 }
 
 func (p *Parser) peekPrecedence() Precedence {
@@ -255,12 +315,12 @@ func (p *Parser) ParseProgram() *ast.Program {
 	program.Stmts = make([]ast.Stmt, 0)
 
 	for !p.currTokenIs(token.EOF) {
-		stmt := p.parseStatement()
+		stmt := p.parseRecovering(true)
 		if stmt != nil {
 			_, ok := stmt.(*ast.ImportStatement)
 			program.Stmts = append(program.Stmts, stmt)
 			if ok && p.doneImports {
-				p.errors = append(p.errors, "import statements must be top level")
+				p.codedErrorAt(errors.InvalidDeclaration, p.currToken, "import statements must be top level")
 			}
 
 			if _, isMod := stmt.(*ast.ModuleDeclarationStmt); !isMod && !ok {
@@ -273,6 +333,65 @@ func (p *Parser) ParseProgram() *ast.Program {
 	return program
 }
 
+// parseRecovering parses a statement, dropping it when it has a syntax error
+// and skipping to where the next statement can start. Errors in the blocks
+// it contains are recovered from there, and leave the statement intact
+func (p *Parser) parseRecovering(topLevel bool) ast.Stmt {
+	enclosing := p.failed
+	defer func() { p.failed = enclosing }()
+
+	p.failed = false
+	stmt := p.parseStatement()
+	if p.failed {
+		p.synchronize(topLevel)
+		return nil
+	}
+	return stmt
+}
+
+// synchronize skips the rest of a broken statement, stopping on the ; or }
+// that ends it, or before the } ending the enclosing block or a keyword that
+// starts a declaration
+func (p *Parser) synchronize(topLevel bool) {
+	depth := 0
+	for !p.currTokenIs(token.EOF) {
+		switch p.currToken.Type {
+		case token.LeftCurlyBracket:
+			depth++
+		case token.RightCurlyBracket:
+			depth--
+			if depth <= 0 {
+				return
+			}
+		case token.Semicolon:
+			if depth <= 0 {
+				return
+			}
+		}
+		if p.peekTokenIs(token.EOF) {
+			return
+		}
+		if depth <= 0 && !topLevel && p.peekTokenIs(token.RightCurlyBracket) {
+			return
+		}
+		// a stray declaration keyword means a brace was left open
+		if (depth <= 0 || topLevel) && p.peekStartsDeclaration() {
+			return
+		}
+		p.nextToken()
+	}
+}
+
+func (p *Parser) peekStartsDeclaration() bool {
+	switch p.peekToken.Type {
+	case token.Define, token.Public, token.Import, token.Module, token.Extern, token.AnnotationStart:
+		return true
+	case token.Func:
+		return p.peekPeekTokenIs(token.Identifier)
+	}
+	return false
+}
+
 // Statements
 func (p *Parser) parseStatement() ast.Stmt {
 	if p.currTokenIs(token.AnnotationStart) {
@@ -285,11 +404,11 @@ func (p *Parser) parseStatement() ast.Stmt {
 			if inner, ok := s.Stmt.(*ast.StructDefinitionStmt); ok {
 				inner.SetAnnotations([]*ast.Annotation{annotation})
 			} else {
-				p.errors = append(p.errors, "can only provide annotations for struct definitions")
+				p.codedErrorAt(errors.InvalidDeclaration, p.currToken, "can only provide annotations for struct definitions")
 				return nil
 			}
 		default:
-			p.errors = append(p.errors, "can only provide annotations for struct definitions")
+			p.codedErrorAt(errors.InvalidDeclaration, p.currToken, "can only provide annotations for struct definitions")
 			return nil
 		}
 		return stmt
@@ -299,6 +418,9 @@ func (p *Parser) parseStatement() ast.Stmt {
 	case token.Mut:
 		fallthrough
 	case token.Const:
+		if p.peekTokenIs(token.LeftParen) && p.peekPeekTokenIs(token.Identifier) && !p.isTypeName(p.peekPeekToken.Literal) {
+			return p.parseTupleDeclarationStmt()
+		}
 		return p.parseVarDeclarationStmt()
 	case token.Public:
 		pubStmt := &ast.PubStatement{Token: p.currToken}
@@ -314,7 +436,7 @@ func (p *Parser) parseStatement() ast.Stmt {
 			break
 		case token.Extern:
 			if !p.expectPeek(token.Func) {
-				p.errors = append(p.errors, "expected function declaration")
+				p.errorAt(p.currToken, "expected function declaration")
 			}
 			pubStmt.Stmt = p.parseFunctionDeclarationStmt(true)
 		case token.Define:
@@ -325,9 +447,12 @@ func (p *Parser) parseStatement() ast.Stmt {
 			} else if p.currTokenIs(token.Interface) {
 				pubStmt.Stmt = p.parseInterfaceDefinitionStmt()
 				break
+			} else if p.currTokenIs(token.Enum) {
+				pubStmt.Stmt = p.parseEnumDefinitionStmt()
+				break
 			}
 		default:
-			p.errors = append(p.errors, fmt.Sprintf("%d:%d cannot define pub for token: %s", p.currToken.Line, p.currToken.Column, p.currToken.Type))
+			p.codedErrorAt(errors.InvalidDeclaration, p.currToken, "cannot define pub for token: %s", p.currToken.Type)
 			return nil
 		}
 		return pubStmt
@@ -336,7 +461,7 @@ func (p *Parser) parseStatement() ast.Stmt {
 	case token.For:
 		return p.parseForStmt()
 	case token.Extern:
-		p.errors = append(p.errors, fmt.Sprintf("%d:%d cannot extern for token: %s", p.currToken.Line, p.currToken.Column, p.currToken.Type))
+		p.codedErrorAt(errors.InvalidDeclaration, p.currToken, "cannot extern for token: %s", p.currToken.Type)
 		return nil
 	case token.Func:
 		if p.peekTokenIs(token.Identifier) {
@@ -350,8 +475,10 @@ func (p *Parser) parseStatement() ast.Stmt {
 			return p.parseStructDefinitionStmt()
 		} else if p.currTokenIs(token.Interface) {
 			return p.parseInterfaceDefinitionStmt()
+		} else if p.currTokenIs(token.Enum) {
+			return p.parseEnumDefinitionStmt()
 		}
-		p.errors = append(p.errors, fmt.Sprintf("%d:%d expected interface or struct, got %s instead", p.currToken.Line, p.currToken.Column, p.currToken.Literal))
+		p.errorAt(p.currToken, "expected interface, struct or enum, got %s instead", p.currToken.Literal)
 		return nil
 	case token.Import:
 		return p.parseImportStatement()
@@ -378,6 +505,14 @@ func (p *Parser) parseStatement() ast.Stmt {
 			p.nextToken()
 		}
 		return stmt
+	case token.Defer:
+		stmt := &ast.DeferStmt{Token: p.currToken}
+		p.nextToken()
+		stmt.CallExpr = p.parseExpression(LOWEST)
+		if p.peekTokenIs(token.Semicolon) {
+			p.nextToken()
+		}
+		return stmt
 	default:
 		return p.parseExpressionOrAssignmentStmt()
 	}
@@ -408,7 +543,7 @@ func (p *Parser) parseVarDeclarationStmt() *ast.VarDeclarationStmt {
 	stmt := &ast.VarDeclarationStmt{Token: p.currToken, Constant: isConst}
 
 	// parse type. constant variables do not need a type annotation, mutable variables that are uninitialized do
-	if p.isPeekTokenType() {
+	if p.isPeekTokenType() || p.peekTokenIs(token.LeftParen) {
 		p.nextToken()
 		stmt.Type = p.parseType()
 	}
@@ -422,10 +557,10 @@ func (p *Parser) parseVarDeclarationStmt() *ast.VarDeclarationStmt {
 
 	if p.peekTokenIs(token.Semicolon) {
 		if isConst {
-			p.errors = append(p.errors, "const variable must be initialized")
+			p.codedErrorAt(errors.InvalidDeclaration, p.currToken, "const variable must be initialized")
 			return nil
 		} else if stmt.Type == nil {
-			p.errors = append(p.errors, "uninitialized mut variable must have a type")
+			p.codedErrorAt(errors.InvalidDeclaration, p.currToken, "uninitialized mut variable must have a type")
 			return nil
 		} else {
 			p.nextToken() // advance past ;
@@ -451,6 +586,51 @@ func (p *Parser) parseVarDeclarationStmt() *ast.VarDeclarationStmt {
 	return stmt
 }
 
+// parseTupleDeclarationStmt parses const (a, b) = value;
+func (p *Parser) parseTupleDeclarationStmt() *ast.TupleDeclarationStmt {
+	stmt := &ast.TupleDeclarationStmt{Token: p.currToken, Constant: p.currTokenIs(token.Const)}
+
+	p.nextToken() // advance to (
+	stmt.Names = p.parseIdentifierList()
+	if stmt.Names == nil {
+		return nil
+	}
+
+	if !p.expectPeek(token.Assign) {
+		return nil
+	}
+	p.nextToken() // advance past =
+	stmt.Value = p.parseExpression(LOWEST)
+
+	if p.peekTokenIs(token.Semicolon) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// parseIdentifierList parses the names of a destructuring pattern, starting
+// on its ( and ending on its )
+func (p *Parser) parseIdentifierList() []*ast.Identifier {
+	names := make([]*ast.Identifier, 0)
+	for {
+		if !p.expectPeek(token.Identifier) {
+			return nil
+		}
+		names = append(names, &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal})
+		if !p.peekTokenIs(token.Comma) {
+			break
+		}
+		p.nextToken()
+	}
+
+	if !p.expectPeek(token.RightParen) {
+		return nil
+	}
+
+	return names
+}
+
 func (p *Parser) parseReturnStmt() *ast.ReturnStmt {
 	stmt := &ast.ReturnStmt{Token: p.currToken}
 
@@ -526,12 +706,13 @@ func (p *Parser) parseBlockStmt() *ast.BlockStmt {
 	p.nextToken() // advance past {
 
 	for !p.currTokenIs(token.RightCurlyBracket) && !p.currTokenIs(token.EOF) {
-		stmt := p.parseStatement()
+		stmt := p.parseRecovering(false)
 		if stmt != nil {
 			block.Stmts = append(block.Stmts, stmt)
 		}
 		p.nextToken() // what is this advance for
 	}
+	block.End = p.currToken
 	return block
 }
 
@@ -605,8 +786,7 @@ func (p *Parser) parseIntegerLiteral() ast.Expr {
 
 	value, err := strconv.ParseInt(p.currToken.Literal, 0, 64)
 	if err != nil {
-		msg := fmt.Sprintf("%d:%d could not parse %q as integer", p.currToken.Line, p.currToken.Column, p.currToken.Literal)
-		p.errors = append(p.errors, msg)
+		p.codedErrorAt(errors.InvalidLiteral, p.currToken, "could not parse %q as integer", p.currToken.Literal)
 		return nil
 	}
 
@@ -649,10 +829,22 @@ func (p *Parser) parseBooleanLiteral() ast.Expr {
 
 // this is a prefixParseFn
 func (p *Parser) parseGroupedExpr() ast.Expr {
+	tok := p.currToken
 	p.nextToken() // advance past (
 
 	expr := p.parseExpression(LOWEST)
 
+	// a comma makes the group a tuple
+	if p.peekTokenIs(token.Comma) {
+		tuple := &ast.TupleLiteral{Token: tok, Elements: []ast.Expr{expr}}
+		for p.peekTokenIs(token.Comma) {
+			p.nextToken()
+			p.nextToken()
+			tuple.Elements = append(tuple.Elements, p.parseExpression(LOWEST))
+		}
+		expr = tuple
+	}
+
 	if !p.expectPeek(token.RightParen) {
 		return nil
 	}
@@ -748,7 +940,7 @@ func (p *Parser) parseFunctionParameters() ([]*ast.Identifier, []types.Type) {
 	}
 
 	if !p.expectPeek(token.RightParen) {
-		p.errors = append(p.errors, fmt.Sprintf("%d:%d missing closing parenthesis", p.peekToken.Line, p.peekToken.Column))
+		p.errorAt(p.peekToken, "missing closing parenthesis")
 		return nil, nil
 	}
 
@@ -775,7 +967,7 @@ func (p *Parser) parseFunctionDeclarationStmt(extern bool) ast.Stmt {
 	}
 
 	if !p.expectPeek(token.LeftParen) {
-		p.errors = append(p.errors, fmt.Sprintf("%d:%d missing opening parenthesis for function declaration", p.peekToken.Line, p.peekToken.Column))
+		p.errorAt(p.peekToken, "missing opening parenthesis for function declaration")
 		return nil
 	}
 
@@ -802,7 +994,7 @@ func (p *Parser) parseFunctionDeclarationStmt(extern bool) ast.Stmt {
 	}
 
 	if !p.expectPeek(token.LeftCurlyBracket) {
-		p.errors = append(p.errors, fmt.Sprintf("%d:%d expected body for function %s declaration", p.peekToken.Line, p.peekToken.Column, stmt.Name.String()))
+		p.errorAt(p.peekToken, "expected body for function %s declaration", stmt.Name.String())
 		return nil
 	}
 
@@ -839,7 +1031,7 @@ func (p *Parser) parseMacroParameters() []*ast.Identifier {
 	}
 
 	if !p.expectPeek(token.RightParen) {
-		p.errors = append(p.errors, fmt.Sprintf("%d:%d missing closing parenthesis", p.peekToken.Line, p.peekToken.Column))
+		p.errorAt(p.peekToken, "missing closing parenthesis")
 		return nil
 	}
 
@@ -933,6 +1125,11 @@ func (p *Parser) parseIndexExpr(left ast.Expr) ast.Expr {
 	return expr
 }
 
+// parseTryExpr parses the postfix ? of value?
+func (p *Parser) parseTryExpr(left ast.Expr) ast.Expr {
+	return &ast.TryExpr{Token: p.currToken, Value: left}
+}
+
 func (p *Parser) parseNullLiteral() ast.Expr {
 	return &ast.NullLiteral{Token: p.currToken}
 }
@@ -951,11 +1148,11 @@ func (p *Parser) parseForStmt() ast.Stmt {
 		if p.peekTokenIs(token.Assign) {
 			p.parseAssignedThreePartForStmt(expr, forStmt)
 		} else if p.peekTokenIs(token.In) || p.peekTokenIs(token.Comma) {
-			return p.parseForInStmt(forStmt.Token)
+			return p.parseForInStmt(forStmt.Token, expr)
 		} else {
 			forStmt.Condition = expr
 			if !p.expectPeek(token.RightParen) {
-				p.errors = append(p.errors, fmt.Sprintf("%d:%d expected ) after condition, got %s", p.currToken.Line, p.currToken.Column, p.currToken.Literal))
+				p.errorAt(p.currToken, "expected ) after condition, got %s", p.currToken.Literal)
 				return nil
 			}
 		}
@@ -1004,8 +1201,7 @@ func (p *Parser) parseFloatLiteral() ast.Expr {
 
 	value, err := strconv.ParseFloat(p.currToken.Literal, 64)
 	if err != nil {
-		msg := fmt.Sprintf("%d:%d could not parse %q as float", p.currToken.Line, p.currToken.Column, p.currToken.Literal)
-		p.errors = append(p.errors, msg)
+		p.codedErrorAt(errors.InvalidLiteral, p.currToken, "could not parse %q as float", p.currToken.Literal)
 		return nil
 	}
 
@@ -1069,11 +1265,29 @@ func (p *Parser) isPeekTokenType() bool {
 	if ok {
 		return true
 	}
+	if _, ok = p.definedEnums[p.peekToken.Literal]; ok {
+		return true
+	}
 	_, ok = p.typeParameters[p.peekToken.Literal]
 
 	return ok
 }
 
+// isTypeName reports whether name is a declared struct, interface or enum or
+// a type parameter in scope
+func (p *Parser) isTypeName(name string) bool {
+	if _, ok := p.definedStructs[name]; ok {
+		return true
+	}
+	if _, ok := p.definedInterfaces[name]; ok {
+		return true
+	}
+	if _, ok := p.definedEnums[name]; ok {
+		return true
+	}
+	return p.typeParameters[name]
+}
+
 func (p *Parser) parseType() types.Type {
 	if t, ok := typeMap[p.currToken.Type]; ok {
 		return t
@@ -1091,6 +1305,8 @@ func (p *Parser) parseType() types.Type {
 		return p.parseOptionType()
 	case token.ChannelType:
 		return p.parseChannelType()
+	case token.LeftParen:
+		return p.parseTupleType()
 	case token.Identifier:
 		var t types.Type = nil
 		var ok bool
@@ -1101,9 +1317,12 @@ func (p *Parser) parseType() types.Type {
 
 		if p.genericNames[p.currToken.Literal] && p.peekTokenIs(token.LessThan) {
 			name := p.currToken.Literal
+			if enumType, ok := p.definedEnums[name]; ok {
+				return p.parseGenericEnumType(enumType.(types.EnumType))
+			}
 			templateType, ok := p.definedStructs[name]
 			if !ok {
-				p.errors = append(p.errors, fmt.Sprintf("%d:%d unknown generic struct %s", p.currToken.Line, p.currToken.Column, name))
+				p.codedErrorAt(errors.UnknownType, p.currToken, "unknown generic struct %s", name)
 				return nil
 			}
 
@@ -1113,7 +1332,7 @@ func (p *Parser) parseType() types.Type {
 			typeArgs := p.parseTypeArgs()
 
 			if len(typeArgs) != len(template.TypeParams) {
-				p.errors = append(p.errors, fmt.Sprintf("%d:%d %s expects exactly %d type arguments", p.currToken.Line, p.currToken.Column, name, len(template.TypeArgs)))
+				p.codedErrorAt(errors.WrongArgumentCount, p.currToken, "%s expects exactly %d type arguments", name, len(template.TypeArgs))
 				return nil
 			}
 
@@ -1146,9 +1365,13 @@ func (p *Parser) parseType() types.Type {
 			return t
 		}
 
+		if t, ok = p.definedEnums[p.currToken.Literal]; ok {
+			return t
+		}
+
 	}
 
-	p.errors = append(p.errors, fmt.Sprintf("%d:%d unknown type %q", p.peekToken.Line, p.peekToken.Column, p.peekToken.Type))
+	p.codedErrorAt(errors.UnknownType, p.peekToken, "unknown type %q", p.peekToken.Type)
 	return nil
 }
 
@@ -1168,11 +1391,11 @@ func (p *Parser) parseInterfaceMethod() types.Type {
 
 func (p *Parser) parseFunctionType() types.Type {
 	if !p.expectPeek(token.LessThan) {
-		p.errors = append(p.errors, getTypeParseError("function", token.LessThan, p.peekToken.Type))
+		p.typeParseError("function", token.LessThan)
 		return nil
 	}
 	if !p.expectPeek(token.LeftParen) {
-		p.errors = append(p.errors, getTypeParseError("function", token.LeftParen, p.peekToken.Type))
+		p.typeParseError("function", token.LeftParen)
 		return nil
 	}
 	params := make([]types.Type, 0)
@@ -1186,25 +1409,25 @@ func (p *Parser) parseFunctionType() types.Type {
 		}
 
 		if !p.expectPeek(token.Comma) {
-			p.errors = append(p.errors, getTypeParseError("function", token.Comma, p.peekToken.Type))
+			p.typeParseError("function", token.Comma)
 			return nil
 		}
 		params = append(params, t)
 	}
 
 	if !p.expectPeek(token.RightParen) {
-		p.errors = append(p.errors, getTypeParseError("function", token.RightParen, p.peekToken.Type))
+		p.typeParseError("function", token.RightParen)
 		return nil
 	}
 
 	if !p.expectPeek(token.Arrow) {
-		p.errors = append(p.errors, getTypeParseError("function", token.Arrow, p.peekToken.Type))
+		p.typeParseError("function", token.Arrow)
 		return nil
 	}
 	p.nextToken()
 	r := p.parseType()
 	if !p.expectPeek(token.GreaterThan) {
-		p.errors = append(p.errors, getTypeParseError("function", token.GreaterThan, p.peekToken.Type))
+		p.typeParseError("function", token.GreaterThan)
 	}
 
 	return types.FunctionType{Params: params, Return: r}
@@ -1212,30 +1435,38 @@ func (p *Parser) parseFunctionType() types.Type {
 
 func (p *Parser) parseResultType() types.Type {
 	if !p.expectPeek(token.LessThan) {
-		p.errors = append(p.errors, getTypeParseError("result", token.LessThan, p.peekToken.Type))
+		p.typeParseError("result", token.LessThan)
 		return nil
 	}
 	p.nextToken()
 	t := p.parseType()
 
+	// the error type is optional, and string when left out
+	var e types.Type
+	if p.peekTokenIs(token.Comma) {
+		p.nextToken()
+		p.nextToken()
+		e = p.parseType()
+	}
+
 	if !p.expectPeek(token.GreaterThan) {
-		p.errors = append(p.errors, getTypeParseError("result", token.GreaterThan, p.peekToken.Type))
+		p.typeParseError("result", token.GreaterThan)
 		return nil
 	}
 
-	return types.ResultType{T: t}
+	return types.ResultType{T: t, E: e}
 }
 
 func (p *Parser) parseOptionType() types.Type {
 	if !p.expectPeek(token.LessThan) {
-		p.errors = append(p.errors, getTypeParseError("option", token.LessThan, p.peekToken.Type))
+		p.typeParseError("option", token.LessThan)
 		return nil
 	}
 	p.nextToken()
 	t := p.parseType()
 
 	if !p.expectPeek(token.GreaterThan) {
-		p.errors = append(p.errors, getTypeParseError("option", token.GreaterThan, p.peekToken.Type))
+		p.typeParseError("option", token.GreaterThan)
 		return nil
 	}
 
@@ -1244,30 +1475,54 @@ func (p *Parser) parseOptionType() types.Type {
 
 func (p *Parser) parseChannelType() types.Type {
 	if !p.expectPeek(token.LessThan) {
-		p.errors = append(p.errors, getTypeParseError("chan", token.LessThan, p.peekToken.Type))
+		p.typeParseError("chan", token.LessThan)
 		return nil
 	}
 	p.nextToken()
 	t := p.parseType()
 
 	if !p.expectPeek(token.GreaterThan) {
-		p.errors = append(p.errors, getTypeParseError("chan", token.GreaterThan, p.peekToken.Type))
+		p.typeParseError("chan", token.GreaterThan)
 		return nil
 	}
 
 	return types.ChannelType{ElemType: t}
 }
 
+// parseTupleType parses (T1, T2, ...), which needs at least two elements
+func (p *Parser) parseTupleType() types.Type {
+	tuple := types.TupleType{}
+	for {
+		p.nextToken()
+		tuple.Types = append(tuple.Types, p.parseType())
+		if !p.peekTokenIs(token.Comma) {
+			break
+		}
+		p.nextToken()
+	}
+
+	if !p.expectPeek(token.RightParen) {
+		p.typeParseError("tuple", token.RightParen)
+		return nil
+	}
+	if len(tuple.Types) < 2 {
+		p.errorAt(p.currToken, "tuple type needs at least two elements")
+		return nil
+	}
+
+	return tuple
+}
+
 func (p *Parser) parseArrayType() types.Type {
 	if !p.expectPeek(token.LessThan) {
-		p.errors = append(p.errors, getTypeParseError("array", token.GreaterThan, p.peekToken.Type))
+		p.typeParseError("array", token.GreaterThan)
 		return nil
 	}
 	p.nextToken()
 	t := p.parseType() // recursively get type for array
 
 	if !p.expectPeek(token.GreaterThan) {
-		p.errors = append(p.errors, getTypeParseError("array", token.LessThan, p.peekToken.Type))
+		p.typeParseError("array", token.LessThan)
 		return nil
 	}
 
@@ -1276,14 +1531,14 @@ func (p *Parser) parseArrayType() types.Type {
 
 func (p *Parser) parseMapType() types.Type {
 	if !p.expectPeek(token.LessThan) {
-		p.errors = append(p.errors, getTypeParseError("map", token.GreaterThan, p.peekToken.Type))
+		p.typeParseError("map", token.GreaterThan)
 		return nil
 	}
 	p.nextToken()
 	k := p.parseType() // recursively get type for key type
 
 	if !p.expectPeek(token.Comma) {
-		p.errors = append(p.errors, getTypeParseError("map", token.Comma, p.peekToken.Type))
+		p.typeParseError("map", token.Comma)
 		return nil
 	}
 
@@ -1291,7 +1546,7 @@ func (p *Parser) parseMapType() types.Type {
 	v := p.parseType()
 
 	if !p.expectPeek(token.GreaterThan) {
-		p.errors = append(p.errors, getTypeParseError("map", token.LessThan, p.peekToken.Type))
+		p.typeParseError("map", token.LessThan)
 		return nil
 	}
 
@@ -1301,7 +1556,7 @@ func (p *Parser) parseMapType() types.Type {
 func (p *Parser) parseStructDefinitionStmt() ast.Stmt {
 	stmt := &ast.StructDefinitionStmt{}
 	if !p.expectPeek(token.Identifier) {
-		p.errors = append(p.errors, fmt.Sprintf("%d:%d expected identifier, got %s", p.currToken.Line, p.currToken.Column, p.currToken.Literal))
+		p.errorAt(p.currToken, "expected identifier, got %s", p.currToken.Literal)
 		return nil
 	}
 	name := &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
@@ -1319,7 +1574,7 @@ func (p *Parser) parseStructDefinitionStmt() ast.Stmt {
 	}
 
 	if !p.expectPeek(token.LeftCurlyBracket) {
-		p.errors = append(p.errors, fmt.Sprintf("%d:%d expected {, got %s", p.currToken.Line, p.currToken.Column, p.currToken.Literal))
+		p.errorAt(p.currToken, "expected {, got %s", p.currToken.Literal)
 		return nil
 	}
 
@@ -1329,20 +1584,20 @@ func (p *Parser) parseStructDefinitionStmt() ast.Stmt {
 	for !p.peekTokenIs(token.RightCurlyBracket) {
 		p.nextToken() // move to ident
 		if !p.currTokenIs(token.Identifier) {
-			p.errors = append(p.errors, fmt.Sprintf("%d:%d expected identifier, got %s", p.currToken.Line, p.currToken.Column, p.currToken.Literal))
+			p.errorAt(p.currToken, "expected identifier, got %s", p.currToken.Literal)
 			return nil
 		}
 		fields = append(fields, p.currToken.Literal)
+		stmt.FieldNames = append(stmt.FieldNames, &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal})
 		p.nextToken() // we don't have a way to peek for a set of types so advance and let the type parser catch it
 		tt = append(tt, p.parseType())
 
-		if !p.peekTokenIs(token.RightCurlyBracket) && !p.expectPeek(token.Comma) {
-			p.errors = append(p.errors, fmt.Sprintf("%d:%d expected , or } got %s", p.currToken.Line, p.currToken.Column, p.currToken.Literal))
+		if !p.expectSeparator(token.RightCurlyBracket, "}") {
 			return nil
 		}
 	}
 	if !p.expectPeek(token.RightCurlyBracket) {
-		p.errors = append(p.errors, fmt.Sprintf("%d:%d expected }, got %s", p.currToken.Line, p.currToken.Column, p.currToken.Literal))
+		p.errorAt(p.currToken, "expected }, got %s", p.currToken.Literal)
 		return nil
 	}
 	t := types.StructType{
@@ -1361,10 +1616,108 @@ func (p *Parser) parseStructDefinitionStmt() ast.Stmt {
 	return stmt
 }
 
+// parseGenericEnumType parses the type arguments of Name<...>. Enums are not
+// monomorphized, so the arguments are kept on the type and substituted into
+// its payloads
+func (p *Parser) parseGenericEnumType(template types.EnumType) types.Type {
+	name := p.currToken.Literal
+	p.nextToken()
+	p.nextToken()
+	typeArgs := p.parseTypeArgs()
+
+	if len(typeArgs) != len(template.TypeParams) {
+		p.codedErrorAt(errors.WrongArgumentCount, p.currToken, "%s expects exactly %d type arguments", name, len(template.TypeParams))
+		return nil
+	}
+
+	subs := make(map[string]types.Type)
+	for i, tp := range template.TypeParams {
+		subs[tp.Name] = typeArgs[i]
+	}
+
+	result := types.SubstituteTypeParams(template, subs).(types.EnumType)
+	result.TypeArgs = typeArgs
+	result.TypeParams = nil
+
+	return result
+}
+
+func (p *Parser) parseEnumDefinitionStmt() ast.Stmt {
+	stmt := &ast.EnumDefinitionStmt{Token: p.currToken}
+	if !p.expectPeek(token.Identifier) {
+		p.errorAt(p.currToken, "expected identifier, got %s", p.currToken.Literal)
+		return nil
+	}
+	stmt.Name = &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+
+	typeParams := make([]*types.TypeParam, 0)
+	if p.peekTokenIs(token.LessThan) {
+		p.nextToken()
+		typeParams = p.parseTypeParamList()
+		p.typeParameters = make(map[string]bool)
+		for _, t := range typeParams {
+			p.typeParameters[t.Name] = true
+		}
+		p.genericNames[stmt.Name.Value] = true
+	}
+
+	// payloads may hold the enum being defined
+	t := types.EnumType{Name: stmt.Name.Value, TypeParams: typeParams}
+	p.definedEnums[stmt.Name.Value] = t
+
+	if !p.expectPeek(token.LeftCurlyBracket) {
+		p.errorAt(p.currToken, "expected {, got %s", p.currToken.Literal)
+		return nil
+	}
+
+	variants := make([]string, 0)
+	payloads := make([][]types.Type, 0)
+
+	for !p.peekTokenIs(token.RightCurlyBracket) {
+		p.nextToken() // move to variant name
+		if !p.currTokenIs(token.Identifier) {
+			p.errorAt(p.currToken, "expected identifier, got %s", p.currToken.Literal)
+			return nil
+		}
+		variants = append(variants, p.currToken.Literal)
+		stmt.VariantNames = append(stmt.VariantNames, &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal})
+
+		payload := make([]types.Type, 0)
+		if p.peekTokenIs(token.LeftParen) {
+			p.nextToken()
+			for !p.peekTokenIs(token.RightParen) {
+				p.nextToken()
+				payload = append(payload, p.parseType())
+				if !p.expectSeparator(token.RightParen, ")") {
+					return nil
+				}
+			}
+			p.nextToken()
+		}
+		payloads = append(payloads, payload)
+
+		if !p.expectSeparator(token.RightCurlyBracket, "}") {
+			return nil
+		}
+	}
+	if !p.expectPeek(token.RightCurlyBracket) {
+		p.errorAt(p.peekToken, "expected }, got %s", p.peekToken.Literal)
+		return nil
+	}
+
+	t.Variants = variants
+	t.Payloads = payloads
+	stmt.Type = t
+	p.definedEnums[stmt.Name.Value] = t
+	p.typeParameters = nil
+
+	return stmt
+}
+
 func (p *Parser) parseInterfaceDefinitionStmt() ast.Stmt {
 	stmt := &ast.InterfaceDefinitionStmt{}
 	if !p.expectPeek(token.Identifier) {
-		p.errors = append(p.errors, fmt.Sprintf("%d:%d expected identifier, got %s", p.currToken.Line, p.currToken.Column, p.currToken.Literal))
+		p.errorAt(p.currToken, "expected identifier, got %s", p.currToken.Literal)
 		return nil
 	}
 
@@ -1375,7 +1728,7 @@ func (p *Parser) parseInterfaceDefinitionStmt() ast.Stmt {
 	p.definedInterfaces[stmt.Name.Value] = t
 
 	if !p.expectPeek(token.LeftCurlyBracket) {
-		p.errors = append(p.errors, fmt.Sprintf("%d:%d expected {, got %s", p.peekToken.Line, p.peekToken.Column, p.peekToken.Literal))
+		p.errorAt(p.peekToken, "expected {, got %s", p.peekToken.Literal)
 		return nil
 	}
 
@@ -1385,20 +1738,20 @@ func (p *Parser) parseInterfaceDefinitionStmt() ast.Stmt {
 	for !p.peekTokenIs(token.RightCurlyBracket) {
 		p.nextToken() // move to ident
 		if !p.currTokenIs(token.Identifier) {
-			p.errors = append(p.errors, fmt.Sprintf("%d:%d expected identifier, got %s", p.currToken.Line, p.currToken.Column, p.currToken.Literal))
+			p.errorAt(p.currToken, "expected identifier, got %s", p.currToken.Literal)
 			return nil
 		}
 		methods = append(methods, p.currToken.Literal)
+		stmt.MethodNames = append(stmt.MethodNames, &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal})
 		p.nextToken() // we don't have a way to peek for a set of types so advance and let the type parser catch it
 		tt = append(tt, p.parseInterfaceMethod())
 
-		if !p.peekTokenIs(token.RightCurlyBracket) && !p.expectPeek(token.Comma) {
-			p.errors = append(p.errors, fmt.Sprintf("%d:%d expected , or } got %s", p.peekToken.Line, p.peekToken.Column, p.peekToken.Literal))
+		if !p.expectSeparator(token.RightCurlyBracket, "}") {
 			return nil
 		}
 	}
 	if !p.expectPeek(token.RightCurlyBracket) {
-		p.errors = append(p.errors, fmt.Sprintf("%d:%d expected }, got %s", p.peekToken.Line, p.peekToken.Column, p.peekToken.Literal))
+		p.errorAt(p.peekToken, "expected }, got %s", p.peekToken.Literal)
 		return nil
 	}
 	t.Methods = methods
@@ -1417,33 +1770,33 @@ func (p *Parser) parseInterfaceDefinitionStmt() ast.Stmt {
 func (p *Parser) parseStructLiteral(tok token.Token) *ast.StructLiteral {
 	expr := &ast.StructLiteral{Token: tok, Name: tok.Literal, Fields: make([]string, 0), Values: make([]ast.Expr, 0)}
 	if !p.expectPeek(token.LeftCurlyBracket) {
-		p.errors = append(p.errors, fmt.Sprintf("%d:%d expected {, got %s", p.peekToken.Line, p.peekToken.Column, p.peekToken.Literal))
+		p.errorAt(p.peekToken, "expected {, got %s", p.peekToken.Literal)
 		return nil
 	}
 
 	for !p.peekTokenIs(token.RightCurlyBracket) {
 		p.nextToken() // advance to field name
 		if !p.currTokenIs(token.Identifier) {
-			p.errors = append(p.errors, fmt.Sprintf("%d:%d expected identifier, got %s", p.peekToken.Line, p.peekToken.Column, p.peekToken.Literal))
+			p.errorAt(p.peekToken, "expected identifier, got %s", p.peekToken.Literal)
 			return nil
 		}
 		expr.Fields = append(expr.Fields, p.currToken.Literal)
+		expr.FieldNames = append(expr.FieldNames, &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal})
 		if !p.expectPeek(token.Colon) {
-			p.errors = append(p.errors, fmt.Sprintf("%d:%d expected :, got %s", p.peekToken.Line, p.peekToken.Column, p.peekToken.Literal))
+			p.errorAt(p.peekToken, "expected :, got %s", p.peekToken.Literal)
 			return nil
 		}
 
 		p.nextToken() // move to value expr
 		expr.Values = append(expr.Values, p.parseExpression(LOWEST))
 
-		if !p.peekTokenIs(token.RightCurlyBracket) && !p.expectPeek(token.Comma) {
-			p.errors = append(p.errors, fmt.Sprintf("%d:%d expected , or } got %s", p.peekToken.Line, p.peekToken.Column, p.peekToken.Literal))
+		if !p.expectSeparator(token.RightCurlyBracket, "}") {
 			return nil
 		}
 	}
 
 	if !p.expectPeek(token.RightCurlyBracket) {
-		p.errors = append(p.errors, fmt.Sprintf("%d:%d expected }, got %s", p.peekToken.Line, p.peekToken.Column, p.peekToken.Literal))
+		p.errorAt(p.peekToken, "expected }, got %s", p.peekToken.Literal)
 		return nil
 	}
 	return expr
@@ -1470,7 +1823,7 @@ func (p *Parser) parseSelectorExpr(left ast.Expr) ast.Expr {
 func (p *Parser) parseScopeAccessExpr(left ast.Expr) ast.Expr {
 	ident, ok := left.(*ast.Identifier)
 	if !ok {
-		p.errors = append(p.errors, fmt.Sprintf("%d:%d expected identifier, got %s", p.currToken.Line, p.currToken.Column, p.currToken.Literal))
+		p.errorAt(p.currToken, "expected identifier, got %s", p.currToken.Literal)
 		return nil
 	}
 	p.nextToken()
@@ -1520,7 +1873,7 @@ func (p *Parser) parseMatchExpr() ast.Expr {
 	m := &ast.MatchExpr{Token: p.currToken}
 
 	if !p.expectPeek(token.Identifier) {
-		p.errors = append(p.errors, fmt.Sprintf("%d:%d expected identifier, got %s", p.peekToken.Line, p.peekToken.Column, p.peekToken.Literal))
+		p.errorAt(p.peekToken, "expected identifier, got %s", p.peekToken.Literal)
 		return nil
 	}
 	subject := p.parseIdentifier()
@@ -1549,32 +1902,34 @@ func (p *Parser) parseMatchExpr() ast.Expr {
 	}
 	m.Subject = subject
 	if !p.expectPeek(token.LeftCurlyBracket) {
-		p.errors = append(p.errors, fmt.Sprintf("%d:%d expected {, got %s", p.peekToken.Line, p.peekToken.Column, p.peekToken.Literal))
+		p.errorAt(p.peekToken, "expected {, got %s", p.peekToken.Literal)
 		return nil
 	}
 	if !p.expectPeek(token.Identifier) {
-		p.errors = append(p.errors, fmt.Sprintf("%d:%d expected ok, err, some, or none, got %s", p.peekToken.Line, p.peekToken.Column, p.peekToken.Literal))
+		p.errorAt(p.peekToken, "expected ok, err, some, none or an enum variant, got %s", p.peekToken.Literal)
 		return nil
 	}
 
 	switch p.currToken.Literal {
 	case "ok", "err":
 		if err := p.parseResultMatch(m, p.currToken.Literal); err != nil {
-			p.errors = append(p.errors, err.Error())
+			p.report(*err)
 			return nil
 		}
 	case "some", "none":
 		if err := p.parseOptionMatch(m, p.currToken.Literal); err != nil {
-			p.errors = append(p.errors, err.Error())
+			p.report(*err)
 			return nil
 		}
 	default:
-		p.errors = append(p.errors, fmt.Sprintf("%d:%d expected ok, err, some, or none, got %s", p.currToken.Line, p.currToken.Column, p.currToken.Literal))
-		return nil
+		if err := p.parseEnumMatch(m); err != nil {
+			p.report(*err)
+			return nil
+		}
 	}
 
 	if !p.expectPeek(token.RightCurlyBracket) {
-		p.errors = append(p.errors, fmt.Sprintf("%d:%d expected }, got %s", p.peekToken.Line, p.peekToken.Column, p.peekToken.Literal))
+		p.errorAt(p.peekToken, "expected }, got %s", p.peekToken.Literal)
 		return nil
 	}
 
@@ -1663,41 +2018,85 @@ func (p *Parser) parseTypeMatchExpr() ast.Expr {
 	return m
 }
 
-func (p *Parser) parseMatchArmWithBinding(a *ast.MatchArm, isOk bool, isSome bool) error {
+// parseEnumMatch parses arms naming enum variants, each binding the variant's
+// payload, and an optional _ arm for the variants not named
+func (p *Parser) parseEnumMatch(m *ast.MatchExpr) *errors.PositionError {
+	for {
+		if p.currToken.Literal == "_" {
+			if !p.expectPeek(token.Arrow) {
+				return p.positionError(p.peekToken, "expected ->, got %s", p.peekToken.Literal)
+			}
+			p.nextToken()
+			m.Default = p.parseBlockStmt()
+		} else {
+			arm := &ast.MatchArm{Pattern: &ast.MatchPattern{Variant: &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}}}
+			if p.peekTokenIs(token.LeftParen) {
+				p.nextToken()
+				for !p.peekTokenIs(token.RightParen) {
+					if !p.expectPeek(token.Identifier) {
+						return p.positionError(p.peekToken, "expected identifier, got %s", p.peekToken.Literal)
+					}
+					arm.Pattern.Bindings = append(arm.Pattern.Bindings, &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal})
+					if !p.peekTokenIs(token.RightParen) && !p.expectPeek(token.Comma) {
+						return p.positionError(p.peekToken, "expected , or ), got %s", p.peekToken.Literal)
+					}
+				}
+				p.nextToken()
+			}
+			if !p.expectPeek(token.Arrow) {
+				return p.positionError(p.peekToken, "expected ->, got %s", p.peekToken.Literal)
+			}
+			p.nextToken()
+			arm.Body = p.parseBlockStmt()
+			m.Arms = append(m.Arms, arm)
+		}
+		if !p.expectPeek(token.Comma) {
+			return p.positionError(p.peekToken, "expected , got %s", p.peekToken.Literal)
+		}
+		if p.peekTokenIs(token.RightCurlyBracket) {
+			return nil
+		}
+		if !p.expectPeek(token.Identifier) {
+			return p.positionError(p.peekToken, "expected an enum variant or _, got %s", p.peekToken.Literal)
+		}
+	}
+}
+
+func (p *Parser) parseMatchArmWithBinding(a *ast.MatchArm, isOk bool, isSome bool) *errors.PositionError {
 	pattern := &ast.MatchPattern{IsOk: isOk, IsSome: isSome}
 	if !p.expectPeek(token.LeftParen) {
-		return fmt.Errorf("%d:%d expected (, got %s", p.peekToken.Line, p.peekToken.Column, p.peekToken.Literal)
+		return p.positionError(p.peekToken, "expected (, got %s", p.peekToken.Literal)
 	}
 	if !p.expectPeek(token.Identifier) {
-		return fmt.Errorf("%d:%d expected identifier, got %s", p.peekToken.Line, p.peekToken.Column, p.peekToken.Literal)
+		return p.positionError(p.peekToken, "expected identifier, got %s", p.peekToken.Literal)
 	}
 	binding := p.parseIdentifier()
 	pattern.Binding = binding.(*ast.Identifier)
 	a.Pattern = pattern
 	if !p.expectPeek(token.RightParen) {
-		return fmt.Errorf("%d:%d expected ), got %s", p.peekToken.Line, p.peekToken.Column, p.peekToken.Literal)
+		return p.positionError(p.peekToken, "expected ), got %s", p.peekToken.Literal)
 	}
 	if !p.expectPeek(token.Arrow) {
-		return fmt.Errorf("%d:%d expected ->, got %s", p.peekToken.Line, p.peekToken.Column, p.peekToken.Literal)
+		return p.positionError(p.peekToken, "expected ->, got %s", p.peekToken.Literal)
 	}
 	p.nextToken()
 	a.Body = p.parseBlockStmt()
 	if !p.expectPeek(token.Comma) {
-		return fmt.Errorf("%d:%d expected , got %s", p.peekToken.Line, p.peekToken.Column, p.peekToken.Literal)
+		return p.positionError(p.peekToken, "expected , got %s", p.peekToken.Literal)
 	}
 	return nil
 }
 
-func (p *Parser) parseResultArmPair(okArm *ast.MatchArm, errArm *ast.MatchArm, first string) error {
+func (p *Parser) parseResultArmPair(okArm *ast.MatchArm, errArm *ast.MatchArm, first string) *errors.PositionError {
 	if first == "ok" {
 		if err := p.parseMatchArmWithBinding(okArm, true, false); err != nil {
 			return err
 		}
 		if !p.expectPeek(token.Identifier) {
-			return fmt.Errorf("%d:%d expected Identifer, got %s", p.peekToken.Line, p.peekToken.Column, p.peekToken.Type)
+			return p.positionError(p.peekToken, "expected Identifer, got %s", p.peekToken.Type)
 		}
 		if p.currToken.Literal != "err" {
-			return fmt.Errorf("%d:%d expected err, got %s", p.currToken.Line, p.currToken.Column, p.currToken.Literal)
+			return p.positionError(p.currToken, "expected err, got %s", p.currToken.Literal)
 		}
 		return p.parseMatchArmWithBinding(errArm, false, false)
 	}
@@ -1706,15 +2105,15 @@ func (p *Parser) parseResultArmPair(okArm *ast.MatchArm, errArm *ast.MatchArm, f
 		return err
 	}
 	if !p.expectPeek(token.Identifier) {
-		return fmt.Errorf("%d:%d expected Identifier got %s", p.peekToken.Line, p.peekToken.Column, p.peekToken.Type)
+		return p.positionError(p.peekToken, "expected Identifier got %s", p.peekToken.Type)
 	}
 	if p.currToken.Literal != "ok" {
-		return fmt.Errorf("%d:%d expected ok, got %s", p.currToken.Line, p.currToken.Column, p.currToken.Literal)
+		return p.positionError(p.currToken, "expected ok, got %s", p.currToken.Literal)
 	}
 	return p.parseMatchArmWithBinding(okArm, true, false)
 }
 
-func (p *Parser) parseResultMatch(m *ast.MatchExpr, first string) error {
+func (p *Parser) parseResultMatch(m *ast.MatchExpr, first string) *errors.PositionError {
 	okArm := &ast.MatchArm{}
 	errArm := &ast.MatchArm{}
 	if err := p.parseResultArmPair(okArm, errArm, first); err != nil {
@@ -1725,7 +2124,7 @@ func (p *Parser) parseResultMatch(m *ast.MatchExpr, first string) error {
 	return nil
 }
 
-func (p *Parser) parseOptionMatch(m *ast.MatchExpr, first string) error {
+func (p *Parser) parseOptionMatch(m *ast.MatchExpr, first string) *errors.PositionError {
 	someArm := &ast.MatchArm{}
 	noneArm := &ast.MatchArm{}
 
@@ -1734,10 +2133,10 @@ func (p *Parser) parseOptionMatch(m *ast.MatchExpr, first string) error {
 			return err
 		}
 		if !p.expectPeek(token.Identifier) {
-			return fmt.Errorf("%d:%d expected none, got %s", p.peekToken.Line, p.peekToken.Column, p.peekToken.Literal)
+			return p.positionError(p.peekToken, "expected none, got %s", p.peekToken.Literal)
 		}
 		if p.currToken.Literal != "none" {
-			return fmt.Errorf("%d:%d expected none, got %s", p.currToken.Line, p.currToken.Column, p.currToken.Literal)
+			return p.positionError(p.currToken, "expected none, got %s", p.currToken.Literal)
 		}
 		if err := p.parseMatchArmNone(noneArm); err != nil {
 			return err
@@ -1747,10 +2146,10 @@ func (p *Parser) parseOptionMatch(m *ast.MatchExpr, first string) error {
 			return err
 		}
 		if !p.expectPeek(token.Identifier) {
-			return fmt.Errorf("%d:%d expected some, got %s", p.peekToken.Line, p.peekToken.Column, p.peekToken.Literal)
+			return p.positionError(p.peekToken, "expected some, got %s", p.peekToken.Literal)
 		}
 		if p.currToken.Literal != "some" {
-			return fmt.Errorf("%d:%d expected some, got %s", p.currToken.Line, p.currToken.Column, p.currToken.Literal)
+			return p.positionError(p.currToken, "expected some, got %s", p.currToken.Literal)
 		}
 		if err := p.parseMatchArmWithBinding(someArm, false, true); err != nil {
 			return err
@@ -1762,15 +2161,15 @@ func (p *Parser) parseOptionMatch(m *ast.MatchExpr, first string) error {
 	return nil
 }
 
-func (p *Parser) parseMatchArmNone(a *ast.MatchArm) error {
+func (p *Parser) parseMatchArmNone(a *ast.MatchArm) *errors.PositionError {
 	a.Pattern = &ast.MatchPattern{IsSome: false}
 	if !p.expectPeek(token.Arrow) {
-		return fmt.Errorf("%d:%d expected ->, got %s", p.peekToken.Line, p.peekToken.Column, p.peekToken.Literal)
+		return p.positionError(p.peekToken, "expected ->, got %s", p.peekToken.Literal)
 	}
 	p.nextToken()
 	a.Body = p.parseBlockStmt()
 	if !p.expectPeek(token.Comma) {
-		return fmt.Errorf("%d:%d expected , got %s", p.peekToken.Line, p.peekToken.Column, p.peekToken.Literal)
+		return p.positionError(p.peekToken, "expected , got %s", p.peekToken.Literal)
 	}
 	return nil
 }
@@ -1781,7 +2180,7 @@ func (p *Parser) parseTypeCast() ast.Expr {
 
 func (p *Parser) parseGenericType() *types.TypeParam {
 	if !p.expectPeek(token.Identifier) {
-		p.errors = append(p.errors, fmt.Sprintf("%d:%d expected ident, got %s", p.peekToken.Line, p.peekToken.Column, p.peekToken.Literal))
+		p.errorAt(p.peekToken, "expected ident, got %s", p.peekToken.Literal)
 		return nil
 	}
 	ident := p.parseIdentifier().(*ast.Identifier)
@@ -1809,7 +2208,7 @@ func (p *Parser) parseTypeParamList() []*types.TypeParam {
 	}
 
 	if !p.expectPeek(token.GreaterThan) {
-		p.errors = append(p.errors, fmt.Sprintf("expected >, got %s", p.currToken.Literal))
+		p.errorAt(p.currToken, "expected >, got %s", p.currToken.Literal)
 		return nil
 	}
 
@@ -1829,7 +2228,7 @@ func (p *Parser) parseTypeArgs() []types.Type {
 	}
 
 	if !p.expectPeek(token.GreaterThan) {
-		p.errors = append(p.errors, fmt.Sprintf("expected < after type arg list, got %s", p.peekToken.Literal))
+		p.errorAt(p.peekToken, "expected < after type arg list, got %s", p.peekToken.Literal)
 		return nil
 	}
 
@@ -1858,8 +2257,8 @@ func (p *Parser) parseSliceExpr(left ast.Expr) ast.Expr {
 	return expr
 }
 
-func getTypeParseError(name string, expected token.TokenType, got token.TokenType) string {
-	return fmt.Sprintf("expected %q for %s type annotation, got %q", expected, name, got)
+func (p *Parser) typeParseError(name string, expected token.TokenType) {
+	p.errorAt(p.peekToken, "expected %q for %s type annotation, got %q", expected, name, p.peekToken.Type)
 }
 
 func (p *Parser) parseDeclaredThreePartForStmt(stmt *ast.ForStmt) {
@@ -1867,14 +2266,14 @@ func (p *Parser) parseDeclaredThreePartForStmt(stmt *ast.ForStmt) {
 	p.nextToken()
 	stmt.Condition = p.parseExpression(LOWEST)
 	if !p.expectPeek(token.Semicolon) {
-		p.errors = append(p.errors, fmt.Sprintf("%d:%d expected ; after condition, got %s", p.currToken.Line, p.currToken.Column, p.currToken.Literal))
+		p.errorAt(p.currToken, "expected ; after condition, got %s", p.currToken.Literal)
 		return
 	}
 
 	p.nextToken()
 	stmt.Post = p.parseExpressionOrAssignmentStmt()
 	if !p.expectPeek(token.RightParen) {
-		p.errors = append(p.errors, fmt.Sprintf("%d:%d expected ) after post, got %s", p.currToken.Line, p.currToken.Column, p.currToken.Literal))
+		p.errorAt(p.currToken, "expected ) after post, got %s", p.currToken.Literal)
 		return
 	}
 }
@@ -1893,33 +2292,60 @@ func (p *Parser) parseAssignedThreePartForStmt(expr ast.Expr, stmt *ast.ForStmt)
 	}
 
 	if !p.expectPeek(token.Semicolon) {
-		p.errors = append(p.errors, fmt.Sprintf("%d:%d expected ; after init, got %s", p.currToken.Line, p.currToken.Column, p.currToken.Literal))
+		p.errorAt(p.currToken, "expected ; after init, got %s", p.currToken.Literal)
 		return
 	}
 	p.nextToken()
 	stmt.Condition = p.parseExpression(LOWEST)
 	if !p.expectPeek(token.Semicolon) {
-		p.errors = append(p.errors, fmt.Sprintf("%d:%d expected ; after condition, got %s", p.currToken.Line, p.currToken.Column, p.currToken.Literal))
+		p.errorAt(p.currToken, "expected ; after condition, got %s", p.currToken.Literal)
 		return
 	}
 	p.nextToken()
 	stmt.Post = p.parseExpressionOrAssignmentStmt()
 	if !p.expectPeek(token.RightParen) {
-		p.errors = append(p.errors, fmt.Sprintf("%d:%d expected ) after post, got %s", p.currToken.Line, p.currToken.Column, p.currToken.Literal))
+		p.errorAt(p.currToken, "expected ) after post, got %s", p.currToken.Literal)
 		return
 	}
 }
 
-func (p *Parser) parseForInStmt(tok token.Token) ast.Stmt {
+// parseForInStmt parses the rest of a for-in loop whose first variable or
+// destructuring pattern has been parsed as first
+func (p *Parser) parseForInStmt(tok token.Token, first ast.Expr) ast.Stmt {
 	stmt := &ast.ForInStmt{Token: tok}
 
 	if p.peekTokenIs(token.Comma) {
-		stmt.Key = p.parseIdentifier().(*ast.Identifier)
-		p.nextToken()
+		key, ok := first.(*ast.Identifier)
+		if !ok {
+			p.errorAt(p.currToken, "expected identifier for for-in key, got %s", first.String())
+			return nil
+		}
+		stmt.Key = key
 		p.nextToken()
-		stmt.Value = p.parseIdentifier().(*ast.Identifier)
+		if p.peekTokenIs(token.LeftParen) {
+			p.nextToken()
+			stmt.Names = p.parseIdentifierList()
+			if stmt.Names == nil {
+				return nil
+			}
+		} else {
+			p.nextToken()
+			stmt.Value = p.parseIdentifier().(*ast.Identifier)
+		}
+	} else if tuple, ok := first.(*ast.TupleLiteral); ok {
+		for _, el := range tuple.Elements {
+			name, ok := el.(*ast.Identifier)
+			if !ok {
+				p.errorAt(tuple.Token, "expected identifier in for-in pattern, got %s", el.String())
+				return nil
+			}
+			stmt.Names = append(stmt.Names, name)
+		}
+	} else if value, ok := first.(*ast.Identifier); ok {
+		stmt.Value = value
 	} else {
-		stmt.Value = p.parseIdentifier().(*ast.Identifier)
+		p.errorAt(p.currToken, "expected identifier for for-in value, got %s", first.String())
+		return nil
 	}
 	if !p.expectPeek(token.In) {
 		return nil
@@ -1939,7 +2365,8 @@ func (p *Parser) parseForInStmt(tok token.Token) ast.Stmt {
 
 func (p *Parser) parseSendStmt(ch ast.Expr) ast.Stmt {
 	stmt := &ast.SendStmt{
-		Chan: ch,
+		Token: p.peekToken,
+		Chan:  ch,
 	}
 	p.nextToken() // advance past ident
 	p.nextToken() // advance past <-
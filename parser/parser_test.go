@@ -5,6 +5,7 @@ import (
 	"strconv"
 	"strings"
 	"sydney/ast"
+	"sydney/errors"
 	"sydney/lexer"
 	"sydney/types"
 	"testing"
@@ -1810,6 +1811,19 @@ func TestResultTypeParsing(t *testing.T) {
 	}
 
 	testVarDeclarationStmt(t, stmt, "x", false)
+
+	l = lexer.New("mut result<int, array<string>> y;")
+	p = New(l)
+	program = p.ParseProgram()
+	checkParserErrors(t, p)
+	stmt = program.Stmts[0].(*ast.VarDeclarationStmt)
+	rTyp, ok = stmt.Type.(types.ResultType)
+	if !ok {
+		t.Fatalf("stmt.Type is not types.ResultType. got=%T", stmt.Type)
+	}
+	if rTyp.Err().Signature() != "array<string>" {
+		t.Fatalf("error type wrong. want array<string>, got=%q", rTyp.Err().Signature())
+	}
 }
 
 func TestMatchExpr(t *testing.T) {
@@ -1860,6 +1874,101 @@ func TestMatchExpr(t *testing.T) {
 	testIntegerLiteral(t, errBody.Expr, 0)
 }
 
+func TestEnumDefinition(t *testing.T) {
+	source := `define enum Shape { Circle(float), Rect(float, float), Empty }
+	define enum Tree<T> { Leaf(T), Node(Tree<T>, Tree<T>), }`
+
+	l := lexer.New(source)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	if len(program.Stmts) != 2 {
+		t.Fatalf("program.Body does not contain %d statements. got=%d\n", 2, len(program.Stmts))
+	}
+
+	tests := []struct {
+		name     string
+		variants []string
+		payloads []string
+	}{
+		{"Shape", []string{"Circle", "Rect", "Empty"}, []string{"float", "float, float", ""}},
+		{"Tree", []string{"Leaf", "Node"}, []string{"T", "Tree<T>, Tree<T>"}},
+	}
+
+	for i, tt := range tests {
+		stmt, ok := program.Stmts[i].(*ast.EnumDefinitionStmt)
+		if !ok {
+			t.Fatalf("expected program.Stmts[%d] to be *ast.EnumDefinitionStmt. got=%T", i, program.Stmts[i])
+		}
+		if stmt.Name.Value != tt.name {
+			t.Fatalf("stmt.Name.Value wrong. want %q, got=%q", tt.name, stmt.Name.Value)
+		}
+		if len(stmt.Type.Variants) != len(tt.variants) {
+			t.Fatalf("wrong number of variants. want %d, got=%d", len(tt.variants), len(stmt.Type.Variants))
+		}
+		for j, v := range tt.variants {
+			if stmt.Type.Variants[j] != v || stmt.VariantNames[j].Value != v {
+				t.Fatalf("variant %d wrong. want %q, got=%q", j, v, stmt.Type.Variants[j])
+			}
+			payload := make([]string, 0)
+			for _, pt := range stmt.Type.Payloads[j] {
+				payload = append(payload, pt.Signature())
+			}
+			if strings.Join(payload, ", ") != tt.payloads[j] {
+				t.Fatalf("payload of %s wrong. want %q, got=%q", v, tt.payloads[j], strings.Join(payload, ", "))
+			}
+		}
+	}
+}
+
+func TestEnumMatchExpr(t *testing.T) {
+	source := `match s {
+		Circle(r) -> { r; },
+		Rect(w, _) -> { w; },
+		Empty -> { 0.0; },
+		_ -> { 1.0; },
+	}`
+
+	l := lexer.New(source)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Stmts[0].(*ast.ExpressionStmt)
+	expr, ok := stmt.Expr.(*ast.MatchExpr)
+	if !ok {
+		t.Fatalf("stmt.Expr is not *ast.MatchExpr. got=%T", stmt.Expr)
+	}
+
+	testIdentifier(t, expr.Subject, "s")
+	expected := []struct {
+		variant  string
+		bindings []string
+	}{
+		{"Circle", []string{"r"}},
+		{"Rect", []string{"w", "_"}},
+		{"Empty", nil},
+	}
+	if len(expr.Arms) != len(expected) {
+		t.Fatalf("wrong number of arms. want %d, got=%d", len(expected), len(expr.Arms))
+	}
+	for i, e := range expected {
+		arm := expr.Arms[i]
+		if arm.Pattern.Variant.Value != e.variant {
+			t.Fatalf("arm %d variant wrong. want %q, got=%q", i, e.variant, arm.Pattern.Variant.Value)
+		}
+		if len(arm.Pattern.Bindings) != len(e.bindings) {
+			t.Fatalf("arm %d has %d bindings, want %d", i, len(arm.Pattern.Bindings), len(e.bindings))
+		}
+		for j, b := range e.bindings {
+			testIdentifier(t, arm.Pattern.Bindings[j], b)
+		}
+	}
+	if expr.Default == nil {
+		t.Fatalf("expected a default arm")
+	}
+}
+
 func TestThreePartForLoop(t *testing.T) {
 	source := "for (mut i = 0; i < 10; i = i + 1) { print(i); }"
 
@@ -2412,6 +2521,94 @@ func TestSliceExpr(t *testing.T) {
 	}
 }
 
+func TestErrorRecovery(t *testing.T) {
+	input := `const a = 1 +;
+func f(int x -> int { return x; }
+func g(int y) -> int {
+    const z = ;
+    mut w = y * 2;
+    return w;
+}
+define struct P { x int y int }
+const b = g(2);`
+
+	expectedErrors := []struct {
+		line, col int
+		code      errors.Code
+		message   string
+	}{
+		{1, 14, errors.ExpectedExpression, "no prefix parse function for Semicolon found"},
+		{2, 14, errors.UnexpectedToken, "expected next token to be RightParen, got Arrow instead"},
+		{2, 14, errors.UnexpectedToken, "missing closing parenthesis"},
+		{4, 15, errors.ExpectedExpression, "no prefix parse function for Semicolon found"},
+		{8, 25, errors.UnexpectedToken, "expected , or } got y"},
+	}
+
+	p := New(lexer.New(input))
+	p.SetFile("bad.sy")
+	program := p.ParseProgram()
+
+	errs := p.Errors()
+	if len(errs) != len(expectedErrors) {
+		t.Fatalf("expected %d errors, got %d: %v", len(expectedErrors), len(errs), errs)
+	}
+	for i, tt := range expectedErrors {
+		err := errs[i]
+		if err.Line != tt.line || err.Col != tt.col || err.Message != tt.message || err.File != "bad.sy" {
+			t.Errorf("errors[%d]: expected bad.sy:%d:%d: %s, got %s", i, tt.line, tt.col, tt.message, err.Error())
+		}
+		if err.Code != tt.code {
+			t.Errorf("errors[%d]: expected code %s, got %s", i, tt.code, err.Code)
+		}
+	}
+
+	// the statements without errors are kept
+	if len(program.Stmts) != 2 {
+		t.Fatalf("expected 2 statements, got %d", len(program.Stmts))
+	}
+	fn, ok := program.Stmts[0].(*ast.FunctionDeclarationStmt)
+	if !ok || fn.Name.Value != "g" {
+		t.Fatalf("program.Stmts[0] is not function g, got %s", program.Stmts[0].String())
+	}
+	if len(fn.Body.Stmts) != 2 {
+		t.Errorf("expected g to keep 2 statements, got %d", len(fn.Body.Stmts))
+	}
+	testVarDeclarationStmt(t, program.Stmts[1], "b", true)
+}
+
+func TestMissingSeparatorError(t *testing.T) {
+	tests := []struct {
+		input   string
+		line    int
+		col     int
+		message string
+	}{
+		{"define interface I { a() -> int b() -> int }", 1, 33, "expected , or } got b"},
+		{"define struct P { x int y int }", 1, 25, "expected , or } got y"},
+		{"define enum E { A(int string), B }", 1, 23, "expected , or ) got string"},
+		{"define enum E { A B }", 1, 19, "expected , or } got B"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		errs := p.Errors()
+		if len(errs) == 0 {
+			t.Errorf("%q: expected an error", tt.input)
+			continue
+		}
+		// one error for the missing comma, not one from expectPeek as well
+		if errs[0].Line != tt.line || errs[0].Col != tt.col || errs[0].Message != tt.message {
+			t.Errorf("%q: expected %d:%d: %s, got %s", tt.input, tt.line, tt.col, tt.message, errs[0].Error())
+		}
+		for _, err := range errs[1:] {
+			if err.Line == errs[0].Line && err.Col == errs[0].Col {
+				t.Errorf("%q: duplicate error at %d:%d: %s", tt.input, err.Line, err.Col, err.Message)
+			}
+		}
+	}
+}
+
 // Utilities
 
 func checkParserErrors(t *testing.T, p *Parser) {
@@ -2710,6 +2907,47 @@ func TestSpawnStmtNoArgs(t *testing.T) {
 	}
 }
 
+func TestDeferStmt(t *testing.T) {
+	tests := []struct {
+		source   string
+		function string
+		numArgs  int
+	}{
+		{"defer close(f);", "close", 1},
+		{"defer f.close();", "f.close", 0},
+		{"defer io:close(f)", "io:close", 1},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.source)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Stmts) != 1 {
+			t.Fatalf("program.Stmts has wrong length. want=1, got=%d", len(program.Stmts))
+		}
+
+		stmt, ok := program.Stmts[0].(*ast.DeferStmt)
+		if !ok {
+			t.Fatalf("program.Stmts[0] is not *ast.DeferStmt. got=%T", program.Stmts[0])
+		}
+
+		callExpr, ok := stmt.CallExpr.(*ast.CallExpr)
+		if !ok {
+			t.Fatalf("stmt.CallExpr is not *ast.CallExpr. got=%T", stmt.CallExpr)
+		}
+
+		if callExpr.Function.String() != tt.function {
+			t.Errorf("wrong function. want=%s, got=%s", tt.function, callExpr.Function.String())
+		}
+
+		if len(callExpr.Arguments) != tt.numArgs {
+			t.Errorf("wrong number of arguments. want=%d, got=%d", tt.numArgs, len(callExpr.Arguments))
+		}
+	}
+}
+
 func TestSendStmt(t *testing.T) {
 	source := "ch <- 5;"
 	l := lexer.New(source)
@@ -3539,3 +3777,100 @@ func TestTypeMatchExpr(t *testing.T) {
 	}
 	testIntegerLiteral(t, defaultBody.Expr, 0)
 }
+
+func TestTuples(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`const t = (1, "two", 3.0);`, `const t = (1, two, 3.0);`},
+		{`const (q, r) = divmod(7, 2);`, `const (q, r) = divmod(7, 2);`},
+		{`mut (a, _) = (1, 2);`, `mut (a, _) = (1, 2);`},
+		{`const x = (1 + 2) * 3;`, `const x = ((1 + 2) * 3);`},
+		{`for ((k, v) in pairs) { k; }`, "for (k, v) in pairs {\nk\n}"},
+		{`for (i, (k, v) in pairs) { k; }`, "for i, (k, v) in pairs {\nk\n}"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected %q, got %q", tt.expected, program.String())
+		}
+	}
+}
+
+func TestTupleTypes(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`mut (int, string) pair;`, "(int, string)"},
+		{`const (int, array<float>) pair = (1, [2.0]);`, "(int, array<float>)"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt, ok := program.Stmts[0].(*ast.VarDeclarationStmt)
+		if !ok {
+			t.Fatalf("expected *ast.VarDeclarationStmt, got=%T", program.Stmts[0])
+		}
+		if stmt.Type.Signature() != tt.expected {
+			t.Errorf("expected type %q, got %q", tt.expected, stmt.Type.Signature())
+		}
+	}
+
+	l := lexer.New(`func divmod(int a, int b) -> (int, int) { return (a / b, a % b); }`)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	fn := program.Stmts[0].(*ast.FunctionDeclarationStmt)
+	ret := fn.Type.(types.FunctionType).Return
+	if ret.Signature() != "(int, int)" {
+		t.Errorf("expected return type (int, int), got %q", ret.Signature())
+	}
+}
+
+func TestTryExpr(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`const f = open(path)?;`, `const f = open(path)?;`},
+		{`parse(s)? + 1;`, `(parse(s)? + 1)`},
+		{`-parse(s)?;`, `(-parse(s)?)`},
+		{`lookup(m)?.name;`, `lookup(m)?.name`},
+		{`xs[0]??;`, `(xs[0])??`},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected %q, got %q", tt.expected, program.String())
+		}
+	}
+
+	l := lexer.New(`read(fd)?;`)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	stmt := program.Stmts[0].(*ast.ExpressionStmt)
+	expr, ok := stmt.Expr.(*ast.TryExpr)
+	if !ok {
+		t.Fatalf("stmt.Expr is not *ast.TryExpr. got=%T", stmt.Expr)
+	}
+	if _, ok := expr.Value.(*ast.CallExpr); !ok {
+		t.Fatalf("expr.Value is not *ast.CallExpr. got=%T", expr.Value)
+	}
+}
@@ -37,8 +37,8 @@ func StartEval(in io.Reader, out io.Writer) {
 		c := typechecker.New(env)
 		c.Check(program, nil)
 
-		if len(c.Errors()) != 0 {
-			errors.PrintPositionErrors(out, c.Errors())
+		renderErrors(out, line, c.Errors())
+		if errors.HasErrors(c.Errors()) {
 			continue
 		}
 
@@ -46,7 +46,7 @@ func StartEval(in io.Reader, out io.Writer) {
 		expanded := evaluator.ExpandMacros(program, macroScope)
 
 		if len(p.Errors()) != 0 {
-			printErrors(out, p.Errors())
+			renderErrors(out, line, p.Errors())
 			continue
 		}
 
@@ -80,15 +80,15 @@ func StartVM(in io.Reader, out io.Writer) {
 		p := parser.New(l)
 		program := p.ParseProgram()
 		if len(p.Errors()) != 0 {
-			printErrors(out, p.Errors())
+			renderErrors(out, line, p.Errors())
 			continue
 		}
 
 		c := typechecker.New(typeEnv)
 		errs := c.Check(program, nil)
 
-		if len(errs) != 0 {
-			errors.PrintPositionErrors(out, errs)
+		renderErrors(out, line, errs)
+		if errors.HasErrors(errs) {
 			continue
 		}
 
@@ -103,6 +103,9 @@ func StartVM(in io.Reader, out io.Writer) {
 		err = machine.Run()
 		if err != nil {
 			fmt.Fprintf(out, "Honk! runtime error:\n %s\n", err)
+			if rerr, ok := err.(*vm.RuntimeError); ok {
+				io.WriteString(out, rerr.StackTrace())
+			}
 			continue
 		}
 
@@ -112,8 +115,10 @@ func StartVM(in io.Reader, out io.Writer) {
 	}
 }
 
-func printErrors(out io.Writer, errors []string) {
-	for _, msg := range errors {
-		io.WriteString(out, "\t"+msg+"\n")
-	}
+// renderErrors shows errors against the line they were found in, which is
+// not a file the renderer could read
+func renderErrors(out io.Writer, line string, errs []errors.PositionError) {
+	r := errors.NewRenderer(out, errors.Text, false)
+	r.AddSource("", line)
+	r.Render(errs)
 }
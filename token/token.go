@@ -25,6 +25,7 @@ const (
 	Define    TokenType = "Define"
 	Struct    TokenType = "Struct"
 	Interface TokenType = "Interface"
+	Enum      TokenType = "Enum"
 	Import    TokenType = "Import"
 	Module    TokenType = "Module"
 	Public    TokenType = "Public"
@@ -34,6 +35,7 @@ const (
 	Break     TokenType = "Break"
 	In        TokenType = "In"
 	Spawn     TokenType = "Spawn"
+	Defer     TokenType = "Defer"
 	TypeOf    TokenType = "TypeOf"
 
 	// Types
@@ -73,6 +75,7 @@ const (
 	GreaterThan TokenType = "GreaterThan"
 	LessThan    TokenType = "LessThan"
 	Bang        TokenType = "Bang"
+	Question    TokenType = "Question"
 
 	// Multi char symbols
 	EqualTo            TokenType = "Equality"
@@ -0,0 +1,212 @@
+package typechecker
+
+import (
+	"sort"
+	"strings"
+
+	"sydney/ast"
+	"sydney/types"
+)
+
+// Declaration is where a name was introduced. File is the source path of the
+// declaring program, or "" when it was declared in a program without one
+type Declaration struct {
+	Name *ast.Identifier
+	File string
+	Kind DeclarationKind
+}
+
+// DeclarationKind says what sort of thing a declaration names
+type DeclarationKind int
+
+const (
+	VariableDeclaration DeclarationKind = iota
+	ConstantDeclaration
+	ParameterDeclaration
+	FunctionDeclaration
+	MethodDeclaration // a function taking a struct or interface first
+	StructDeclaration
+	InterfaceDeclaration
+	FieldDeclaration
+	EnumDeclaration
+	VariantDeclaration
+)
+
+// Reference is a place an identifier was resolved to a declaration
+type Reference struct {
+	File      string
+	Line, Col int
+}
+
+type position struct {
+	file      string
+	line, col int
+}
+
+func (d Declaration) position() position {
+	line, col := d.Name.Pos()
+	return position{d.File, line, col}
+}
+
+// Same reports whether two declarations are the same source name, which also
+// holds for declarations found by separate checks of the same file
+func (d Declaration) Same(other Declaration) bool {
+	return d.position() == other.position()
+}
+
+func (e *TypeEnv) declare(name string, decl Declaration) {
+	e.decls[name] = decl
+}
+
+// Declaration looks up where name was declared, walking enclosing scopes
+func (e *TypeEnv) Declaration(name string) (Declaration, bool) {
+	decl, ok := e.decls[name]
+	if !ok && e.outer != nil {
+		return e.outer.Declaration(name)
+	}
+	return decl, ok
+}
+
+// importQualified copies the "Struct.method" style names of another env,
+// which are reachable without a module prefix
+func (e *TypeEnv) importQualified(from *TypeEnv) {
+	for name, typ := range from.store {
+		if strings.Contains(name, ".") {
+			e.Set(name, typ)
+		}
+	}
+	for name, decl := range from.decls {
+		if strings.Contains(name, ".") {
+			e.declare(name, decl)
+		}
+	}
+}
+
+// SetFiles tells the checker which source file each top level statement came
+// from, so declarations in merged programs point back at the right file
+func (c *Checker) SetFiles(programs []*ast.Program) {
+	c.stmtFiles = fileOf(programs)
+}
+
+// Definition returns the declaration the identifier at line:col of file
+// resolved to during checking, or the declaration itself when the identifier
+// is the name being declared
+func (c *Checker) Definition(file string, line, col int) (Declaration, bool) {
+	pos := position{file, line, col}
+	if decl, ok := c.uses[pos]; ok {
+		return decl, true
+	}
+	decl, ok := c.declared[pos]
+	return decl, ok
+}
+
+// References returns every identifier that resolved to decl
+func (c *Checker) References(decl Declaration) []Reference {
+	var refs []Reference
+	for pos, d := range c.uses {
+		if d.Same(decl) {
+			refs = append(refs, Reference{File: pos.file, Line: pos.line, Col: pos.col})
+		}
+	}
+	sort.Slice(refs, func(i, j int) bool {
+		a, b := refs[i], refs[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Col < b.Col
+	})
+	return refs
+}
+
+// Implementations links an interface method to the struct methods that
+// implement it and back, since renaming one has to rename the others
+func (c *Checker) Implementations(decl Declaration) []Declaration {
+	return c.implementations[decl.position()]
+}
+
+func (c *Checker) shareDeclarations(pkgChecker *Checker) {
+	pkgChecker.uses = c.uses
+	pkgChecker.declared = c.declared
+	pkgChecker.implementations = c.implementations
+	pkgChecker.inferences = c.inferences
+	pkgChecker.typeArguments = c.typeArguments
+}
+
+func (c *Checker) linkImplementation(structName string, it types.InterfaceType) {
+	for _, method := range it.Methods {
+		impl, ok := c.env.Declaration(mangleMethod(structName, method))
+		if !ok {
+			continue
+		}
+		iface, ok := c.env.Declaration(mangleMethod(it.Name, method))
+		if !ok {
+			continue
+		}
+		c.implementations[impl.position()] = append(c.implementations[impl.position()], iface)
+		c.implementations[iface.position()] = append(c.implementations[iface.position()], impl)
+	}
+}
+
+func (c *Checker) declare(env *TypeEnv, name string, ident *ast.Identifier, kind DeclarationKind) {
+	if ident == nil {
+		return
+	}
+	decl := Declaration{Name: ident, File: c.currentFile, Kind: kind}
+	env.declare(name, decl)
+	c.declared[decl.position()] = decl
+}
+
+func variableKind(constant bool) DeclarationKind {
+	if constant {
+		return ConstantDeclaration
+	}
+	return VariableDeclaration
+}
+
+// functionKind tells methods, which are declared under their receiver's
+// name, from plain functions
+func functionKind(name string, node *ast.FunctionDeclarationStmt) DeclarationKind {
+	if name != node.Name.Value {
+		return MethodDeclaration
+	}
+	return FunctionDeclaration
+}
+
+// use records that the name written at node refers to name's declaration
+func (c *Checker) use(env *TypeEnv, node ast.Node, name string) {
+	if env == nil {
+		return
+	}
+	if decl, ok := env.Declaration(name); ok {
+		line, col := node.Pos()
+		c.uses[position{c.currentFile, line, col}] = decl
+	}
+}
+
+// fileOf records the source file of every statement of a package's programs
+func fileOf(programs []*ast.Program) map[ast.Stmt]string {
+	files := make(map[ast.Stmt]string)
+	for _, program := range programs {
+		for _, stmt := range program.Stmts {
+			files[stmt] = program.File
+		}
+	}
+	return files
+}
+
+// exportDeclarations copies the declarations of a package's exported names,
+// and the fields and methods of its exported types, into its export env
+func exportDeclarations(pkgChecker *Checker, exportEnv *TypeEnv) {
+	for name, decl := range pkgChecker.env.decls {
+		owner, _, _ := strings.Cut(name, ".")
+		_, exported := exportEnv.store[owner]
+		_, genericFn := pkgChecker.genericFunctions[owner]
+		_, genericStruct := pkgChecker.genericStructs[owner]
+		if exported || genericFn || genericStruct {
+			exportEnv.declare(name, decl)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package typechecker
+
+import (
+	"fmt"
+	"sydney/ast"
+	"sydney/errors"
+	"sydney/types"
+)
+
+// checkDeferStmt types defer f(x), which calls f(x) when the enclosing
+// function returns. As in Go, the arguments are evaluated at the defer
+// statement: they are bound to hidden locals there, and the backends run the
+// call on those through a closure built here
+func (c *Checker) checkDeferStmt(node *ast.DeferStmt) types.Type {
+	if c.currentReturnType == nil {
+		c.appendError(errors.MisplacedControlFlow, "defer statement outside of function body", node)
+	}
+
+	callExpr, ok := node.CallExpr.(*ast.CallExpr)
+	if !ok {
+		e := c.newError(errors.NotCallable, "must defer function call", node)
+		e.Help = "wrap the statements in a function literal and defer a call to it"
+		c.report(e)
+		return types.Unit
+	}
+
+	discard, inDefer, deferredTypes := c.inDiscardPosition, c.inDefer, c.deferredTypes
+	c.inDiscardPosition, c.inDefer, c.deferredTypes = true, true, map[ast.Expr]types.Type{}
+	c.typeOf(callExpr, nil)
+	typed := c.deferredTypes
+	c.inDiscardPosition, c.inDefer, c.deferredTypes = discard, inDefer, deferredTypes
+
+	node.Bindings = nil
+	call := *callExpr
+	call.Arguments = make([]ast.Expr, len(callExpr.Arguments))
+	for i, arg := range callExpr.Arguments {
+		call.Arguments[i] = c.bindDeferred(node, arg, typed)
+	}
+	switch fn := callExpr.Function.(type) {
+	case *ast.Identifier, *ast.ScopeAccessExpr, *ast.FunctionLiteral:
+		// nothing to evaluate
+	case *ast.SelectorExpr:
+		selector := *fn
+		if len(callExpr.Arguments) > 0 && callExpr.Arguments[0] == fn.Left {
+			// a method, whose receiver was bound with the arguments
+			selector.Left = call.Arguments[0]
+		} else if _, ok := toInterface(typed[fn.Left]); ok {
+			selector.Left = c.bindDeferred(node, fn.Left, typed)
+		} else {
+			call.Function = c.bindDeferred(node, fn, typed)
+			break
+		}
+		call.Function = &selector
+	default:
+		call.Function = c.bindDeferred(node, fn, typed)
+	}
+
+	fnType := types.FunctionType{Params: []types.Type{}, Return: types.Unit}
+	node.Closure = &ast.FunctionLiteral{
+		Token: node.Token,
+		Body: &ast.BlockStmt{
+			Token: node.Token,
+			Stmts: []ast.Stmt{&ast.ExpressionStmt{Token: node.Token, Expr: &call}},
+		},
+		Type: fnType,
+	}
+	node.Closure.SetResolvedType(fnType)
+
+	return types.Unit
+}
+
+// bindDeferred binds expr, part of a deferred call, to a hidden local of the
+// defer statement and returns the local's name to use in its place
+func (c *Checker) bindDeferred(node *ast.DeferStmt, expr ast.Expr, typed map[ast.Expr]types.Type) ast.Expr {
+	switch expr.(type) {
+	case *ast.IntegerLiteral, *ast.FloatLiteral, *ast.StringLiteral, *ast.BooleanLiteral, *ast.ByteLiteral, *ast.NullLiteral:
+		return expr // the same whenever it is evaluated
+	}
+
+	t := typed[expr]
+	if it := expr.GetCastTo(); it != nil {
+		t = *it
+	}
+	if t == nil {
+		return expr
+	}
+
+	name := &ast.Identifier{Token: node.Token, Value: fmt.Sprintf("__defer_%d__", c.deferredLocals)}
+	name.SetResolvedType(t)
+	c.deferredLocals++
+	node.Bindings = append(node.Bindings, &ast.VarDeclarationStmt{
+		Token:    node.Token,
+		Name:     name,
+		Value:    expr,
+		Constant: true,
+		Type:     t,
+	})
+	return name
+}
@@ -0,0 +1,117 @@
+package typechecker
+
+import (
+	"fmt"
+	"strings"
+
+	"sydney/ast"
+	"sydney/errors"
+)
+
+// newError is an error at node in the file being checked. Statements added
+// by derives have no file, so their errors fall back to the module name
+func (c *Checker) newError(code errors.Code, msg string, node ast.Node) errors.PositionError {
+	e := errors.PositionError{Code: code, Message: msg, File: c.currentFile}
+	if e.File == "" {
+		e.File = c.currentModule
+	}
+	if node != nil {
+		e.Line, e.Col = node.Pos()
+	}
+	if ident, ok := node.(*ast.Identifier); ok && ident != nil {
+		e.Length = len(ident.Value)
+	}
+	return e
+}
+
+func (c *Checker) report(e errors.PositionError) {
+	c.errors = append(c.errors, e)
+}
+
+func (c *Checker) appendError(code errors.Code, msg string, node ast.Node) {
+	c.report(c.newError(code, msg, node))
+}
+
+// warnUnreachable warns about next when stmt always leaves the block before
+// it, reporting whether it did
+func (c *Checker) warnUnreachable(stmt, next ast.Stmt) bool {
+	var keyword string
+	switch stmt.(type) {
+	case *ast.ReturnStmt:
+		keyword = "return"
+	case *ast.BreakStmt:
+		keyword = "break"
+	case *ast.ContinueStmt:
+		keyword = "continue"
+	default:
+		return false
+	}
+
+	e := c.newError(errors.UnreachableCode, "unreachable code", next)
+	e.Severity = errors.Warning
+	line, col := stmt.Pos()
+	e.Labels = append(e.Labels, errors.Label{File: e.File, Line: line, Col: col, Length: len(keyword), Message: fmt.Sprintf("any code after this %s never runs", keyword)})
+	c.report(e)
+	return true
+}
+
+// declaredHere labels where name was declared, unless that is at node itself
+func (c *Checker) declaredHere(e *errors.PositionError, name string, node ast.Node, msg string) {
+	decl, ok := c.env.Declaration(name)
+	if !ok || decl.Name == nil {
+		return
+	}
+	line, col := decl.Name.Pos()
+	if node != nil {
+		if nodeLine, nodeCol := node.Pos(); nodeLine == line && nodeCol == col && decl.File == c.currentFile {
+			return
+		}
+	}
+	file := decl.File
+	if file == "" {
+		file = e.File
+	}
+	e.Labels = append(e.Labels, errors.Label{File: file, Line: line, Col: col, Length: len(decl.Name.Value), Message: msg})
+}
+
+// suggest adds a hint naming the visible name closest to an unknown one
+func (c *Checker) suggest(e *errors.PositionError, unknown string) {
+	best, bestDistance := "", (len(unknown)+2)/3+1
+	seen := map[string]bool{}
+	for env := c.env; env != nil; env = env.outer {
+		for name := range env.store {
+			if seen[name] || strings.ContainsAny(name, ".:") || name == unknown {
+				continue
+			}
+			seen[name] = true
+			d := editDistance(unknown, name)
+			if d < bestDistance || (d == bestDistance && best != "" && name < best) {
+				best, bestDistance = name, d
+			}
+		}
+	}
+	if best != "" {
+		e.Help = fmt.Sprintf("did you mean `%s`?", best)
+	}
+}
+
+// editDistance is the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,242 @@
+package typechecker
+
+import (
+	"fmt"
+	"strings"
+
+	"sydney/ast"
+	"sydney/errors"
+	"sydney/types"
+)
+
+func (c *Checker) hoistEnum(node *ast.EnumDefinitionStmt) {
+	c.declare(c.env, node.Name.Value, node.Name, EnumDeclaration)
+	for _, variant := range node.VariantNames {
+		c.declare(c.env, node.Name.Value+"."+variant.Value, variant, VariantDeclaration)
+	}
+	for _, payload := range node.Type.Payloads {
+		for i, t := range payload {
+			payload[i] = c.resolveType(t)
+		}
+	}
+	c.definedEnums[node.Name.Value] = node.Type
+}
+
+// enumDefinition is the declared form of t with its type arguments applied.
+// Types written in an enum's own payloads only name it, as they are parsed
+// before its variants are
+func (c *Checker) enumDefinition(t types.EnumType) types.EnumType {
+	def, ok := c.definedEnums[t.Name]
+	if t.Module != "" && t.Module != c.currentModule {
+		def, ok = c.moduleTypes[t.Module][t.Name].(types.EnumType)
+		def.Module = t.Module
+	}
+	if !ok {
+		return t
+	}
+	if len(t.TypeArgs) > 0 && len(t.TypeArgs) == len(def.TypeParams) {
+		subs := make(map[string]types.Type)
+		for i, tp := range def.TypeParams {
+			subs[tp.Name] = t.TypeArgs[i]
+		}
+		def = types.SubstituteTypeParams(def, subs).(types.EnumType)
+		def.TypeArgs = t.TypeArgs
+		def.TypeParams = nil
+	}
+	return def
+}
+
+// enumConstructor finds the enum a selector such as Shape.Circle or
+// shapes:Shape.Circle names a variant of. Variables shadow enums of the same
+// name
+func (c *Checker) enumConstructor(selector *ast.SelectorExpr) (types.EnumType, bool) {
+	switch left := selector.Left.(type) {
+	case *ast.Identifier:
+		if _, _, isVar := c.env.Get(left.Value); isVar {
+			return types.EnumType{}, false
+		}
+		enum, ok := c.definedEnums[left.Value]
+		if ok {
+			c.use(c.env, left, left.Value)
+		}
+		return enum, ok
+	case *ast.ScopeAccessExpr:
+		enum, ok := c.moduleTypes[left.Module.Value][left.Member.Value].(types.EnumType)
+		if ok {
+			enum.Module = left.Module.Value
+			c.use(c.packages[left.Module.Value], left.Member, left.Member.Value)
+		}
+		return enum, ok
+	}
+	return types.EnumType{}, false
+}
+
+// checkEnumConstructor checks the payload given to a variant. The type
+// arguments of a generic enum come from the expected type when it is the
+// same enum, and are inferred from the payload otherwise
+func (c *Checker) checkEnumConstructor(enum types.EnumType, selector *ast.SelectorExpr, args []ast.Expr, expected types.Type) types.Type {
+	variant, ok := selector.Value.(*ast.Identifier)
+	if !ok {
+		return types.Unit
+	}
+	idx := enum.VariantIndex(variant.Value)
+	if idx == -1 {
+		e := c.newError(errors.UnresolvedName, fmt.Sprintf("enum %s has no variant %s", enum.Name, variant.Value), variant)
+		suggestAmong(&e, variant.Value, enum.Variants)
+		c.report(e)
+		return enum
+	}
+	if left, ok := selector.Left.(*ast.ScopeAccessExpr); ok {
+		c.use(c.packages[left.Module.Value], variant, enum.Name+"."+variant.Value)
+	} else {
+		c.use(c.env, variant, enum.Name+"."+variant.Value)
+	}
+
+	payload := enum.Payloads[idx]
+	if len(args) != len(payload) {
+		c.appendError(errors.WrongArgumentCount, fmt.Sprintf("variant %s.%s takes %d values, got %d", enum.Name, variant.Value, len(payload), len(args)), variant)
+		return enum
+	}
+
+	subs := make(map[string]types.Type)
+	if et, ok := expected.(types.EnumType); ok && et.Name == enum.Name && len(et.TypeArgs) == len(enum.TypeParams) {
+		for i, tp := range enum.TypeParams {
+			subs[tp.Name] = et.TypeArgs[i]
+		}
+	}
+	for i, arg := range args {
+		want := types.SubstituteTypeParams(payload[i], subs)
+		if containsTypeParamRef(want) {
+			want = nil
+		}
+		argType := c.typeOf(arg, want)
+		if argType == nil {
+			continue
+		}
+		c.unifyType(payload[i], argType, subs)
+		want = types.SubstituteTypeParams(payload[i], subs)
+		if !c.typesMatch(argType, want) {
+			c.appendError(errors.MismatchedTypes, fmt.Sprintf("type mismatch for value %d of %s.%s: expected %s, got %s", i+1, enum.Name, variant.Value, want.Signature(), argType.Signature()), arg)
+			continue
+		}
+		c.boxIfNecessary(arg, argType, want)
+	}
+
+	result := enum
+	if len(enum.TypeParams) > 0 {
+		typeArgs := make([]types.Type, len(enum.TypeParams))
+		for i, tp := range enum.TypeParams {
+			sub, ok := subs[tp.Name]
+			if !ok {
+				e := c.newError(errors.CannotInfer, fmt.Sprintf("cannot infer type parameter %s for %s.%s", tp.Name, enum.Name, variant.Value), variant)
+				e.Help = fmt.Sprintf("annotate the type, as in `const %s<...> x = %s.%s`", enum.Name, enum.Name, variant.Value)
+				c.report(e)
+				return enum
+			}
+			typeArgs[i] = sub
+		}
+		result = types.SubstituteTypeParams(enum, subs).(types.EnumType)
+		result.TypeArgs = typeArgs
+		result.TypeParams = nil
+	}
+
+	selector.ContainerType = result
+	selector.ResolvedType = result
+	return result
+}
+
+// checkEnumMatch checks that each arm names a variant of the subject once and
+// binds all of its values, and that every variant is handled
+func (c *Checker) checkEnumMatch(expr *ast.MatchExpr, subject types.EnumType) types.Type {
+	enum := c.enumDefinition(subject)
+	expr.SubjectType = enum
+
+	discard := c.inDiscardPosition
+	c.inDiscardPosition = false
+	defer func() { c.inDiscardPosition = discard }()
+
+	var resultType types.Type
+	merge := func(armType types.Type) {
+		if armType == nil || armType == types.Never {
+			return
+		}
+		if resultType == nil {
+			resultType = armType
+			return
+		}
+		if !discard && !c.typesMatch(armType, resultType) {
+			c.appendError(errors.MismatchedTypes, fmt.Sprintf("type mismatch: match arms must result in same type, got %s and %s", resultType.Signature(), armType.Signature()), expr)
+		}
+	}
+
+	matched := make(map[string]bool)
+	for _, arm := range expr.Arms {
+		variant := arm.Pattern.Variant
+		idx := enum.VariantIndex(variant.Value)
+		if idx == -1 {
+			e := c.newError(errors.InvalidMatch, fmt.Sprintf("enum %s has no variant %s", enum.Name, variant.Value), variant)
+			suggestAmong(&e, variant.Value, enum.Variants)
+			c.report(e)
+			continue
+		}
+		if matched[variant.Value] {
+			c.appendError(errors.InvalidMatch, fmt.Sprintf("variant %s is matched more than once", variant.Value), variant)
+			continue
+		}
+		matched[variant.Value] = true
+		c.use(c.env, variant, enum.Name+"."+variant.Value)
+
+		payload := enum.Payloads[idx]
+		if len(arm.Pattern.Bindings) != len(payload) {
+			c.appendError(errors.InvalidMatch, fmt.Sprintf("variant %s.%s has %d values, but the arm binds %d", enum.Name, variant.Value, len(payload), len(arm.Pattern.Bindings)), variant)
+			continue
+		}
+
+		c.pushScope()
+		for i, binding := range arm.Pattern.Bindings {
+			if binding.Value == "_" {
+				continue
+			}
+			c.env.Set(binding.Value, payload[i])
+			c.declare(c.env, binding.Value, binding, VariableDeclaration)
+		}
+		merge(c.check(arm.Body))
+		c.popScope()
+	}
+
+	if expr.Default != nil {
+		c.pushScope()
+		merge(c.check(expr.Default))
+		c.popScope()
+	} else {
+		var missing []string
+		for _, v := range enum.Variants {
+			if !matched[v] {
+				missing = append(missing, v)
+			}
+		}
+		if len(missing) > 0 {
+			e := c.newError(errors.NonExhaustiveMatch, fmt.Sprintf("match on %s is not exhaustive: missing %s", enum.Signature(), strings.Join(missing, ", ")), expr)
+			e.Help = "add an arm for each missing variant, or a `_` arm"
+			c.report(e)
+		}
+	}
+
+	if resultType == nil {
+		return types.Never
+	}
+	return resultType
+}
+
+// suggestAmong adds a hint naming the candidate closest to an unknown name
+func suggestAmong(e *errors.PositionError, unknown string, candidates []string) {
+	best, bestDistance := "", (len(unknown)+2)/3+1
+	for _, name := range candidates {
+		if d := editDistance(unknown, name); d < bestDistance {
+			best, bestDistance = name, d
+		}
+	}
+	if best != "" {
+		e.Help = fmt.Sprintf("did you mean `%s`?", best)
+	}
+}
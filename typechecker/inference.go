@@ -0,0 +1,78 @@
+package typechecker
+
+import (
+	"sort"
+
+	"sydney/ast"
+	"sydney/types"
+)
+
+// Inference is a type the checker worked out that the source leaves
+// unwritten: the type of a variable declared without one, or the type
+// arguments of a generic call that omits them. Line and Col are those of the
+// variable or function name
+type Inference struct {
+	File      string
+	Line, Col int
+	Name      string
+	Type      types.Type   // the variable's type
+	TypeArgs  []types.Type // the call's type arguments
+}
+
+// Inferences returns what was inferred in file, in source order
+func (c *Checker) Inferences(file string) []Inference {
+	var result []Inference
+	for pos, inf := range c.inferences {
+		if pos.file == file {
+			result = append(result, inf)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Line != result[j].Line {
+			return result[i].Line < result[j].Line
+		}
+		return result[i].Col < result[j].Col
+	})
+	return result
+}
+
+// TypeArguments returns the type arguments of the generic call whose
+// function name is at line:col of file, whether written or inferred
+func (c *Checker) TypeArguments(file string, line, col int) ([]types.Type, bool) {
+	typeArgs, ok := c.typeArguments[position{file, line, col}]
+	return typeArgs, ok
+}
+
+func (c *Checker) instantiate(name *ast.Identifier, typeArgs []types.Type) {
+	if c.instantiating > 0 {
+		return
+	}
+	line, col := name.Pos()
+	c.typeArguments[position{c.currentFile, line, col}] = typeArgs
+}
+
+func (c *Checker) inferVariable(name *ast.Identifier, t types.Type) {
+	if t == nil || t == types.Unit {
+		return
+	}
+	c.infer(name, Inference{Type: t})
+}
+
+func (c *Checker) inferTypeArguments(name *ast.Identifier, typeArgs []types.Type) {
+	c.infer(name, Inference{TypeArgs: typeArgs})
+}
+
+// infer records an inference unless the checker is looking at a copy of a
+// generic function, where the same source position is checked once per
+// instantiation
+func (c *Checker) infer(name *ast.Identifier, inf Inference) {
+	if c.instantiating > 0 || name == nil {
+		return
+	}
+	line, col := name.Pos()
+	if line <= 0 {
+		return
+	}
+	inf.File, inf.Line, inf.Col, inf.Name = c.currentFile, line, col, name.Value
+	c.inferences[position{c.currentFile, line, col}] = inf
+}
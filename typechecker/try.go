@@ -0,0 +1,80 @@
+package typechecker
+
+import (
+	"fmt"
+
+	"sydney/ast"
+	"sydney/errors"
+	"sydney/types"
+)
+
+// checkTryExpr types value?, which is the T of a result<T, E> or option<T>.
+// The err or none is returned as is, so the enclosing function must return
+// the same kind of wrapper, with the same E for a result
+func (c *Checker) checkTryExpr(expr *ast.TryExpr) types.Type {
+	valType := c.typeOf(expr.Value, nil)
+	if valType == nil {
+		c.appendError(errors.CannotInfer, fmt.Sprintf("cannot resolve type for %s", expr.Value.String()), expr.Value)
+		return types.Unit
+	}
+
+	var inner types.Type
+	switch t := valType.(type) {
+	case types.ResultType:
+		inner = t.T
+	case types.OptionType:
+		inner = t.T
+	default:
+		e := c.newError(errors.InvalidOperation, fmt.Sprintf("invalid operation: ? is not defined for type %s", valType.Signature()), expr)
+		e.Help = "? unwraps a result or an option"
+		c.report(e)
+		return types.Unit
+	}
+
+	if c.currentReturnType == nil {
+		c.appendError(errors.MisplacedControlFlow, "? operator outside of function body", expr)
+		expr.SetResolvedType(inner)
+		return inner
+	}
+	if c.inDefer {
+		e := c.newError(errors.MisplacedControlFlow, "? operator in deferred call", expr)
+		e.Help = "the deferred call runs after the function has returned, so match on the value instead"
+		c.report(e)
+		expr.SetResolvedType(inner)
+		return inner
+	}
+
+	compatible := false
+	switch valType.(type) {
+	case types.ResultType:
+		_, compatible = c.currentReturnType.(types.ResultType)
+	case types.OptionType:
+		_, compatible = c.currentReturnType.(types.OptionType)
+	}
+	if !compatible {
+		kind := "a result"
+		if _, ok := valType.(types.OptionType); ok {
+			kind = "an option"
+		}
+		e := c.newError(errors.MismatchedTypes, fmt.Sprintf("type mismatch: ? on %s cannot return from function returning %s", valType.Signature(), c.currentReturnType.Signature()), expr)
+		e.Help = fmt.Sprintf("? can only be used in a function that returns %s, or match on the value instead", kind)
+		c.report(e)
+		expr.SetResolvedType(inner)
+		return inner
+	}
+
+	// the err is returned as is, so it must already be the error type the
+	// function returns
+	if result, ok := valType.(types.ResultType); ok {
+		ret := c.currentReturnType.(types.ResultType)
+		if c.resolveType(result.Err()).Signature() != c.resolveType(ret.Err()).Signature() {
+			e := c.newError(errors.MismatchedTypes, fmt.Sprintf("type mismatch: ? on %s cannot return %s errors from function returning %s", valType.Signature(), result.Err().Signature(), ret.Signature()), expr)
+			e.Help = "match on the value to convert the error instead"
+			c.report(e)
+		}
+	}
+
+	expr.ReturnType = c.currentReturnType
+	expr.SetResolvedType(inner)
+	return inner
+}
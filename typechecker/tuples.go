@@ -0,0 +1,114 @@
+package typechecker
+
+import (
+	"fmt"
+
+	"sydney/ast"
+	"sydney/errors"
+	"sydney/types"
+)
+
+// checkTupleLiteral types each element against the matching element of the
+// expected tuple, if there is one, so that (circle, 1) can be a (Shape, int)
+func (c *Checker) checkTupleLiteral(expr *ast.TupleLiteral, expectedType types.Type) types.Type {
+	expected, ok := expectedType.(types.TupleType)
+	if ok && len(expected.Types) != len(expr.Elements) {
+		ok = false
+	}
+
+	elems := make([]types.Type, len(expr.Elements))
+	for i, element := range expr.Elements {
+		var want types.Type
+		if ok {
+			want = expected.Types[i]
+		}
+		t := c.typeOf(element, want)
+		if t == nil || t == types.Unit {
+			c.appendError(errors.CannotInfer, fmt.Sprintf("cannot resolve type for tuple element %d", i+1), element)
+			t = types.Unit
+		} else if want != nil && c.typesMatch(t, want) {
+			c.boxIfNecessary(element, t, want)
+			t = want
+		}
+		elems[i] = t
+	}
+
+	tuple := types.TupleType{Types: elems}
+	expr.ResolvedType = tuple
+	return tuple
+}
+
+// checkTupleIndex types t.0, written t[0]. The index must be an int literal
+// so the element's type is known
+func (c *Checker) checkTupleIndex(expr *ast.IndexExpr, tuple types.TupleType) types.Type {
+	lit, ok := expr.Index.(*ast.IntegerLiteral)
+	if !ok {
+		e := c.newError(errors.InvalidIndex, fmt.Sprintf("tuple %s must be indexed by an int literal, got %s", tuple.Signature(), expr.Index.String()), expr.Index)
+		e.Help = "destructure the tuple instead, as in `const (a, b) = t;`"
+		c.report(e)
+		return nil
+	}
+	if lit.Value < 0 || lit.Value >= int64(len(tuple.Types)) {
+		c.appendError(errors.InvalidIndex, fmt.Sprintf("index %d out of range for tuple %s of %d values", lit.Value, tuple.Signature(), len(tuple.Types)), expr.Index)
+		return nil
+	}
+	c.typeOf(lit, nil)
+
+	elem := tuple.Types[lit.Value]
+	expr.ResolvedType = elem
+	expr.ContainerType = tuple
+	return elem
+}
+
+func (c *Checker) checkTupleDeclStmt(node *ast.TupleDeclarationStmt) types.Type {
+	valType := c.typeOf(node.Value, nil)
+	c.destructure(node.Names, valType, node.Value, node.Constant)
+	return types.Unit
+}
+
+// destructure declares one variable per element of a tuple in the current
+// scope. _ skips an element
+func (c *Checker) destructure(names []*ast.Identifier, t types.Type, value ast.Node, constant bool) {
+	if t == nil {
+		c.appendError(errors.CannotInfer, fmt.Sprintf("cannot resolve type for %s", value.String()), value)
+		return
+	}
+	tuple, ok := t.(types.TupleType)
+	if !ok {
+		c.appendError(errors.MismatchedTypes, fmt.Sprintf("type mismatch: cannot destructure %s, expected a tuple", t.Signature()), value)
+		return
+	}
+	if len(tuple.Types) != len(names) {
+		c.appendError(errors.MismatchedTypes, fmt.Sprintf("type mismatch: cannot destructure %s into %d names", tuple.Signature(), len(names)), value)
+		return
+	}
+
+	for i, name := range names {
+		if name.Value == "_" {
+			continue
+		}
+		if _, exists := c.env.store[name.Value]; exists {
+			c.appendError(errors.DuplicateDeclaration, fmt.Sprintf("variable %s already declared", name.Value), name)
+			continue
+		}
+		name.SetResolvedType(tuple.Types[i])
+		c.env.Set(name.Value, tuple.Types[i])
+		c.declare(c.env, name.Value, name, variableKind(constant))
+		if constant {
+			c.env.SetConst(name.Value)
+		}
+		c.inferVariable(name, tuple.Types[i])
+	}
+}
+
+// checkMapKey reports a tuple used as a map key, which the backends cannot
+// hash
+func (c *Checker) checkMapKey(t types.Type, node ast.Node) bool {
+	if _, ok := t.(types.TupleType); !ok {
+		return true
+	}
+	e := c.newError(errors.InvalidIndex, fmt.Sprintf("tuple %s cannot be a map key", t.Signature()), node)
+	e.Help = "key the map by a string built from the values instead"
+	c.report(e)
+	return false
+}
@@ -11,6 +11,7 @@ type TypeEnv struct {
 	store     map[string]types.Type
 	outer     *TypeEnv
 	constants map[string]bool
+	decls     map[string]Declaration
 }
 
 func NewTypeEnv(parent *TypeEnv) *TypeEnv {
@@ -18,6 +19,7 @@ func NewTypeEnv(parent *TypeEnv) *TypeEnv {
 		store:     make(map[string]types.Type),
 		outer:     parent,
 		constants: make(map[string]bool),
+		decls:     make(map[string]Declaration),
 	}
 }
 
@@ -79,3 +81,23 @@ func (e *TypeEnv) IsConst(name string) bool {
 
 	return isConst
 }
+
+// Symbols returns every name visible from this scope, inner scopes shadowing
+// outer ones. Names declared without a value, such as struct types, map to nil
+func (e *TypeEnv) Symbols() map[string]types.Type {
+	symbols := make(map[string]types.Type)
+	if e.outer != nil {
+		symbols = e.outer.Symbols()
+	}
+	for name := range e.decls {
+		if !strings.Contains(name, ".") {
+			symbols[name] = nil
+		}
+	}
+	for name, t := range e.store {
+		if !strings.Contains(name, ".") {
+			symbols[name] = t
+		}
+	}
+	return symbols
+}
@@ -22,12 +22,14 @@ type Checker struct {
 	currentMatchResultType types.Type
 	definedStructs         map[string]types.StructType
 	definedInterfaces      map[string]types.InterfaceType
+	definedEnums           map[string]types.EnumType
 	methodToInterfaces     map[string][]types.InterfaceType
 	packages               map[string]*TypeEnv
 
 	moduleTypes map[string]map[string]types.Type
 
 	inLoop            bool
+	inDefer           bool // checking a deferred call, which runs after the function returns
 	inDiscardPosition bool
 	isLastInBlock     bool
 
@@ -41,6 +43,18 @@ type Checker struct {
 	pendingInserts          map[int][]ast.Stmt
 	currentModule           string
 	moduleInterfacesIndexed bool
+
+	uses            map[position]Declaration // identifier position → what it resolved to
+	declared        map[position]Declaration
+	implementations map[position][]Declaration
+	stmtFiles       map[ast.Stmt]string
+	currentFile     string
+	inferences      map[position]Inference
+	typeArguments   map[position][]types.Type // generic callee position → its type arguments
+	instantiating   int                       // > 0 while checking a copy of a generic function
+	unimplemented   []*Unimplemented
+	deferredTypes   map[ast.Expr]types.Type // the type of each expression in the deferred call being checked
+	deferredLocals  int                     // hidden locals bound for deferred calls so far
 }
 
 func New(globalEnv *TypeEnv) *Checker {
@@ -59,6 +73,7 @@ func New(globalEnv *TypeEnv) *Checker {
 		currentMatchResultType: nil,
 		definedStructs:         make(map[string]types.StructType),
 		definedInterfaces:      make(map[string]types.InterfaceType),
+		definedEnums:           make(map[string]types.EnumType),
 		methodToInterfaces:     make(map[string][]types.InterfaceType),
 		packages:               make(map[string]*TypeEnv),
 		moduleTypes:            map[string]map[string]types.Type{},
@@ -69,6 +84,11 @@ func New(globalEnv *TypeEnv) *Checker {
 		structNodes:            make(map[string]*ast.StructDefinitionStmt),
 		pendingInserts:         make(map[int][]ast.Stmt),
 		program:                nil,
+		uses:                   make(map[position]Declaration),
+		declared:               make(map[position]Declaration),
+		implementations:        make(map[position][]Declaration),
+		inferences:             make(map[position]Inference),
+		typeArguments:          make(map[position][]types.Type),
 	}
 }
 
@@ -118,6 +138,12 @@ func (c *Checker) Env() *TypeEnv {
 	return c.env
 }
 
+// Package returns the exports of an imported module
+func (c *Checker) Package(name string) (*TypeEnv, bool) {
+	env, ok := c.packages[name]
+	return env, ok
+}
+
 func (c *Checker) CheckAsPackage(node ast.Node, packages []*loader.Package) []errors.PositionError {
 	if packages != nil {
 		c.checkPackages(packages)
@@ -128,16 +154,14 @@ func (c *Checker) CheckAsPackage(node ast.Node, packages []*loader.Package) []er
 		pkgEnv.Set(v.Name, v.BuiltIn.T)
 	}
 	for _, env := range c.packages {
-		for name, typ := range env.store {
-			if strings.Contains(name, ".") {
-				pkgEnv.Set(name, typ)
-			}
-		}
+		pkgEnv.importQualified(env)
 	}
 
 	pkgChecker := NewWithModuleTypes(pkgEnv, c.moduleTypes)
 	pkgChecker.packages = c.packages
 	pkgChecker.currentModule = c.currentModule
+	c.shareDeclarations(pkgChecker)
+	pkgChecker.stmtFiles = c.stmtFiles
 
 	if program, ok := node.(*ast.Program); ok {
 		pkgChecker.program = program
@@ -160,21 +184,27 @@ func (c *Checker) Check(node ast.Node, packages []*loader.Package) []errors.Posi
 	return c.errors
 }
 
+// CheckExpression type checks a single expression against the checker's
+// environment, for callers such as the debugger that evaluate expressions
+// outside of a program
+func (c *Checker) CheckExpression(expr ast.Expr) (types.Type, []errors.PositionError) {
+	t := c.typeOf(expr, nil)
+	return t, c.errors
+}
+
 func (c *Checker) checkPackage(pkg *loader.Package, registry map[string]*TypeEnv) *Checker {
 	pkgEnv := NewTypeEnv(nil)
 	// Populate env with struct methods from already-checked dependencies
 	// (e.g. "Socket.read") so cross-module method calls work.
 	// Bare names are accessed via scope
 	for _, env := range registry {
-		for name, typ := range env.store {
-			if strings.Contains(name, ".") {
-				pkgEnv.Set(name, typ)
-			}
-		}
+		pkgEnv.importQualified(env)
 	}
 	pkgChecker := NewWithModuleTypes(pkgEnv, c.moduleTypes)
 	pkgChecker.packages = registry
 	pkgChecker.currentModule = pkg.Name
+	c.shareDeclarations(pkgChecker)
+	pkgChecker.stmtFiles = fileOf(pkg.Programs)
 
 	merged := &ast.Program{}
 	for _, program := range pkg.Programs {
@@ -275,38 +305,39 @@ func (c *Checker) checkPackages(packages []*loader.Package) []errors.PositionErr
 	registry := make(map[string]*TypeEnv)
 
 	for _, pkg := range packages {
-		c.checkPackage(pkg, registry)
+		pkgChecker := c.checkPackage(pkg, registry)
 		exportEnv := NewTypeEnv(nil)
 		// set non-functions
 		functions := c.exportNonFunctions(pkg, exportEnv)
 		c.exportFunctions(functions, exportEnv)
 		// set functions
+		exportDeclarations(pkgChecker, exportEnv)
 
 		registry[pkg.Name] = exportEnv
 	}
 	c.packages = registry
 	for _, env := range registry {
-		for name, typ := range env.store {
-			if strings.Contains(name, ".") {
-				c.env.Set(name, typ)
-			}
-		}
+		c.env.importQualified(env)
 	}
 	return c.errors
 }
 
 func (c *Checker) checkReturnStmt(node *ast.ReturnStmt) types.Type {
 	if c.currentReturnType == nil {
-		c.appendError("return statement outside of function body", node)
+		c.appendError(errors.MisplacedControlFlow, "return statement outside of function body", node)
 	}
 
 	var valType types.Type = types.Unit
 	if node.ReturnValue != nil {
 		valType = c.typeOf(node.ReturnValue, c.currentReturnType)
 	}
+	if valType == nil || c.currentReturnType == nil {
+		// the error is reported where the value failed to check
+		return types.Never
+	}
 
 	if !c.typesMatch(valType, c.currentReturnType) {
-		c.appendError(fmt.Sprintf("cannot return %s from function expecting %s", valType.Signature(), c.currentReturnType.Signature()), node)
+		c.appendError(errors.MismatchedTypes, fmt.Sprintf("cannot return %s from function expecting %s", valType.Signature(), c.currentReturnType.Signature()), node)
 	} else {
 		c.boxIfNecessary(node.ReturnValue, valType, c.currentReturnType)
 	}
@@ -321,8 +352,8 @@ func (c *Checker) checkForStmt(node *ast.ForStmt) types.Type {
 	}
 	c.inLoop = true
 	conditionType := c.typeOf(node.Condition, types.Bool)
-	if conditionType != types.Bool {
-		c.appendError(fmt.Sprintf("cannot use expression of type %s for loop condition", conditionType.Signature()), node)
+	if conditionType != nil && conditionType != types.Bool {
+		c.appendError(errors.MismatchedTypes, fmt.Sprintf("cannot use expression of type %s for loop condition", conditionType.Signature()), node)
 	}
 	if node.Post != nil {
 		c.check(node.Post)
@@ -343,18 +374,30 @@ func (c *Checker) checkForInStmt(node *ast.ForInStmt) types.Type {
 	a, aok := iterType.(types.ArrayType)
 	if mok {
 		node.Key.SetResolvedType(m.KeyType)
-		node.Value.SetResolvedType(m.ValueType)
 		c.env.Set(node.Key.Value, m.KeyType)
-		c.env.Set(node.Value.Value, m.ValueType)
+		c.declare(c.env, node.Key.Value, node.Key, VariableDeclaration)
+		if node.Names != nil {
+			c.destructure(node.Names, m.ValueType, node.Iterable, false)
+		} else {
+			node.Value.SetResolvedType(m.ValueType)
+			c.env.Set(node.Value.Value, m.ValueType)
+			c.declare(c.env, node.Value.Value, node.Value, VariableDeclaration)
+		}
 	} else if aok {
 		if node.Key != nil {
 			node.Key.SetResolvedType(types.Int)
 			c.env.Set(node.Key.Value, types.Int)
+			c.declare(c.env, node.Key.Value, node.Key, VariableDeclaration)
 		}
-		node.Value.SetResolvedType(a.ElemType)
-		c.env.Set(node.Value.Value, a.ElemType)
-	} else {
-		c.appendError(fmt.Sprintf("cannot iterate over value of type %s", iterType.Signature()), node)
+		if node.Names != nil {
+			c.destructure(node.Names, a.ElemType, node.Iterable, false)
+		} else {
+			node.Value.SetResolvedType(a.ElemType)
+			c.env.Set(node.Value.Value, a.ElemType)
+			c.declare(c.env, node.Value.Value, node.Value, VariableDeclaration)
+		}
+	} else if iterType != nil {
+		c.appendError(errors.NotIterable, fmt.Sprintf("cannot iterate over value of type %s", iterType.Signature()), node)
 	}
 
 	c.inLoop = true
@@ -380,10 +423,11 @@ func (c *Checker) checkVarDeclStmt(node *ast.VarDeclarationStmt) types.Type {
 	valType := c.typeOf(node.Value, varType)
 	if node.Type == nil {
 		node.Type = valType
+		c.inferVariable(node.Name, valType)
 	}
 
 	if valType == types.Unit {
-		c.appendError(fmt.Sprintf("cannot assign unit to variable %s", name), node)
+		c.appendError(errors.CannotInfer, fmt.Sprintf("cannot assign unit to variable %s", name), node)
 		return types.Unit
 	}
 
@@ -392,21 +436,24 @@ func (c *Checker) checkVarDeclStmt(node *ast.VarDeclarationStmt) types.Type {
 	}
 
 	if valType == nil && varType == nil {
-		c.appendError(fmt.Sprintf("cannot resolve type for variable %s", name), node)
+		c.appendError(errors.CannotInfer, fmt.Sprintf("cannot resolve type for variable %s", name), node)
 		return types.Unit
 	}
 
 	if ok && !outer {
 		if valType == nil || varType == nil {
-			c.appendError(fmt.Sprintf("cannot resolve type for variable %s", name), node)
+			c.appendError(errors.CannotInfer, fmt.Sprintf("cannot resolve type for variable %s", name), node)
 			return types.Unit
 		}
 		if !c.typesMatch(valType, varType) {
-			c.appendError(fmt.Sprintf("type mismatch: cannot assign %s to variable %s of type %s", valType.Signature(), node.Name.String(), node.Type.Signature()), node)
+			e := c.newError(errors.MismatchedTypes, fmt.Sprintf("type mismatch: cannot assign %s to variable %s of type %s", valType.Signature(), node.Name.String(), node.Type.Signature()), node)
+			c.declaredHere(&e, name, node.Name, fmt.Sprintf("declared as %s here", varType.Signature()))
+			c.report(e)
 		}
 	} else {
 		c.boxIfNecessary(node.Value, valType, varType)
 		c.env.Set(name, valType)
+		c.declare(c.env, name, node.Name, variableKind(node.Constant))
 		if node.Constant {
 			c.env.SetConst(name)
 		}
@@ -424,15 +471,24 @@ func (c *Checker) checkVarAssignmentStmt(node *ast.VarAssignmentStmt) types.Type
 	valType := c.typeOf(node.Value, varType)
 	isConst := c.env.IsConst(name)
 	if !ok {
-		c.appendError(fmt.Sprintf("cannot assign to undefined variable %s", name), node)
+		c.appendError(errors.UnresolvedName, fmt.Sprintf("cannot assign to undefined variable %s", name), node)
 		return types.Unit
 	}
+	c.use(c.env, node.Identifier, name)
 	if isConst {
-		c.appendError(fmt.Sprintf("cannot assign to constant variable %s", name), node)
+		e := c.newError(errors.AssignToConstant, fmt.Sprintf("cannot assign to constant variable %s", name), node.Identifier)
+		c.declaredHere(&e, name, nil, "declared as a constant here")
+		e.Help = fmt.Sprintf("declare %s with `mut` to allow assigning to it", name)
+		c.report(e)
+	}
+	if valType == nil {
+		return types.Unit
 	}
 
 	if !c.typesMatch(varType, valType) {
-		c.appendError(fmt.Sprintf("type mismatch: cannot assign %s to variable %s of type %s", valType.Signature(), name, varType.Signature()), node)
+		e := c.newError(errors.MismatchedTypes, fmt.Sprintf("type mismatch: cannot assign %s to variable %s of type %s", valType.Signature(), name, varType.Signature()), node.Value)
+		c.declaredHere(&e, name, nil, fmt.Sprintf("declared as %s here", varType.Signature()))
+		c.report(e)
 	}
 	c.boxIfNecessary(node.Value, valType, varType)
 
@@ -449,11 +505,16 @@ func (c *Checker) checkBlockStmt(node *ast.BlockStmt) types.Type {
 
 func (c *Checker) checkBlockStmtInCurrentScope(node *ast.BlockStmt) types.Type {
 	var lastType types.Type = types.Unit
+	unreachable := false
 	for i, stmt := range node.Stmts {
 		prev := c.isLastInBlock
 		c.isLastInBlock = i == len(node.Stmts)-1
 		lastType = c.check(stmt)
 		c.isLastInBlock = prev
+		// one warning covers the rest of the block
+		if !unreachable && i < len(node.Stmts)-1 {
+			unreachable = c.warnUnreachable(stmt, node.Stmts[i+1])
+		}
 	}
 	node.Scope = c.env
 	return lastType
@@ -464,7 +525,7 @@ func (c *Checker) checkSelectorAssignmentStmt(node *ast.SelectorAssignmentStmt)
 
 	structType, ok := toStruct(str)
 	if !ok {
-		c.appendError(fmt.Sprintf("cannot assign to field of non-struct value of type %s", str.Signature()), node)
+		c.appendError(errors.UnknownField, fmt.Sprintf("cannot assign to field of non-struct value of type %s", str.Signature()), node)
 		return types.Unit
 	}
 
@@ -475,14 +536,18 @@ func (c *Checker) checkSelectorAssignmentStmt(node *ast.SelectorAssignmentStmt)
 
 	idx := slices.Index(structType.Fields, field.Value)
 	if idx == -1 {
-		c.appendError(fmt.Sprintf("struct %s of type %s has no field %s", structType.Name, structType.Name, field.Value), node)
+		c.appendError(errors.UnknownField, fmt.Sprintf("struct %s of type %s has no field %s", structType.Name, structType.Name, field.Value), node)
 		return types.Unit
 	}
+	c.use(c.env, field, structType.Name+"."+field.Value)
 
 	valType := c.typeOf(node.Value, structType.Types[idx])
+	if valType == nil {
+		return types.Unit
+	}
 
 	if !c.typesMatch(valType, structType.Types[idx]) {
-		c.appendError(fmt.Sprintf("type mismatch: cannot assign %s to struct %s field of type %s", valType.Signature(), structType.Name, structType.Types[idx].Signature()), node)
+		c.appendError(errors.MismatchedTypes, fmt.Sprintf("type mismatch: cannot assign %s to struct %s field of type %s", valType.Signature(), structType.Name, structType.Types[idx].Signature()), node)
 	}
 
 	node.Left.ContainerType = structType
@@ -495,10 +560,12 @@ func (c *Checker) check(n ast.Node) types.Type {
 	switch node := n.(type) {
 	case *ast.Program:
 		for _, stmt := range node.Stmts {
+			c.currentFile = c.stmtFiles[stmt]
 			c.hoistBase(stmt)
 		}
 
 		for _, stmt := range node.Stmts {
+			c.currentFile = c.stmtFiles[stmt]
 			c.hoistFunctions(stmt)
 		}
 
@@ -506,6 +573,7 @@ func (c *Checker) check(n ast.Node) types.Type {
 
 		for i, stmt := range node.Stmts {
 			c.stmtIndex = i
+			c.currentFile = c.stmtFiles[stmt]
 			c.check(stmt)
 		}
 		c.insertPending(node)
@@ -524,6 +592,8 @@ func (c *Checker) check(n ast.Node) types.Type {
 		return c.checkForStmt(node)
 	case *ast.VarDeclarationStmt:
 		return c.checkVarDeclStmt(node)
+	case *ast.TupleDeclarationStmt:
+		return c.checkTupleDeclStmt(node)
 	case *ast.VarAssignmentStmt:
 		return c.checkVarAssignmentStmt(node)
 	case *ast.IndexAssignmentStmt:
@@ -536,13 +606,13 @@ func (c *Checker) check(n ast.Node) types.Type {
 		return c.checkSelectorAssignmentStmt(node)
 	case *ast.ContinueStmt:
 		if !c.inLoop {
-			c.appendError(fmt.Sprintf("continue statement cannot be outside of loop"), node)
+			c.appendError(errors.MisplacedControlFlow, fmt.Sprintf("continue statement cannot be outside of loop"), node)
 			return nil
 		}
 		return types.Unit
 	case *ast.BreakStmt:
 		if !c.inLoop {
-			c.appendError(fmt.Sprintf("break statement cannot be outside of loop"), node)
+			c.appendError(errors.MisplacedControlFlow, fmt.Sprintf("break statement cannot be outside of loop"), node)
 			return nil
 		}
 		return types.Unit
@@ -551,27 +621,29 @@ func (c *Checker) check(n ast.Node) types.Type {
 	case *ast.SpawnStmt:
 		callExpr, ok := node.CallExpr.(*ast.CallExpr)
 		if !ok {
-			c.appendError("must spawn function call", node)
+			c.appendError(errors.NotCallable, "must spawn function call", node)
 			return types.Unit
 		}
 		c.typeOf(callExpr, nil)
 		return types.Unit
+	case *ast.DeferStmt:
+		return c.checkDeferStmt(node)
 	case *ast.SendStmt:
 		chTypeRaw := c.typeOf(node.Chan, nil)
 		if chTypeRaw == nil {
-			c.appendError(fmt.Sprintf("cannot resolve type for ident %s", node.Chan), node)
+			c.appendError(errors.UnresolvedName, fmt.Sprintf("cannot resolve type for ident %s", node.Chan), node)
 			return types.Unit
 		}
 		chType, ok := chTypeRaw.(types.ChannelType)
 		if !ok {
-			c.appendError(fmt.Sprintf("%s is not a channel", node.Chan), node)
+			c.appendError(errors.InvalidChannelOperation, fmt.Sprintf("%s is not a channel", node.Chan), node)
 			return types.Unit
 		}
 
 		valType := c.typeOf(node.Value, nil)
 
 		if !c.typesMatch(chType.ElemType, valType) {
-			c.appendError(fmt.Sprintf("type mismatch: cannot send value of type %s to channel expecting %s", valType, chType.ElemType.Signature()), node)
+			c.appendError(errors.MismatchedTypes, fmt.Sprintf("type mismatch: cannot send value of type %s to channel expecting %s", valType, chType.ElemType.Signature()), node)
 		}
 		return types.Unit
 	}
@@ -584,12 +656,18 @@ func (c *Checker) hoistBase(n ast.Node) {
 	case *ast.PubStatement:
 		c.hoistBase(node.Stmt)
 	case *ast.StructDefinitionStmt:
+		c.declare(c.env, node.Name.Value, node.Name, StructDeclaration)
+		for _, field := range node.FieldNames {
+			c.declare(c.env, node.Name.Value+"."+field.Value, field, FieldDeclaration)
+		}
 		if len(node.Type.TypeParams) > 0 {
 			c.genericStructs[node.Name.Value] = node
 			return
 		}
 		c.definedStructs[node.Name.Value] = node.Type
 		c.structNodes[node.Name.Value] = node
+	case *ast.EnumDefinitionStmt:
+		c.hoistEnum(node)
 
 	case *ast.InterfaceDefinitionStmt:
 		node.Type.MethodIndices = make(map[string]int)
@@ -597,6 +675,10 @@ func (c *Checker) hoistBase(n ast.Node) {
 			node.Type.MethodIndices[mn] = i
 		}
 		c.env.Set(node.Name.Value, node.Type)
+		c.declare(c.env, node.Name.Value, node.Name, InterfaceDeclaration)
+		for _, method := range node.MethodNames {
+			c.declare(c.env, mangleMethod(node.Name.Value, method.Value), method, MethodDeclaration)
+		}
 		c.assertInterfaceConsistent(node.Type)
 		c.definedInterfaces[node.Name.Value] = node.Type
 		c.indexInterface(node.Type)
@@ -608,11 +690,12 @@ func (c *Checker) hoistBase(n ast.Node) {
 			// Only check the current scope's store to allow shadowing
 			if _, exists := c.env.store[name]; !exists {
 				c.env.Set(name, node.Type)
+				c.declare(c.env, name, node.Name, variableKind(node.Constant))
 				if node.Constant {
 					c.env.SetConst(name)
 				}
 			} else {
-				c.appendError(fmt.Sprintf("variable %s already declared", name), node)
+				c.appendError(errors.DuplicateDeclaration, fmt.Sprintf("variable %s already declared", name), node)
 			}
 		}
 	}
@@ -626,12 +709,14 @@ func (c *Checker) hoistGenericFunction(name string, node *ast.FunctionDeclaratio
 	}
 	resolved := types.SubstituteTypeParams(ft, subs).(types.FunctionType)
 	c.env.Set(name, resolved)
+	c.declare(c.env, name, node.Name, FunctionDeclaration)
 
 	if len(resolved.Params) > 0 {
 		if st, ok := toStruct(resolved.Params[0]); ok {
 			mangled := st.Name + "." + node.Name.Value
 			node.MangledName = mangled
 			c.env.Set(mangled, resolved)
+			c.declare(c.env, mangled, node.Name, MethodDeclaration)
 		}
 	}
 }
@@ -668,11 +753,12 @@ func (c *Checker) hoistFunctions(n ast.Node) {
 
 		_, fromOuter, exists := c.env.Get(node.Name.Value)
 		if exists && !fromOuter && !node.IsExtern {
-			c.appendError(fmt.Sprintf("function %s already declared", node.Name.Value), node)
+			c.appendError(errors.DuplicateDeclaration, fmt.Sprintf("function %s already declared", node.Name.Value), node)
 			return
 		}
 
 		c.env.Set(name, node.Type)
+		c.declare(c.env, name, node.Name, functionKind(name, node))
 	}
 }
 
@@ -682,6 +768,9 @@ func (c *Checker) typeOf(e ast.Expr, expectedType types.Type) types.Type {
 	}
 	result := c.typeOfInner(e, expectedType)
 	c.boxIfNecessary(e, result, expectedType)
+	if c.deferredTypes != nil {
+		c.deferredTypes[e] = result
+	}
 	return result
 }
 
@@ -708,6 +797,8 @@ func (c *Checker) typeOfInner(e ast.Expr, expectedType types.Type) types.Type {
 		expr.Type = c.resolveFunctionType(expr.Type)
 		oldInLoop := c.inLoop
 		c.inLoop = true
+		oldInDefer := c.inDefer
+		c.inDefer = false
 		oldReturnType := c.currentReturnType
 		c.currentReturnType = expr.Type.Return
 		oldEnv := c.env
@@ -719,15 +810,19 @@ func (c *Checker) typeOfInner(e ast.Expr, expectedType types.Type) types.Type {
 
 		for i, param := range expr.Parameters {
 			c.env.Set(param.Value, expr.Type.Params[i])
+			c.declare(c.env, param.Value, param, ParameterDeclaration)
 		}
 
 		c.check(expr.Body)
 
 		c.env = oldEnv
 		c.currentReturnType = oldReturnType
+		c.inDefer = oldInDefer
 		c.inLoop = oldInLoop
 
 		return expr.Type
+	case *ast.TupleLiteral:
+		return c.checkTupleLiteral(expr, expectedType)
 	case *ast.ArrayLiteral:
 		var targetedElemType types.Type
 		if expected, ok := toArray(expectedType); ok {
@@ -737,12 +832,15 @@ func (c *Checker) typeOfInner(e ast.Expr, expectedType types.Type) types.Type {
 		var elemType types.Type
 		for i, element := range expr.Elements {
 			eType := c.typeOf(element, targetedElemType)
+			if eType == nil {
+				continue
+			}
 			if elemType == nil {
 				elemType = eType
 			}
 
 			if targetedElemType != nil && !c.typesMatch(eType, targetedElemType) {
-				c.appendError(fmt.Sprintf("type mismatch: array element got %s, expected %s", eType.Signature(), targetedElemType.Signature()), expr)
+				c.appendError(errors.MismatchedTypes, fmt.Sprintf("type mismatch: array element got %s, expected %s", eType.Signature(), targetedElemType.Signature()), expr)
 				continue
 			}
 
@@ -756,7 +854,7 @@ func (c *Checker) typeOfInner(e ast.Expr, expectedType types.Type) types.Type {
 				resolved = types.ArrayType{ElemType: targetedElemType, CollectionType: types.CollectionType{IsEmpty: isEmpty}}
 			} else {
 				if isEmpty {
-					c.appendError("empty array literal requires a type annotation", expr)
+					c.appendError(errors.CannotInfer, "empty array literal requires a type annotation", expr)
 				}
 				resolved = types.ArrayType{ElemType: types.Null, CollectionType: types.CollectionType{IsEmpty: isEmpty}}
 			}
@@ -787,6 +885,9 @@ func (c *Checker) typeOfInner(e ast.Expr, expectedType types.Type) types.Type {
 			v := expr.Pairs[k]
 			kType := c.typeOf(k, expected.KeyType)
 			vType := c.typeOf(v, expected.ValueType)
+			if kType == nil || vType == nil {
+				continue
+			}
 
 			if keyType == nil {
 				keyType = kType
@@ -795,15 +896,16 @@ func (c *Checker) typeOfInner(e ast.Expr, expectedType types.Type) types.Type {
 			}
 
 			if !c.typesMatch(kType, keyType) {
-				c.appendError(fmt.Sprintf("type mismatch: map key got %s, expected %s", kType.Signature(), keyType.Signature()), expr)
+				c.appendError(errors.MismatchedTypes, fmt.Sprintf("type mismatch: map key got %s, expected %s", kType.Signature(), keyType.Signature()), expr)
 			}
 			if !c.typesMatch(valType, vType) {
-				c.appendError(fmt.Sprintf("type mismatch: map value got %s, expected %s", vType.Signature(), valType.Signature()), expr)
+				c.appendError(errors.MismatchedTypes, fmt.Sprintf("type mismatch: map value got %s, expected %s", vType.Signature(), valType.Signature()), expr)
 			}
 			c.boxIfNecessary(k, kType, keyType)
 			c.boxIfNecessary(v, vType, valType)
 		}
 
+		c.checkMapKey(keyType, expr)
 		expr.ResolvedType = types.MapType{ValueType: valType, KeyType: keyType}
 		e = expr
 
@@ -816,9 +918,12 @@ func (c *Checker) typeOfInner(e ast.Expr, expectedType types.Type) types.Type {
 	case *ast.Identifier:
 		t, _, ok := c.env.Get(expr.Value)
 		if !ok {
-			c.appendError(fmt.Sprintf("undefined identifier: %s", expr.Value), expr)
+			e := c.newError(errors.UnresolvedName, fmt.Sprintf("undefined identifier: %s", expr.Value), expr)
+			c.suggest(&e, expr.Value)
+			c.report(e)
 			return types.Unit
 		}
+		c.use(c.env, expr, expr.Value)
 		expr.ResolvedType = t
 		e = expr
 		return t
@@ -838,7 +943,7 @@ func (c *Checker) typeOfInner(e ast.Expr, expectedType types.Type) types.Type {
 	case *ast.IfExpr:
 		t := c.typeOf(expr.Condition, types.Bool)
 		if t != types.Bool {
-			c.appendError(fmt.Sprintf("cannot use expression of type %s for if condition", t), expr)
+			c.appendError(errors.MismatchedTypes, fmt.Sprintf("cannot use expression of type %s for if condition", t), expr)
 			return nil
 		}
 		discard := c.inDiscardPosition
@@ -849,7 +954,7 @@ func (c *Checker) typeOfInner(e ast.Expr, expectedType types.Type) types.Type {
 			aType = c.check(expr.Alternative)
 
 			if !discard && !c.typesMatch(cType, aType) {
-				c.appendError(fmt.Sprintf("consequence and alternative for if expression must result in same type"), expr)
+				c.appendError(errors.MismatchedTypes, fmt.Sprintf("consequence and alternative for if expression must result in same type"), expr)
 				c.inDiscardPosition = discard
 				return types.Unit
 			}
@@ -869,21 +974,28 @@ func (c *Checker) typeOfInner(e ast.Expr, expectedType types.Type) types.Type {
 		e = expr
 		return resolved
 	case *ast.SelectorExpr:
+		if enum, ok := c.enumConstructor(expr); ok {
+			return c.checkEnumConstructor(enum, expr, nil, expectedType)
+		}
 		t := c.typeOf(expr.Left, nil)
+		if t == nil {
+			return nil
+		}
 		structType, ok := t.(types.StructType)
 		if !ok {
-			c.appendError(fmt.Sprintf("cannot access field of non-struct value %s of type %s", expr.Left.TokenLiteral(), t.Signature()), expr)
+			c.appendError(errors.UnknownField, fmt.Sprintf("cannot access field of non-struct value %s of type %s", expr.Left.TokenLiteral(), t.Signature()), expr)
 		}
 		val, ok := expr.Value.(*ast.Identifier)
 		if !ok {
-			c.appendError(fmt.Sprintf("idk what to put here I'll figure it out later"), expr)
+			c.appendError(errors.UnknownField, fmt.Sprintf("idk what to put here I'll figure it out later"), expr)
 			return types.Unit
 		}
 		i := slices.Index(structType.Fields, val.Value)
 		if i == -1 {
-			c.appendError(fmt.Sprintf("field %s of struct type %s not found", val.Value, expr.Left.TokenLiteral()), expr)
+			c.appendError(errors.UnknownField, fmt.Sprintf("field %s of struct type %s not found", val.Value, expr.Left.TokenLiteral()), expr)
 			return types.Unit
 		}
+		c.use(c.env, val, structType.Name+"."+val.Value)
 
 		expr.ContainerType = structType
 		expr.ResolvedType = structType.Types[i]
@@ -907,7 +1019,7 @@ func (c *Checker) typeOfInner(e ast.Expr, expectedType types.Type) types.Type {
 			}
 
 			if !found {
-				c.appendError(fmt.Sprintf("unknown generic struct %s", expr.Name), expr)
+				c.appendError(errors.UnknownType, fmt.Sprintf("unknown generic struct %s", expr.Name), expr)
 				return types.Unit
 			}
 
@@ -925,18 +1037,24 @@ func (c *Checker) typeOfInner(e ast.Expr, expectedType types.Type) types.Type {
 			}
 
 			if !found {
-				c.appendError(fmt.Sprintf("unknown type %s:%s", expr.Module, expr.Name), expr)
+				c.appendError(errors.UnknownType, fmt.Sprintf("unknown type %s:%s", expr.Module, expr.Name), expr)
 				return types.Unit
 			}
 		} else {
 			var ok bool
 			structType, ok = c.definedStructs[expr.Name]
 			if !ok {
-				c.appendError(fmt.Sprintf("unknown type %s", expr.Name), expr)
+				c.appendError(errors.UnknownType, fmt.Sprintf("unknown type %s", expr.Name), expr)
 				return types.Unit
 			}
 		}
 
+		if expr.Module != "" {
+			c.use(c.packages[expr.Module], expr, expr.Name)
+		} else {
+			c.use(c.env, expr, expr.Name)
+		}
+
 		providedFields := make(map[string]ast.Expr)
 		for i, name := range expr.Fields {
 			providedFields[name] = expr.Values[i]
@@ -944,21 +1062,28 @@ func (c *Checker) typeOfInner(e ast.Expr, expectedType types.Type) types.Type {
 
 		for _, expected := range structType.Fields {
 			if _, ok := providedFields[expected]; !ok {
-				c.appendError(fmt.Sprintf("missing field %s in struct literal %s", expected, expr.Name), expr)
+				c.appendError(errors.UnknownField, fmt.Sprintf("missing field %s in struct literal %s", expected, expr.Name), expr)
 			}
 		}
 
 		for i, fieldName := range expr.Fields {
 			idx := slices.Index(structType.Fields, fieldName)
 			if idx == -1 {
-				c.appendError(fmt.Sprintf("field %s of struct type %s not found", fieldName, expr.Name), expr)
+				c.appendError(errors.UnknownField, fmt.Sprintf("field %s of struct type %s not found", fieldName, expr.Name), expr)
 				continue
 			}
 
+			if i < len(expr.FieldNames) {
+				c.use(c.env, expr.FieldNames[i], expr.Name+"."+fieldName)
+			}
+
 			expectedType = structType.Types[idx]
 			actualType := c.typeOf(expr.Values[i], expectedType)
+			if actualType == nil {
+				continue
+			}
 			if !c.typesMatch(actualType, expectedType) {
-				c.appendError(fmt.Sprintf("type mismatch for field %s in struct %s: expected %s, got %s", fieldName, expr.Name, expectedType.Signature(), actualType.Signature()), expr)
+				c.appendError(errors.MismatchedTypes, fmt.Sprintf("type mismatch for field %s in struct %s: expected %s, got %s", fieldName, expr.Name, expectedType.Signature(), actualType.Signature()), expr)
 			} else {
 				c.boxIfNecessary(expr.Values[i], actualType, expectedType)
 			}
@@ -970,14 +1095,15 @@ func (c *Checker) typeOfInner(e ast.Expr, expectedType types.Type) types.Type {
 	case *ast.ScopeAccessExpr:
 		pkgEnv, ok := c.packages[expr.Module.Value]
 		if !ok {
-			c.appendError(fmt.Sprintf("module %s not found", expr.Module.Value), expr)
+			c.appendError(errors.UnknownModuleMember, fmt.Sprintf("module %s not found", expr.Module.Value), expr)
 			return types.Unit
 		}
 		typ, _, found := pkgEnv.Get(expr.Member.Value)
 		if !found {
-			c.appendError(fmt.Sprintf("%s is not exported from module %s", expr.Member.Value, expr.Module.Value), expr)
+			c.appendError(errors.UnknownModuleMember, fmt.Sprintf("%s is not exported from module %s", expr.Member.Value, expr.Module.Value), expr)
 			return types.Unit
 		}
+		c.use(pkgEnv, expr.Member, expr.Member.Value)
 
 		return typ
 	case *ast.MatchExpr:
@@ -991,28 +1117,37 @@ func (c *Checker) typeOfInner(e ast.Expr, expectedType types.Type) types.Type {
 		return resolved
 	case *ast.SliceExpr:
 		leftType := c.typeOf(expr.Left, nil)
+		if leftType == nil {
+			return nil
+		}
 		if _, ok := toArray(leftType); !ok && !isString(leftType) {
-			c.appendError(fmt.Sprintf("unsupported slice type %s", leftType.Signature()), expr)
+			c.appendError(errors.InvalidIndex, fmt.Sprintf("unsupported slice type %s", leftType.Signature()), expr)
 			return types.Unit
 		}
 		var startType types.Type = nil
 		if expr.Start != nil {
 			startType = c.typeOf(expr.Start, types.Int)
+			if startType == nil {
+				return nil
+			}
 			if startType != types.Int {
-				c.appendError(fmt.Sprintf("unsupported start type %s", startType.Signature()), expr)
+				c.appendError(errors.InvalidIndex, fmt.Sprintf("unsupported start type %s", startType.Signature()), expr)
 				return types.Unit
 			}
 		}
 		var endType types.Type = nil
 		if expr.End != nil {
 			endType = c.typeOf(expr.End, types.Int)
+			if endType == nil {
+				return nil
+			}
 			if endType != types.Int {
-				c.appendError(fmt.Sprintf("unsupported end type %s", endType.Signature()), expr)
+				c.appendError(errors.InvalidIndex, fmt.Sprintf("unsupported end type %s", endType.Signature()), expr)
 				return types.Unit
 			}
 		}
 		if endType == nil && startType == nil {
-			c.appendError("must provide start or end for slice expression", expr)
+			c.appendError(errors.InvalidIndex, "must provide start or end for slice expression", expr)
 			return types.Unit
 		}
 		expr.SetResolvedType(leftType)
@@ -1022,12 +1157,14 @@ func (c *Checker) typeOfInner(e ast.Expr, expectedType types.Type) types.Type {
 		if expr.Capacity != nil {
 			capType := c.typeOf(expr.Capacity, types.Int)
 			if capType != types.Int {
-				c.appendError("channel capacity must be int", expr)
+				c.appendError(errors.InvalidChannelOperation, "channel capacity must be int", expr)
 				return types.Unit
 			}
 		}
 		expr.SetResolvedType(expr.Type)
 		return expr.Type
+	case *ast.TryExpr:
+		return c.checkTryExpr(expr)
 	case *ast.ReceiveExpr:
 		chTypeRaw := c.typeOf(expr.Chan, nil)
 		if chTypeRaw == nil {
@@ -1035,7 +1172,7 @@ func (c *Checker) typeOfInner(e ast.Expr, expectedType types.Type) types.Type {
 		}
 		chType, ok := chTypeRaw.(types.ChannelType)
 		if !ok {
-			c.appendError(fmt.Sprintf("only channels can receive, got %s", chTypeRaw.Signature()), expr)
+			c.appendError(errors.InvalidChannelOperation, fmt.Sprintf("only channels can receive, got %s", chTypeRaw.Signature()), expr)
 		}
 		expr.SetResolvedType(chType.ElemType)
 		return chType.ElemType
@@ -1044,125 +1181,143 @@ func (c *Checker) typeOfInner(e ast.Expr, expectedType types.Type) types.Type {
 }
 
 func (c *Checker) checkInfixExpr(operator string, lt types.Type, rt types.Type, expr ast.Node) types.Type {
+	if lt == nil || rt == nil {
+		return nil
+	}
 	switch operator {
 	case "==", "!=":
 		if !c.typesMatch(lt, rt) {
-			c.appendError(fmt.Sprintf("type mismatch: cannot compare types %s to %s", lt.Signature(), rt.Signature()), expr)
+			c.appendError(errors.MismatchedTypes, fmt.Sprintf("type mismatch: cannot compare types %s to %s", lt.Signature(), rt.Signature()), expr)
+		} else if _, ok := lt.(types.TupleType); ok {
+			e := c.newError(errors.InvalidOperation, fmt.Sprintf("invalid operation: %s is not defined for type %s", operator, lt.Signature()), expr)
+			e.Help = "compare the values one at a time, as in `a[0] == b[0] && a[1] == b[1]`"
+			c.report(e)
 		}
 
 		return types.Bool
 	case ">", ">=", "<", "<=":
 		if !c.typesMatch(lt, rt) {
-			c.appendError(fmt.Sprintf("type mismatch: cannot compare types %s to %s", lt.Signature(), rt.Signature()), expr)
+			c.appendError(errors.MismatchedTypes, fmt.Sprintf("type mismatch: cannot compare types %s to %s", lt.Signature(), rt.Signature()), expr)
 		}
 
 		if lt != types.Float && lt != types.Int && lt != types.Byte {
-			c.appendError(fmt.Sprintf("invalid operation: %s is not defined for type %s", operator, lt.Signature()), expr)
+			c.appendError(errors.InvalidOperation, fmt.Sprintf("invalid operation: %s is not defined for type %s", operator, lt.Signature()), expr)
 		}
 
 		return types.Bool
 	case "&&", "||":
 		if !c.typesMatch(lt, rt) {
-			c.appendError(fmt.Sprintf("type mismatch: cannot perform boolean operation on types %s and %s", lt.Signature(), rt.Signature()), expr)
+			c.appendError(errors.MismatchedTypes, fmt.Sprintf("type mismatch: cannot perform boolean operation on types %s and %s", lt.Signature(), rt.Signature()), expr)
 		}
 		if lt != types.Bool {
-			c.appendError(fmt.Sprintf("invalid operation: %s is not defined for type %s", operator, lt.Signature()), expr)
+			c.appendError(errors.InvalidOperation, fmt.Sprintf("invalid operation: %s is not defined for type %s", operator, lt.Signature()), expr)
 		}
 		return types.Bool
 	case "+":
 		if !c.typesMatch(lt, rt) {
-			c.appendError(fmt.Sprintf("type mismatch: cannot add types %s and %s", lt.Signature(), rt.Signature()), expr)
+			c.appendError(errors.MismatchedTypes, fmt.Sprintf("type mismatch: cannot add types %s and %s", lt.Signature(), rt.Signature()), expr)
 		}
 
 		if lt != types.String && lt != types.Float && lt != types.Int && lt != types.Byte {
-			c.appendError(fmt.Sprintf("invalid operation: %s is not defined for type %s", operator, lt.Signature()), expr)
+			c.appendError(errors.InvalidOperation, fmt.Sprintf("invalid operation: %s is not defined for type %s", operator, lt.Signature()), expr)
 		}
 
 		return lt
 
 	case "-":
 		if !c.typesMatch(lt, rt) {
-			c.appendError(fmt.Sprintf("type mismatch: cannot subtract types %s and %s", lt.Signature(), rt.Signature()), expr)
+			c.appendError(errors.MismatchedTypes, fmt.Sprintf("type mismatch: cannot subtract types %s and %s", lt.Signature(), rt.Signature()), expr)
 		}
 
 		if lt != types.Float && lt != types.Int && lt != types.Byte {
-			c.appendError(fmt.Sprintf("invalid operation: %s is not defined for type %s", operator, lt.Signature()), expr)
+			c.appendError(errors.InvalidOperation, fmt.Sprintf("invalid operation: %s is not defined for type %s", operator, lt.Signature()), expr)
 		}
 
 		return lt
 	case "*":
 		if !c.typesMatch(lt, rt) {
-			c.appendError(fmt.Sprintf("type mismatch: cannot multiply types %s and %s", lt.Signature(), rt.Signature()), expr)
+			c.appendError(errors.MismatchedTypes, fmt.Sprintf("type mismatch: cannot multiply types %s and %s", lt.Signature(), rt.Signature()), expr)
 		}
 
 		if lt != types.Float && lt != types.Int {
-			c.appendError(fmt.Sprintf("invalid operation: %s is not defined for type %s", operator, lt.Signature()), expr)
+			c.appendError(errors.InvalidOperation, fmt.Sprintf("invalid operation: %s is not defined for type %s", operator, lt.Signature()), expr)
 		}
 
 		return lt
 	case "/":
 		if !c.typesMatch(lt, rt) {
-			c.appendError(fmt.Sprintf("type mismatch: cannot divide types %s and %s", lt.Signature(), rt.Signature()), expr)
+			c.appendError(errors.MismatchedTypes, fmt.Sprintf("type mismatch: cannot divide types %s and %s", lt.Signature(), rt.Signature()), expr)
 		}
 
 		if lt != types.Float && lt != types.Int {
-			c.appendError(fmt.Sprintf("invalid operation: %s is not defined for type %s", operator, lt.Signature()), expr)
+			c.appendError(errors.InvalidOperation, fmt.Sprintf("invalid operation: %s is not defined for type %s", operator, lt.Signature()), expr)
 		}
 
 		return lt
 	case "%":
 		if !c.typesMatch(lt, rt) {
-			c.appendError(fmt.Sprintf("type mismatch: cannot modulo types %s and %s", lt.Signature(), rt.Signature()), expr)
+			c.appendError(errors.MismatchedTypes, fmt.Sprintf("type mismatch: cannot modulo types %s and %s", lt.Signature(), rt.Signature()), expr)
 		}
 
 		if lt != types.Int {
-			c.appendError(fmt.Sprintf("invalid operation: %s is not defined for type %s", operator, lt.Signature()), expr)
+			c.appendError(errors.InvalidOperation, fmt.Sprintf("invalid operation: %s is not defined for type %s", operator, lt.Signature()), expr)
 		}
 
 		return types.Int
 	default:
-		c.appendError(fmt.Sprintf("unknown operator %s", operator), expr)
+		c.appendError(errors.InvalidOperation, fmt.Sprintf("unknown operator %s", operator), expr)
 		return nil
 	}
 }
 
 func (c *Checker) checkPrefixExpr(operator string, t types.Type, expr ast.Node) types.Type {
+	if t == nil {
+		return nil
+	}
 	if operator == "!" {
 		if t != types.Bool {
-			c.appendError(fmt.Sprintf("invalid operation: %s is not defined for %s", operator, t.Signature()), expr)
+			c.appendError(errors.InvalidOperation, fmt.Sprintf("invalid operation: %s is not defined for %s", operator, t.Signature()), expr)
 			return nil
 		}
 		return t
 	} else if operator == "-" {
 		if t != types.Float && t != types.Int {
-			c.appendError(fmt.Sprintf("invalid operation: %s is not defined for %s", operator, t.Signature()), expr)
+			c.appendError(errors.InvalidOperation, fmt.Sprintf("invalid operation: %s is not defined for %s", operator, t.Signature()), expr)
 			return nil
 		}
 
 		return t
 	}
 
-	c.appendError(fmt.Sprintf("unknown operator %s", operator), expr)
+	c.appendError(errors.InvalidOperation, fmt.Sprintf("unknown operator %s", operator), expr)
 	return nil
 }
 
 func (c *Checker) typeOfCallExpr(expr *ast.CallExpr, expected types.Type) types.Type {
 	if scope, ok := expr.Function.(*ast.ScopeAccessExpr); ok {
 		template, generic := c.genericFunctions[scope.Member.Value]
+		if generic {
+			c.use(c.packages[scope.Module.Value], scope.Member, scope.Member.Value)
+		}
 
 		if generic && len(expr.TypeArgs) == 0 {
 			typeArgs := c.inferTypeArgs(expr, template)
 			if typeArgs != nil {
 				expr.TypeArgs = typeArgs
+				c.inferTypeArguments(scope.Member, typeArgs)
 			}
 		}
 
 		if generic && len(expr.TypeArgs) > 0 {
+			c.instantiate(scope.Member, expr.TypeArgs)
 			return c.monomorphizeCall(expr, template)
 		}
 	}
 
 	if selector, ok := expr.Function.(*ast.SelectorExpr); ok {
+		if enum, ok := c.enumConstructor(selector); ok {
+			return c.checkEnumConstructor(enum, selector, expr.Arguments, expected)
+		}
 		receiverType := c.typeOf(selector.Left, nil)
 		if st, ok := receiverType.(types.ScopeType); ok {
 			receiverType = c.resolveType(st)
@@ -1170,6 +1325,7 @@ func (c *Checker) typeOfCallExpr(expr *ast.CallExpr, expected types.Type) types.
 		methodName := selector.Value.(*ast.Identifier).Value
 		mangled := mangleMethod(receiverType.Signature(), methodName)
 		if t, _, ok := c.env.Get(mangled); ok {
+			c.use(c.env, selector.Value, mangled)
 			if len(expr.Arguments) == 0 || expr.Arguments[0] != selector.Left {
 				expr.Arguments = append([]ast.Expr{selector.Left}, expr.Arguments...)
 			}
@@ -1192,6 +1348,7 @@ func (c *Checker) typeOfCallExpr(expr *ast.CallExpr, expected types.Type) types.
 		if it, ok := toInterface(receiverType); ok {
 			for i, m := range it.Methods {
 				if m == methodName {
+					c.use(c.env, selector.Value, mangleMethod(it.Name, methodName))
 					return c.validateFunctionCall(expr, it.Types[i])
 				}
 			}
@@ -1200,26 +1357,32 @@ func (c *Checker) typeOfCallExpr(expr *ast.CallExpr, expected types.Type) types.
 
 	if ident, ok := expr.Function.(*ast.Identifier); ok {
 		template, generic := c.genericFunctions[ident.Value]
+		if generic {
+			c.use(c.env, ident, ident.Value)
+		}
 
 		if generic && len(expr.TypeArgs) == 0 {
 			typeArgs := c.inferTypeArgs(expr, template)
 			if typeArgs != nil {
 				expr.TypeArgs = typeArgs
+				c.inferTypeArguments(ident, typeArgs)
 			}
 		}
 
 		if generic && len(expr.TypeArgs) > 0 {
+			c.instantiate(ident, expr.TypeArgs)
 			return c.monomorphizeCall(expr, template)
 		}
 
 		if len(expr.Arguments) > 0 {
 			receiverType := c.typeOf(expr.Arguments[0], nil)
 			if receiverType == nil {
-				c.appendError(fmt.Sprintf("cannot resolve type for %s function call", ident.Value), expr)
+				c.appendError(errors.CannotInfer, fmt.Sprintf("cannot resolve type for %s function call", ident.Value), expr)
 				return nil
 			}
 			mangled := mangleMethod(receiverType.Signature(), ident.Value)
 			if t, _, ok := c.env.Get(mangled); ok {
+				c.use(c.env, ident, mangled)
 				return c.validateFunctionCall(expr, t)
 			}
 		}
@@ -1244,7 +1407,7 @@ func (c *Checker) typeOfCallExpr(expr *ast.CallExpr, expected types.Type) types.
 		case "values":
 			return c.checkValuesBuiltIn(expr)
 		case "ok":
-			return c.checkOkBuiltIn(expr)
+			return c.checkOkBuiltIn(expr, expected)
 		case "err":
 			return c.checkErrBuiltIn(expr, expected)
 		case "some":
@@ -1271,7 +1434,7 @@ func (c *Checker) typeOfCallExpr(expr *ast.CallExpr, expected types.Type) types.
 
 func (c *Checker) checkLenBuiltIn(expr *ast.CallExpr) types.Type {
 	if len(expr.Arguments) != 1 {
-		c.appendError(fmt.Sprintf("len() expects exactly 1 argument"), expr)
+		c.appendError(errors.WrongArgumentCount, fmt.Sprintf("len() expects exactly 1 argument"), expr)
 		return types.Int
 	}
 
@@ -1279,7 +1442,7 @@ func (c *Checker) checkLenBuiltIn(expr *ast.CallExpr) types.Type {
 	_, isArray := argType.(types.ArrayType)
 	_, isMap := argType.(types.MapType)
 	if argType != types.String && !isArray && !isMap && !isString(argType) {
-		c.appendError(fmt.Sprintf("invalid argument type %s for len()", argType.Signature()), expr)
+		c.appendError(errors.InvalidArgument, fmt.Sprintf("invalid argument type %s for len()", argType.Signature()), expr)
 	}
 
 	return types.Int
@@ -1294,7 +1457,7 @@ func (c *Checker) checkPrintBuiltIn(expr *ast.CallExpr) types.Type {
 
 func (c *Checker) checkFirstBuiltIn(expr *ast.CallExpr) types.Type {
 	if len(expr.Arguments) != 1 {
-		c.appendError(fmt.Sprintf("first() expects exactly 1 argument"), expr)
+		c.appendError(errors.WrongArgumentCount, fmt.Sprintf("first() expects exactly 1 argument"), expr)
 		return types.Int
 	}
 
@@ -1302,7 +1465,7 @@ func (c *Checker) checkFirstBuiltIn(expr *ast.CallExpr) types.Type {
 	arrType, isArray := argType.(types.ArrayType)
 
 	if !isArray {
-		c.appendError(fmt.Sprintf("invalid argument type %s for first()", argType.Signature()), expr)
+		c.appendError(errors.InvalidArgument, fmt.Sprintf("invalid argument type %s for first()", argType.Signature()), expr)
 		return types.Unit
 	}
 
@@ -1311,7 +1474,7 @@ func (c *Checker) checkFirstBuiltIn(expr *ast.CallExpr) types.Type {
 
 func (c *Checker) checkLastBuiltIn(expr *ast.CallExpr) types.Type {
 	if len(expr.Arguments) != 1 {
-		c.appendError(fmt.Sprintf("last() expects exactly 1 argument"), expr)
+		c.appendError(errors.WrongArgumentCount, fmt.Sprintf("last() expects exactly 1 argument"), expr)
 		return types.Int
 	}
 
@@ -1319,7 +1482,7 @@ func (c *Checker) checkLastBuiltIn(expr *ast.CallExpr) types.Type {
 	arrType, isArray := argType.(types.ArrayType)
 
 	if !isArray {
-		c.appendError(fmt.Sprintf("invalid argument type %s for last()", argType.Signature()), expr)
+		c.appendError(errors.InvalidArgument, fmt.Sprintf("invalid argument type %s for last()", argType.Signature()), expr)
 		return types.Unit
 	}
 
@@ -1328,21 +1491,24 @@ func (c *Checker) checkLastBuiltIn(expr *ast.CallExpr) types.Type {
 
 func (c *Checker) checkAppendBuiltIn(expr *ast.CallExpr) types.Type {
 	if len(expr.Arguments) != 2 {
-		c.appendError(fmt.Sprintf("append() expects exactly 2 arguments"), expr)
+		c.appendError(errors.WrongArgumentCount, fmt.Sprintf("append() expects exactly 2 arguments"), expr)
 		return types.Unit
 	}
 	argType := c.typeOf(expr.Arguments[0], nil)
+	if argType == nil {
+		return nil
+	}
 
 	arrType, isArray := argType.(types.ArrayType)
 
 	if !isArray {
-		c.appendError(fmt.Sprintf("invalid argument type %s for append()", argType.Signature()), expr)
+		c.appendError(errors.InvalidArgument, fmt.Sprintf("invalid argument type %s for append()", argType.Signature()), expr)
 		return types.Unit
 	}
 
 	valType := c.typeOf(expr.Arguments[1], nil)
-	if !c.typesMatch(valType, arrType.ElemType) {
-		c.appendError(fmt.Sprintf("type mismatch: got %s for append() value", valType.Signature()), expr)
+	if valType != nil && !c.typesMatch(valType, arrType.ElemType) {
+		c.appendError(errors.MismatchedTypes, fmt.Sprintf("type mismatch: got %s for append() value", valType.Signature()), expr)
 	}
 
 	return arrType
@@ -1350,13 +1516,13 @@ func (c *Checker) checkAppendBuiltIn(expr *ast.CallExpr) types.Type {
 
 func (c *Checker) checkRestBuiltIn(expr *ast.CallExpr) types.Type {
 	if len(expr.Arguments) != 1 {
-		c.appendError(fmt.Sprintf("rest() expects exactly 1 argument"), expr)
+		c.appendError(errors.WrongArgumentCount, fmt.Sprintf("rest() expects exactly 1 argument"), expr)
 	}
 
 	argType := c.typeOf(expr.Arguments[0], nil)
 	arrType, isArray := argType.(types.ArrayType)
 	if !isArray {
-		c.appendError(fmt.Sprintf("invalid argument type %s for rest()", argType.Signature()), expr)
+		c.appendError(errors.InvalidArgument, fmt.Sprintf("invalid argument type %s for rest()", argType.Signature()), expr)
 		return types.Unit
 	}
 
@@ -1365,23 +1531,23 @@ func (c *Checker) checkRestBuiltIn(expr *ast.CallExpr) types.Type {
 
 func (c *Checker) checkSliceBuiltIn(expr *ast.CallExpr) types.Type {
 	if len(expr.Arguments) != 3 {
-		c.appendError(fmt.Sprintf("slice() expects exactly 3 arguments"), expr)
+		c.appendError(errors.WrongArgumentCount, fmt.Sprintf("slice() expects exactly 3 arguments"), expr)
 	}
 	arrayArgType := c.typeOf(expr.Arguments[0], nil)
 	startType := c.typeOf(expr.Arguments[1], types.Int)
 	endType := c.typeOf(expr.Arguments[2], types.Int)
 	arrType, isArray := arrayArgType.(types.ArrayType)
 	if !isArray {
-		c.appendError(fmt.Sprintf("invalid argument type %s for slice()", arrType.Signature()), expr)
+		c.appendError(errors.InvalidArgument, fmt.Sprintf("invalid argument type %s for slice()", arrType.Signature()), expr)
 		return types.Unit
 	}
 
 	if startType != types.Int {
-		c.appendError(fmt.Sprintf("invalid argument type %s for slice()", arrType.Signature()), expr)
+		c.appendError(errors.InvalidArgument, fmt.Sprintf("invalid argument type %s for slice()", arrType.Signature()), expr)
 	}
 
 	if endType != types.Int {
-		c.appendError(fmt.Sprintf("invalid argument type %s for slice()", arrType.Signature()), expr)
+		c.appendError(errors.InvalidArgument, fmt.Sprintf("invalid argument type %s for slice()", arrType.Signature()), expr)
 	}
 
 	return arrType
@@ -1389,12 +1555,12 @@ func (c *Checker) checkSliceBuiltIn(expr *ast.CallExpr) types.Type {
 
 func (c *Checker) checkKeysBuiltIn(expr *ast.CallExpr) types.Type {
 	if len(expr.Arguments) != 1 {
-		c.appendError(fmt.Sprintf("keys() expects exactly 1 argument"), expr)
+		c.appendError(errors.WrongArgumentCount, fmt.Sprintf("keys() expects exactly 1 argument"), expr)
 	}
 	t := c.typeOf(expr.Arguments[0], nil)
 	mapType, ok := t.(types.MapType)
 	if !ok {
-		c.appendError(fmt.Sprintf("invalid argument type %s for keys()", t.Signature()), expr)
+		c.appendError(errors.InvalidArgument, fmt.Sprintf("invalid argument type %s for keys()", t.Signature()), expr)
 	}
 
 	return types.ArrayType{ElemType: mapType.KeyType}
@@ -1402,32 +1568,36 @@ func (c *Checker) checkKeysBuiltIn(expr *ast.CallExpr) types.Type {
 
 func (c *Checker) checkValuesBuiltIn(expr *ast.CallExpr) types.Type {
 	if len(expr.Arguments) != 1 {
-		c.appendError(fmt.Sprintf("values() expects exactly 1 argument"), expr)
+		c.appendError(errors.WrongArgumentCount, fmt.Sprintf("values() expects exactly 1 argument"), expr)
 	}
 	t := c.typeOf(expr.Arguments[0], nil)
 	mapType, ok := t.(types.MapType)
 	if !ok {
-		c.appendError(fmt.Sprintf("invalid argument type %s for keys()", t.Signature()), expr)
+		c.appendError(errors.InvalidArgument, fmt.Sprintf("invalid argument type %s for keys()", t.Signature()), expr)
 	}
 
 	return types.ArrayType{ElemType: mapType.ValueType}
 }
 
-func (c *Checker) checkOkBuiltIn(expr *ast.CallExpr) types.Type {
+func (c *Checker) checkOkBuiltIn(expr *ast.CallExpr, contextType types.Type) types.Type {
 	if len(expr.Arguments) != 1 {
-		c.appendError(fmt.Sprintf("ok() expects exactly 1 argument"), expr)
+		c.appendError(errors.WrongArgumentCount, fmt.Sprintf("ok() expects exactly 1 argument"), expr)
 	}
 
 	t := c.typeOf(expr.Arguments[0], nil)
 
+	// the error type can only come from where the result is used
 	resolved := types.ResultType{T: t}
+	if rt, ok := contextType.(types.ResultType); ok {
+		resolved.E = rt.E
+	}
 	expr.ResolvedType = &resolved
 	return resolved
 }
 
 func (c *Checker) checkSomeBuiltIn(expr *ast.CallExpr) types.Type {
 	if len(expr.Arguments) != 1 {
-		c.appendError(fmt.Sprintf("some() expects exactly 1 argument"), expr)
+		c.appendError(errors.WrongArgumentCount, fmt.Sprintf("some() expects exactly 1 argument"), expr)
 	}
 
 	t := c.typeOf(expr.Arguments[0], nil)
@@ -1439,39 +1609,45 @@ func (c *Checker) checkSomeBuiltIn(expr *ast.CallExpr) types.Type {
 
 func (c *Checker) checkErrBuiltIn(expr *ast.CallExpr, contextType types.Type) types.Type {
 	if len(expr.Arguments) != 1 {
-		c.appendError(fmt.Sprintf("err() expects exactly 1 argument"), expr)
+		c.appendError(errors.WrongArgumentCount, fmt.Sprintf("err() expects exactly 1 argument"), expr)
 	}
 
 	t := c.typeOf(expr.Arguments[0], nil)
-	if t != types.String {
-		c.appendError(fmt.Sprintf("invalid argument type %s for err()", t.Signature()), expr)
-	}
 
 	if contextType == nil && c.currentMatchResultType != nil {
 		contextType = c.currentMatchResultType
-	} else if contextType == nil {
-		c.appendError("cannot infer result type for err()", expr)
-		return types.ResultType{T: types.Unit}
 	}
 	var resolved types.ResultType
 	if rt, ok := contextType.(types.ResultType); ok {
-		resolved = types.ResultType{T: rt.T}
+		resolved = types.ResultType{T: rt.T, E: rt.E}
 	} else {
 		resolved = types.ResultType{T: contextType}
 	}
+
+	errType := resolved.Err()
+	if !c.typesMatch(t, errType) {
+		c.appendError(errors.InvalidArgument, fmt.Sprintf("invalid argument type %s for err(), expected %s", t.Signature(), errType.Signature()), expr)
+	} else {
+		c.boxIfNecessary(expr.Arguments[0], t, errType)
+	}
+
+	if contextType == nil {
+		c.appendError(errors.CannotInfer, "cannot infer result type for err()", expr)
+		return types.ResultType{T: types.Unit}
+	}
 	expr.ResolvedType = &resolved
 	return resolved
 }
 
 func (c *Checker) checkNoneBuiltIn(expr *ast.CallExpr, contextType types.Type) types.Type {
 	if len(expr.Arguments) != 0 {
-		c.appendError(fmt.Sprintf("none() expects no arguments"), expr)
+		c.appendError(errors.WrongArgumentCount, fmt.Sprintf("none() expects no arguments"), expr)
 	}
 
 	if contextType == nil && c.currentMatchResultType != nil {
 		contextType = c.currentMatchResultType
 	} else if contextType == nil {
-		c.appendError("cannot infer option type for none()", expr)
+		c.appendError(errors.CannotInfer, "cannot infer option type for none()", expr)
 		return types.OptionType{T: types.Unit}
 	}
 	var resolved types.OptionType
@@ -1486,11 +1662,11 @@ func (c *Checker) checkNoneBuiltIn(expr *ast.CallExpr, contextType types.Type) t
 
 func (c *Checker) checkChanBuiltIn(expr *ast.CallExpr) types.Type {
 	if len(expr.Arguments) != 0 {
-		c.appendError(fmt.Sprintf("chan() expects no arguments"), expr)
+		c.appendError(errors.WrongArgumentCount, fmt.Sprintf("chan() expects no arguments"), expr)
 		return nil
 	}
 	if len(expr.TypeArgs) != 1 {
-		c.appendError(fmt.Sprintf("chan() expects exactly 1 type argument argument"), expr)
+		c.appendError(errors.WrongArgumentCount, fmt.Sprintf("chan() expects exactly 1 type argument argument"), expr)
 		return nil
 	}
 
@@ -1523,17 +1699,19 @@ func (c *Checker) isInterfaceMethod(t types.Type, name string) (string, bool) {
 
 func (c *Checker) validateFunctionCall(expr *ast.CallExpr, fnTypeRaw types.Type) types.Type {
 	if fnTypeRaw == nil || fnTypeRaw == types.Unit {
-		c.appendError(fmt.Sprintf("unresolved symbol: %s", expr.Function.String()), expr)
+		c.appendError(errors.UnresolvedName, fmt.Sprintf("unresolved symbol: %s", expr.Function.String()), expr)
 		return nil
 	}
 
 	fnType, ok := fnTypeRaw.(types.FunctionType)
 	if !ok {
-		c.appendError(fmt.Sprintf("cannot call non-function %s %s", fnTypeRaw.Signature(), expr.Function.String()), expr)
+		c.appendError(errors.NotCallable, fmt.Sprintf("cannot call non-function %s %s", fnTypeRaw.Signature(), expr.Function.String()), expr)
 		return nil
 	}
 	if len(expr.Arguments) != len(fnType.Params) || fnType.Variadic {
-		c.appendError(fmt.Sprintf("wrong number of arguments for function %s, wanted %d, got %d", expr.Function.String(), len(expr.Arguments), len(fnType.Params)), expr)
+		e := c.newError(errors.WrongArgumentCount, fmt.Sprintf("wrong number of arguments for function %s, wanted %d, got %d", expr.Function.String(), len(fnType.Params), len(expr.Arguments)), expr)
+		e.Notes = append(e.Notes, fmt.Sprintf("%s has type %s", expr.Function.String(), fnType.Signature()))
+		c.report(e)
 		return nil
 	}
 
@@ -1544,7 +1722,11 @@ func (c *Checker) validateFunctionCall(expr *ast.CallExpr, fnTypeRaw types.Type)
 		}
 		pType := fnType.Params[i]
 		if !c.typesMatch(aType, pType) {
-			c.appendError(fmt.Sprintf("type mismatch: got %s for arg %d in function %s call, expected %s", aType.Signature(), i+1, expr.Function.String(), fnType.Params[i].Signature()), expr)
+			e := c.newError(errors.MismatchedTypes, fmt.Sprintf("type mismatch: got %s for arg %d in function %s call, expected %s", aType.Signature(), i+1, expr.Function.String(), fnType.Params[i].Signature()), arg)
+			if fn, ok := expr.Function.(*ast.Identifier); ok {
+				c.declaredHere(&e, fn.Value, fn, fmt.Sprintf("%s declared here", fn.Value))
+			}
+			c.report(e)
 			return nil
 		}
 		c.boxIfNecessary(expr.Arguments[i], aType, pType)
@@ -1562,7 +1744,11 @@ func (c *Checker) structSatisfiesInterface(s types.StructType, i types.Interface
 		mtr, _, ok := c.env.Get(mangledName)
 		if !ok {
 			if appendErr {
-				c.appendError(fmt.Sprintf("struct %s does not satisfy interface %s, missing method %s", s.Name, i.Name, method), node)
+				e := c.newError(errors.InterfaceNotSatisfied, fmt.Sprintf("struct %s does not satisfy interface %s, missing method %s", s.Name, i.Name, method), node)
+				c.declaredHere(&e, mangleMethod(i.Name, method), nil, fmt.Sprintf("%s is required by %s here", method, i.Name))
+				e.Help = fmt.Sprintf("add a function %s taking a %s first", method, s.Name)
+				c.report(e)
+				c.recordUnimplemented(s, i, method)
 			}
 			satisfies = false
 			continue
@@ -1577,7 +1763,7 @@ func (c *Checker) structSatisfiesInterface(s types.StructType, i types.Interface
 		}
 		if !c.compareMethodSignature(mt, emt) {
 			if appendErr {
-				c.appendError(fmt.Sprintf("struct %s does not satisfy interface %s, wrong signature for method %s. got %s, want %s", s.Name, i.Name, method, mt.Signature(), emt.Signature()), node)
+				c.appendError(errors.InterfaceNotSatisfied, fmt.Sprintf("struct %s does not satisfy interface %s, wrong signature for method %s. got %s, want %s", s.Name, i.Name, method, mt.Signature(), emt.Signature()), node)
 			}
 			satisfies = false
 			continue
@@ -1593,7 +1779,7 @@ func (c *Checker) interfaceSatisfiesInterface(i1 types.InterfaceType, i2 types.I
 		i1Idx := slices.Index(i1.Methods, method)
 		if i1Idx == -1 {
 			if node != nil {
-				c.appendError(fmt.Sprintf("interface %s does not satisfy interface %s, missing method %s", i1.Name, i2.Name, method), node)
+				c.appendError(errors.InterfaceNotSatisfied, fmt.Sprintf("interface %s does not satisfy interface %s, missing method %s", i1.Name, i2.Name, method), node)
 			}
 			satisfies = false
 			continue
@@ -1602,7 +1788,7 @@ func (c *Checker) interfaceSatisfiesInterface(i1 types.InterfaceType, i2 types.I
 		i2m := i2.Types[idx]
 		if !c.typesMatch(i1m, i2m) {
 			if node != nil {
-				c.appendError(fmt.Sprintf("interface %s does not satisfy interface %s, method %s has wrong signature. wanted %s, got %s", i1.Name, i2.Name, method, i2m.Signature(), i1m.Signature()), node)
+				c.appendError(errors.InterfaceNotSatisfied, fmt.Sprintf("interface %s does not satisfy interface %s, method %s has wrong signature. wanted %s, got %s", i1.Name, i2.Name, method, i2m.Signature(), i1m.Signature()), node)
 			}
 			satisfies = false
 			continue
@@ -1659,6 +1845,20 @@ func (c *Checker) typesMatch(actual, expected types.Type) bool {
 		}
 	}
 
+	if et, ok := expected.(types.TupleType); ok {
+		if at, ok := actual.(types.TupleType); ok {
+			if len(at.Types) != len(et.Types) {
+				return false
+			}
+			for i := range et.Types {
+				if !c.typesMatch(at.Types[i], et.Types[i]) {
+					return false
+				}
+			}
+			return true
+		}
+	}
+
 	if it, ok := toInterface(expected); ok {
 		if st, ok := toStruct(actual); ok {
 			return c.structSatisfiesInterface(st, it, nil, true)
@@ -1734,26 +1934,29 @@ func (c *Checker) checkIndexAssignment(node *ast.IndexAssignmentStmt) types.Type
 	}
 
 	valType := c.typeOf(node.Value, elemType)
-	if valType == nil {
+	if valType == nil || t == nil || indexOrKeyType == nil {
 		return types.Unit
 	}
 
 	switch colType := t.(type) {
 	case types.ArrayType:
 		if indexOrKeyType != types.Int {
-			c.appendError(fmt.Sprintf("index must be type int, got %s", indexOrKeyType.Signature()), node)
+			c.appendError(errors.InvalidIndex, fmt.Sprintf("index must be type int, got %s", indexOrKeyType.Signature()), node)
 		}
 
 		if !c.typesMatch(valType, colType.ElemType) {
-			c.appendError(fmt.Sprintf("type mismatch: cannot assign %s to element of array of type %s", valType.Signature(), colType.Signature()), node)
+			c.appendError(errors.MismatchedTypes, fmt.Sprintf("type mismatch: cannot assign %s to element of array of type %s", valType.Signature(), colType.Signature()), node)
 		}
 	case types.MapType:
+		if !c.checkMapKey(indexOrKeyType, node.Left.Index) {
+			return types.Unit
+		}
 		if !c.typesMatch(indexOrKeyType, colType.KeyType) {
-			c.appendError(fmt.Sprintf("type mismatch: key for map of type %s must be %s, got %s", colType.Signature(), colType.KeyType.Signature(), indexOrKeyType.Signature()), node)
+			c.appendError(errors.MismatchedTypes, fmt.Sprintf("type mismatch: key for map of type %s must be %s, got %s", colType.Signature(), colType.KeyType.Signature(), indexOrKeyType.Signature()), node)
 		}
 
 		if !c.typesMatch(valType, colType.ValueType) {
-			c.appendError(fmt.Sprintf("type mismatch: cannot assign %s to entry of map of type %s", valType.Signature(), colType.Signature()), node)
+			c.appendError(errors.MismatchedTypes, fmt.Sprintf("type mismatch: cannot assign %s to entry of map of type %s", valType.Signature(), colType.Signature()), node)
 		}
 	}
 
@@ -1779,12 +1982,15 @@ func (c *Checker) checkGenericFunctionBody(node *ast.FunctionDeclarationStmt) {
 	c.currentReturnType = fType.Return
 	oldEnv := c.env
 	c.env = NewTypeEnv(oldEnv)
+	c.instantiating++
 
 	for i, param := range clonedFn.Params {
 		c.env.Set(param.Value, fType.Params[i])
+		c.declare(c.env, param.Value, param, ParameterDeclaration)
 	}
 
 	c.check(clonedFn.Body)
+	c.instantiating--
 
 	c.env = oldEnv
 	c.currentReturnType = oldReturnType
@@ -1810,6 +2016,7 @@ func (c *Checker) checkFunctionDeclaration(node *ast.FunctionDeclarationStmt) ty
 	_, _, ok := c.env.Get(node.Name.Value)
 	if !ok {
 		c.env.Set(name, node.Type)
+		c.declare(c.env, name, node.Name, functionKind(name, node))
 	}
 
 	if !node.IsExtern {
@@ -1822,12 +2029,13 @@ func (c *Checker) checkFunctionDeclaration(node *ast.FunctionDeclarationStmt) ty
 
 		for i, param := range node.Params {
 			c.env.Set(param.Value, fType.Params[i])
+			c.declare(c.env, param.Value, param, ParameterDeclaration)
 		}
 
 		c.check(node.Body)
 
 		if fType.Return != types.Unit && !allPathsReturn(node.Body) {
-			c.appendError(fmt.Sprintf("function %s missing return on all paths", node.Name.Value), node)
+			c.appendError(errors.MissingReturn, fmt.Sprintf("function %s missing return on all paths", node.Name.Value), node)
 		}
 		c.env = oldEnv
 		c.currentReturnType = oldReturnType
@@ -1864,20 +2072,26 @@ func allPathsReturn(block *ast.BlockStmt) bool {
 
 func (c *Checker) checkIndexExpr(e ast.Node, expr *ast.IndexExpr) types.Type {
 	lt := c.typeOf(expr.Left, nil)
+	if tt, ok := lt.(types.TupleType); ok {
+		return c.checkTupleIndex(expr, tt)
+	}
 	idxT := c.typeOf(expr.Index, nil)
+	if lt == nil || idxT == nil {
+		return nil
+	}
 	mt, mok := lt.(types.MapType)
 	at, aok := lt.(types.ArrayType)
 
 	if lt == types.String {
 		if idxT != types.Int {
-			c.appendError(fmt.Sprintf("index must be type int, got %s", idxT.Signature()), expr)
+			c.appendError(errors.InvalidIndex, fmt.Sprintf("index must be type int, got %s", idxT.Signature()), expr)
 		}
 		return types.Byte
 	}
 
 	if aok {
 		if idxT != types.Int {
-			c.appendError(fmt.Sprintf("index type for array must be int, got %s", idxT.Signature()), expr)
+			c.appendError(errors.InvalidIndex, fmt.Sprintf("index type for array must be int, got %s", idxT.Signature()), expr)
 			return nil
 		}
 		expr.ResolvedType = at.ElemType
@@ -1885,8 +2099,9 @@ func (c *Checker) checkIndexExpr(e ast.Node, expr *ast.IndexExpr) types.Type {
 		e = expr
 		return at.ElemType
 	} else if mok {
-		if idxT != mt.KeyType {
-			c.appendError(fmt.Sprintf("index type for map %s must be %s, got %s", mt.Signature(), mt.KeyType.Signature(), idxT.Signature()), expr)
+		c.checkMapKey(idxT, expr.Index)
+		if !c.typesMatch(idxT, mt.KeyType) {
+			c.appendError(errors.InvalidIndex, fmt.Sprintf("index type for map %s must be %s, got %s", mt.Signature(), mt.KeyType.Signature(), idxT.Signature()), expr)
 			return nil
 		}
 
@@ -1897,7 +2112,7 @@ func (c *Checker) checkIndexExpr(e ast.Node, expr *ast.IndexExpr) types.Type {
 		e = expr
 		return optType
 	}
-	c.appendError(fmt.Sprintf("index operation undefined for type: %s", lt.Signature()), expr)
+	c.appendError(errors.InvalidIndex, fmt.Sprintf("index operation undefined for type: %s", lt.Signature()), expr)
 
 	return nil
 }
@@ -1916,6 +2131,10 @@ func (c *Checker) extractDeclNameAndType(stmt ast.Stmt, pkgName string) (string,
 		it := stmt.Type
 		it.Module = pkgName
 		return stmt.Name.Value, it
+	case *ast.EnumDefinitionStmt:
+		et := stmt.Type
+		et.Module = pkgName
+		return stmt.Name.Value, et
 	}
 
 	return "", nil
@@ -1924,13 +2143,26 @@ func (c *Checker) extractDeclNameAndType(stmt ast.Stmt, pkgName string) (string,
 func (c *Checker) checkMatchExpr(expr *ast.MatchExpr) types.Type {
 	subType := c.typeOf(expr.Subject, nil)
 
+	enum, isEnum := subType.(types.EnumType)
+	if expr.Arms != nil || expr.Default != nil {
+		if !isEnum {
+			c.appendError(errors.InvalidMatch, fmt.Sprintf("can only match variants on an enum, got %s", subType.Signature()), expr)
+			return nil
+		}
+		return c.checkEnumMatch(expr, enum)
+	}
+	if isEnum {
+		c.appendError(errors.InvalidMatch, fmt.Sprintf("match on enum %s must name its variants", enum.Signature()), expr)
+		return nil
+	}
+
 	if option, ok := subType.(types.OptionType); ok {
 		return c.checkOptionMatch(expr, option)
 	}
 
 	result, ok := subType.(types.ResultType)
 	if !ok {
-		c.appendError(fmt.Sprintf("can only match on result or option type"), expr)
+		c.appendError(errors.InvalidMatch, fmt.Sprintf("can only match on result or option type"), expr)
 		return nil
 	}
 	expr.SubjectType = result.T
@@ -1940,34 +2172,34 @@ func (c *Checker) checkMatchExpr(expr *ast.MatchExpr) types.Type {
 
 	okEnv := NewTypeEnv(c.env)
 	okEnv.Set(expr.OkArm.Pattern.Binding.Value, c.resolveType(result.T))
+	c.declare(okEnv, expr.OkArm.Pattern.Binding.Value, expr.OkArm.Pattern.Binding, VariableDeclaration)
 	oldEnv := c.env
 	c.env = okEnv
 	okBranch := c.check(expr.OkArm.Body)
 	if okBranch == nil {
-		c.appendError(fmt.Sprintf("cannot resolve type for ok branch"), expr)
+		c.appendError(errors.CannotInfer, fmt.Sprintf("cannot resolve type for ok branch"), expr)
 		c.inDiscardPosition = discard
 		return nil
 	}
 	c.env = oldEnv
 
+	errType := c.resolveType(result.Err())
+	expr.ErrArm.Pattern.Binding.SetResolvedType(errType)
 	errEnv := NewTypeEnv(c.env)
-	errEnv.Set(expr.ErrArm.Pattern.Binding.Value, types.String)
+	errEnv.Set(expr.ErrArm.Pattern.Binding.Value, errType)
+	c.declare(errEnv, expr.ErrArm.Pattern.Binding.Value, expr.ErrArm.Pattern.Binding, VariableDeclaration)
 	oldEnv = c.env
 	c.env = errEnv
 	oldMatchResultType := c.currentMatchResultType
-	if rt, ok := okBranch.(types.ResultType); ok {
-		c.currentMatchResultType = rt.T
-	} else {
-		c.currentMatchResultType = okBranch
-	}
+	c.currentMatchResultType = okBranch
 	errBranch := c.check(expr.ErrArm.Body)
 	if errBranch == nil {
-		c.appendError(fmt.Sprintf("cannot resolve type for err branch"), expr)
+		c.appendError(errors.CannotInfer, fmt.Sprintf("cannot resolve type for err branch"), expr)
 		c.inDiscardPosition = discard
 		return nil
 	}
 	if !discard && !c.typesMatch(errBranch, okBranch) {
-		c.appendError(fmt.Sprintf("type mismatch: match arms must result in same type, got %s and %s", okBranch.Signature(), errBranch.Signature()), expr)
+		c.appendError(errors.MismatchedTypes, fmt.Sprintf("type mismatch: match arms must result in same type, got %s and %s", okBranch.Signature(), errBranch.Signature()), expr)
 	}
 	c.inDiscardPosition = discard
 	c.env = oldEnv
@@ -1988,7 +2220,7 @@ func (c *Checker) checkTypeMatchExpr(expr *ast.MatchTypeExpr) types.Type {
 	isAny := subjType == types.Any
 	if !isAny {
 		if _, ok := subjType.(types.InterfaceType); !ok {
-			c.appendError("match typeof requires any or interface type", expr)
+			c.appendError(errors.InvalidMatch, "match typeof requires any or interface type", expr)
 			return types.Unit
 		}
 	}
@@ -2001,7 +2233,7 @@ func (c *Checker) checkTypeMatchExpr(expr *ast.MatchTypeExpr) types.Type {
 		if it, ok := subjType.(types.InterfaceType); ok {
 			if st, ok := resolved.(types.StructType); ok {
 				if !c.structSatisfiesInterface(st, it, expr, true) {
-					c.appendError(fmt.Sprintf("struct %s does not satisfy interface %s", st.Name, it.Name), expr)
+					c.appendError(errors.InterfaceNotSatisfied, fmt.Sprintf("struct %s does not satisfy interface %s", st.Name, it.Name), expr)
 					c.inDiscardPosition = discard
 					return types.Unit
 				}
@@ -2009,6 +2241,7 @@ func (c *Checker) checkTypeMatchExpr(expr *ast.MatchTypeExpr) types.Type {
 		}
 		c.pushScope()
 		c.env.Set(arm.Binding.Value, resolved)
+		c.declare(c.env, arm.Binding.Value, arm.Binding, VariableDeclaration)
 		retType := c.check(arm.Body)
 		c.popScope()
 		returnedTypes[i] = retType
@@ -2028,8 +2261,8 @@ func (c *Checker) checkTypeMatchExpr(expr *ast.MatchTypeExpr) types.Type {
 			resultType = t
 		}
 
-		if !discard && resultType != t {
-			c.appendError("all arms of type match must result in same type", expr)
+		if !discard && !c.typesMatch(t, resultType) {
+			c.appendError(errors.MismatchedTypes, "all arms of type match must result in same type", expr)
 			return types.Unit
 		}
 	}
@@ -2045,11 +2278,12 @@ func (c *Checker) checkOptionMatch(expr *ast.MatchExpr, option types.OptionType)
 
 	someEnv := NewTypeEnv(c.env)
 	someEnv.Set(expr.SomeArm.Pattern.Binding.Value, option.T)
+	c.declare(someEnv, expr.SomeArm.Pattern.Binding.Value, expr.SomeArm.Pattern.Binding, VariableDeclaration)
 	oldEnv := c.env
 	c.env = someEnv
 	someBranch := c.check(expr.SomeArm.Body)
 	if someBranch == nil {
-		c.appendError("cannot resolve type for some branch", expr)
+		c.appendError(errors.CannotInfer, "cannot resolve type for some branch", expr)
 		c.inDiscardPosition = discard
 		return nil
 	}
@@ -2060,12 +2294,12 @@ func (c *Checker) checkOptionMatch(expr *ast.MatchExpr, option types.OptionType)
 	c.env = noneEnv
 	noneBranch := c.check(expr.NoneArm.Body)
 	if noneBranch == nil {
-		c.appendError("cannot resolve type for none branch", expr)
+		c.appendError(errors.CannotInfer, "cannot resolve type for none branch", expr)
 		c.inDiscardPosition = discard
 		return nil
 	}
 	if !discard && !c.typesMatch(noneBranch, someBranch) {
-		c.appendError(fmt.Sprintf("type mismatch: match arms must result in same type, got %s and %s", someBranch.Signature(), noneBranch.Signature()), expr)
+		c.appendError(errors.MismatchedTypes, fmt.Sprintf("type mismatch: match arms must result in same type, got %s and %s", someBranch.Signature(), noneBranch.Signature()), expr)
 	}
 	c.inDiscardPosition = discard
 	c.env = oldEnv
@@ -2090,7 +2324,7 @@ func (c *Checker) doResolveType(t types.Type) types.Type {
 	case types.ScopeType:
 		tt, ok := c.moduleTypes[t.Module][t.Name]
 		if !ok {
-			c.appendError(fmt.Sprintf("module %s type %s is not declared", t.Module, t.Name), nil)
+			c.appendError(errors.UnknownType, fmt.Sprintf("module %s type %s is not declared", t.Module, t.Name), nil)
 			return nil
 		}
 		switch resolved := tt.(type) {
@@ -2104,6 +2338,11 @@ func (c *Checker) doResolveType(t types.Type) types.Type {
 				resolved.Module = t.Module
 			}
 			return resolved
+		case types.EnumType:
+			if resolved.Module == "" {
+				resolved.Module = t.Module
+			}
+			return resolved
 		}
 		return tt
 	case types.MapType:
@@ -2132,13 +2371,27 @@ func (c *Checker) doResolveType(t types.Type) types.Type {
 		}
 		return t
 	case types.ResultType:
-		rt := c.resolveType(t.T)
-		return types.ResultType{T: rt}
+		rt := types.ResultType{T: c.resolveType(t.T)}
+		if t.E != nil {
+			rt.E = c.resolveType(t.E)
+		}
+		return rt
 	case types.OptionType:
 		rt := c.resolveType(t.T)
 		return types.OptionType{T: rt}
 	case types.FunctionType:
 		return c.resolveFunctionType(t)
+	case types.EnumType:
+		for i, typ := range t.TypeArgs {
+			t.TypeArgs[i] = c.resolveType(typ)
+		}
+		return t
+	case types.TupleType:
+		elems := make([]types.Type, len(t.Types))
+		for i, typ := range t.Types {
+			elems[i] = c.resolveType(typ)
+		}
+		return types.TupleType{Types: elems}
 	}
 
 	return t
@@ -2154,12 +2407,12 @@ func (c *Checker) resolveFunctionType(ft types.FunctionType) types.FunctionType
 
 func (c *Checker) checkFloatBuiltIn(expr *ast.CallExpr) types.Type {
 	if len(expr.Arguments) != 1 {
-		c.appendError("float() expects exactly 1 argument", expr)
+		c.appendError(errors.WrongArgumentCount, "float() expects exactly 1 argument", expr)
 		return types.Float
 	}
 	t := c.typeOf(expr.Arguments[0], nil)
 	if t != types.Int && t != types.Byte && t != types.Float {
-		c.appendError(fmt.Sprintf("invalid argument type %s for float(), expected int or byte", t.Signature()), expr)
+		c.appendError(errors.InvalidArgument, fmt.Sprintf("invalid argument type %s for float(), expected int or byte", t.Signature()), expr)
 	}
 
 	return types.Float
@@ -2167,13 +2420,13 @@ func (c *Checker) checkFloatBuiltIn(expr *ast.CallExpr) types.Type {
 
 func (c *Checker) checkPanicCall(expr *ast.CallExpr) types.Type {
 	if len(expr.Arguments) != 1 {
-		c.appendError("panic() expects exactly 1 argument", expr)
+		c.appendError(errors.WrongArgumentCount, "panic() expects exactly 1 argument", expr)
 		return types.Never
 	}
 
 	t := c.typeOf(expr.Arguments[0], types.String)
 	if t != types.String {
-		c.appendError(fmt.Sprintf("invalid argument type %s for panic(), expected string", t.Signature()), expr)
+		c.appendError(errors.InvalidArgument, fmt.Sprintf("invalid argument type %s for panic(), expected string", t.Signature()), expr)
 	}
 	return types.Never
 }
@@ -2196,7 +2449,7 @@ func (c *Checker) monomorphizeCall(expr *ast.CallExpr, template *ast.FunctionDec
 	templateType := template.Type.(types.FunctionType)
 
 	if len(expr.TypeArgs) != len(template.TypeParams) {
-		c.appendError(fmt.Sprintf("%s expects exactly %d type arguments", funcName, len(template.TypeParams)), expr)
+		c.appendError(errors.WrongArgumentCount, fmt.Sprintf("%s expects exactly %d type arguments", funcName, len(template.TypeParams)), expr)
 		return nil
 	}
 
@@ -2226,7 +2479,9 @@ func (c *Checker) monomorphizeCall(expr *ast.CallExpr, template *ast.FunctionDec
 		clonedFn.TypeParams = nil // no longer generic
 		ast.SubstituteTypeParams(clonedFn.Body, subs)
 
+		c.instantiating++
 		c.checkFunctionDeclaration(clonedFn)
+		c.instantiating--
 
 		if c.program != nil {
 			c.pendingInserts[c.stmtIndex] = append(c.pendingInserts[c.stmtIndex], clonedFn)
@@ -2259,7 +2514,7 @@ func (c *Checker) monomorphizeStructLiteral(expr *ast.StructLiteral, template ty
 		}
 	}()
 	if len(expr.TypeArgs) != len(template.TypeParams) {
-		c.appendError(fmt.Sprintf("%s expects exactly %d type arguments", expr.Name, len(template.TypeParams)), expr)
+		c.appendError(errors.WrongArgumentCount, fmt.Sprintf("%s expects exactly %d type arguments", expr.Name, len(template.TypeParams)), expr)
 		return types.StructType{}, false
 	}
 
@@ -2448,18 +2703,10 @@ func mangleMethod(module, name string) string {
 	return module + "." + name
 }
 
-func (c *Checker) appendError(msg string, node ast.Node) {
-	e := errors.PositionError{Message: msg, File: c.currentModule}
-	if node != nil {
-		e.Line, e.Col = node.Pos()
-	}
-	c.errors = append(c.errors, e)
-}
-
 func (c *Checker) inferTypeArgs(expr *ast.CallExpr, template *ast.FunctionDeclarationStmt) []types.Type {
 	templateType := template.Type.(types.FunctionType)
 	if len(expr.Arguments) != len(templateType.Params) {
-		c.appendError(fmt.Sprintf("%s expects %d arguments", template.Name.Value, len(templateType.Params)), expr)
+		c.appendError(errors.WrongArgumentCount, fmt.Sprintf("%s expects %d arguments", template.Name.Value, len(templateType.Params)), expr)
 		return nil
 	}
 
@@ -2469,11 +2716,11 @@ func (c *Checker) inferTypeArgs(expr *ast.CallExpr, template *ast.FunctionDeclar
 		c.unifyType(p, argType, subs)
 	}
 
-	result := make([]types.Type, len(templateType.Params))
+	result := make([]types.Type, len(template.TypeParams))
 	for i, p := range template.TypeParams {
 		resolved, ok := subs[p.Name]
 		if !ok {
-			c.appendError(fmt.Sprintf("cannot infer type parameter %s for %s", p.Name, template.Name.Value), expr)
+			c.appendError(errors.CannotInfer, fmt.Sprintf("cannot infer type parameter %s for %s", p.Name, template.Name.Value), expr)
 			return nil
 		}
 
@@ -2501,6 +2748,21 @@ func (c *Checker) unifyType(p types.Type, arg types.Type, subs map[string]types.
 	case types.ResultType:
 		if a, ok := arg.(types.ResultType); ok {
 			c.unifyType(t.T, a.T, subs)
+			if t.E != nil && a.E != nil {
+				c.unifyType(t.E, a.E, subs)
+			}
+		}
+	case types.EnumType:
+		if a, ok := arg.(types.EnumType); ok && a.Name == t.Name && len(a.TypeArgs) == len(t.TypeArgs) {
+			for i := range t.TypeArgs {
+				c.unifyType(t.TypeArgs[i], a.TypeArgs[i], subs)
+			}
+		}
+	case types.TupleType:
+		if a, ok := arg.(types.TupleType); ok && len(a.Types) == len(t.Types) {
+			for i := range t.Types {
+				c.unifyType(t.Types[i], a.Types[i], subs)
+			}
 		}
 	}
 }
@@ -2551,6 +2813,7 @@ func (c *Checker) discoverImplementations() {
 	for sn, st := range c.definedStructs {
 		for _, it := range c.candidateInterfaces(sn) {
 			if c.structSatisfiesInterface(st, it, nil, false) {
+				c.linkImplementation(sn, it)
 				st.Interfaces = append(st.Interfaces, it)
 				c.definedStructs[sn] = st
 				if node, ok := c.structNodes[sn]; ok {
@@ -2599,9 +2862,21 @@ func containsTypeParamRef(t types.Type) bool {
 			}
 		}
 	case types.ResultType:
-		return containsTypeParamRef(tt.T)
+		return containsTypeParamRef(tt.T) || (tt.E != nil && containsTypeParamRef(tt.E))
 	case types.OptionType:
 		return containsTypeParamRef(tt.T)
+	case types.EnumType:
+		for _, ta := range tt.TypeArgs {
+			if containsTypeParamRef(ta) {
+				return true
+			}
+		}
+	case types.TupleType:
+		for _, et := range tt.Types {
+			if containsTypeParamRef(et) {
+				return true
+			}
+		}
 	}
 	return false
 }
@@ -1,8 +1,11 @@
 package typechecker
 
 import (
+	"fmt"
+	"slices"
 	"strings"
 	"sydney/ast"
+	"sydney/errors"
 	"sydney/lexer"
 	"sydney/parser"
 	"testing"
@@ -1849,6 +1852,488 @@ func TestTypeMatchExprErrors(t *testing.T) {
 	testTypeErrors(t, tt)
 }
 
+func TestEnums(t *testing.T) {
+	sources := []string{
+		`define enum Shape { Circle(float), Rect(float, float), Empty }
+		func area(Shape s) -> float {
+			return match s {
+				Circle(r) -> { 3.14 * r * r; },
+				Rect(w, h) -> { w * h; },
+				Empty -> { 0.0; },
+			};
+		}
+		area(Shape.Rect(2.0, 3.0));
+		area(Shape.Empty);`,
+
+		`define enum Shape { Circle(float), Rect(float, float), Empty }
+		const s = Shape.Circle(1.0);
+		const round = match s {
+			Circle(_) -> { true; },
+			_ -> { false; },
+		};`,
+
+		`define enum Tree<T> { Leaf(T), Node(Tree<T>, Tree<T>) }
+		func sum(Tree<int> t) -> int {
+			return match t {
+				Leaf(v) -> { v; },
+				Node(l, r) -> { sum(l) + sum(r); },
+			};
+		}
+		sum(Tree.Node(Tree.Leaf(1), Tree.Leaf(2)));`,
+
+		`define enum Maybe<T> { Just(T), Nothing }
+		const Maybe<string> m = Maybe.Nothing;
+		const string s = match m {
+			Just(v) -> { v; },
+			Nothing -> { "none"; },
+		};`,
+	}
+	for _, src := range sources {
+		l := lexer.New(src)
+		p := parser.New(l)
+		program := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			t.Fatalf("parser errors: %v", p.Errors())
+		}
+		c := New(nil)
+		c.Check(program, nil)
+		if len(c.Errors()) != 0 {
+			t.Fatalf("input %q expected no errors, got %v", src, c.Errors())
+		}
+	}
+}
+
+func TestEnumErrors(t *testing.T) {
+	tt := []TypeErrorTest{
+		{
+			input: `define enum Shape { Circle(float), Empty }
+			Shape.Square(1.0);`,
+			expectedError: "enum Shape has no variant Square",
+		},
+		{
+			input: `define enum Shape { Circle(float), Empty }
+			Shape.Circle(1.0, 2.0);`,
+			expectedError: "variant Shape.Circle takes 1 values, got 2",
+		},
+		{
+			input: `define enum Shape { Circle(float), Empty }
+			Shape.Circle("one");`,
+			expectedError: "type mismatch for value 1 of Shape.Circle: expected float, got string",
+		},
+		{
+			input: `define enum Maybe<T> { Just(T), Nothing }
+			const m = Maybe.Nothing;`,
+			expectedError: "cannot infer type parameter T for Maybe.Nothing",
+		},
+		{
+			input: `define enum Maybe<T> { Just(T), Nothing }
+			const Maybe<int> m = Maybe.Just("one");`,
+			expectedError: "type mismatch for value 1 of Maybe.Just: expected int, got string",
+		},
+		{
+			input: `define enum Shape { Circle(float), Empty }
+			const s = Shape.Empty;
+			match s {
+				Circle(r, x) -> { r; },
+				Empty -> { 0.0; },
+			};`,
+			expectedError: "variant Shape.Circle has 1 values, but the arm binds 2",
+		},
+		{
+			input: `define enum Shape { Circle(float), Empty }
+			const s = Shape.Empty;
+			match s {
+				Circle(r) -> { r; },
+				Circle(r) -> { r; },
+				Empty -> { 0.0; },
+			};`,
+			expectedError: "variant Circle is matched more than once",
+		},
+		{
+			input: `define enum Shape { Circle(float), Empty }
+			const s = Shape.Empty;
+			const x = match s {
+				Circle(r) -> { r; },
+				Empty -> { "none"; },
+			};`,
+			expectedError: "match arms must result in same type",
+		},
+		{
+			input: `define enum Shape { Circle(float), Empty }
+			const s = Shape.Empty;
+			match s {
+				ok(v) -> { v; },
+				err(e) -> { 0.0; },
+			};`,
+			expectedError: "match on enum Shape must name its variants",
+		},
+		{
+			input: `const n = 1;
+			match n {
+				Circle(r) -> { r; },
+				_ -> { 0; },
+			};`,
+			expectedError: "can only match variants on an enum, got int",
+		},
+	}
+
+	testTypeErrors(t, tt)
+}
+
+func TestTuples(t *testing.T) {
+	sources := []string{
+		`func divmod(int a, int b) -> (int, int) {
+			return (a / b, a % b);
+		}
+		const (q, r) = divmod(17, 5);
+		const int sum = q + r;`,
+
+		`const t = (1, "one", 1.0);
+		const int n = t[0];
+		const string s = t[1];
+		mut (x, _, y) = t;
+		x = 2;`,
+
+		`const pairs = [(1, "a"), (2, "b")];
+		for (i, (n, s) in pairs) {
+			const int total = i + n;
+			const string name = s;
+		}`,
+
+		`define interface Shape { area() -> float }
+		define struct Square { side float }
+		func area(Square s) -> float { return s.side * s.side; }
+		const (Shape, int) p = (Square { side: 2.0 }, 1);
+		p[0].area();`,
+
+		`const (any, int) p = (1, 2);
+		mut (int, string) z;
+		z = (1, "one");`,
+
+		`define struct Circle { radius float }
+		define interface Shape { area() -> float }
+		func area(Circle c) -> float { c.radius * c.radius * 3.14; }
+		func describe(Shape s) -> (string, float) {
+			match typeof s {
+				Circle(c) -> { ("circle", c.radius); },
+				_ -> { ("shape", 0.0); },
+			};
+		}`,
+	}
+	for _, src := range sources {
+		l := lexer.New(src)
+		p := parser.New(l)
+		program := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			t.Fatalf("parser errors: %v", p.Errors())
+		}
+		c := New(nil)
+		c.Check(program, nil)
+		if len(c.Errors()) != 0 {
+			t.Fatalf("input %q expected no errors, got %v", src, c.Errors())
+		}
+	}
+}
+
+func TestTupleErrors(t *testing.T) {
+	tt := []TypeErrorTest{
+		{
+			input:         `const (a, b, c) = (1, 2);`,
+			expectedError: "cannot destructure (int, int) into 3 names",
+		},
+		{
+			input:         `const (a, b) = 1;`,
+			expectedError: "cannot destructure int, expected a tuple",
+		},
+		{
+			input: `const t = (1, 2);
+			t[2];`,
+			expectedError: "index 2 out of range for tuple (int, int) of 2 values",
+		},
+		{
+			input: `const t = (1, 2);
+			const i = 0;
+			t[i];`,
+			expectedError: "tuple (int, int) must be indexed by an int literal, got i",
+		},
+		{
+			input:         `const (int, string) t = (1, 2);`,
+			expectedError: "cannot assign (int, int) to variable t of type (int, string)",
+		},
+		{
+			input: `const (a, b) = (1, 2);
+			a = 3;`,
+			expectedError: "cannot assign to constant variable a",
+		},
+		{
+			input:         `const (a, a) = (1, 2);`,
+			expectedError: "variable a already declared",
+		},
+		{
+			input:         `for ((a, b) in [1, 2]) { a; }`,
+			expectedError: "cannot destructure int, expected a tuple",
+		},
+		{
+			input: `const a = (1, 2);
+			const b = a == (1, 2);`,
+			expectedError: "invalid operation: == is not defined for type (int, int)",
+		},
+		{
+			input:         `const b = (1, "a") != (1, "b");`,
+			expectedError: "invalid operation: != is not defined for type (int, string)",
+		},
+		{
+			input: `const m = {(1, 2): 3};
+			m[(1, 2)];`,
+			expectedError: "tuple (int, int) cannot be a map key",
+		},
+		{
+			input: `const m = {1: 2};
+			m[(1, 2)];`,
+			expectedError: "tuple (int, int) cannot be a map key",
+		},
+		{
+			input: `mut map<(int, int), int> m = {};
+			m[(1, 2)] = 3;`,
+			expectedError: "tuple (int, int) cannot be a map key",
+		},
+	}
+
+	testTypeErrors(t, tt)
+}
+
+func TestTryExpr(t *testing.T) {
+	sources := []string{
+		`func half(int n) -> result<int> {
+			if (n % 2 != 0) { return err("odd"); }
+			return ok(n / 2);
+		}
+		func quarter(int n) -> result<string> {
+			const int q = half(half(n)?)?;
+			return ok("done");
+		}`,
+
+		`func find(array<int> xs, int x) -> option<int> {
+			for (i, v in xs) {
+				if (v == x) { return some(i); }
+			}
+			return none();
+		}
+		func both(array<int> xs) -> option<(int, int)> {
+			return some((find(xs, 1)?, find(xs, 2)?));
+		}`,
+
+		`func lookup(map<string, int> m, string k) -> option<int> {
+			const f = func() -> option<int> {
+				return some(m[k]? + 1);
+			};
+			return f();
+		}`,
+	}
+	for _, src := range sources {
+		l := lexer.New(src)
+		p := parser.New(l)
+		program := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			t.Fatalf("parser errors: %v", p.Errors())
+		}
+		c := New(nil)
+		c.Check(program, nil)
+		if len(c.Errors()) != 0 {
+			t.Fatalf("input %q expected no errors, got %v", src, c.Errors())
+		}
+	}
+}
+
+func TestTryExprErrors(t *testing.T) {
+	tt := []TypeErrorTest{
+		{
+			input: `func f() -> result<int> {
+				const x = 1;
+				return ok(x?);
+			}`,
+			expectedError: "invalid operation: ? is not defined for type int",
+		},
+		{
+			input: `func half(int n) -> result<int> { return ok(n / 2); }
+			func f() -> int { return half(4)?; }`,
+			expectedError: "type mismatch: ? on result<int> cannot return from function returning int",
+		},
+		{
+			input: `func half(int n) -> result<int> { return ok(n / 2); }
+			func f() -> option<int> { return some(half(4)?); }`,
+			expectedError: "type mismatch: ? on result<int> cannot return from function returning option<int>",
+		},
+		{
+			input: `func g() -> option<int> { return some(1); }
+			func f() { g()?; }`,
+			expectedError: "type mismatch: ? on option<int> cannot return from function returning unit",
+		},
+		{
+			input: `func g() -> option<int> { return some(1); }
+			const x = g()?;`,
+			expectedError: "? operator outside of function body",
+		},
+	}
+
+	testTypeErrors(t, tt)
+}
+
+func TestTypedErrors(t *testing.T) {
+	sources := []string{
+		`define enum NetError { Timeout(int), Refused }
+		func dial(int n) -> result<int, NetError> {
+			if (n == 0) { return err(NetError.Refused); }
+			return ok(n);
+		}
+		func fetch(int n) -> result<string, NetError> {
+			const int fd = dial(n)?;
+			return ok("fetched");
+		}
+		const int secs = match fetch(1) {
+			ok(s) -> { 0; },
+			err(e) -> {
+				match e {
+					Timeout(s) -> { s; },
+					Refused -> { -1; },
+				};
+			},
+		};`,
+
+		`define struct ParseError { line int, msg string }
+		define interface Describe { describe() -> string }
+		func describe(ParseError p) -> string { return p.msg; }
+		func parse(int n) -> result<int, Describe> {
+			return err(ParseError { line: n, msg: "bad" });
+		}
+		match parse(1) {
+			ok(v) -> { print(v); },
+			err(d) -> { print(d.describe()); },
+		};`,
+
+		`const result<int, string> r = err("same as result<int>");
+		const result<int> s = r;`,
+	}
+	for _, src := range sources {
+		l := lexer.New(src)
+		p := parser.New(l)
+		program := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			t.Fatalf("parser errors: %v", p.Errors())
+		}
+		c := New(nil)
+		c.Check(program, nil)
+		if len(c.Errors()) != 0 {
+			t.Fatalf("input %q expected no errors, got %v", src, c.Errors())
+		}
+	}
+}
+
+func TestTypedErrorErrors(t *testing.T) {
+	tt := []TypeErrorTest{
+		{
+			input: `define enum NetError { Timeout(int), Refused }
+			func f() -> result<int, NetError> { return err("refused"); }`,
+			expectedError: "invalid argument type string for err(), expected NetError",
+		},
+		{
+			input: `define enum NetError { Timeout(int), Refused }
+			define struct ParseError { line int }
+			func parse() -> result<int, ParseError> { return ok(1); }
+			func f() -> result<int, NetError> { return ok(parse()?); }`,
+			expectedError: "? on result<int, ParseError> cannot return ParseError errors from function returning result<int, NetError>",
+		},
+		{
+			input: `define enum NetError { Timeout(int), Refused }
+			const result<int, NetError> r = ok(1);
+			const result<int> s = r;`,
+			expectedError: "cannot assign result<int, NetError> to variable s of type result<int>",
+		},
+		{
+			input: `define enum NetError { Timeout(int), Refused }
+			func reason(result<int, NetError> r) -> string {
+				return match r {
+					ok(v) -> { "fine"; },
+					err(e) -> { e; },
+				};
+			}`,
+			expectedError: "NetError",
+		},
+	}
+
+	testTypeErrors(t, tt)
+}
+
+func TestDeferStmt(t *testing.T) {
+	sources := []string{
+		`define struct File { fd int }
+		func close(File f) -> result<int> { return ok(f.fd); }
+		func read(int fd) -> int {
+			const f = File { fd: fd };
+			defer f.close();
+			defer print("read", fd);
+			if (fd < 0) { return -1; }
+			return fd;
+		}`,
+
+		`func each(array<int> xs) {
+			for (x in xs) {
+				defer print(x);
+			}
+		}
+		const f = func() -> int {
+			defer print("closure");
+			return 1;
+		};`,
+
+		`define interface Closer { close() -> int }
+		func cleanup(Closer c) -> int {
+			defer c.close();
+			return 0;
+		}`,
+	}
+	for _, src := range sources {
+		l := lexer.New(src)
+		p := parser.New(l)
+		program := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			t.Fatalf("parser errors: %v", p.Errors())
+		}
+		c := New(nil)
+		c.Check(program, nil)
+		if len(c.Errors()) != 0 {
+			t.Fatalf("input %q expected no errors, got %v", src, c.Errors())
+		}
+	}
+}
+
+func TestDeferStmtErrors(t *testing.T) {
+	tt := []TypeErrorTest{
+		{
+			input:         `defer print("done");`,
+			expectedError: "defer statement outside of function body",
+		},
+		{
+			input:         `func f() { defer 1 + 2; }`,
+			expectedError: "must defer function call",
+		},
+		{
+			input: `func g() -> result<int> { return ok(1); }
+			func f() -> result<int> {
+				defer print(g()?);
+				return ok(1);
+			}`,
+			expectedError: "? operator in deferred call",
+		},
+		{
+			input:         `func f(int x) { defer print(x + "s"); }`,
+			expectedError: "cannot add types int and string",
+		},
+	}
+
+	testTypeErrors(t, tt)
+}
+
 func TestMonomorphizedFunctionInsertedBeforeCall(t *testing.T) {
 	src := `func identity<T>(T x) -> T { return x; }
 	const int r = identity<int>(42);`
@@ -1892,3 +2377,468 @@ func TestMonomorphizedFunctionInsertedBeforeCall(t *testing.T) {
 		t.Errorf("monomorphized function at index %d should appear before call site at index %d", fnIdx, callIdx)
 	}
 }
+
+func TestDefinitions(t *testing.T) {
+	src := `define struct Point { x int, y int }
+define interface Shape { area() -> float }
+func area(Point p) -> float { return float(p.x * p.y); }
+func scale(Point p, int k) -> Point {
+	mut Point q = Point { x: p.x * k, y: p.y };
+	q = Point { x: q.x, y: p.y * k };
+	return q;
+}
+const Point origin = Point { x: 0, y: 0 };
+const float a = origin.area();
+func measure(Shape s) -> float { return s.area(); }
+`
+	tests := []struct {
+		line, col         int
+		wantLine, wantCol int
+	}{
+		{3, 46, 1, 23},  // p.x -> field x
+		{3, 44, 3, 17},  // p -> param
+		{5, 16, 1, 15},  // Point literal -> struct
+		{6, 2, 5, 12},   // q assignment -> local
+		{6, 17, 5, 12},  // q.x -> local q
+		{7, 9, 5, 12},   // return q
+		{10, 17, 9, 13}, // origin -> global
+		{10, 24, 3, 6},  // origin.area() -> method
+		{11, 43, 2, 26}, // s.area() -> interface method
+	}
+
+	p := parser.New(lexer.New(src))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+	c := New(nil)
+	c.Check(program, nil)
+	if len(c.Errors()) != 0 {
+		t.Fatalf("expected no errors, got %v", c.Errors())
+	}
+
+	for _, tt := range tests {
+		decl, ok := c.Definition("", tt.line, tt.col)
+		if !ok {
+			t.Errorf("%d:%d: no definition found", tt.line, tt.col)
+			continue
+		}
+		line, col := decl.Name.Pos()
+		if line != tt.wantLine || col != tt.wantCol {
+			t.Errorf("%d:%d: expected definition at %d:%d, got %s at %d:%d", tt.line, tt.col, tt.wantLine, tt.wantCol, decl.Name.Value, line, col)
+		}
+	}
+}
+
+func TestReferences(t *testing.T) {
+	src := `define struct Point { x int, y int }
+define interface Summer { sum() -> int }
+func sum(Point p) -> int { return p.x + p.y; }
+const int x = 1;
+func shadow() -> int { const int x = 2; return x; }
+const Point o = Point { x: x, y: 2 };
+const int s = o.sum();
+`
+	p := parser.New(lexer.New(src))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+	c := New(nil)
+	c.Check(program, nil)
+	if len(c.Errors()) != 0 {
+		t.Fatalf("expected no errors, got %v", c.Errors())
+	}
+
+	tests := []struct {
+		line, col int
+		expected  []Reference
+	}{
+		// field x: the selector and the struct literal key
+		{1, 23, []Reference{{Line: 3, Col: 37}, {Line: 6, Col: 25}}},
+		// global x, but not the shadowing local
+		{4, 11, []Reference{{Line: 6, Col: 28}}},
+		// local x
+		{5, 34, []Reference{{Line: 5, Col: 48}}},
+		// method sum called through the receiver
+		{3, 6, []Reference{{Line: 7, Col: 17}}},
+	}
+
+	for _, tt := range tests {
+		decl, ok := c.Definition("", tt.line, tt.col)
+		if !ok {
+			t.Errorf("%d:%d: no declaration found", tt.line, tt.col)
+			continue
+		}
+		refs := c.References(decl)
+		if len(refs) != len(tt.expected) {
+			t.Errorf("%d:%d: expected %d references, got %v", tt.line, tt.col, len(tt.expected), refs)
+			continue
+		}
+		for i, ref := range refs {
+			if ref != tt.expected[i] {
+				t.Errorf("%d:%d: expected reference %v, got %v", tt.line, tt.col, tt.expected[i], ref)
+			}
+		}
+	}
+
+	method, _ := c.Definition("", 3, 6)
+	impls := c.Implementations(method)
+	if len(impls) != 1 || impls[0].Name.Value != "sum" {
+		t.Fatalf("expected sum to implement Summer.sum, got %v", impls)
+	}
+	if line, col := impls[0].Name.Pos(); line != 2 || col != 27 {
+		t.Errorf("expected interface method at 2:27, got %d:%d", line, col)
+	}
+}
+
+func TestSymbols(t *testing.T) {
+	src := `define struct Point { x int, y int }
+const int x = 1;
+func f(string y) -> string {
+	const string x = y;
+	return x;
+}
+`
+	p := parser.New(lexer.New(src))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+	c := New(nil)
+	c.Check(program, nil)
+	if len(c.Errors()) != 0 {
+		t.Fatalf("expected no errors, got %v", c.Errors())
+	}
+
+	global := c.Env().Symbols()
+	if typ, ok := global["Point"]; !ok || typ != nil {
+		t.Errorf("expected struct Point to be visible without a value, got %v", typ)
+	}
+	if typ := global["x"]; typ == nil || typ.Signature() != "int" {
+		t.Errorf("expected global x to be int, got %v", typ)
+	}
+
+	fn := program.Stmts[2].(*ast.FunctionDeclarationStmt)
+	local := fn.Body.Scope.(*TypeEnv).Symbols()
+	if typ := local["x"]; typ == nil || typ.Signature() != "string" {
+		t.Errorf("expected local x to shadow the global, got %v", typ)
+	}
+	if _, ok := local["y"]; !ok {
+		t.Errorf("expected parameter y to be visible in the function body")
+	}
+	if _, ok := local["f"]; !ok {
+		t.Errorf("expected function f to be visible in its own body")
+	}
+}
+
+func TestInferences(t *testing.T) {
+	src := `func pick<T>(T a, T b) -> T { const c = a; return c; }
+const x = pick(1, 2);
+const string y = pick<string>("a", "b");
+mut z = [x];
+`
+	tests := []struct {
+		line, col int
+		name      string
+		want      string
+	}{
+		{2, 7, "x", "int"},
+		{2, 11, "pick", "<int>"},
+		{4, 5, "z", "array<int>"},
+	}
+
+	p := parser.New(lexer.New(src))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+	c := New(nil)
+	c.Check(program, nil)
+	if len(c.Errors()) != 0 {
+		t.Fatalf("expected no errors, got %v", c.Errors())
+	}
+
+	inferences := c.Inferences("")
+	if len(inferences) != len(tests) {
+		t.Fatalf("expected %d inferences, got %d: %v", len(tests), len(inferences), inferences)
+	}
+	for i, tt := range tests {
+		inf := inferences[i]
+		got := ""
+		if inf.Type != nil {
+			got = inf.Type.Signature()
+		} else {
+			var args []string
+			for _, arg := range inf.TypeArgs {
+				args = append(args, arg.Signature())
+			}
+			got = "<" + strings.Join(args, ", ") + ">"
+		}
+		if inf.Line != tt.line || inf.Col != tt.col || inf.Name != tt.name || got != tt.want {
+			t.Errorf("inference %d: expected %s %s at %d:%d, got %s %s at %d:%d", i, tt.name, tt.want, tt.line, tt.col, inf.Name, got, inf.Line, inf.Col)
+		}
+	}
+
+	typeArgs, ok := c.TypeArguments("", 3, 18)
+	if !ok || len(typeArgs) != 1 || typeArgs[0].Signature() != "string" {
+		t.Errorf("expected explicit type arguments [string] at 3:18, got %v", typeArgs)
+	}
+}
+
+func TestDeclarationKinds(t *testing.T) {
+	src := `define struct Point { x int }
+define interface Shape { area() -> float }
+func area(Point p) -> float { return float(p.x); }
+func twice(int n) -> int { mut m = n; return m * 2; }
+const int k = twice(1);
+`
+	tests := []struct {
+		line, col int
+		want      DeclarationKind
+	}{
+		{1, 15, StructDeclaration},
+		{1, 23, FieldDeclaration},
+		{2, 18, InterfaceDeclaration},
+		{2, 26, MethodDeclaration},
+		{3, 6, MethodDeclaration},
+		{3, 17, ParameterDeclaration},
+		{4, 6, FunctionDeclaration},
+		{4, 32, VariableDeclaration},
+		{5, 11, ConstantDeclaration},
+		{5, 15, FunctionDeclaration},
+	}
+
+	p := parser.New(lexer.New(src))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+	c := New(nil)
+	c.Check(program, nil)
+	if len(c.Errors()) != 0 {
+		t.Fatalf("expected no errors, got %v", c.Errors())
+	}
+
+	for _, tt := range tests {
+		decl, ok := c.Definition("", tt.line, tt.col)
+		if !ok {
+			t.Errorf("%d:%d: no declaration found", tt.line, tt.col)
+			continue
+		}
+		if decl.Kind != tt.want {
+			t.Errorf("%d:%d: expected %s to be declaration kind %d, got %d", tt.line, tt.col, decl.Name.Value, tt.want, decl.Kind)
+		}
+	}
+}
+
+func TestUnimplemented(t *testing.T) {
+	src := `define interface Shape { area() -> float, scale(int k) -> Shape }
+define struct Circle { r float }
+define struct Square { s int }
+func area(Square sq) -> float { return float(sq.s); }
+func measure(Shape sh) -> float { return sh.area(); }
+measure(Circle { r: 1.0 });
+measure(Square { s: 1 });
+measure(Circle { r: 2.0 });
+`
+	tests := []struct {
+		structName string
+		methods    []string
+	}{
+		{"Circle", []string{"area", "scale"}},
+		{"Square", []string{"scale"}},
+	}
+
+	p := parser.New(lexer.New(src))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+	c := New(nil)
+	c.Check(program, nil)
+
+	unimplemented := c.Unimplemented()
+	if len(unimplemented) != len(tests) {
+		t.Fatalf("expected %d unimplemented interfaces, got %d", len(tests), len(unimplemented))
+	}
+	for i, tt := range tests {
+		u := unimplemented[i]
+		if u.Struct.Name != tt.structName || u.Interface.Name != "Shape" {
+			t.Errorf("expected %s to be missing methods of Shape, got %s missing methods of %s", tt.structName, u.Struct.Name, u.Interface.Name)
+		}
+		if !slices.Equal(u.Methods, tt.methods) {
+			t.Errorf("expected %s to be missing %v, got %v", tt.structName, tt.methods, u.Methods)
+		}
+	}
+}
+
+func TestDiagnosticHints(t *testing.T) {
+	tests := []struct {
+		input    string
+		code     errors.Code
+		message  string
+		line     int
+		col      int
+		labels   []string // line:col message
+		help     string
+		severity errors.Severity
+		notes    []string
+	}{
+		{
+			"mut int total = 0;\ntotal = \"a\";",
+			errors.MismatchedTypes,
+			"type mismatch: cannot assign string to variable total of type int",
+			2, 9,
+			[]string{"1:9 declared as int here"},
+			"",
+			errors.Error,
+			nil,
+		},
+		{
+			"const count = 1;\ncount = 2;",
+			errors.AssignToConstant,
+			"cannot assign to constant variable count",
+			2, 1,
+			[]string{"1:7 declared as a constant here"},
+			"declare count with `mut` to allow assigning to it",
+			errors.Error,
+			nil,
+		},
+		{
+			"const count = 1;\nconst x = cuont + 1;",
+			errors.UnresolvedName,
+			"undefined identifier: cuont",
+			2, 11,
+			nil,
+			"did you mean `count`?",
+			errors.Error,
+			nil,
+		},
+		{
+			"func add(int a, int b) -> int { return a + b; }\nadd(1, \"two\");",
+			errors.MismatchedTypes,
+			"type mismatch: got string for arg 2 in function add call, expected int",
+			2, 8,
+			[]string{"1:6 add declared here"},
+			"",
+			errors.Error,
+			nil,
+		},
+		{
+			"define enum Shape { Circle(float), Rect(float, float), Empty }\nconst s = Shape.Empty;\nmatch s {\n    Circle(r) -> { r; },\n};",
+			errors.NonExhaustiveMatch,
+			"match on Shape is not exhaustive: missing Rect, Empty",
+			3, 1,
+			nil,
+			"add an arm for each missing variant, or a `_` arm",
+			errors.Error,
+			nil,
+		},
+		{
+			"define enum Shape { Circle(float), Empty }\nconst s = Shape.Cirle(1.0);",
+			errors.UnresolvedName,
+			"enum Shape has no variant Cirle",
+			2, 17,
+			nil,
+			"did you mean `Circle`?",
+			errors.Error,
+			nil,
+		},
+		{
+			"func add(int a, int b) -> int { return a + b; }\nadd(1);",
+			errors.WrongArgumentCount,
+			"wrong number of arguments for function add, wanted 2, got 1",
+			2, 4,
+			nil,
+			"",
+			errors.Error,
+			[]string{"add has type func<(int, int) -> int>"},
+		},
+		{
+			"func double(int x) -> int {\n    return x * 2;\n    print(x);\n    x;\n}",
+			errors.UnreachableCode,
+			"unreachable code",
+			3, 5,
+			[]string{"2:5 any code after this return never runs"},
+			"",
+			errors.Warning,
+			nil,
+		},
+		{
+			"for (x in [1, 2]) {\n    break;\n    print(x);\n}",
+			errors.UnreachableCode,
+			"unreachable code",
+			3, 5,
+			[]string{"2:5 any code after this break never runs"},
+			"",
+			errors.Warning,
+			nil,
+		},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			t.Fatalf("parser errors: %v", p.Errors())
+		}
+		c := New(nil)
+		errs := c.Check(program, nil)
+		if len(errs) == 0 {
+			t.Fatalf("expected error %q, got none", tt.message)
+		}
+		e := errs[0]
+		if e.Code != tt.code || e.Message != tt.message || e.Line != tt.line || e.Col != tt.col {
+			t.Errorf("expected %d:%d %s %q, got %d:%d %s %q", tt.line, tt.col, tt.code, tt.message, e.Line, e.Col, e.Code, e.Message)
+		}
+		var labels []string
+		for _, label := range e.Labels {
+			labels = append(labels, fmt.Sprintf("%d:%d %s", label.Line, label.Col, label.Message))
+		}
+		if !slices.Equal(labels, tt.labels) {
+			t.Errorf("expected labels %v, got %v", tt.labels, labels)
+		}
+		if e.Help != tt.help {
+			t.Errorf("expected help %q, got %q", tt.help, e.Help)
+		}
+		if e.Severity != tt.severity {
+			t.Errorf("expected severity %s, got %s", tt.severity, e.Severity)
+		}
+		if !slices.Equal(e.Notes, tt.notes) {
+			t.Errorf("expected notes %v, got %v", tt.notes, e.Notes)
+		}
+		// one warning per block, and warnings alone do not stop compilation
+		if tt.severity == errors.Warning && (len(errs) != 1 || errors.HasErrors(errs)) {
+			t.Errorf("expected only the warning, got %v", errs)
+		}
+	}
+}
+
+func TestUnresolvedOperandTypeErrors(t *testing.T) {
+	// the interface is missing a comma, so the parser leaves its methods out
+	shape := "define interface Shape { area() -> int perimeter() -> int }\n"
+	tests := []TypeErrorTest{
+		{shape + `func f(Shape s) -> int { return s.area(); }`, `cannot access field of non-struct value s`},
+		{shape + `func f(Shape s) -> int { return s.area() + 1; }`, `cannot access field of non-struct value s`},
+		{shape + `func f(Shape s) -> int { return -s.area(); }`, `cannot access field of non-struct value s`},
+		{shape + `func f(Shape s) -> int { mut x = 1; x = s.area(); return x; }`, `cannot access field of non-struct value s`},
+		{shape + `func f(Shape s) -> int { const b = s.area().x; return 0; }`, `cannot access field of non-struct value s`},
+		{`func f() -> int { return nope; }`, `undefined identifier: nope`},
+		{`const m = {1: 2}; m[nope];`, `undefined identifier: nope`},
+		{`mut a = [1]; a[nope] = 1;`, `undefined identifier: nope`},
+		{`append([1], nope);`, `undefined identifier: nope`},
+		{`for (mut i = 0; nope; i = i + 1) {}`, `undefined identifier: nope`},
+		{`for (v in nope) {}`, `undefined identifier: nope`},
+		{`[1, 2][0:nope];`, `undefined identifier: nope`},
+		{`{nope: 1, 2: 3};`, `undefined identifier: nope`},
+		{`define struct P { x int }
+		const p = P { x: nope };`, `undefined identifier: nope`},
+		{`define struct P { x int }
+		mut p = P { x: 1 };
+		p.x = nope;`, `undefined identifier: nope`},
+		{`define enum E { A(int), B }
+		E.A(nope);`, `undefined identifier: nope`},
+	}
+	testTypeErrors(t, tests)
+}
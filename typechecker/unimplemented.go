@@ -0,0 +1,37 @@
+package typechecker
+
+import "sydney/types"
+
+// Unimplemented is an interface a struct was used as without declaring all
+// of the interface's methods
+type Unimplemented struct {
+	Struct    types.StructType
+	Interface types.InterfaceType
+	Methods   []string // the missing methods, in the interface's order
+}
+
+// Unimplemented returns the interfaces structs were found not to satisfy
+// because of missing methods
+func (c *Checker) Unimplemented() []Unimplemented {
+	result := make([]Unimplemented, len(c.unimplemented))
+	for i, u := range c.unimplemented {
+		result[i] = *u
+	}
+	return result
+}
+
+func (c *Checker) recordUnimplemented(s types.StructType, i types.InterfaceType, method string) {
+	for _, u := range c.unimplemented {
+		if u.Struct.Name != s.Name || u.Interface.Name != i.Name {
+			continue
+		}
+		for _, m := range u.Methods {
+			if m == method {
+				return
+			}
+		}
+		u.Methods = append(u.Methods, method)
+		return
+	}
+	c.unimplemented = append(c.unimplemented, &Unimplemented{Struct: s, Interface: i, Methods: []string{method}})
+}
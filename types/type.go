@@ -3,6 +3,7 @@ package types
 import (
 	"bytes"
 	"fmt"
+	"strings"
 )
 
 type Type interface {
@@ -44,6 +45,17 @@ type StructType struct {
 	TypeArgs            []Type
 }
 
+// EnumType is a set of variants, each carrying the values in its payload.
+// Generic enums are not monomorphized: TypeArgs only refine the payloads
+type EnumType struct {
+	Name       string
+	Module     string
+	Variants   []string
+	Payloads   [][]Type
+	TypeParams []*TypeParam
+	TypeArgs   []Type
+}
+
 type InterfaceType struct {
 	Name          string
 	Module        string
@@ -52,8 +64,10 @@ type InterfaceType struct {
 	Types         []Type
 }
 
+// ResultType is result<T, E>. E is nil for result<T>, whose errors are strings
 type ResultType struct {
 	T Type
+	E Type
 }
 
 type OptionType struct {
@@ -78,6 +92,11 @@ type ChannelType struct {
 	ElemType Type
 }
 
+// TupleType is a fixed number of values of possibly different types
+type TupleType struct {
+	Types []Type
+}
+
 const (
 	Int    BasicType = "int"
 	Float  BasicType = "float"
@@ -129,6 +148,27 @@ func (s StructType) Signature() string {
 	return s.Name
 }
 
+func (e EnumType) Signature() string {
+	if len(e.TypeArgs) == 0 {
+		return e.Name
+	}
+	args := make([]string, len(e.TypeArgs))
+	for i, a := range e.TypeArgs {
+		args[i] = a.Signature()
+	}
+	return e.Name + "<" + strings.Join(args, ", ") + ">"
+}
+
+// VariantIndex is the tag of the named variant, or -1 when there is none
+func (e EnumType) VariantIndex(name string) int {
+	for i, v := range e.Variants {
+		if v == name {
+			return i
+		}
+	}
+	return -1
+}
+
 func (i InterfaceType) Signature() string {
 	return i.Name
 }
@@ -137,10 +177,22 @@ func (r ResultType) Signature() string {
 	var out bytes.Buffer
 	out.WriteString("result<")
 	out.WriteString(r.T.Signature())
+	if r.Err() != String {
+		out.WriteString(", ")
+		out.WriteString(r.E.Signature())
+	}
 	out.WriteString(">")
 	return out.String()
 }
 
+// Err is the type of the error value, string unless another was given
+func (r ResultType) Err() Type {
+	if r.E == nil {
+		return String
+	}
+	return r.E
+}
+
 func (o OptionType) Signature() string {
 	return "option<" + o.T.Signature() + ">"
 }
@@ -167,6 +219,14 @@ func (t ChannelType) Signature() string {
 	return "chan<" + t.ElemType.Signature() + ">"
 }
 
+func (t TupleType) Signature() string {
+	elems := make([]string, len(t.Types))
+	for i, e := range t.Types {
+		elems[i] = e.Signature()
+	}
+	return "(" + strings.Join(elems, ", ") + ")"
+}
+
 func SubstituteTypeParams(t Type, subs map[string]Type) Type {
 	switch tt := t.(type) {
 	case *TypeParamRef:
@@ -183,9 +243,13 @@ func SubstituteTypeParams(t Type, subs map[string]Type) Type {
 			ElemType: SubstituteTypeParams(tt.ElemType, subs),
 		}
 	case ResultType:
-		return ResultType{
+		rt := ResultType{
 			T: SubstituteTypeParams(tt.T, subs),
 		}
+		if tt.E != nil {
+			rt.E = SubstituteTypeParams(tt.E, subs)
+		}
+		return rt
 	case OptionType:
 		return OptionType{
 			T: SubstituteTypeParams(tt.T, subs),
@@ -212,6 +276,29 @@ func SubstituteTypeParams(t Type, subs map[string]Type) Type {
 		}
 		tt.Types = types
 		return tt
+	case EnumType:
+		payloads := make([][]Type, len(tt.Payloads))
+		for i, payload := range tt.Payloads {
+			payloads[i] = make([]Type, len(payload))
+			for j, t := range payload {
+				payloads[i][j] = SubstituteTypeParams(t, subs)
+			}
+		}
+		if tt.TypeArgs != nil {
+			ta := make([]Type, len(tt.TypeArgs))
+			for i, a := range tt.TypeArgs {
+				ta[i] = SubstituteTypeParams(a, subs)
+			}
+			tt.TypeArgs = ta
+		}
+		tt.Payloads = payloads
+		return tt
+	case TupleType:
+		elems := make([]Type, len(tt.Types))
+		for i, e := range tt.Types {
+			elems[i] = SubstituteTypeParams(e, subs)
+		}
+		return TupleType{Types: elems}
 	}
 	return t
 }
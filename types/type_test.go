@@ -25,6 +25,30 @@ func TestString(t *testing.T) {
 			MapType{KeyType: String, ValueType: String},
 			"map<string, string>",
 		},
+		{
+			EnumType{Name: "Shape", Variants: []string{"Circle"}, Payloads: [][]Type{{Float}}},
+			"Shape",
+		},
+		{
+			EnumType{Name: "Tree", Variants: []string{"Leaf"}, Payloads: [][]Type{{Int}}, TypeArgs: []Type{Int}},
+			"Tree<int>",
+		},
+		{
+			TupleType{Types: []Type{Int, ArrayType{ElemType: String}}},
+			"(int, array<string>)",
+		},
+		{
+			ResultType{T: Int},
+			"result<int>",
+		},
+		{
+			ResultType{T: Int, E: String},
+			"result<int>",
+		},
+		{
+			ResultType{T: String, E: EnumType{Name: "NetError"}},
+			"result<string, NetError>",
+		},
 	}
 
 	for _, test := range tests {
@@ -0,0 +1,249 @@
+package vm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"sydney/object"
+)
+
+// Breakpoint is a line breakpoint. When Condition is set it only fires while
+// the condition, a bool expression evaluated in the paused frame, is true.
+// HitCondition then filters on how often it has fired so far: "N" or "== N",
+// ">= N", "> N", "<= N", "< N", or "% N" for every Nth hit. A breakpoint with
+// a LogMessage never stops; it emits the message as an OutputEvent instead,
+// with every {expression} in it replaced by its value. Expressions are type
+// checked and compiled once, when the breakpoint is set.
+type Breakpoint struct {
+	File         string
+	Line         int
+	Condition    string
+	HitCondition string
+	LogMessage   string
+
+	hits      int
+	hitOp     string
+	hitCount  int
+	condition *lineExpr
+	message   []logPart
+}
+
+// lineExpr is an expression of a breakpoint, compiled when the breakpoint is
+// set for each function with code on its line
+type lineExpr struct {
+	src      string
+	compiled map[*object.CompiledFunction]compiledIn
+}
+
+type compiledIn struct {
+	expr *debugExpr
+	err  error // why it does not check in this function's scope
+}
+
+// logPart is literal text of a log message, or an expression in it
+type logPart struct {
+	text string
+	expr *lineExpr
+}
+
+// setBreakpoint validates cmd and installs it, replacing any breakpoint on
+// the same line
+func (d *Debugger) setBreakpoint(cmd *AddBreakpoint) (*Breakpoint, error) {
+	bp := &Breakpoint{
+		File:         normalizePath(cmd.File),
+		Line:         cmd.Line,
+		Condition:    strings.TrimSpace(cmd.Condition),
+		HitCondition: strings.TrimSpace(cmd.HitCondition),
+		LogMessage:   cmd.LogMessage,
+	}
+
+	if bp.HitCondition != "" {
+		op, n, err := parseHitCondition(bp.HitCondition)
+		if err != nil {
+			return nil, err
+		}
+		bp.hitOp, bp.hitCount = op, n
+	}
+
+	if bp.Condition != "" {
+		cond, err := d.compile(bp.Condition, bp.File, bp.Line)
+		if err != nil {
+			return nil, fmt.Errorf("bad condition: %s", err)
+		}
+		bp.condition = cond
+	}
+
+	if bp.LogMessage != "" {
+		message, err := d.parseLogMessage(bp.LogMessage, bp.File, bp.Line)
+		if err != nil {
+			return nil, fmt.Errorf("bad log message: %s", err)
+		}
+		bp.message = message
+	}
+
+	d.bpMu.Lock()
+	defer d.bpMu.Unlock()
+
+	if d.breakpoints[bp.File] == nil {
+		d.breakpoints[bp.File] = make(map[int]*Breakpoint)
+	}
+	d.breakpoints[bp.File][bp.Line] = bp
+
+	return bp, nil
+}
+
+func (d *Debugger) breakpointAt(file string, line int) *Breakpoint {
+	d.bpMu.Lock()
+	defer d.bpMu.Unlock()
+	return d.breakpoints[file][line]
+}
+
+func (d *Debugger) clearBreakpoints(file string) {
+	d.bpMu.Lock()
+	defer d.bpMu.Unlock()
+	delete(d.breakpoints, normalizePath(file))
+}
+
+// breakpointFires runs on the VM goroutine when frame reaches the line of bp.
+// A condition that fails to evaluate stops, so the mistake is noticed.
+func (d *Debugger) breakpointFires(bp *Breakpoint, frame *Frame) bool {
+	if bp.condition != nil {
+		val, err := d.evalIn(bp.condition, frame)
+		if err != nil {
+			d.output(fmt.Sprintf("breakpoint condition %q: %s\n", bp.Condition, err))
+			return true
+		}
+		b, ok := val.(*object.Boolean)
+		if !ok {
+			d.output(fmt.Sprintf("breakpoint condition %q is not a bool\n", bp.Condition))
+			return true
+		}
+		if !b.Value {
+			return false
+		}
+	}
+
+	bp.hits++
+	if bp.hitOp != "" && !hitMatches(bp.hitOp, bp.hits, bp.hitCount) {
+		return false
+	}
+
+	if bp.message != nil {
+		d.output(d.interpolate(bp.message, frame) + "\n")
+		return false
+	}
+
+	return true
+}
+
+func (d *Debugger) output(text string) {
+	d.eventCh <- &OutputEvent{Text: text}
+}
+
+// evalIn runs e in frame, with the bytecode compiled for frame's function
+func (d *Debugger) evalIn(e *lineExpr, frame *Frame) (object.Object, error) {
+	c, ok := e.compiled[frame.cl.Fn]
+	if !ok {
+		return nil, fmt.Errorf("%s is not in scope in %s", e.src, frame.cl.Fn.Name)
+	}
+	if c.err != nil {
+		return nil, c.err
+	}
+	return d.run(c.expr, frame)
+}
+
+// parseLogMessage splits msg into text and the {expression}s to replace with
+// their values, compiled for the line of the breakpoint; "{{" and "}}" stand
+// for literal braces
+func (d *Debugger) parseLogMessage(msg, file string, line int) ([]logPart, error) {
+	var parts []logPart
+	var text strings.Builder
+	for i := 0; i < len(msg); i++ {
+		ch := msg[i]
+		if (ch == '{' || ch == '}') && i+1 < len(msg) && msg[i+1] == ch {
+			text.WriteByte(ch)
+			i++
+			continue
+		}
+
+		end := strings.IndexByte(msg[i+1:], '}')
+		if ch != '{' || end < 0 {
+			text.WriteByte(ch)
+			continue
+		}
+
+		src := msg[i+1 : i+1+end]
+		expr, err := d.compile(src, file, line)
+		if err != nil {
+			return nil, fmt.Errorf("{%s}: %s", src, err)
+		}
+		if text.Len() > 0 {
+			parts = append(parts, logPart{text: text.String()})
+			text.Reset()
+		}
+		parts = append(parts, logPart{expr: expr})
+		i += end + 1
+	}
+	if text.Len() > 0 {
+		parts = append(parts, logPart{text: text.String()})
+	}
+
+	return parts, nil
+}
+
+// interpolate renders a log message with each expression's value in frame
+func (d *Debugger) interpolate(parts []logPart, frame *Frame) string {
+	var out strings.Builder
+	for _, part := range parts {
+		if part.expr == nil {
+			out.WriteString(part.text)
+			continue
+		}
+		val, err := d.evalIn(part.expr, frame)
+		if err != nil {
+			out.WriteString("<" + err.Error() + ">")
+		} else {
+			out.WriteString(val.Inspect())
+		}
+	}
+
+	return out.String()
+}
+
+func parseHitCondition(cond string) (string, int, error) {
+	op, count := "==", cond
+	for _, candidate := range []string{"==", ">=", "<=", ">", "<", "%"} {
+		if strings.HasPrefix(cond, candidate) {
+			op = candidate
+			count = strings.TrimSpace(cond[len(candidate):])
+			break
+		}
+	}
+
+	n, err := strconv.Atoi(count)
+	if err != nil || n < 0 || (op == "%" && n == 0) {
+		return "", 0, fmt.Errorf("bad hit condition %q", cond)
+	}
+
+	return op, n, nil
+}
+
+func hitMatches(op string, hits int, n int) bool {
+	switch op {
+	case "==":
+		return hits == n
+	case ">=":
+		return hits >= n
+	case "<=":
+		return hits <= n
+	case ">":
+		return hits > n
+	case "<":
+		return hits < n
+	case "%":
+		return hits%n == 0
+	}
+
+	return false
+}
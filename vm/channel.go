@@ -9,6 +9,7 @@ import (
 // The object.Channel that sits on the Sydney stack is just a handle (an int ID)
 // that maps to one of these via the scheduler's channels map.
 type Channel struct {
+	id       int
 	elemType types.Type
 	closed   bool
 
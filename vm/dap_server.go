@@ -0,0 +1,761 @@
+package vm
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// dapServer speaks the Debug Adapter Protocol to an editor. The sydney
+// process is both the adapter and the debuggee: launch and attach only
+// start the already compiled program. Fibers are reported as DAP threads
+// with thread id = fiber id + 1, since some clients treat thread 0 as unset,
+// and stack frame ids encode both the fiber and the frame's depth.
+type dapServer struct {
+	d   *Debugger
+	in  *bufio.Reader
+	out io.Writer
+
+	writeMu sync.Mutex
+	seq     int
+
+	responses chan DebugEvent // answers to commands sent on cmdCh
+	stopped   atomic.Bool
+	finished  atomic.Bool
+	doneOnce  sync.Once
+
+	mu       sync.Mutex
+	lastStop *StoppedEvent
+	threads  []dapThread
+	frames   map[int][]FrameInfo // call stacks by fiber, once requested
+
+	configured bool
+	launched   bool
+	ready      chan struct{}
+
+	// stdio mode only: the program's stdout, forwarded as output events
+	progOut    *os.File
+	progOutEOF chan struct{}
+}
+
+type dapMessage struct {
+	Seq       int             `json:"seq"`
+	Type      string          `json:"type"`
+	Command   string          `json:"command"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+type dapResponse struct {
+	Seq        int    `json:"seq"`
+	Type       string `json:"type"`
+	RequestSeq int    `json:"request_seq"`
+	Success    bool   `json:"success"`
+	Command    string `json:"command"`
+	Message    string `json:"message,omitempty"`
+	Body       any    `json:"body,omitempty"`
+}
+
+type dapEvent struct {
+	Seq   int    `json:"seq"`
+	Type  string `json:"type"`
+	Event string `json:"event"`
+	Body  any    `json:"body,omitempty"`
+}
+
+type (
+	dapThread struct {
+		Id   int    `json:"id"`
+		Name string `json:"name"`
+	}
+
+	dapSource struct {
+		Name string `json:"name"`
+		Path string `json:"path"`
+	}
+
+	dapStackFrame struct {
+		Id     int        `json:"id"`
+		Name   string     `json:"name"`
+		Source *dapSource `json:"source,omitempty"`
+		Line   int        `json:"line"`
+		Column int        `json:"column"`
+	}
+
+	dapScope struct {
+		Name               string `json:"name"`
+		VariablesReference int    `json:"variablesReference"`
+		Expensive          bool   `json:"expensive"`
+	}
+
+	dapVariable struct {
+		Name               string `json:"name"`
+		Value              string `json:"value"`
+		Type               string `json:"type,omitempty"`
+		VariablesReference int    `json:"variablesReference"`
+	}
+
+	dapBreakpoint struct {
+		Verified bool   `json:"verified"`
+		Line     int    `json:"line"`
+		Message  string `json:"message,omitempty"`
+	}
+)
+
+// variablesReference values at or above scopeReference name a scope of a
+// frame; below it they are debugger value handles
+const scopeReference = 1 << 20
+
+const (
+	localsScope = iota
+	closureScope
+)
+
+func scopeRef(frameId int, kind int) int {
+	return scopeReference + (frameId-1)*2 + kind
+}
+
+func frameId(fiber, depth int) int {
+	return fiber*MaxFrames + depth + 1
+}
+
+func splitFrameId(id int) (fiber, depth int) {
+	return (id - 1) / MaxFrames, (id - 1) % MaxFrames
+}
+
+// ServeDAP accepts a single DAP client and returns once the client has sent
+// launch (or attach) and configurationDone, or has left, so the program can
+// start. With an empty addr the protocol runs over stdin/stdout and the
+// program's own output is forwarded to the client as output events; otherwise
+// addr is a TCP address to listen on.
+func (d *Debugger) ServeDAP(addr string) error {
+	if addr != "" {
+		l, err := net.Listen("tcp", addr)
+		if err != nil {
+			return err
+		}
+		log.Printf("debug adapter listening on %s", l.Addr())
+		conn, err := l.Accept()
+		l.Close()
+		if err != nil {
+			return err
+		}
+		d.newDAPServer(conn, conn).run()
+		return nil
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	s := d.newDAPServer(os.Stdin, os.Stdout)
+	os.Stdout = w
+	s.progOut = w
+	s.progOutEOF = make(chan struct{})
+	go s.forwardOutput(r)
+
+	s.run()
+	return nil
+}
+
+func (d *Debugger) newDAPServer(in io.Reader, out io.Writer) *dapServer {
+	return &dapServer{
+		d:         d,
+		in:        bufio.NewReader(in),
+		out:       out,
+		responses: make(chan DebugEvent),
+		threads:   []dapThread{fiberThread(FiberInfo{})},
+		frames:    map[int][]FrameInfo{},
+		ready:     make(chan struct{}),
+	}
+}
+
+// run serves the client until it has launched the program and finished
+// configuring it
+func (s *dapServer) run() {
+	go s.pump()
+	go s.serve()
+
+	<-s.ready
+}
+
+func fiberThread(f FiberInfo) dapThread {
+	name := "main"
+	if f.Id != 0 {
+		name = fmt.Sprintf("fiber %d", f.Id)
+	}
+
+	switch f.Block {
+	case "send", "receive":
+		name += fmt.Sprintf(" (%s on channel %d)", f.Block, f.Channel)
+	case "io":
+		name += fmt.Sprintf(" (waiting on %s)", f.Builtin)
+	}
+
+	return dapThread{Id: f.Id + 1, Name: name}
+}
+
+func (s *dapServer) read() (*dapMessage, error) {
+	length := -1
+	for {
+		line, err := s.in.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			break
+		}
+		if v, ok := strings.CutPrefix(line, "Content-Length:"); ok {
+			length, err = strconv.Atoi(strings.TrimSpace(v))
+			if err != nil {
+				return nil, fmt.Errorf("bad Content-Length: %s", v)
+			}
+		}
+	}
+	if length < 0 {
+		return nil, fmt.Errorf("missing Content-Length header")
+	}
+
+	body := make([]byte, length)
+	_, err := io.ReadFull(s.in, body)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := &dapMessage{}
+	err = json.Unmarshal(body, msg)
+	if err != nil {
+		return nil, err
+	}
+
+	return msg, nil
+}
+
+// write frames a response or event; seq is assigned here so that messages
+// from the request loop and the event pump stay ordered
+func (s *dapServer) write(msg any) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	// nobody is listening once the client has detached
+	if s.d.Detached() {
+		return
+	}
+
+	s.seq++
+	switch m := msg.(type) {
+	case *dapResponse:
+		m.Seq = s.seq
+	case *dapEvent:
+		m.Seq = s.seq
+	}
+
+	body, err := json.Marshal(msg)
+	if err != nil {
+		log.Printf("dap: cannot encode message: %s", err)
+		return
+	}
+	fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(body), body)
+}
+
+func (s *dapServer) respond(req *dapMessage, body any) {
+	s.write(&dapResponse{Type: "response", RequestSeq: req.Seq, Success: true, Command: req.Command, Body: body})
+}
+
+func (s *dapServer) fail(req *dapMessage, format string, args ...any) {
+	s.write(&dapResponse{Type: "response", RequestSeq: req.Seq, Command: req.Command, Message: fmt.Sprintf(format, args...)})
+}
+
+func (s *dapServer) event(name string, body any) {
+	s.write(&dapEvent{Type: "event", Event: name, Body: body})
+}
+
+// request sends cmd to the stopped VM and waits for its answer
+func (s *dapServer) request(cmd DebugCommand) DebugEvent {
+	s.d.cmdCh <- cmd
+	return <-s.responses
+}
+
+// resume hands control back to the VM with the given stepping mode
+func (s *dapServer) resume(mode DebugMode) bool {
+	if !s.stopped.CompareAndSwap(true, false) {
+		return false
+	}
+	s.mu.Lock()
+	s.frames = map[int][]FrameInfo{}
+	s.mu.Unlock()
+	s.d.cmdCh <- &SetMode{Flag: mode}
+	return true
+}
+
+func (s *dapServer) finish() {
+	s.doneOnce.Do(func() { close(s.d.done) })
+}
+
+// detach ends the session from the client side. A program that has not
+// finished stops at its next instruction, starting it first if the client
+// left before launching it.
+func (s *dapServer) detach() {
+	s.d.Detach()
+	s.resume(DebugContinue)
+	s.release()
+	s.finish()
+}
+
+func (s *dapServer) serve() {
+	for {
+		msg, err := s.read()
+		if err != nil {
+			if err != io.EOF {
+				log.Printf("dap: %s", err)
+			}
+			// the client went away: nothing is left to drive the program
+			if s.finished.Load() {
+				s.finish()
+			} else {
+				s.detach()
+			}
+			return
+		}
+
+		if msg.Type != "request" {
+			continue
+		}
+		s.handle(msg)
+	}
+}
+
+func (s *dapServer) handle(req *dapMessage) {
+	switch req.Command {
+	case "initialize":
+		s.respond(req, map[string]any{
+			"supportsConfigurationDoneRequest":  true,
+			"supportsConditionalBreakpoints":    true,
+			"supportsHitConditionalBreakpoints": true,
+			"supportsLogPoints":                 true,
+			"supportsEvaluateForHovers":         true,
+			"supportsExceptionInfoRequest":      true,
+			"exceptionBreakpointFilters": []map[string]any{
+				{"filter": "error", "label": "Runtime errors"},
+				{"filter": "panic", "label": "Panics"},
+			},
+		})
+		s.event("initialized", nil)
+	case "launch", "attach":
+		var args struct {
+			StopOnEntry bool `json:"stopOnEntry"`
+		}
+		if !s.arguments(req, &args) {
+			return
+		}
+		if !args.StopOnEntry {
+			s.d.Flag = DebugContinue
+		}
+		s.respond(req, nil)
+		s.launched = true
+		s.checkReady()
+	case "configurationDone":
+		s.respond(req, nil)
+		s.configured = true
+		s.checkReady()
+	case "setBreakpoints":
+		s.setBreakpoints(req)
+	case "setExceptionBreakpoints":
+		s.setExceptionBreakpoints(req)
+	case "exceptionInfo":
+		s.exceptionInfo(req)
+	case "threads":
+		s.respond(req, map[string]any{"threads": s.listThreads()})
+	case "stackTrace":
+		s.stackTrace(req)
+	case "scopes":
+		s.scopes(req)
+	case "variables":
+		s.variables(req)
+	case "evaluate":
+		s.evaluate(req)
+	case "continue":
+		s.resume(DebugContinue)
+		s.respond(req, map[string]any{"allThreadsContinued": true})
+	case "next":
+		s.resume(DebugStepOver)
+		s.respond(req, nil)
+	case "stepIn":
+		s.resume(DebugStepLine)
+		s.respond(req, nil)
+	case "stepOut":
+		s.resume(DebugStepOut)
+		s.respond(req, nil)
+	case "pause":
+		s.d.RequestPause()
+		s.respond(req, nil)
+	case "disconnect", "terminate":
+		s.respond(req, nil)
+		if s.finished.Load() {
+			s.finish()
+		} else {
+			s.detach()
+		}
+	default:
+		s.fail(req, "unsupported request %q", req.Command)
+	}
+}
+
+func (s *dapServer) checkReady() {
+	if s.launched && s.configured {
+		s.release()
+	}
+}
+
+// release lets run return and the program start
+func (s *dapServer) release() {
+	select {
+	case <-s.ready:
+	default:
+		close(s.ready)
+	}
+}
+
+// arguments decodes the arguments of req into v, failing the request when
+// they are malformed
+func (s *dapServer) arguments(req *dapMessage, v any) bool {
+	if len(req.Arguments) == 0 {
+		return true
+	}
+	err := json.Unmarshal(req.Arguments, v)
+	if err != nil {
+		s.fail(req, "bad arguments: %s", err)
+		return false
+	}
+	return true
+}
+
+func (s *dapServer) setBreakpoints(req *dapMessage) {
+	var args struct {
+		Source      dapSource `json:"source"`
+		Breakpoints []struct {
+			Line         int    `json:"line"`
+			Condition    string `json:"condition"`
+			HitCondition string `json:"hitCondition"`
+			LogMessage   string `json:"logMessage"`
+		} `json:"breakpoints"`
+	}
+	if !s.arguments(req, &args) {
+		return
+	}
+
+	// the request replaces every breakpoint in the file
+	s.d.clearBreakpoints(args.Source.Path)
+
+	bps := make([]dapBreakpoint, 0, len(args.Breakpoints))
+	for _, bp := range args.Breakpoints {
+		_, err := s.d.setBreakpoint(&AddBreakpoint{
+			File:         args.Source.Path,
+			Line:         bp.Line,
+			Condition:    bp.Condition,
+			HitCondition: bp.HitCondition,
+			LogMessage:   bp.LogMessage,
+		})
+		result := dapBreakpoint{Verified: err == nil, Line: bp.Line}
+		if err != nil {
+			result.Message = err.Error()
+		}
+		bps = append(bps, result)
+	}
+
+	s.respond(req, map[string]any{"breakpoints": bps})
+}
+
+func (s *dapServer) setExceptionBreakpoints(req *dapMessage) {
+	var args struct {
+		Filters []string `json:"filters"`
+	}
+	if !s.arguments(req, &args) {
+		return
+	}
+
+	s.d.setExceptionBreaks(&SetExceptionBreaks{
+		Errors: slices.Contains(args.Filters, "error"),
+		Panics: slices.Contains(args.Filters, "panic"),
+	})
+	s.respond(req, map[string]any{"breakpoints": []dapBreakpoint{}})
+}
+
+func (s *dapServer) exceptionInfo(req *dapMessage) {
+	s.mu.Lock()
+	stop := s.lastStop
+	s.mu.Unlock()
+
+	if !s.stopped.Load() || stop == nil || stop.Reason != StopException {
+		s.fail(req, "not stopped on an exception")
+		return
+	}
+
+	id := "error"
+	if stop.Panic {
+		id = "panic"
+	}
+	s.respond(req, map[string]any{
+		"exceptionId": id,
+		"description": stop.Error,
+		"breakMode":   "always",
+	})
+}
+
+func (s *dapServer) listThreads() []dapThread {
+	// fibers can only be listed while the VM is parked. The lock is taken
+	// after the round trip so the event pump never waits on it meanwhile
+	if s.stopped.Load() {
+		resp, ok := s.request(&GetFibers{}).(*FibersResponse)
+		if ok {
+			threads := []dapThread{}
+			for _, f := range resp.Fibers {
+				if f.State != Done.String() {
+					threads = append(threads, fiberThread(f))
+				}
+			}
+			s.mu.Lock()
+			s.threads = threads
+			s.mu.Unlock()
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.threads
+}
+
+// inspect points the debugger at a fiber for the following queries
+func (s *dapServer) inspect(fiber int) bool {
+	resp, ok := s.request(&SelectFiber{Id: fiber}).(*SelectFiberResponse)
+	return ok && resp.Error == ""
+}
+
+func (s *dapServer) stackTrace(req *dapMessage) {
+	var args struct {
+		ThreadId   int `json:"threadId"`
+		StartFrame int `json:"startFrame"`
+		Levels     int `json:"levels"`
+	}
+	if !s.arguments(req, &args) {
+		return
+	}
+	fiber := args.ThreadId - 1
+
+	frames := []dapStackFrame{}
+	if !s.stopped.Load() || !s.inspect(fiber) {
+		s.respond(req, map[string]any{"stackFrames": frames, "totalFrames": 0})
+		return
+	}
+
+	resp, ok := s.request(&GetCallStack{}).(*CallStackResponse)
+	if !ok {
+		s.fail(req, "cannot read call stack")
+		return
+	}
+	s.mu.Lock()
+	s.frames[fiber] = resp.Frames
+	s.mu.Unlock()
+
+	for _, f := range resp.Frames {
+		frame := dapStackFrame{Id: frameId(fiber, f.Index), Name: f.Function, Line: f.Line, Column: f.Col}
+		if f.File != "" {
+			frame.Source = &dapSource{Name: filepath.Base(f.File), Path: f.File}
+		}
+		frames = append(frames, frame)
+	}
+
+	total := len(frames)
+	if args.StartFrame > 0 {
+		frames = frames[min(args.StartFrame, len(frames)):]
+	}
+	if args.Levels > 0 && args.Levels < len(frames) {
+		frames = frames[:args.Levels]
+	}
+
+	s.respond(req, map[string]any{"stackFrames": frames, "totalFrames": total})
+}
+
+func (s *dapServer) scopes(req *dapMessage) {
+	var args struct {
+		FrameId int `json:"frameId"`
+	}
+	if !s.arguments(req, &args) {
+		return
+	}
+
+	scopes := []dapScope{}
+	if !s.stopped.Load() || args.FrameId <= 0 {
+		s.respond(req, map[string]any{"scopes": scopes})
+		return
+	}
+	fiber, depth := splitFrameId(args.FrameId)
+
+	name := "Locals"
+	s.mu.Lock()
+	if frames := s.frames[fiber]; fiber == 0 && depth == len(frames)-1 {
+		name = "Globals"
+	}
+	s.mu.Unlock()
+	scopes = append(scopes, dapScope{Name: name, VariablesReference: scopeRef(args.FrameId, localsScope)})
+
+	if !s.inspect(fiber) {
+		s.respond(req, map[string]any{"scopes": scopes})
+		return
+	}
+	if resp, ok := s.request(&GetLocals{Frame: depth}).(*LocalsResponse); ok && len(resp.Free) > 0 {
+		scopes = append(scopes, dapScope{Name: "Closure", VariablesReference: scopeRef(args.FrameId, closureScope)})
+	}
+
+	s.respond(req, map[string]any{"scopes": scopes})
+}
+
+func (s *dapServer) variables(req *dapMessage) {
+	var args struct {
+		VariablesReference int `json:"variablesReference"`
+	}
+	if !s.arguments(req, &args) {
+		return
+	}
+	ref := args.VariablesReference
+
+	var vars []LocalVar
+	if s.stopped.Load() {
+		if ref >= scopeReference {
+			fiber, depth := splitFrameId((ref-scopeReference)/2 + 1)
+			kind := (ref - scopeReference) % 2
+			if !s.inspect(fiber) {
+				s.respond(req, map[string]any{"variables": []dapVariable{}})
+				return
+			}
+			if resp, ok := s.request(&GetLocals{Frame: depth}).(*LocalsResponse); ok {
+				vars = resp.Locals
+				if kind == closureScope {
+					vars = resp.Free
+				}
+			}
+		} else if resp, ok := s.request(&GetVariables{Ref: ref}).(*VariablesResponse); ok {
+			vars = resp.Children
+		}
+	}
+
+	out := make([]dapVariable, 0, len(vars))
+	for _, v := range vars {
+		out = append(out, dapVariable{Name: v.Name, Value: v.Value, Type: v.Type, VariablesReference: v.Ref})
+	}
+
+	s.respond(req, map[string]any{"variables": out})
+}
+
+func (s *dapServer) evaluate(req *dapMessage) {
+	var args struct {
+		Expression string `json:"expression"`
+		FrameId    int    `json:"frameId"`
+	}
+	if !s.arguments(req, &args) {
+		return
+	}
+
+	if !s.stopped.Load() {
+		s.fail(req, "expressions can only be evaluated while paused")
+		return
+	}
+
+	// without a frame, evaluate in the innermost frame of the stopped fiber
+	s.mu.Lock()
+	fiber, depth := s.lastStop.Fiber, 0
+	s.mu.Unlock()
+	if args.FrameId > 0 {
+		fiber, depth = splitFrameId(args.FrameId)
+	}
+	if !s.inspect(fiber) {
+		s.fail(req, "no thread %d", fiber+1)
+		return
+	}
+
+	resp, ok := s.request(&Evaluate{Expr: args.Expression, Frame: depth}).(*EvaluateResponse)
+	if !ok {
+		s.fail(req, "cannot evaluate %s", args.Expression)
+		return
+	}
+	if resp.Error != "" {
+		s.fail(req, "%s", resp.Error)
+		return
+	}
+
+	s.respond(req, map[string]any{
+		"result":             resp.Value,
+		"type":               resp.Type,
+		"variablesReference": resp.Ref,
+	})
+}
+
+// pump turns debugger events into DAP events and routes command responses
+// back to the request waiting for them
+func (s *dapServer) pump() {
+	for evt := range s.d.eventCh {
+		switch e := evt.(type) {
+		case *StoppedEvent:
+			s.mu.Lock()
+			s.lastStop = e
+			s.mu.Unlock()
+			s.stopped.Store(true)
+			// a stop racing with detach would otherwise wait forever
+			if s.d.Detached() {
+				s.resume(DebugContinue)
+				continue
+			}
+			body := map[string]any{
+				"reason":            e.Reason,
+				"threadId":          e.Fiber + 1,
+				"allThreadsStopped": true,
+			}
+			if e.Error != "" {
+				body["text"] = e.Error
+			}
+			s.event("stopped", body)
+		case *OutputEvent:
+			s.event("output", map[string]any{"category": "console", "output": e.Text})
+		case *TerminatedEvent:
+			if s.progOut != nil {
+				s.progOut.Close()
+				<-s.progOutEOF
+			}
+			exitCode := 0
+			if e.Error != "" {
+				exitCode = 1
+				s.event("output", map[string]any{"category": "stderr", "output": "Runtime error: " + e.Error + "\n"})
+			}
+			s.finished.Store(true)
+			s.event("exited", map[string]any{"exitCode": exitCode})
+			s.event("terminated", nil)
+		default:
+			s.responses <- evt
+		}
+	}
+}
+
+func (s *dapServer) forwardOutput(r *os.File) {
+	defer close(s.progOutEOF)
+
+	buf := make([]byte, 4096)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			s.event("output", map[string]any{"category": "stdout", "output": string(buf[:n])})
+		}
+		if err != nil {
+			return
+		}
+	}
+}
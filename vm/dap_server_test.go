@@ -0,0 +1,305 @@
+package vm
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// dapClient drives a dapServer over an in-memory connection. Everything the
+// server writes is read as it arrives, since the server blocks on writes
+type dapClient struct {
+	t        *testing.T
+	conn     net.Conn
+	seq      int
+	messages chan map[string]any
+}
+
+// startDAPSession serves the DAP for source on one end of a pipe and runs the
+// program once the client has launched and configured it. The program's
+// error is delivered on the returned channel once it has finished
+func startDAPSession(t *testing.T, source string) (*dapClient, chan error) {
+	t.Helper()
+
+	dbg, machine := newDebugSession(t, source)
+	server, conn := net.Pipe()
+	exited := make(chan error, 1)
+	go func() {
+		dbg.newDAPServer(server, server).run()
+		err := machine.Run()
+		dbg.Terminate(err)
+		exited <- err
+	}()
+
+	c := &dapClient{t: t, conn: conn, messages: make(chan map[string]any, 64)}
+	go c.readMessages()
+	t.Cleanup(func() { conn.Close() })
+
+	return c, exited
+}
+
+func (c *dapClient) readMessages() {
+	defer close(c.messages)
+	r := textproto.NewReader(bufio.NewReader(c.conn))
+	for {
+		header, err := r.ReadMIMEHeader()
+		if err != nil {
+			return
+		}
+		length, err := strconv.Atoi(header.Get("Content-Length"))
+		if err != nil {
+			return
+		}
+		body := make([]byte, length)
+		if _, err := io.ReadFull(r.R, body); err != nil {
+			return
+		}
+		var msg map[string]any
+		if err := json.Unmarshal(body, &msg); err != nil {
+			return
+		}
+		c.messages <- msg
+	}
+}
+
+// send writes a request and returns the server's successful response to it
+func (c *dapClient) send(command string, args any) map[string]any {
+	c.t.Helper()
+
+	resp := c.request(command, args)
+	if resp["success"] != true {
+		c.t.Fatalf("%s failed: %v", command, resp["message"])
+	}
+	return resp
+}
+
+// request writes a request and returns the server's response to it
+func (c *dapClient) request(command string, args any) map[string]any {
+	c.t.Helper()
+
+	c.seq++
+	body, err := json.Marshal(map[string]any{"seq": c.seq, "type": "request", "command": command, "arguments": args})
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	if _, err := fmt.Fprintf(c.conn, "Content-Length: %d\r\n\r\n%s", len(body), body); err != nil {
+		c.t.Fatalf("sending %s: %v", command, err)
+	}
+
+	resp := c.expect("response", command)
+	if resp["request_seq"] != float64(c.seq) {
+		c.t.Fatalf("expected a response to request %d, got %v", c.seq, resp)
+	}
+	return resp
+}
+
+// expect skips messages until a response to command or an event named name
+func (c *dapClient) expect(kind, name string) map[string]any {
+	c.t.Helper()
+
+	key := "event"
+	if kind == "response" {
+		key = "command"
+	}
+	for {
+		select {
+		case msg, ok := <-c.messages:
+			if !ok {
+				c.t.Fatalf("connection closed waiting for %s %s", kind, name)
+			}
+			if msg["type"] == kind && msg[key] == name {
+				return msg
+			}
+		case <-time.After(5 * time.Second):
+			c.t.Fatalf("timed out waiting for %s %s", kind, name)
+		}
+	}
+}
+
+func body(msg map[string]any) map[string]any {
+	b, _ := msg["body"].(map[string]any)
+	return b
+}
+
+func TestDAPSession(t *testing.T) {
+	source := `func add(int a, int b) -> int {
+	const sum = a + b;
+	return sum;
+}
+const x = add(1, 2);`
+	c, exited := startDAPSession(t, source)
+
+	resp := c.send("initialize", map[string]any{"adapterID": "sydney"})
+	if body(resp)["supportsConfigurationDoneRequest"] != true {
+		t.Errorf("expected configurationDone support, got %v", body(resp))
+	}
+	c.expect("event", "initialized")
+
+	resp = c.send("setBreakpoints", map[string]any{
+		"source":      map[string]any{"path": "/main.sy"},
+		"breakpoints": []map[string]any{{"line": 2}},
+	})
+	bps, _ := body(resp)["breakpoints"].([]any)
+	if len(bps) != 1 || bps[0].(map[string]any)["verified"] != true {
+		t.Fatalf("expected one verified breakpoint, got %v", body(resp))
+	}
+
+	c.send("launch", map[string]any{})
+	c.send("configurationDone", nil)
+
+	stopped := body(c.expect("event", "stopped"))
+	if stopped["reason"] != StopBreakpoint || stopped["threadId"] != float64(1) {
+		t.Fatalf("expected a breakpoint stop on thread 1, got %v", stopped)
+	}
+
+	resp = c.send("stackTrace", map[string]any{"threadId": 1})
+	frames, _ := body(resp)["stackFrames"].([]any)
+	expected := []struct {
+		name string
+		line float64
+	}{{"add", 2}, {"<main>", 5}}
+	if len(frames) != len(expected) {
+		t.Fatalf("expected %d frames, got %v", len(expected), frames)
+	}
+	for i, want := range expected {
+		frame := frames[i].(map[string]any)
+		if frame["name"] != want.name || frame["line"] != want.line {
+			t.Errorf("frame %d: expected %s at line %v, got %v", i, want.name, want.line, frame)
+		}
+	}
+
+	c.send("continue", map[string]any{"threadId": 1})
+	if code := body(c.expect("event", "exited"))["exitCode"]; code != float64(0) {
+		t.Errorf("expected exit code 0, got %v", code)
+	}
+	c.expect("event", "terminated")
+
+	c.send("disconnect", nil)
+	select {
+	case <-exited:
+	case <-time.After(5 * time.Second):
+		t.Fatal("program did not finish after disconnect")
+	}
+}
+
+func TestDAPExceptionInfo(t *testing.T) {
+	source := `func check(int n) {
+	if (n > 1) {
+		panic("too big");
+	}
+}
+check(2);`
+	c, exited := startDAPSession(t, source)
+
+	c.send("initialize", map[string]any{"adapterID": "sydney"})
+	c.send("setExceptionBreakpoints", map[string]any{"filters": []string{"panic"}})
+	c.send("launch", map[string]any{})
+	c.send("configurationDone", nil)
+
+	stopped := body(c.expect("event", "stopped"))
+	if stopped["reason"] != StopException || stopped["text"] != "panic: too big" {
+		t.Fatalf("expected to stop on the panic, got %v", stopped)
+	}
+
+	info := body(c.send("exceptionInfo", map[string]any{"threadId": 1}))
+	if info["exceptionId"] != "panic" || info["description"] != "panic: too big" {
+		t.Errorf("expected the panic's exception info, got %v", info)
+	}
+
+	c.send("continue", map[string]any{"threadId": 1})
+	if code := body(c.expect("event", "exited"))["exitCode"]; code != float64(1) {
+		t.Errorf("expected exit code 1, got %v", code)
+	}
+	c.expect("event", "terminated")
+
+	c.send("disconnect", nil)
+	select {
+	case <-exited:
+	case <-time.After(5 * time.Second):
+		t.Fatal("program did not finish after disconnect")
+	}
+}
+
+func TestDAPRejectsBadArguments(t *testing.T) {
+	c, _ := startDAPSession(t, `const x = 1;`)
+	c.send("initialize", map[string]any{"adapterID": "sydney"})
+
+	tests := []struct {
+		command string
+		args    any
+	}{
+		{"launch", map[string]any{"stopOnEntry": "yes"}},
+		{"setBreakpoints", map[string]any{"breakpoints": 1}},
+		{"setExceptionBreakpoints", map[string]any{"filters": "panic"}},
+		{"stackTrace", map[string]any{"threadId": "main"}},
+		{"scopes", map[string]any{"frameId": []int{1}}},
+		{"variables", map[string]any{"variablesReference": "x"}},
+		{"evaluate", map[string]any{"expression": 1}},
+	}
+
+	for _, tt := range tests {
+		resp := c.request(tt.command, tt.args)
+		if resp["success"] != false || resp["message"] == nil {
+			t.Errorf("%s: expected a failed response, got %v", tt.command, resp)
+		}
+	}
+}
+
+func TestDAPDisconnectStopsProgram(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		stop   bool // disconnect at a breakpoint rather than while running
+	}{
+		{"paused", "mut n = 0;\nn = 1;\nn = 2;", true},
+		{"running", "mut n = 0;\nfor (mut i = 0; i >= 0; i = i + 1) { n = i; }", false},
+	}
+
+	for _, tt := range tests {
+		c, exited := startDAPSession(t, tt.source)
+		c.send("initialize", map[string]any{"adapterID": "sydney"})
+		if tt.stop {
+			c.send("setBreakpoints", map[string]any{
+				"source":      map[string]any{"path": "/main.sy"},
+				"breakpoints": []map[string]any{{"line": 2}},
+			})
+		}
+		c.send("launch", map[string]any{})
+		c.send("configurationDone", nil)
+		if tt.stop {
+			c.expect("event", "stopped")
+		}
+
+		c.send("disconnect", nil)
+		select {
+		case err := <-exited:
+			if !errors.Is(err, errDetached) {
+				t.Errorf("%s: expected the program to be detached, got %v", tt.name, err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s: program did not stop after disconnect", tt.name)
+		}
+	}
+}
+
+func TestDAPClientLeavesBeforeLaunch(t *testing.T) {
+	c, exited := startDAPSession(t, `const x = 1;`)
+	c.send("initialize", map[string]any{"adapterID": "sydney"})
+	c.conn.Close()
+
+	select {
+	case err := <-exited:
+		if !errors.Is(err, errDetached) {
+			t.Errorf("expected the program to be detached, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("program did not stop after the client left")
+	}
+}
@@ -0,0 +1,337 @@
+package vm
+
+import (
+	"fmt"
+	"slices"
+
+	"sydney/ast"
+	"sydney/code"
+	"sydney/compiler"
+	"sydney/errors"
+	"sydney/lexer"
+	"sydney/object"
+	"sydney/parser"
+	"sydney/typechecker"
+	"sydney/types"
+)
+
+// evalFiberId marks the scratch fiber debugger expressions run on
+const evalFiberId = -1
+
+// evalSliceLimit bounds how many scheduler slices an expression may run for
+const evalSliceLimit = 10000
+
+var errEvalBlocking = fmt.Errorf("channels, spawn and blocking builtins cannot be used in debugger expressions")
+
+// debugExpr is an expression compiled to run in the frames of one function.
+// The frame's locals and captured variables are passed in as arguments, and
+// it carries its own constants so compiling it leaves the program's alone.
+type debugExpr struct {
+	fn        *object.CompiledFunction
+	constants []object.Object
+	t         types.Type
+	locals    []int // slots of the frame passed as arguments,
+	free      []int // then the captured variables
+}
+
+// slotType gives the type a local or, when free is set, captured slot is in
+// scope with, or nil to leave it out
+type slotType func(sym *code.DebugSymbol, free bool, i int) types.Type
+
+// evaluate type checks and runs src, a single Sydney expression, in the scope
+// of a frame of the inspected fiber, counted from the innermost. Locals and
+// captured variables are copied in and cannot be assigned; globals are shared
+// with the program.
+func (vm *VM) evaluate(src string, depth int) (object.Object, types.Type, error) {
+	expr, err := parseExpression(src)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fiber := vm.inspectedFiber()
+	idx := fiber.frameIdx - 1 - depth
+	if depth < 0 || idx < 0 {
+		return nil, nil, fmt.Errorf("no frame %d", depth)
+	}
+	frame := fiber.frames[idx]
+
+	// the top level frame's slots are the globals, already in scope
+	scope := frame.cl.Fn
+	if fiber.id == 0 && idx == 0 {
+		scope = nil
+	}
+
+	de, err := compileExpression(expr, vm.debugger.symbolTable, vm.constants, scope, func(sym *code.DebugSymbol, free bool, i int) types.Type {
+		val := slotValue(fiber, frame, free, i)
+		if val == nil {
+			return nil
+		}
+		if sym.T != nil {
+			return sym.T
+		}
+		return typeOfValue(val)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	result, err := vm.runExpression(de, fiber, frame)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return result, de.t, nil
+}
+
+// compileBreakpoints returns the compiler for breakpoint conditions and log
+// messages. It works from a snapshot of the program, so it can run while the
+// VM does.
+func (vm *VM) compileBreakpoints() func(src, file string, line int) (*lineExpr, error) {
+	main := vm.scheduler.mainFiber.frames[0].cl.Fn
+	constants := slices.Clip(vm.constants)
+	fns := []*object.CompiledFunction{main}
+	for _, c := range constants {
+		if fn, ok := c.(*object.CompiledFunction); ok {
+			fns = append(fns, fn)
+		}
+	}
+	symbolTable := vm.debugger.symbolTable
+
+	declared := func(sym *code.DebugSymbol, free bool, i int) types.Type {
+		return sym.T
+	}
+
+	return func(src, file string, line int) (*lineExpr, error) {
+		e := &lineExpr{src: src, compiled: map[*object.CompiledFunction]compiledIn{}}
+		var firstErr error
+		for _, fn := range fns {
+			if !hasLine(fn, file, line) {
+				continue
+			}
+			scope := fn
+			if fn == main {
+				scope = nil
+			}
+			// checking annotates the tree, so each scope gets its own
+			expr, err := parseExpression(src)
+			if err != nil {
+				return nil, err
+			}
+			de, err := compileExpression(expr, symbolTable, constants, scope, declared)
+			e.compiled[fn] = compiledIn{expr: de, err: err}
+			if err == nil {
+				continue
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+
+		if len(e.compiled) == 0 {
+			// no code on the line, so it never runs, but a mistake in it
+			// is still worth reporting
+			expr, err := parseExpression(src)
+			if err != nil {
+				return nil, err
+			}
+			_, err = compileExpression(expr, symbolTable, constants, nil, declared)
+			return e, err
+		}
+		for _, c := range e.compiled {
+			if c.err == nil {
+				return e, nil
+			}
+		}
+		return nil, firstErr
+	}
+}
+
+// compileExpression type checks and compiles expr in the scope of frames of
+// fn, or of the top level when fn is nil, binding the slots slotType gives a
+// type for
+func compileExpression(expr ast.Expr, symbolTable *compiler.SymbolTable, constants []object.Object, fn *object.CompiledFunction, slotType slotType) (*debugExpr, error) {
+	if symbolTable == nil {
+		symbolTable = compiler.NewSymbolTable()
+	}
+
+	env := typechecker.NewTypeEnv(nil)
+	for _, sym := range symbolTable.Slots() {
+		if sym.Name != "" && sym.Type != nil && *sym.Type != nil {
+			env.Set(sym.Name, *sym.Type)
+		}
+	}
+
+	de := &debugExpr{}
+	var names []string
+	var paramTypes []types.Type
+	bind := func(sym *code.DebugSymbol, free bool, i int) {
+		if sym == nil {
+			return
+		}
+		t := slotType(sym, free, i)
+		if t == nil {
+			return
+		}
+		env.Set(sym.Name, t)
+		names = append(names, sym.Name)
+		paramTypes = append(paramTypes, t)
+		if free {
+			de.free = append(de.free, i)
+		} else {
+			de.locals = append(de.locals, i)
+		}
+	}
+
+	if fn != nil && fn.DebugSymbols != nil {
+		env = typechecker.NewTypeEnv(env)
+		for i, sym := range fn.DebugSymbols.Locals {
+			bind(sym, false, i)
+		}
+		for i, sym := range fn.DebugSymbols.Free {
+			bind(sym, true, i)
+		}
+	}
+
+	checker := typechecker.New(env)
+	t, errs := checker.CheckExpression(expr)
+	for _, err := range errs {
+		if err.Severity == errors.Error {
+			return nil, fmt.Errorf("%s", err.Message)
+		}
+	}
+
+	// a clipped pool makes the compiler copy it before adding constants
+	comp := compiler.NewWithState(symbolTable, slices.Clip(constants))
+	compiled, err := comp.CompileExpression(expr, names, paramTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	de.fn, de.constants, de.t = compiled, comp.Constants(), t
+	return de, nil
+}
+
+// hasLine reports whether fn has instructions on line of file
+func hasLine(fn *object.CompiledFunction, file string, line int) bool {
+	if fn.SourceMap == nil {
+		return false
+	}
+	for _, m := range fn.SourceMap.Mappings {
+		if m.Line == line && m.File == file {
+			return true
+		}
+	}
+	return false
+}
+
+func slotValue(fiber *Fiber, frame *Frame, free bool, i int) object.Object {
+	if free {
+		if i < len(frame.cl.Free) {
+			return frame.cl.Free[i]
+		}
+		return nil
+	}
+	if frame.basePointer+i < len(fiber.stack) {
+		return fiber.stack[frame.basePointer+i]
+	}
+	return nil
+}
+
+// runExpression runs de with the values of frame's slots on fiber. A slot
+// not set yet is passed as null.
+func (vm *VM) runExpression(de *debugExpr, fiber *Fiber, frame *Frame) (object.Object, error) {
+	args := make([]object.Object, 0, len(de.locals)+len(de.free))
+	for _, i := range de.locals {
+		args = append(args, orNull(slotValue(fiber, frame, false, i)))
+	}
+	for _, i := range de.free {
+		args = append(args, orNull(slotValue(fiber, frame, true, i)))
+	}
+
+	return vm.runEval(&object.Closure{Fn: de.fn}, de.constants, args)
+}
+
+func orNull(obj object.Object) object.Object {
+	if obj == nil {
+		return Null
+	}
+	return obj
+}
+
+func parseExpression(src string) (ast.Expr, error) {
+	p := parser.New(lexer.New(src))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, fmt.Errorf("%s", p.Errors()[0])
+	}
+
+	if len(program.Stmts) != 1 {
+		return nil, fmt.Errorf("expected a single expression")
+	}
+	stmt, ok := program.Stmts[0].(*ast.ExpressionStmt)
+	if !ok {
+		return nil, fmt.Errorf("expected an expression, got %s", program.Stmts[0].String())
+	}
+
+	return stmt.Expr, nil
+}
+
+// runEval runs cl to completion on a scratch fiber with the debugger
+// detached, so breakpoints inside called functions do not fire. constants
+// extend the program's with those cl was compiled with.
+func (vm *VM) runEval(cl *object.Closure, constants []object.Object, args []object.Object) (object.Object, error) {
+	fiber := NewFiber(evalFiberId)
+	fiber.stack[0] = cl
+	copy(fiber.stack[1:], args)
+	fiber.PushFrame(NewFrame(cl, 1), cl)
+	fiber.state = Running
+
+	current, debugger, program := vm.scheduler.current, vm.debugger, vm.constants
+	vm.scheduler.current, vm.debugger, vm.constants = fiber, nil, constants
+	defer func() {
+		vm.scheduler.current, vm.debugger, vm.constants = current, debugger, program
+	}()
+
+	for slices := 0; fiber.frameIdx > 0; slices++ {
+		if slices == evalSliceLimit {
+			return nil, fmt.Errorf("expression did not finish")
+		}
+		err := vm.runFiber()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return fiber.stack[0], nil
+}
+
+// typeOfValue recovers a static type from a runtime value, for slots the
+// compiler did not record a type for
+func typeOfValue(obj object.Object) types.Type {
+	switch o := obj.(type) {
+	case *object.Integer:
+		return types.Int
+	case *object.Float:
+		return types.Float
+	case *object.String:
+		return types.String
+	case *object.Boolean:
+		return types.Bool
+	case *object.Byte:
+		return types.Byte
+	case *object.Struct:
+		return o.T.T
+	case *object.Enum:
+		return o.T.T
+	case *object.Tuple:
+		elems := make([]types.Type, len(o.Values))
+		for i, v := range o.Values {
+			if elems[i] = typeOfValue(v); elems[i] == nil {
+				return nil
+			}
+		}
+		return types.TupleType{Types: elems}
+	}
+
+	return nil
+}
@@ -0,0 +1,101 @@
+package vm
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+)
+
+// inspectedFiber is the fiber debugger queries operate on: the one picked
+// with SelectFiber, or the stopped one
+func (vm *VM) inspectedFiber() *Fiber {
+	if vm.debugger != nil && vm.debugger.focus != nil {
+		return vm.debugger.focus
+	}
+	return vm.scheduler.current
+}
+
+func (d *Debugger) handleGetFibers(s *Scheduler) {
+	fibers := []FiberInfo{fiberInfo(s.mainFiber)}
+	for _, f := range s.fibers {
+		fibers = append(fibers, fiberInfo(f))
+	}
+
+	d.eventCh <- &FibersResponse{Fibers: fibers}
+}
+
+func (d *Debugger) handleSelectFiber(cmd *SelectFiber, s *Scheduler) {
+	f := s.fiber(cmd.Id)
+	if f == nil {
+		d.eventCh <- &SelectFiberResponse{Error: fmt.Sprintf("no fiber %d", cmd.Id)}
+		return
+	}
+
+	d.focus = f
+	d.eventCh <- &SelectFiberResponse{Fiber: fiberInfo(f)}
+}
+
+func (d *Debugger) handleGetChannels(s *Scheduler) {
+	ids := make([]int, 0, len(s.channels))
+	for id := range s.channels {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	channels := make([]ChannelInfo, 0, len(ids))
+	for _, id := range ids {
+		ch := s.channels[id]
+		info := ChannelInfo{
+			Id:        id,
+			Capacity:  ch.capacity,
+			Closed:    ch.closed,
+			Buffer:    []LocalVar{},
+			Senders:   []ChannelSender{},
+			Receivers: []int{},
+		}
+
+		// walk the ring buffer from the oldest value
+		for i := 0; i < ch.count; i++ {
+			val := ch.buffer[(ch.head+i)%ch.capacity]
+			info.Buffer = append(info.Buffer, d.variable(fmt.Sprintf("[%d]", i), "", val))
+		}
+		for _, w := range ch.sendQueue {
+			info.Senders = append(info.Senders, ChannelSender{
+				Fiber: w.fiber.id,
+				Value: d.variable("value", "", w.value),
+			})
+		}
+		for _, f := range ch.recvQueue {
+			info.Receivers = append(info.Receivers, f.id)
+		}
+
+		channels = append(channels, info)
+	}
+
+	d.eventCh <- &ChannelsResponse{Channels: channels}
+}
+
+func fiberInfo(f *Fiber) FiberInfo {
+	info := FiberInfo{Id: f.id, State: f.state.String()}
+	if stack := f.callStack(); len(stack) > 0 {
+		info.Function, info.File, info.Line, info.Col = stack[0].Function, stack[0].File, stack[0].Line, stack[0].Col
+	}
+
+	if f.state != Blocked {
+		return info
+	}
+
+	switch {
+	case f.blockCause != nil:
+		info.Channel = f.blockCause.id
+		info.Block = "send"
+		if slices.Contains(f.blockCause.recvQueue, f) {
+			info.Block = "receive"
+		}
+	case f.ioCause != "":
+		info.Block = "io"
+		info.Builtin = f.ioCause
+	}
+
+	return info
+}
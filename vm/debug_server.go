@@ -55,6 +55,9 @@ func (d *Debugger) WaitForClient() {
 		encoder := json.NewEncoder(conn)
 		for evt := range d.eventCh {
 			encoder.Encode(serializeEvent(evt))
+			if _, ok := evt.(*TerminatedEvent); ok {
+				close(d.done)
+			}
 		}
 	}()
 }
@@ -65,10 +68,14 @@ func parseCommand(line string) (DebugCommand, error) {
 
 	switch raw["cmd"] {
 	case "set_breakpoint":
-		return &AddBreakpoint{
+		cmd := &AddBreakpoint{
 			File: raw["file"].(string),
 			Line: int(raw["line"].(float64)),
-		}, nil
+		}
+		cmd.Condition, _ = raw["condition"].(string)
+		cmd.HitCondition, _ = raw["hit_condition"].(string)
+		cmd.LogMessage, _ = raw["log_message"].(string)
+		return cmd, nil
 	case "remove_breakpoint":
 		return &RemoveBreakpoint{
 			File: raw["file"].(string),
@@ -85,11 +92,34 @@ func parseCommand(line string) (DebugCommand, error) {
 	case "step_over":
 		return &SetMode{Flag: DebugStepOver}, nil
 	case "get_locals":
-		return &GetLocals{}, nil
+		frame, _ := raw["frame"].(float64)
+		return &GetLocals{Frame: int(frame)}, nil
+	case "get_variables":
+		ref, _ := raw["ref"].(float64)
+		return &GetVariables{Ref: int(ref)}, nil
 	case "get_stack":
 		return &GetStack{}, nil
 	case "get_callstack":
 		return &GetCallStack{}, nil
+	case "evaluate":
+		frame, _ := raw["frame"].(float64)
+		return &Evaluate{Expr: raw["expr"].(string), Frame: int(frame)}, nil
+	case "add_watch":
+		return &AddWatch{Expr: raw["expr"].(string)}, nil
+	case "remove_watch":
+		return &RemoveWatch{Expr: raw["expr"].(string)}, nil
+	case "get_fibers":
+		return &GetFibers{}, nil
+	case "set_exception_breaks":
+		cmd := &SetExceptionBreaks{}
+		cmd.Errors, _ = raw["errors"].(bool)
+		cmd.Panics, _ = raw["panics"].(bool)
+		return cmd, nil
+	case "get_channels":
+		return &GetChannels{}, nil
+	case "select_fiber":
+		id, _ := raw["fiber"].(float64)
+		return &SelectFiber{Id: int(id)}, nil
 	case "get_source":
 		return &GetSource{
 			File: raw["file"].(string),
@@ -103,11 +133,13 @@ func serializeEvent(evt DebugEvent) map[string]interface{} {
 	switch e := evt.(type) {
 	case *StoppedEvent:
 		return map[string]interface{}{
-			"event":  "stopped",
-			"reason": e.Reason,
-			"file":   e.File,
-			"line":   e.Line,
-			"fiber":  e.Fiber,
+			"event":   "stopped",
+			"reason":  e.Reason,
+			"file":    e.File,
+			"line":    e.Line,
+			"fiber":   e.Fiber,
+			"watches": e.Watches,
+			"error":   e.Error,
 		}
 	case *RunningEvent:
 		return map[string]interface{}{
@@ -128,6 +160,24 @@ func serializeEvent(evt DebugEvent) map[string]interface{} {
 			"event": "response",
 			"type":  "locals",
 			"data":  e.Locals,
+			"free":  e.Free,
+		}
+	case *EvaluateResponse:
+		return map[string]interface{}{
+			"event": "response",
+			"type":  "evaluate",
+			"expr":  e.Expr,
+			"value": e.Value,
+			"vtype": e.Type,
+			"ref":   e.Ref,
+			"error": e.Error,
+		}
+	case *VariablesResponse:
+		return map[string]interface{}{
+			"event": "response",
+			"type":  "variables",
+			"ref":   e.Ref,
+			"data":  e.Children,
 		}
 	case *StackResponse:
 		return map[string]interface{}{
@@ -141,6 +191,25 @@ func serializeEvent(evt DebugEvent) map[string]interface{} {
 			"type":  "callstack",
 			"data":  e.Frames,
 		}
+	case *FibersResponse:
+		return map[string]interface{}{
+			"event": "response",
+			"type":  "fibers",
+			"data":  e.Fibers,
+		}
+	case *ChannelsResponse:
+		return map[string]interface{}{
+			"event": "response",
+			"type":  "channels",
+			"data":  e.Channels,
+		}
+	case *SelectFiberResponse:
+		return map[string]interface{}{
+			"event": "response",
+			"type":  "select_fiber",
+			"data":  e.Fiber,
+			"error": e.Error,
+		}
 	case *SourceResponse:
 		return map[string]interface{}{
 			"event":   "response",
@@ -0,0 +1,116 @@
+package vm
+
+import (
+	"fmt"
+	"sort"
+
+	"sydney/object"
+	"sydney/types"
+)
+
+// variable describes obj for a debugger client. Structs, enums with values,
+// tuples, arrays and maps get a handle so their children can be fetched with
+// GetVariables; handles are only valid until the VM resumes.
+func (d *Debugger) variable(name string, typ string, obj object.Object) LocalVar {
+	v := LocalVar{Name: name, Type: typ}
+	if obj == nil {
+		return v
+	}
+
+	// show what an interface value holds rather than the itab
+	if iface, ok := obj.(*object.Interface); ok && iface.Value != nil {
+		obj = iface.Value
+	}
+
+	if v.Type == "" {
+		v.Type = valueType(obj)
+	}
+
+	switch o := obj.(type) {
+	case *object.Array:
+		v.Value = fmt.Sprintf("array[%d]", len(o.Elements))
+	case *object.Tuple:
+		v.Value = fmt.Sprintf("tuple[%d]", len(o.Values))
+	case *object.Hash:
+		v.Value = fmt.Sprintf("map[%d]", len(o.Pairs))
+	case *object.Struct:
+		v.Value = o.T.T.(types.StructType).Name + " {...}"
+	case *object.Enum:
+		t := o.T.T.(types.EnumType)
+		v.Value = t.Name + "." + t.Variants[o.Tag]
+		if len(o.Values) == 0 {
+			return v
+		}
+		v.Value += "(...)"
+	case *object.Closure:
+		v.Value = "func " + o.Fn.Name
+		return v
+	default:
+		v.Value = obj.Inspect()
+		return v
+	}
+
+	d.handles = append(d.handles, obj)
+	v.Ref = len(d.handles)
+
+	return v
+}
+
+func (d *Debugger) children(ref int) []LocalVar {
+	if ref <= 0 || ref > len(d.handles) {
+		return []LocalVar{}
+	}
+
+	children := []LocalVar{}
+	switch o := d.handles[ref-1].(type) {
+	case *object.Array:
+		for i, e := range o.Elements {
+			children = append(children, d.variable(fmt.Sprintf("[%d]", i), "", e))
+		}
+	case *object.Tuple:
+		for i, e := range o.Values {
+			children = append(children, d.variable(fmt.Sprintf("[%d]", i), "", e))
+		}
+	case *object.Struct:
+		t := o.T.T.(types.StructType)
+		for i, f := range o.Fields {
+			typ := ""
+			if i < len(t.Types) && t.Types[i] != nil {
+				typ = t.Types[i].Signature()
+			}
+			children = append(children, d.variable(t.Fields[i], typ, f))
+		}
+	case *object.Enum:
+		payload := o.T.T.(types.EnumType).Payloads[o.Tag]
+		for i, val := range o.Values {
+			typ := ""
+			if i < len(payload) && payload[i] != nil {
+				typ = payload[i].Signature()
+			}
+			children = append(children, d.variable(fmt.Sprintf("[%d]", i), typ, val))
+		}
+	case *object.Hash:
+		pairs := make([]object.HashPair, 0, len(o.Pairs))
+		for _, p := range o.Pairs {
+			pairs = append(pairs, p)
+		}
+		sort.Slice(pairs, func(i, j int) bool {
+			return pairs[i].Key.Inspect() < pairs[j].Key.Inspect()
+		})
+		for _, p := range pairs {
+			children = append(children, d.variable(p.Key.Inspect(), "", p.Value))
+		}
+	}
+
+	return children
+}
+
+func valueType(obj object.Object) string {
+	switch o := obj.(type) {
+	case *object.Struct:
+		return o.T.T.Signature()
+	case *object.Enum:
+		return o.T.T.Signature()
+	}
+	return string(obj.Type())
+}
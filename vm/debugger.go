@@ -1,9 +1,16 @@
 package vm
 
 import (
-	"sydney/code"
+	"errors"
+	"log"
+	"path/filepath"
+	"slices"
+	"sync"
+	"sync/atomic"
+
 	"sydney/compiler"
 	"sydney/object"
+	"sydney/types"
 )
 
 type DebugMode int
@@ -16,15 +23,39 @@ const (
 	DebugStepOut
 )
 
+// stop reasons reported in StoppedEvent
+const (
+	StopEntry      = "entry"
+	StopStep       = "step"
+	StopBreakpoint = "breakpoint"
+	StopPause      = "pause"
+	StopException  = "exception"
+)
+
 type Debugger struct {
 	Flag        DebugMode
 	cmdCh       chan DebugCommand
 	eventCh     chan DebugEvent
-	breakpoints map[string]map[int]bool // file  -> line -> enabled
-	sources     map[string]string       // file  -> contents
+	breakpoints map[string]map[int]*Breakpoint // file  -> line -> breakpoint
+	bpMu        sync.Mutex                     // breakpoints can change while the VM runs
+	sources     map[string]string              // file  -> contents
 	lastLine    int
 	lastFile    string
 	stepFrame   int
+	stopReason  string
+	started     bool
+	handles     []object.Object // expandable values handed out since the last stop
+	focus       *Fiber          // fiber picked with SelectFiber, until the VM resumes
+	watches     []string
+	watchMu     sync.Mutex
+	eval        func(src string, frame int) (object.Object, types.Type, error)
+	compile     func(src, file string, line int) (*lineExpr, error) // for breakpoints, safe while the VM runs
+	run         func(expr *debugExpr, frame *Frame) (object.Object, error)
+	pause       atomic.Bool
+	breakErrors atomic.Bool // stop on runtime errors, see SetExceptionBreaks
+	breakPanics atomic.Bool
+	detached    atomic.Bool   // the client went away, see Detach
+	done        chan struct{} // closed by the client side once the session is over
 	symbolTable *compiler.SymbolTable
 }
 
@@ -33,11 +64,12 @@ func NewDebugger(symbolTable *compiler.SymbolTable) *Debugger {
 		Flag:        DebugStepLine,
 		cmdCh:       make(chan DebugCommand),
 		eventCh:     make(chan DebugEvent),
-		breakpoints: make(map[string]map[int]bool),
+		breakpoints: make(map[string]map[int]*Breakpoint),
 		sources:     make(map[string]string),
 		lastLine:    0,
 		lastFile:    "",
 		stepFrame:   0,
+		done:        make(chan struct{}),
 		symbolTable: symbolTable,
 	}
 }
@@ -57,21 +89,68 @@ type (
 		Name  string
 		Type  string
 		Value string
+		Ref   int // non-zero for structs, arrays and maps; expand with GetVariables
 	}
 	StackEntry struct {
 		Value string
 		Type  string
 	}
-	FrameInfo struct{}
+	FrameInfo struct {
+		Index    int // 0 is the innermost frame
+		Function string
+		File     string
+		Line     int
+		Col      int
+	}
+	// WatchValue is the result of a watch expression at a stop; Error is set
+	// instead of Value when it could not be evaluated
+	WatchValue struct {
+		Expr  string
+		Value string
+		Type  string
+		Ref   int
+		Error string
+	}
+	// FiberInfo describes a fiber and where it is. Block is "send" or
+	// "receive" with the Channel id, "io" with the Builtin being waited on,
+	// or empty when the fiber is not blocked.
+	FiberInfo struct {
+		Id       int
+		State    string
+		Function string
+		File     string
+		Line     int
+		Col      int
+		Block    string
+		Channel  int
+		Builtin  string
+	}
+	// ChannelInfo describes a channel's buffered values and the fibers
+	// waiting on it, in queue order
+	ChannelInfo struct {
+		Id        int
+		Capacity  int
+		Closed    bool
+		Buffer    []LocalVar
+		Senders   []ChannelSender
+		Receivers []int
+	}
+	ChannelSender struct {
+		Fiber int
+		Value LocalVar
+	}
 )
 
 // events
 type (
 	StoppedEvent struct {
-		Reason string
-		File   string
-		Line   int
-		Fiber  int
+		Reason  string
+		File    string
+		Line    int
+		Fiber   int
+		Watches []WatchValue
+		Error   string // the runtime error, for exception stops
+		Panic   bool   // whether Error was raised by the panic builtin
 	}
 
 	RunningEvent struct{}
@@ -86,6 +165,20 @@ type (
 
 	LocalsResponse struct {
 		Locals []LocalVar
+		Free   []LocalVar
+	}
+
+	VariablesResponse struct {
+		Ref      int
+		Children []LocalVar
+	}
+
+	EvaluateResponse struct {
+		Expr  string
+		Value string
+		Type  string
+		Ref   int
+		Error string
 	}
 
 	StackResponse struct {
@@ -100,16 +193,34 @@ type (
 		File    string
 		Content string
 	}
+
+	FibersResponse struct {
+		Fibers []FiberInfo
+	}
+
+	ChannelsResponse struct {
+		Channels []ChannelInfo
+	}
+
+	SelectFiberResponse struct {
+		Fiber FiberInfo
+		Error string
+	}
 )
 
-func (s *StoppedEvent) dbgEvent()      {}
-func (r *RunningEvent) dbgEvent()      {}
-func (t *TerminatedEvent) dbgEvent()   {}
-func (o *OutputEvent) dbgEvent()       {}
-func (l *LocalsResponse) dbgEvent()    {}
-func (s *StackResponse) dbgEvent()     {}
-func (c *CallStackResponse) dbgEvent() {}
-func (s *SourceResponse) dbgEvent()    {}
+func (s *StoppedEvent) dbgEvent()        {}
+func (r *RunningEvent) dbgEvent()        {}
+func (t *TerminatedEvent) dbgEvent()     {}
+func (o *OutputEvent) dbgEvent()         {}
+func (l *LocalsResponse) dbgEvent()      {}
+func (s *StackResponse) dbgEvent()       {}
+func (c *CallStackResponse) dbgEvent()   {}
+func (s *SourceResponse) dbgEvent()      {}
+func (f *FibersResponse) dbgEvent()      {}
+func (v *VariablesResponse) dbgEvent()   {}
+func (e *EvaluateResponse) dbgEvent()    {}
+func (c *ChannelsResponse) dbgEvent()    {}
+func (s *SelectFiberResponse) dbgEvent() {}
 
 // commands
 type (
@@ -117,9 +228,14 @@ type (
 		Flag DebugMode
 	}
 
+	// AddBreakpoint sets or replaces the breakpoint on a line. Condition,
+	// HitCondition and LogMessage are optional; see Breakpoint.
 	AddBreakpoint struct {
-		File string
-		Line int
+		File         string
+		Line         int
+		Condition    string
+		HitCondition string
+		LogMessage   string
 	}
 
 	RemoveBreakpoint struct {
@@ -127,7 +243,32 @@ type (
 		Line int
 	}
 
-	GetLocals struct{}
+	// GetLocals reads the locals and captured variables of a frame of the
+	// selected fiber, counted from the innermost frame
+	GetLocals struct {
+		Frame int
+	}
+
+	GetVariables struct {
+		Ref int
+	}
+
+	// Evaluate runs an expression in a frame of the selected fiber, counted
+	// from the innermost
+	Evaluate struct {
+		Expr  string
+		Frame int
+	}
+
+	// AddWatch registers an expression that is evaluated in the innermost
+	// frame at every stop and reported in StoppedEvent.Watches
+	AddWatch struct {
+		Expr string
+	}
+
+	RemoveWatch struct {
+		Expr string
+	}
 
 	GetStack struct{}
 
@@ -136,25 +277,74 @@ type (
 	GetSource struct {
 		File string
 	}
-)
 
-func (s *SetMode) dbgCmd()          {}
-func (a *AddBreakpoint) dbgCmd()    {}
-func (r *RemoveBreakpoint) dbgCmd() {}
-func (g *GetLocals) dbgCmd()        {}
-func (g *GetStack) dbgCmd()         {}
-func (g *GetCallStack) dbgCmd()     {}
-func (g *GetSource) dbgCmd()        {}
+	GetFibers struct{}
+
+	GetChannels struct{}
+
+	// SetExceptionBreaks chooses whether runtime errors and `panic` calls
+	// stop at the faulting instruction before the program terminates
+	SetExceptionBreaks struct {
+		Errors bool
+		Panics bool
+	}
 
-func (d *Debugger) shouldStop(ip int, frameIdx int, sm *code.SourceMap) bool {
-	line, _, file := sm.LineForOffset(ip)
+	// SelectFiber makes GetLocals, GetStack, GetCallStack and Evaluate
+	// inspect another fiber until the VM resumes
+	SelectFiber struct {
+		Id int
+	}
+)
+
+func (s *SetMode) dbgCmd()            {}
+func (a *AddBreakpoint) dbgCmd()      {}
+func (r *RemoveBreakpoint) dbgCmd()   {}
+func (g *GetLocals) dbgCmd()          {}
+func (g *GetStack) dbgCmd()           {}
+func (g *GetCallStack) dbgCmd()       {}
+func (g *GetSource) dbgCmd()          {}
+func (g *GetFibers) dbgCmd()          {}
+func (g *GetVariables) dbgCmd()       {}
+func (e *Evaluate) dbgCmd()           {}
+func (a *AddWatch) dbgCmd()           {}
+func (r *RemoveWatch) dbgCmd()        {}
+func (g *GetChannels) dbgCmd()        {}
+func (s *SetExceptionBreaks) dbgCmd() {}
+func (s *SelectFiber) dbgCmd()        {}
+
+// shouldStop reports whether the VM should pause before the next instruction
+// of frame and records why in stopReason
+func (d *Debugger) shouldStop(frame *Frame, frameIdx int) bool {
+	line, _, file := frame.cl.Fn.SourceMap.LineForOffset(frame.ip)
 	if line == 0 {
 		return false
 	}
 
+	// breakpoints fire once when a frame reaches their line, not for every
+	// instruction on it or when a call on that line returns
+	entered := frame.line != line
+	frame.line = line
+
+	if d.pause.CompareAndSwap(true, false) {
+		d.stopReason = StopPause
+		return true
+	}
+
+	if entered {
+		if bp := d.breakpointAt(file, line); bp != nil && d.breakpointFires(bp, frame) {
+			d.stopReason = StopBreakpoint
+			return true
+		}
+	}
+
+	d.stopReason = StopStep
+	if !d.started {
+		d.stopReason = StopEntry
+	}
+
 	switch d.Flag {
 	case DebugContinue:
-		return d.breakpoints[file][line]
+		return false
 	case DebugStepLine:
 		return file != d.lastFile || line != d.lastLine
 	case DebugStepOver:
@@ -168,6 +358,26 @@ func (d *Debugger) shouldStop(ip int, frameIdx int, sm *code.SourceMap) bool {
 	return false
 }
 
+// errDetached ends a program whose debugger client has gone away
+var errDetached = errors.New("debugger detached")
+
+// Detach stops the program at its next instruction, once the client can no
+// longer drive it. Run then returns and Detached reports true.
+func (d *Debugger) Detach() {
+	d.detached.Store(true)
+}
+
+// Detached reports whether the program was stopped by Detach
+func (d *Debugger) Detached() bool {
+	return d.detached.Load()
+}
+
+// RequestPause asks the VM to stop before the next instruction that maps to
+// a source line. Safe to call while the program is running.
+func (d *Debugger) RequestPause() {
+	d.pause.Store(true)
+}
+
 func (d *Debugger) handleCommand(cmd DebugCommand) {
 	switch c := cmd.(type) {
 	case *SetMode:
@@ -176,47 +386,154 @@ func (d *Debugger) handleCommand(cmd DebugCommand) {
 		d.handleAddBreakpoint(c)
 	case *RemoveBreakpoint:
 		d.handleRemoveBreakpoint(c)
-	case *GetCallStack:
-		d.handleGetCallStack(c)
 	case *GetSource:
 		d.handleGetSource(c)
+	case *AddWatch:
+		d.handleAddWatch(c)
+	case *RemoveWatch:
+		d.handleRemoveWatch(c)
+	case *SetExceptionBreaks:
+		d.setExceptionBreaks(c)
 	}
 }
 
+func (d *Debugger) setExceptionBreaks(cmd *SetExceptionBreaks) {
+	d.breakErrors.Store(cmd.Errors)
+	d.breakPanics.Store(cmd.Panics)
+}
+
+// breaksOn reports whether err, raised by the running fiber, should stop
+// the debugger before the program terminates
+func (d *Debugger) breaksOn(err error) bool {
+	if isPanic(err) {
+		return d.breakPanics.Load()
+	}
+	return d.breakErrors.Load()
+}
+
+// isPanic reports whether a runtime error came from the `panic` builtin
+func isPanic(err error) bool {
+	var panicErr *PanicError
+	return errors.As(err, &panicErr)
+}
+
 func (d *Debugger) handleSetMode(cmd *SetMode) {
 	d.Flag = cmd.Flag
 }
 
 func (d *Debugger) handleAddBreakpoint(cmd *AddBreakpoint) {
-	if d.breakpoints[cmd.File] == nil {
-		d.breakpoints[cmd.File] = make(map[int]bool)
+	_, err := d.setBreakpoint(cmd)
+	if err != nil {
+		log.Printf("breakpoint %s:%d: %s", cmd.File, cmd.Line, err)
 	}
-
-	d.breakpoints[cmd.File][cmd.Line] = true
 }
 
 func (d *Debugger) handleRemoveBreakpoint(cmd *RemoveBreakpoint) {
-	d.breakpoints[cmd.File][cmd.Line] = false
+	d.bpMu.Lock()
+	defer d.bpMu.Unlock()
+
+	delete(d.breakpoints[normalizePath(cmd.File)], cmd.Line)
 }
 
-func (d *Debugger) handleGetLocals(dbgSyms *code.DebugSymbols, stack []object.Object, bp int) {
-	locals := make([]LocalVar, len(dbgSyms.Locals))
-	for i, local := range dbgSyms.Locals {
-		if local == nil {
+// normalizePath makes client supplied paths comparable with the absolute
+// paths the compiler records in source maps under `sydney debug`
+func normalizePath(file string) string {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return file
+	}
+	return abs
+}
+
+func (d *Debugger) handleGetLocals(cmd *GetLocals, fiber *Fiber, globals []object.Object) {
+	resp := &LocalsResponse{Locals: []LocalVar{}, Free: []LocalVar{}}
+	idx := fiber.frameIdx - 1 - cmd.Frame
+	if cmd.Frame < 0 || idx < 0 || fiber.frames[idx].cl.Fn.DebugSymbols == nil {
+		d.eventCh <- resp
+		return
+	}
+
+	frame := fiber.frames[idx]
+	syms := frame.cl.Fn.DebugSymbols
+
+	// the slots of the top level program are the globals
+	slots := fiber.stack[frame.basePointer:]
+	if fiber.id == 0 && idx == 0 {
+		slots = globals
+	}
+
+	for i, sym := range syms.Locals {
+		if sym == nil || i >= len(slots) {
 			continue
 		}
-		l := LocalVar{
-			Name: local.Name,
-			Type: local.Type,
-		}
-		if stack[bp+i] != nil {
-			l.Value = stack[bp+i].Inspect()
+		resp.Locals = append(resp.Locals, d.variable(sym.Name, sym.Type, slots[i]))
+	}
+
+	for i, sym := range syms.Free {
+		if i >= len(frame.cl.Free) {
+			break
 		}
-		locals[i] = l
+		resp.Free = append(resp.Free, d.variable(sym.Name, sym.Type, frame.cl.Free[i]))
 	}
-	d.eventCh <- &LocalsResponse{
-		Locals: locals,
+
+	d.eventCh <- resp
+}
+
+func (d *Debugger) handleEvaluate(cmd *Evaluate) {
+	d.eventCh <- d.evaluate(cmd.Expr, cmd.Frame)
+}
+
+func (d *Debugger) evaluate(expr string, frame int) *EvaluateResponse {
+	resp := &EvaluateResponse{Expr: expr}
+	val, t, err := d.eval(expr, frame)
+	if err != nil {
+		resp.Error = err.Error()
+		return resp
+	}
+
+	typ := ""
+	if t != nil {
+		typ = t.Signature()
 	}
+	v := d.variable(expr, typ, val)
+	resp.Value, resp.Type, resp.Ref = v.Value, v.Type, v.Ref
+
+	return resp
+}
+
+func (d *Debugger) handleAddWatch(cmd *AddWatch) {
+	d.watchMu.Lock()
+	defer d.watchMu.Unlock()
+
+	if !slices.Contains(d.watches, cmd.Expr) {
+		d.watches = append(d.watches, cmd.Expr)
+	}
+}
+
+func (d *Debugger) handleRemoveWatch(cmd *RemoveWatch) {
+	d.watchMu.Lock()
+	defer d.watchMu.Unlock()
+
+	d.watches = slices.DeleteFunc(d.watches, func(w string) bool { return w == cmd.Expr })
+}
+
+// evaluateWatches runs every watch expression in the innermost frame
+func (d *Debugger) evaluateWatches() []WatchValue {
+	d.watchMu.Lock()
+	watches := slices.Clone(d.watches)
+	d.watchMu.Unlock()
+
+	values := make([]WatchValue, 0, len(watches))
+	for _, w := range watches {
+		r := d.evaluate(w, 0)
+		values = append(values, WatchValue{Expr: w, Value: r.Value, Type: r.Type, Ref: r.Ref, Error: r.Error})
+	}
+
+	return values
+}
+
+func (d *Debugger) handleGetVariables(cmd *GetVariables) {
+	d.eventCh <- &VariablesResponse{Ref: cmd.Ref, Children: d.children(cmd.Ref)}
 }
 
 func (d *Debugger) handleGetStack(stack []object.Object) {
@@ -237,7 +554,19 @@ func (d *Debugger) handleGetStack(stack []object.Object) {
 	}
 }
 
-func (d *Debugger) handleGetCallStack(cmd *GetCallStack) {
+func (d *Debugger) handleGetCallStack(fiber *Fiber) {
+	frames := make([]FrameInfo, 0, fiber.frameIdx)
+	for i, sf := range fiber.callStack() {
+		frames = append(frames, FrameInfo{
+			Index:    i,
+			Function: sf.Function,
+			File:     sf.File,
+			Line:     sf.Line,
+			Col:      sf.Col,
+		})
+	}
+
+	d.eventCh <- &CallStackResponse{Frames: frames}
 }
 
 func (d *Debugger) handleGetSource(cmd *GetSource) {
@@ -249,6 +578,17 @@ func (d *Debugger) AddSource(file string, content string) {
 	d.sources[file] = content
 }
 
+// Terminate reports the end of the program to the client and waits until the
+// client side has delivered it
+func (d *Debugger) Terminate(err error) {
+	evt := &TerminatedEvent{}
+	if err != nil {
+		evt.Error = err.Error()
+	}
+	d.eventCh <- evt
+	<-d.done
+}
+
 func isResumeCommand(cmd DebugCommand) bool {
 	_, ok := cmd.(*SetMode)
 	return ok
@@ -0,0 +1,579 @@
+package vm
+
+import (
+	"strings"
+	"testing"
+
+	"sydney/compiler"
+	"sydney/object"
+	"sydney/typechecker"
+)
+
+// startDebugSession compiles source as /main.sy and runs it under a fresh
+// debugger on a separate goroutine. The VM starts in step-line mode, so the
+// first event is always a stop on entry.
+func startDebugSession(t *testing.T, source string) *Debugger {
+	t.Helper()
+
+	dbg, machine := newDebugSession(t, source)
+	go func() {
+		err := machine.Run()
+		dbg.Terminate(err)
+	}()
+
+	return dbg
+}
+
+// newDebugSession compiles source as /main.sy for a VM with a debugger
+// attached, without running it
+func newDebugSession(t *testing.T, source string) (*Debugger, *VM) {
+	t.Helper()
+
+	program := parse(source)
+	c := typechecker.New(nil)
+	errs := c.Check(program, nil)
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+
+	symbolTable := compiler.NewSymbolTable()
+	for i, v := range object.Builtins {
+		symbolTable.DefineBuiltin(i, v.Name)
+	}
+	comp := compiler.NewWithState(symbolTable, []object.Object{})
+	comp.ShouldEmitDebug(true)
+	comp.SetFileName("/main.sy")
+	err := comp.Compile(program)
+	if err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	dbg := NewDebugger(symbolTable)
+	machine := New(comp.Bytecode())
+	machine.AttachDebugger(dbg)
+
+	return dbg, machine
+}
+
+func expectStop(t *testing.T, dbg *Debugger, reason string, line int) *StoppedEvent {
+	t.Helper()
+
+	evt, ok := (<-dbg.eventCh).(*StoppedEvent)
+	if !ok {
+		t.Fatalf("expected *StoppedEvent, got %T", evt)
+	}
+	if evt.Reason != reason || evt.Line != line {
+		t.Fatalf("wrong stop. want=%s at %d, got=%s at %d", reason, line, evt.Reason, evt.Line)
+	}
+
+	return evt
+}
+
+func expectTerminated(t *testing.T, dbg *Debugger) *TerminatedEvent {
+	t.Helper()
+
+	evt, ok := (<-dbg.eventCh).(*TerminatedEvent)
+	if !ok {
+		t.Fatalf("expected *TerminatedEvent, got %T", evt)
+	}
+	close(dbg.done)
+
+	return evt
+}
+
+func TestDebuggerStopReasons(t *testing.T) {
+	source := `mut x = 1;
+x = x + 1;
+x = x + 2;
+x = x + 3;`
+
+	dbg := startDebugSession(t, source)
+	expectStop(t, dbg, StopEntry, 1)
+
+	dbg.cmdCh <- &AddBreakpoint{File: "/main.sy", Line: 3}
+	dbg.cmdCh <- &SetMode{Flag: DebugStepLine}
+	expectStop(t, dbg, StopStep, 2)
+
+	dbg.cmdCh <- &SetMode{Flag: DebugContinue}
+	expectStop(t, dbg, StopBreakpoint, 3)
+
+	dbg.cmdCh <- &RemoveBreakpoint{File: "/main.sy", Line: 3}
+	dbg.cmdCh <- &SetMode{Flag: DebugContinue}
+	evt := expectTerminated(t, dbg)
+	if evt.Error != "" {
+		t.Fatalf("unexpected runtime error: %s", evt.Error)
+	}
+}
+
+func TestDebuggerFibers(t *testing.T) {
+	source := `const ch = chan<int>();
+spawn func() { ch <- 1; }();
+const v = <-ch;`
+
+	dbg := startDebugSession(t, source)
+	expectStop(t, dbg, StopEntry, 1)
+
+	dbg.cmdCh <- &AddBreakpoint{File: "/main.sy", Line: 3}
+	dbg.cmdCh <- &SetMode{Flag: DebugContinue}
+	expectStop(t, dbg, StopBreakpoint, 3)
+
+	dbg.cmdCh <- &GetFibers{}
+	resp, ok := (<-dbg.eventCh).(*FibersResponse)
+	if !ok {
+		t.Fatalf("expected *FibersResponse, got %T", resp)
+	}
+	if len(resp.Fibers) != 2 || resp.Fibers[0].Id != 0 || resp.Fibers[1].Id != 1 {
+		t.Fatalf("wrong fibers: %+v", resp.Fibers)
+	}
+
+	dbg.cmdCh <- &SetMode{Flag: DebugContinue}
+	expectTerminated(t, dbg)
+}
+
+func TestDebuggerFiberInspection(t *testing.T) {
+	source := `const ch = chan<int>(1);
+const done = chan<int>();
+spawn func(int n) {
+	ch <- n;
+	ch <- n + 1;
+}(10);
+spawn func() {
+	const x = <-done;
+}();
+const probe = chan<int>(1);
+probe <- 0;
+const p = <-probe;
+const b = <-ch;
+done <- b;
+const c = <-ch;`
+
+	dbg := startDebugSession(t, source)
+	expectStop(t, dbg, StopEntry, 1)
+
+	dbg.cmdCh <- &AddBreakpoint{File: "/main.sy", Line: 13}
+	dbg.cmdCh <- &SetMode{Flag: DebugContinue}
+	expectStop(t, dbg, StopBreakpoint, 13)
+
+	dbg.cmdCh <- &GetFibers{}
+	fibers := (<-dbg.eventCh).(*FibersResponse).Fibers
+	expectedFibers := []FiberInfo{
+		{Id: 0, State: "running", Line: 13},
+		{Id: 1, State: "blocked", Line: 5, Block: "send", Channel: 1},
+		{Id: 2, State: "blocked", Line: 8, Block: "receive", Channel: 2},
+	}
+	if len(fibers) != len(expectedFibers) {
+		t.Fatalf("wrong number of fibers. want=%d, got=%d", len(expectedFibers), len(fibers))
+	}
+	for i, want := range expectedFibers {
+		got := fibers[i]
+		if got.Id != want.Id || got.State != want.State || got.Line != want.Line || got.Block != want.Block || got.Channel != want.Channel {
+			t.Errorf("fiber %d wrong. want=%+v, got=%+v", i, want, got)
+		}
+	}
+
+	dbg.cmdCh <- &GetChannels{}
+	channels := (<-dbg.eventCh).(*ChannelsResponse).Channels
+	if len(channels) != 3 {
+		t.Fatalf("wrong number of channels. want=3, got=%d", len(channels))
+	}
+	ch := channels[0]
+	if ch.Id != 1 || ch.Capacity != 1 || len(ch.Buffer) != 1 || ch.Buffer[0].Value != "10" {
+		t.Fatalf("wrong buffer for channel 1: %+v", ch)
+	}
+	if len(ch.Senders) != 1 || ch.Senders[0].Fiber != 1 || ch.Senders[0].Value.Value != "11" {
+		t.Fatalf("wrong send queue for channel 1: %+v", ch.Senders)
+	}
+	if done := channels[1]; len(done.Receivers) != 1 || done.Receivers[0] != 2 {
+		t.Fatalf("wrong receive queue for channel 2: %+v", done.Receivers)
+	}
+
+	dbg.cmdCh <- &SelectFiber{Id: 1}
+	if resp := (<-dbg.eventCh).(*SelectFiberResponse); resp.Error != "" || resp.Fiber.Id != 1 {
+		t.Fatalf("select fiber failed: %+v", resp)
+	}
+	dbg.cmdCh <- &GetCallStack{}
+	stack := (<-dbg.eventCh).(*CallStackResponse).Frames
+	if len(stack) != 1 || stack[0].Line != 5 {
+		t.Fatalf("wrong call stack for fiber 1: %+v", stack)
+	}
+	dbg.cmdCh <- &GetLocals{}
+	locals := (<-dbg.eventCh).(*LocalsResponse).Locals
+	if len(locals) != 1 || locals[0].Name != "n" || locals[0].Value != "10" {
+		t.Fatalf("wrong locals for fiber 1: %+v", locals)
+	}
+	dbg.cmdCh <- &Evaluate{Expr: "n + 1"}
+	if resp := (<-dbg.eventCh).(*EvaluateResponse); resp.Value != "11" {
+		t.Fatalf("wrong evaluation in fiber 1: %+v", resp)
+	}
+
+	dbg.cmdCh <- &SelectFiber{Id: 7}
+	if resp := (<-dbg.eventCh).(*SelectFiberResponse); resp.Error == "" {
+		t.Fatalf("expected error selecting unknown fiber")
+	}
+
+	dbg.cmdCh <- &SetMode{Flag: DebugContinue}
+	expectTerminated(t, dbg)
+}
+
+func TestDebuggerCallStackAndVariables(t *testing.T) {
+	source := `define struct Point { x int, y int }
+func make_adder(int n) -> fn<(int) -> int> {
+	const base = [n, n];
+	return func(int x) -> int {
+		const p = Point { x: x, y: base[0] };
+		return p.x + p.y;
+	};
+}
+const add2 = make_adder(2);
+add2(5);`
+
+	dbg := startDebugSession(t, source)
+	expectStop(t, dbg, StopEntry, 9)
+
+	dbg.cmdCh <- &AddBreakpoint{File: "/main.sy", Line: 6}
+	dbg.cmdCh <- &SetMode{Flag: DebugContinue}
+	expectStop(t, dbg, StopBreakpoint, 6)
+
+	dbg.cmdCh <- &GetCallStack{}
+	stack := (<-dbg.eventCh).(*CallStackResponse)
+	expectedFrames := []FrameInfo{
+		{Index: 0, Function: "<anonymous>", File: "/main.sy", Line: 6},
+		{Index: 1, Function: "<main>", File: "/main.sy", Line: 10},
+	}
+	if len(stack.Frames) != len(expectedFrames) {
+		t.Fatalf("wrong number of frames. want=%d, got=%d", len(expectedFrames), len(stack.Frames))
+	}
+	for i, want := range expectedFrames {
+		got := stack.Frames[i]
+		if got.Index != want.Index || got.Function != want.Function || got.File != want.File || got.Line != want.Line {
+			t.Errorf("frame %d wrong. want=%+v, got=%+v", i, want, got)
+		}
+	}
+
+	dbg.cmdCh <- &GetLocals{Frame: 0}
+	locals := (<-dbg.eventCh).(*LocalsResponse)
+	if len(locals.Locals) != 2 || locals.Locals[0].Name != "x" || locals.Locals[0].Value != "5" {
+		t.Fatalf("wrong locals: %+v", locals.Locals)
+	}
+	if len(locals.Free) != 1 || locals.Free[0].Name != "base" || locals.Free[0].Ref == 0 {
+		t.Fatalf("wrong free variables: %+v", locals.Free)
+	}
+
+	point := locals.Locals[1]
+	if point.Name != "p" || point.Ref == 0 {
+		t.Fatalf("expected expandable struct p, got %+v", point)
+	}
+	dbg.cmdCh <- &GetVariables{Ref: point.Ref}
+	fields := (<-dbg.eventCh).(*VariablesResponse)
+	if len(fields.Children) != 2 || fields.Children[0].Name != "x" || fields.Children[1].Value != "2" {
+		t.Fatalf("wrong struct fields: %+v", fields.Children)
+	}
+
+	dbg.cmdCh <- &GetLocals{Frame: 1}
+	globals := (<-dbg.eventCh).(*LocalsResponse)
+	if len(globals.Locals) != 2 || globals.Locals[0].Name != "make_adder" || globals.Locals[1].Name != "add2" {
+		t.Fatalf("wrong globals: %+v", globals.Locals)
+	}
+
+	dbg.cmdCh <- &SetMode{Flag: DebugContinue}
+	expectTerminated(t, dbg)
+}
+
+func TestDebuggerConditionalBreakpoints(t *testing.T) {
+	source := `func square(int i) -> int {
+	const sq = i * i;
+	return sq;
+}
+mut total = 0;
+for (mut i = 0; i < 10; i = i + 1) {
+	total = total + square(i);
+}`
+
+	dbg := startDebugSession(t, source)
+	expectStop(t, dbg, StopEntry, 5)
+
+	localValue := func(name string) string {
+		dbg.cmdCh <- &GetLocals{}
+		for _, l := range (<-dbg.eventCh).(*LocalsResponse).Locals {
+			if l.Name == name {
+				return l.Value
+			}
+		}
+		return ""
+	}
+
+	dbg.cmdCh <- &AddBreakpoint{File: "/main.sy", Line: 3, Condition: "sq > 20"}
+	dbg.cmdCh <- &SetMode{Flag: DebugContinue}
+	expectStop(t, dbg, StopBreakpoint, 3)
+	if i := localValue("i"); i != "5" {
+		t.Fatalf("condition stopped at wrong iteration. want i=5, got i=%s", i)
+	}
+
+	dbg.cmdCh <- &AddBreakpoint{File: "/main.sy", Line: 3, HitCondition: "% 2"}
+	dbg.cmdCh <- &SetMode{Flag: DebugContinue}
+	expectStop(t, dbg, StopBreakpoint, 3)
+	if i := localValue("i"); i != "7" {
+		t.Fatalf("hit condition stopped at wrong iteration. want i=7, got i=%s", i)
+	}
+
+	dbg.cmdCh <- &RemoveBreakpoint{File: "/main.sy", Line: 3}
+	dbg.cmdCh <- &AddBreakpoint{File: "/main.sy", Line: 2, LogMessage: "i={i} total={total} {{x}}"}
+	dbg.cmdCh <- &SetMode{Flag: DebugContinue}
+
+	for _, want := range []string{"i=8 total=140 {x}\n", "i=9 total=204 {x}\n"} {
+		out, ok := (<-dbg.eventCh).(*OutputEvent)
+		if !ok {
+			t.Fatalf("expected *OutputEvent, got %T", out)
+		}
+		if out.Text != want {
+			t.Fatalf("wrong log message. want=%q, got=%q", want, out.Text)
+		}
+	}
+
+	expectTerminated(t, dbg)
+}
+
+func TestBreakpointExpressionErrors(t *testing.T) {
+	source := `func square(int i) -> int {
+	const sq = i * i;
+	return sq;
+}
+square(3);`
+
+	dbg, _ := newDebugSession(t, source)
+
+	tests := []struct {
+		bp    AddBreakpoint
+		error string
+	}{
+		{AddBreakpoint{Line: 3, Condition: "sq >"}, "bad condition: "},
+		{AddBreakpoint{Line: 3, Condition: `sq > "a"`}, "bad condition: type mismatch: cannot compare types int to string"},
+		{AddBreakpoint{Line: 3, Condition: "total > 1"}, "bad condition: undefined identifier: total"},
+		{AddBreakpoint{Line: 5, Condition: "sq > 1"}, "bad condition: undefined identifier: sq"},
+		{AddBreakpoint{Line: 3, LogMessage: "sq={sq} x={x}"}, "bad log message: {x}: undefined identifier: x"},
+		{AddBreakpoint{Line: 3, LogMessage: "{i + true}"}, "bad log message: {i + true}: type mismatch"},
+	}
+
+	for _, tt := range tests {
+		tt.bp.File = "/main.sy"
+		_, err := dbg.setBreakpoint(&tt.bp)
+		if err == nil {
+			t.Errorf("%+v: expected an error", tt.bp)
+			continue
+		}
+		if !strings.HasPrefix(err.Error(), tt.error) {
+			t.Errorf("%+v: expected error %q, got %q", tt.bp, tt.error, err)
+		}
+	}
+
+	for _, bp := range []AddBreakpoint{
+		{File: "/main.sy", Line: 3, Condition: "sq > i", LogMessage: "{i}: {sq}"},
+		{File: "/main.sy", Line: 5, Condition: `len("a") == 1`},
+	} {
+		if _, err := dbg.setBreakpoint(&bp); err != nil {
+			t.Errorf("%+v: unexpected error %s", bp, err)
+		}
+	}
+}
+
+func TestBreakpointConditionsCompileOnce(t *testing.T) {
+	source := `func sum(int n) -> int {
+	mut total = 0;
+	for (mut i = 0; i < n; i = i + 1) {
+		total = total + i;
+	}
+	return total;
+}
+sum(50);`
+
+	dbg, machine := newDebugSession(t, source)
+	constants := len(machine.constants)
+	go func() {
+		err := machine.Run()
+		dbg.Terminate(err)
+	}()
+	expectStop(t, dbg, StopEntry, 8)
+
+	dbg.cmdCh <- &AddBreakpoint{File: "/main.sy", Line: 4, Condition: `i == 46 && "a" != "b"`}
+	dbg.cmdCh <- &AddBreakpoint{File: "/main.sy", Line: 6, LogMessage: "{total * 2}"}
+	dbg.cmdCh <- &SetMode{Flag: DebugContinue}
+	expectStop(t, dbg, StopBreakpoint, 4)
+
+	dbg.cmdCh <- &SetMode{Flag: DebugContinue}
+	out, ok := (<-dbg.eventCh).(*OutputEvent)
+	if !ok || out.Text != "2450\n" {
+		t.Fatalf("expected log message 2450, got %+v", out)
+	}
+	expectTerminated(t, dbg)
+
+	if len(machine.constants) != constants {
+		t.Fatalf("constant pool grew from %d to %d", constants, len(machine.constants))
+	}
+}
+
+func TestDebuggerEvaluate(t *testing.T) {
+	source := `define struct Point { x int, y int }
+func make_adder(int n) -> fn<(int) -> int> {
+	const base = [n, n];
+	return func(int x) -> int {
+		const p = Point { x: x, y: base[0] };
+		return p.x + p.y;
+	};
+}
+const add2 = make_adder(2);
+add2(5);
+add2(7);`
+
+	dbg := startDebugSession(t, source)
+	expectStop(t, dbg, StopEntry, 9)
+
+	dbg.cmdCh <- &AddWatch{Expr: "p.x * 10"}
+	dbg.cmdCh <- &AddWatch{Expr: "missing"}
+	dbg.cmdCh <- &AddBreakpoint{File: "/main.sy", Line: 6}
+	dbg.cmdCh <- &SetMode{Flag: DebugContinue}
+	stop := expectStop(t, dbg, StopBreakpoint, 6)
+	if len(stop.Watches) != 2 {
+		t.Fatalf("wrong number of watches. want=2, got=%d", len(stop.Watches))
+	}
+	if w := stop.Watches[0]; w.Value != "50" || w.Type != "int" || w.Error != "" {
+		t.Fatalf("wrong watch value: %+v", w)
+	}
+	if w := stop.Watches[1]; w.Error == "" {
+		t.Fatalf("expected error for undefined watch, got %+v", w)
+	}
+
+	tests := []struct {
+		expr          string
+		frame         int
+		expectedValue string
+		expectedType  string
+		expectError   bool
+	}{
+		{"p.x + base[1]", 0, "7", "int", false},
+		{"x > 3 && len(base) == 2", 0, "true", "bool", false},
+		{"p", 0, "Point {...}", "Point", false},
+		{"add2(1)", 1, "3", "int", false},
+		{"x + \"a\"", 0, "", "", true},
+		{"x", 1, "", "", true},
+		{"x +", 0, "", "", true},
+	}
+
+	for _, tt := range tests {
+		dbg.cmdCh <- &Evaluate{Expr: tt.expr, Frame: tt.frame}
+		resp := (<-dbg.eventCh).(*EvaluateResponse)
+		if tt.expectError {
+			if resp.Error == "" {
+				t.Errorf("%q: expected error, got %+v", tt.expr, resp)
+			}
+			continue
+		}
+		if resp.Error != "" {
+			t.Errorf("%q: unexpected error %s", tt.expr, resp.Error)
+			continue
+		}
+		if resp.Value != tt.expectedValue || !strings.HasPrefix(resp.Type, tt.expectedType) {
+			t.Errorf("%q: want %s (%s), got %s (%s)", tt.expr, tt.expectedValue, tt.expectedType, resp.Value, resp.Type)
+		}
+	}
+
+	dbg.cmdCh <- &RemoveWatch{Expr: "missing"}
+	dbg.cmdCh <- &SetMode{Flag: DebugContinue}
+	stop = expectStop(t, dbg, StopBreakpoint, 6)
+	if len(stop.Watches) != 1 || stop.Watches[0].Value != "70" {
+		t.Fatalf("wrong watches after second stop: %+v", stop.Watches)
+	}
+
+	dbg.cmdCh <- &SetMode{Flag: DebugContinue}
+	expectTerminated(t, dbg)
+}
+
+func TestDebuggerExceptionBreaks(t *testing.T) {
+	sliceError := `func take(int end) -> int {
+	const xs = [1, 2];
+	const s = xs[0:end];
+	return len(s);
+}
+take(1);
+take(5);`
+	panicking := `func check(int n) -> int {
+	if (n > 1) {
+		panic("too big");
+	}
+	return n;
+}
+check(1);
+check(2);`
+
+	tests := []struct {
+		source        string
+		entryLine     int
+		breaks        SetExceptionBreaks
+		expectedLine  int // 0 when the error should not stop
+		callerLine    int
+		expectedError string
+		inspect       string // evaluated in the faulting frame
+		expectedValue string
+	}{
+		{sliceError, 6, SetExceptionBreaks{Errors: true}, 3, 7, "slice bounds out of range: [0:5] with length 2", "end", "5"},
+		{sliceError, 6, SetExceptionBreaks{Panics: true}, 0, 0, "slice bounds out of range: [0:5] with length 2", "", ""},
+		{panicking, 7, SetExceptionBreaks{Panics: true}, 3, 8, "panic: too big", "n * 10", "20"},
+		{panicking, 7, SetExceptionBreaks{Errors: true}, 0, 0, "panic: too big", "", ""},
+	}
+
+	for _, tt := range tests {
+		dbg := startDebugSession(t, tt.source)
+		expectStop(t, dbg, StopEntry, tt.entryLine)
+		dbg.cmdCh <- &tt.breaks
+		dbg.cmdCh <- &SetMode{Flag: DebugContinue}
+
+		if tt.expectedLine != 0 {
+			stop := expectStop(t, dbg, StopException, tt.expectedLine)
+			if stop.Error != tt.expectedError {
+				t.Errorf("wrong stop error. want=%q, got=%q", tt.expectedError, stop.Error)
+			}
+
+			dbg.cmdCh <- &GetCallStack{}
+			frames := (<-dbg.eventCh).(*CallStackResponse).Frames
+			if len(frames) != 2 || frames[1].Line != tt.callerLine {
+				t.Errorf("expected the faulting call stack, got %+v", frames)
+			}
+
+			dbg.cmdCh <- &Evaluate{Expr: tt.inspect}
+			if resp := (<-dbg.eventCh).(*EvaluateResponse); resp.Value != tt.expectedValue {
+				t.Errorf("wrong value for %s. want=%s, got=%+v", tt.inspect, tt.expectedValue, resp)
+			}
+
+			dbg.cmdCh <- &SetMode{Flag: DebugContinue}
+		}
+
+		evt := expectTerminated(t, dbg)
+		if !strings.Contains(evt.Error, tt.expectedError) {
+			t.Errorf("wrong terminated error. want=%q, got=%q", tt.expectedError, evt.Error)
+		}
+	}
+}
+
+func TestParseHitCondition(t *testing.T) {
+	tests := []struct {
+		input string
+		op    string
+		count int
+		err   bool
+	}{
+		{"3", "==", 3, false},
+		{">= 10", ">=", 10, false},
+		{"%5", "%", 5, false},
+		{"% 0", "", 0, true},
+		{"often", "", 0, true},
+	}
+
+	for _, tt := range tests {
+		op, count, err := parseHitCondition(tt.input)
+		if (err != nil) != tt.err {
+			t.Fatalf("%q: unexpected error state: %v", tt.input, err)
+		}
+		if op != tt.op || count != tt.count {
+			t.Errorf("%q: want=%s %d, got=%s %d", tt.input, tt.op, tt.count, op, count)
+		}
+	}
+}
@@ -12,6 +12,7 @@ type Fiber struct {
 	frameIdx   int
 	state      FiberState
 	blockCause *Channel
+	ioCause    string // async builtin the fiber is waiting on
 }
 
 type FiberState int
@@ -23,6 +24,21 @@ const (
 	Done
 )
 
+func (s FiberState) String() string {
+	switch s {
+	case Ready:
+		return "ready"
+	case Running:
+		return "running"
+	case Blocked:
+		return "blocked"
+	case Done:
+		return "done"
+	}
+
+	return "unknown"
+}
+
 func NewFiber(id int) *Fiber {
 	return &Fiber{
 		id:         id,
@@ -9,6 +9,9 @@ type Frame struct {
 	cl          *object.Closure
 	ip          int
 	basePointer int
+	line        int               // last source line reached, only tracked under the debugger
+	defers      []*object.Closure // deferred calls, run last first when the frame returns
+	deferred    bool              // running a deferred call, whose result is dropped
 }
 
 func NewFrame(cl *object.Closure, basePointer int) *Frame {
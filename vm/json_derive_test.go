@@ -38,7 +38,7 @@ func runDeriveTest(t *testing.T, source string) *VM {
 	p := parser.NewWithGenericNames(l, gns)
 	program := p.ParseProgram()
 	if len(p.Errors()) != 0 {
-		t.Fatalf("parse errors: %s", strings.Join(p.Errors(), "\n"))
+		t.Fatalf("parse errors: %v", p.Errors())
 	}
 
 	codegen.ExpandDerives(program)
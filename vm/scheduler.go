@@ -50,6 +50,8 @@ func (s *Scheduler) next() *Fiber {
 // enqueue marks a fiber as ready and adds it back to the run queue.
 func (s *Scheduler) enqueue(f *Fiber) {
 	f.state = Ready
+	f.blockCause = nil
+	f.ioCause = ""
 	s.runQueue = append(s.runQueue, f)
 }
 
@@ -71,6 +73,7 @@ func pushToFiberStack(f *Fiber, value object.Object) {
 
 func (s *Scheduler) registerChannel(id, capacity int) {
 	s.channels[id] = &Channel{
+		id:        id,
 		buffer:    make([]object.Object, capacity),
 		capacity:  capacity,
 		sendQueue: make([]*SenderWait, 0),
@@ -121,6 +124,7 @@ func (s *Scheduler) send(id int, val object.Object) {
 	// We hold onto the value because OpSend already popped it off the
 	// sender's stack. If we didn't save it here, it would be lost.
 	s.current.state = Blocked
+	s.current.blockCause = ch
 	ch.sendQueue = append(ch.sendQueue, &SenderWait{
 		fiber: s.current,
 		value: val,
@@ -182,9 +186,23 @@ func (s *Scheduler) receive(chanID int) {
 	// When a sender eventually arrives, it will call pushToFiberStack
 	// on this fiber to deposit the value before waking it up.
 	s.current.state = Blocked
+	s.current.blockCause = ch
 	ch.recvQueue = append(ch.recvQueue, s.current)
 }
 
+// fiber looks up a fiber by id, including the main fiber
+func (s *Scheduler) fiber(id int) *Fiber {
+	if id == s.mainFiber.id {
+		return s.mainFiber
+	}
+	for _, f := range s.fibers {
+		if f.id == id {
+			return f
+		}
+	}
+	return nil
+}
+
 func (s *Scheduler) hasBlockedFibers() bool {
 	for _, f := range s.fibers {
 		if f.state == Blocked {
@@ -0,0 +1,100 @@
+package vm
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// StackFrame is one active call in a fiber, resolved to a source location
+// through the function's source map
+type StackFrame struct {
+	Function string
+	File     string
+	Line     int
+	Col      int
+}
+
+// RuntimeError is returned from Run when a fiber fails. Error() is the
+// message of the underlying failure; Frames is the Sydney call stack of the
+// failing fiber at the point of failure, innermost frame first.
+type RuntimeError struct {
+	Err    error
+	Fiber  int
+	Frames []StackFrame
+}
+
+func (e *RuntimeError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RuntimeError) Unwrap() error {
+	return e.Err
+}
+
+// PanicError is the failure raised by the `panic` builtin, as opposed to one
+// from an operation such as dividing by zero
+type PanicError struct {
+	Message string // starting with "panic: "
+}
+
+func (e *PanicError) Error() string {
+	return e.Message
+}
+
+// StackTrace renders the failing fiber and one line per frame
+func (e *RuntimeError) StackTrace() string {
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "fiber %d:\n", e.Fiber)
+	for _, f := range e.Frames {
+		out.WriteString("\tat ")
+		out.WriteString(f.String())
+		out.WriteString("\n")
+	}
+
+	return out.String()
+}
+
+func (f StackFrame) String() string {
+	if f.Line == 0 {
+		return fmt.Sprintf("%s (unknown location)", f.Function)
+	}
+	if f.File == "" {
+		return fmt.Sprintf("%s (%d:%d)", f.Function, f.Line, f.Col)
+	}
+
+	return fmt.Sprintf("%s (%s:%d:%d)", f.Function, f.File, f.Line, f.Col)
+}
+
+// callStack resolves every active frame of the fiber, innermost first
+func (f *Fiber) callStack() []StackFrame {
+	frames := make([]StackFrame, 0, f.frameIdx)
+	for i := f.frameIdx - 1; i >= 0; i-- {
+		frames = append(frames, f.frames[i].location())
+	}
+
+	return frames
+}
+
+func (f *Frame) location() StackFrame {
+	sf := StackFrame{Function: f.cl.Fn.Name}
+	if sf.Function == "" {
+		sf.Function = "<anonymous>"
+	}
+
+	// ip of a caller frame has already moved past the call's operands,
+	// so resolve to the closest mapped instruction at or before it
+	if sm := f.cl.Fn.SourceMap; sm != nil && f.ip >= 0 {
+		sf.Line, sf.Col, sf.File = sm.LocationForOffset(f.ip)
+	}
+
+	return sf
+}
+
+func (vm *VM) runtimeError(err error) *RuntimeError {
+	fiber := vm.scheduler.current
+	return &RuntimeError{
+		Err:    err,
+		Fiber:  fiber.id,
+		Frames: fiber.callStack(),
+	}
+}
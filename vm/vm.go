@@ -30,7 +30,7 @@ type VM struct {
 }
 
 func New(bytecode *compiler.Bytecode) *VM {
-	mainFn := &object.CompiledFunction{Instructions: bytecode.Instructions, SourceMap: bytecode.SourceMap, DebugSymbols: bytecode.DebugSymbols}
+	mainFn := &object.CompiledFunction{Name: "<main>", Instructions: bytecode.Instructions, SourceMap: bytecode.SourceMap, DebugSymbols: bytecode.DebugSymbols}
 	mainClosure := &object.Closure{Fn: mainFn}
 	mainFrame := NewFrame(mainClosure, 0)
 
@@ -55,6 +55,11 @@ func NewWithGlobalStore(bytecode *compiler.Bytecode, globals []object.Object) *V
 
 func (vm *VM) AttachDebugger(debugger *Debugger) {
 	vm.debugger = debugger
+	debugger.eval = vm.evaluate
+	debugger.compile = vm.compileBreakpoints()
+	debugger.run = func(expr *debugExpr, frame *Frame) (object.Object, error) {
+		return vm.runExpression(expr, vm.scheduler.current, frame)
+	}
 }
 
 func (vm *VM) StackTop() object.Object {
@@ -89,7 +94,20 @@ func (vm *VM) Run() error {
 
 		err := vm.runFiber()
 		if err != nil {
-			return err
+			rerr := vm.runtimeError(err)
+			if vm.debugger != nil && vm.frameIdx() > 0 && !vm.debugger.Detached() && vm.debugger.breaksOn(err) {
+				// the fiber has not unwound, so its frames and stack can
+				// still be inspected; resuming then ends the program
+				var line int
+				var file string
+				if sm := vm.currentFrame().cl.Fn.SourceMap; sm != nil {
+					line, _, file = sm.LocationForOffset(vm.currentFrame().ip)
+				}
+				vm.debugger.stopReason = StopException
+				vm.debugStop(file, line, err)
+			}
+			vm.unwind()
+			return rerr
 		}
 
 		// If the fiber is still Running, it was preempted — re-enqueue it.
@@ -101,6 +119,61 @@ func (vm *VM) Run() error {
 	return nil
 }
 
+// debugStop reports a stop to the debugger client and serves its queries
+// until it resumes the VM. stopErr is the runtime error for exception stops.
+func (vm *VM) debugStop(file string, line int, stopErr error) {
+	vm.debugger.lastLine = line
+	vm.debugger.lastFile = file
+	vm.debugger.started = true
+
+	evt := &StoppedEvent{
+		Reason:  vm.debugger.stopReason,
+		Line:    line,
+		File:    file,
+		Fiber:   vm.scheduler.current.id,
+		Watches: vm.debugger.evaluateWatches(),
+	}
+	if stopErr != nil {
+		evt.Error = stopErr.Error()
+		evt.Panic = isPanic(stopErr)
+	}
+	vm.debugger.eventCh <- evt
+
+	for {
+		cmd := <-vm.debugger.cmdCh
+		if isResumeCommand(cmd) {
+			vm.debugger.handleCommand(cmd)
+			if isMode(cmd, DebugStepOver) || isMode(cmd, DebugStepOut) {
+				vm.debugger.stepFrame = vm.frameIdx()
+			}
+			vm.debugger.handles = nil
+			vm.debugger.focus = nil
+			return
+		}
+
+		switch c := cmd.(type) {
+		case *GetLocals:
+			vm.debugger.handleGetLocals(c, vm.inspectedFiber(), vm.globals)
+		case *GetStack:
+			vm.debugger.handleGetStack(vm.inspectedFiber().stack)
+		case *GetCallStack:
+			vm.debugger.handleGetCallStack(vm.inspectedFiber())
+		case *GetFibers:
+			vm.debugger.handleGetFibers(vm.scheduler)
+		case *GetChannels:
+			vm.debugger.handleGetChannels(vm.scheduler)
+		case *SelectFiber:
+			vm.debugger.handleSelectFiber(c, vm.scheduler)
+		case *GetVariables:
+			vm.debugger.handleGetVariables(c)
+		case *Evaluate:
+			vm.debugger.handleEvaluate(c)
+		default:
+			vm.debugger.handleCommand(cmd)
+		}
+	}
+}
+
 const fiberQuantum = 1024 // max instructions per time slice
 
 func (vm *VM) runFiber() error {
@@ -118,38 +191,16 @@ func (vm *VM) runFiber() error {
 			}
 		}
 
+		if vm.debugger != nil && vm.debugger.Detached() {
+			return errDetached
+		}
+
 		vm.currentFrame().ip++
 
 		sm := vm.currentFrame().cl.Fn.SourceMap
-		if vm.debugger != nil && sm != nil && vm.debugger.shouldStop(vm.currentFrame().ip, vm.frameIdx(), sm) {
+		if vm.debugger != nil && sm != nil && vm.debugger.shouldStop(vm.currentFrame(), vm.frameIdx()) {
 			line, _, file := sm.LineForOffset(vm.currentFrame().ip)
-			vm.debugger.lastLine = line
-			vm.debugger.lastFile = file
-			vm.debugger.eventCh <- &StoppedEvent{
-				Reason: "breakpoint hit",
-				Line:   line,
-				File:   file,
-				Fiber:  vm.scheduler.current.id,
-			}
-			for {
-				cmd := <-vm.debugger.cmdCh
-				if isResumeCommand(cmd) {
-					vm.debugger.handleCommand(cmd)
-					if isMode(cmd, DebugStepOver) || isMode(cmd, DebugStepOut) {
-						vm.debugger.stepFrame = vm.frameIdx()
-					}
-					break
-				}
-
-				if _, ok := cmd.(*GetLocals); ok && vm.currentFrame().cl.Fn.DebugSymbols != nil {
-					vm.debugger.handleGetLocals(vm.currentFrame().cl.Fn.DebugSymbols, vm.scheduler.current.stack, vm.currentFrame().basePointer)
-				}
-
-				if _, ok := cmd.(*GetStack); ok {
-					vm.debugger.handleGetStack(vm.scheduler.current.stack)
-				}
-				vm.debugger.handleCommand(cmd)
-			}
+			vm.debugStop(file, line, nil)
 		}
 
 		ip = vm.currentFrame().ip
@@ -289,15 +340,26 @@ func (vm *VM) runFiber() error {
 				return err
 			}
 		case code.OpReturnValue:
+			called, err := vm.callDeferred()
+			if err != nil {
+				return err
+			}
+			if called {
+				continue
+			}
+
 			returnValue := vm.pop()
 
 			// pop frame
 			frame := vm.popFrame()
 			// restore stack pointer
 			vm.setSp(frame.basePointer - 1)
+			if frame.deferred {
+				continue
+			}
 
 			// push return value onto stack
-			err := vm.push(returnValue)
+			err = vm.push(returnValue)
 			if err != nil {
 				return err
 			}
@@ -305,12 +367,23 @@ func (vm *VM) runFiber() error {
 				return nil
 			}
 		case code.OpReturn:
+			called, err := vm.callDeferred()
+			if err != nil {
+				return err
+			}
+			if called {
+				continue
+			}
+
 			// pop frame
 			frame := vm.popFrame()
 			// restore stack pointer, also has effect of popping last value off stack
 			vm.setSp(frame.basePointer - 1)
+			if frame.deferred {
+				continue
+			}
 
-			err := vm.push(Null)
+			err = vm.push(Null)
 			if err != nil {
 				return err
 			}
@@ -450,8 +523,14 @@ func (vm *VM) runFiber() error {
 			}
 
 			// look up closure using itab
+			if int(method) >= len(i.Itab.MethodsIndices) {
+				return fmt.Errorf("method %d not found in itab %s -> %s", method, i.Itab.InterfaceName, i.Itab.ConcreteName)
+			}
 			globalIdx := i.Itab.MethodsIndices[method]
-			closure := vm.globals[globalIdx].(*object.Closure)
+			closure, ok := vm.globals[globalIdx].(*object.Closure)
+			if !ok {
+				return fmt.Errorf("method %d of %s -> %s is not a function", method, i.Itab.InterfaceName, i.Itab.ConcreteName)
+			}
 
 			args := make([]object.Object, numArgs)
 			for i := numArgs - 1; i >= 0; i-- {
@@ -539,6 +618,9 @@ func (vm *VM) runFiber() error {
 				if end == -1 {
 					end = int64(len(asArr.Elements))
 				}
+				if start < 0 || start > end || end > int64(len(asArr.Elements)) {
+					return fmt.Errorf("slice bounds out of range: [%d:%d] with length %d", start, end, len(asArr.Elements))
+				}
 				newOjb := &object.Array{
 					Elements: asArr.Elements[start:end],
 				}
@@ -551,6 +633,9 @@ func (vm *VM) runFiber() error {
 				if end == -1 {
 					end = int64(len(asStr.Value))
 				}
+				if start < 0 || start > end || end > int64(len(asStr.Value)) {
+					return fmt.Errorf("slice bounds out of range: [%d:%d] with length %d", start, end, len(asStr.Value))
+				}
 				newOjb := &object.String{
 					Value: asStr.Value[start:end],
 				}
@@ -560,6 +645,9 @@ func (vm *VM) runFiber() error {
 				}
 			}
 		case code.OpSpawn:
+			if vm.scheduler.current.id == evalFiberId {
+				return errEvalBlocking
+			}
 			numArgs := int(code.ReadUint8(ins[ip+1:]))
 			vm.currentFrame().ip += 1
 
@@ -568,7 +656,7 @@ func (vm *VM) runFiber() error {
 				args[i] = vm.pop()
 			}
 			cl := vm.pop().(*object.Closure)
-			fiber := NewFiber(len(vm.scheduler.fibers) - 1)
+			fiber := NewFiber(len(vm.scheduler.fibers) + 1) // main fiber is 0
 			fiber.stack[0] = cl
 			for i, arg := range args {
 				fiber.stack[i+1] = arg
@@ -589,11 +677,17 @@ func (vm *VM) runFiber() error {
 				return err
 			}
 		case code.OpSend:
+			if vm.scheduler.current.id == evalFiberId {
+				return errEvalBlocking
+			}
 			val := vm.pop()
 			ch := vm.pop().(*object.Channel)
 			vm.scheduler.send(ch.Id, val)
 			return nil // yield
 		case code.OpReceive:
+			if vm.scheduler.current.id == evalFiberId {
+				return errEvalBlocking
+			}
 			ch := vm.pop().(*object.Channel)
 			vm.scheduler.receive(ch.Id)
 			return nil
@@ -643,6 +737,97 @@ func (vm *VM) runFiber() error {
 					return err
 				}
 			}
+		case code.OpEnum:
+			objIdx := code.ReadUint16(ins[ip+1:])
+			typeObj := vm.constants[objIdx].(*object.TypeObject)
+			tag := code.ReadUint8(ins[ip+3:])
+			numValues := code.ReadUint8(ins[ip+4:])
+			vm.currentFrame().ip += 4
+			values := make([]object.Object, numValues)
+			for i := int(numValues) - 1; i >= 0; i-- {
+				values[i] = vm.pop()
+			}
+
+			err := vm.push(&object.Enum{T: typeObj, Tag: int(tag), Values: values})
+			if err != nil {
+				return err
+			}
+		case code.OpEnumTag:
+			tag := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+
+			obj := vm.pop()
+			e, ok := obj.(*object.Enum)
+			if !ok {
+				return fmt.Errorf("expected enum, got %T", obj)
+			}
+
+			err := vm.push(nativeBoolToBooleanObject(e.Tag == int(tag)))
+			if err != nil {
+				return err
+			}
+		case code.OpEnumField:
+			idx := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+
+			obj := vm.pop()
+			e, ok := obj.(*object.Enum)
+			if !ok {
+				return fmt.Errorf("expected enum, got %T", obj)
+			}
+
+			err := vm.push(e.Values[idx])
+			if err != nil {
+				return err
+			}
+		case code.OpTuple:
+			numValues := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+			values := make([]object.Object, numValues)
+			for i := int(numValues) - 1; i >= 0; i-- {
+				values[i] = vm.pop()
+			}
+
+			err := vm.push(&object.Tuple{Values: values})
+			if err != nil {
+				return err
+			}
+		case code.OpTupleField:
+			idx := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+
+			obj := vm.pop()
+			t, ok := obj.(*object.Tuple)
+			if !ok {
+				return fmt.Errorf("expected tuple, got %T", obj)
+			}
+
+			err := vm.push(t.Values[idx])
+			if err != nil {
+				return err
+			}
+		case code.OpDefer:
+			frame := vm.currentFrame()
+			frame.defers = append(frame.defers, vm.pop().(*object.Closure))
+		case code.OpUnpack:
+			numValues := int(code.ReadUint8(ins[ip+1:]))
+			vm.currentFrame().ip += 1
+
+			obj := vm.pop()
+			t, ok := obj.(*object.Tuple)
+			if !ok {
+				return fmt.Errorf("expected tuple, got %T", obj)
+			}
+			if len(t.Values) != numValues {
+				return fmt.Errorf("cannot unpack tuple of %d values into %d", len(t.Values), numValues)
+			}
+
+			for i := numValues - 1; i >= 0; i-- {
+				err := vm.push(t.Values[i])
+				if err != nil {
+					return err
+				}
+			}
 		}
 	}
 	vm.scheduler.current.state = Done
@@ -761,6 +946,9 @@ func (vm *VM) executeBinaryIntegerOperation(op code.Opcode, left, right object.O
 		}
 		result = leftVal / rightVal
 	case code.OpModulo:
+		if rightVal == 0 {
+			return errors.New("division by zero")
+		}
 		result = leftVal % rightVal
 	default:
 		return fmt.Errorf("unknown integer operator: %d", op)
@@ -1079,11 +1267,15 @@ func (vm *VM) callBuiltIn(builtin *object.BuiltIn, numArgs int) error {
 	args := vm.stack()[vm.sp()-numArgs : vm.sp()] // pull slice of args off stack
 
 	if builtin.AsyncFn != nil {
+		if vm.scheduler.current.id == evalFiberId {
+			return errEvalBlocking
+		}
 		fiber := vm.scheduler.current
 		argsCopy := make([]object.Object, numArgs)
 		copy(argsCopy, args)
 		vm.setSp(vm.sp() - numArgs - 1)
 		fiber.state = Blocked
+		fiber.ioCause = object.GetBuiltInName(builtin)
 		vm.scheduler.ioBlockedCount++
 
 		done := func(result object.Object) {
@@ -1098,6 +1290,9 @@ func (vm *VM) callBuiltIn(builtin *object.BuiltIn, numArgs int) error {
 	vm.setSp(vm.sp() - numArgs - 1) // pop args and function
 
 	if err, ok := result.(*object.Error); ok {
+		if err.Panic {
+			return &PanicError{Message: err.Message}
+		}
 		return fmt.Errorf("%s", err.Message)
 	}
 
@@ -1110,6 +1305,61 @@ func (vm *VM) callBuiltIn(builtin *object.BuiltIn, numArgs int) error {
 	return nil
 }
 
+// callDeferred starts the current frame's most recently deferred call, if it
+// has one left, and steps ip back so the return runs again after the call
+func (vm *VM) callDeferred() (bool, error) {
+	frame := vm.currentFrame()
+	if len(frame.defers) == 0 {
+		return false, nil
+	}
+	cl := frame.defers[len(frame.defers)-1]
+	frame.defers = frame.defers[:len(frame.defers)-1]
+	frame.ip--
+
+	err := vm.push(cl)
+	if err != nil {
+		return false, err
+	}
+	err = vm.callClosure(cl, 0)
+	if err != nil {
+		return false, err
+	}
+	vm.currentFrame().deferred = true
+
+	return true, nil
+}
+
+// unwind runs the deferred calls of the failing fiber's frames, innermost
+// first, each on a scratch fiber. A deferred call that fails or blocks is
+// abandoned; the program still reports the original failure
+func (vm *VM) unwind() {
+	fiber, debugger := vm.scheduler.current, vm.debugger
+	vm.debugger = nil
+	defer func() {
+		vm.scheduler.current, vm.debugger = fiber, debugger
+	}()
+
+	for i := fiber.frameIdx - 1; i >= 0; i-- {
+		frame := fiber.frames[i]
+		for len(frame.defers) > 0 {
+			cl := frame.defers[len(frame.defers)-1]
+			frame.defers = frame.defers[:len(frame.defers)-1]
+
+			scratch := NewFiber(fiber.id)
+			scratch.stack[0] = cl
+			scratch.PushFrame(NewFrame(cl, 1), cl)
+			scratch.state = Running
+			vm.scheduler.current = scratch
+			for scratch.frameIdx > 0 && scratch.state == Running {
+				if err := vm.runFiber(); err != nil {
+					vm.unwind()
+					break
+				}
+			}
+		}
+	}
+}
+
 func (vm *VM) pushClosure(constIdx, numFree int) error {
 	constant := vm.constants[constIdx]
 	fn, ok := constant.(*object.CompiledFunction)
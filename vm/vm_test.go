@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"sydney/ast"
 	"sydney/compiler"
+	"sydney/errors"
 	"sydney/lexer"
 	"sydney/object"
 	"sydney/parser"
@@ -756,6 +757,342 @@ func TestOptionMatch(t *testing.T) {
 	runVmTests(t, tests)
 }
 
+func TestEnumMatch(t *testing.T) {
+	tests := []vmTestCase{
+		{ // payload bindings
+			source: `define enum Shape { Circle(int), Rect(int, int), Empty }
+			const s = Shape.Rect(2, 3);
+			match s {
+				Circle(r) -> { r * r; },
+				Rect(w, h) -> { w * h; },
+				Empty -> { 0; },
+			};`,
+			expected: 6,
+		},
+		{ // last arm of an exhaustive match
+			source: `define enum Shape { Circle(int), Rect(int, int), Empty }
+			func area(Shape s) -> int {
+				return match s {
+					Circle(r) -> { r * r; },
+					Rect(w, h) -> { w * h; },
+					Empty -> { 0; },
+				};
+			}
+			area(Shape.Circle(3)) + area(Shape.Empty);`,
+			expected: 9,
+		},
+		{ // default arm
+			source: `define enum Shape { Circle(int), Rect(int, int), Empty }
+			const s = Shape.Empty;
+			match s {
+				Circle(_) -> { 1; },
+				_ -> { 2; },
+			};`,
+			expected: 2,
+		},
+		{ // recursive generic enum
+			source: `define enum Tree<T> { Leaf(T), Node(Tree<T>, Tree<T>) }
+			func sum(Tree<int> t) -> int {
+				return match t {
+					Leaf(v) -> { v; },
+					Node(l, r) -> { sum(l) + sum(r); },
+				};
+			}
+			sum(Tree.Node(Tree.Leaf(1), Tree.Node(Tree.Leaf(2), Tree.Leaf(3))));`,
+			expected: 6,
+		},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestTuples(t *testing.T) {
+	tests := []vmTestCase{
+		{ // multiple returns
+			source: `func divmod(int a, int b) -> (int, int) {
+				return (a / b, a % b);
+			}
+			const (q, r) = divmod(17, 5);
+			q * 10 + r;`,
+			expected: 32,
+		},
+		{ // indexing
+			source:   `const t = (1, "two", 3); t[1];`,
+			expected: "two",
+		},
+		{ // skipped values and mutable bindings
+			source:   `mut (x, _, y) = (1, 2, 3); x = x + y; x;`,
+			expected: 4,
+		},
+		{ // destructuring inside a function
+			source: `func sum((int, int) p) -> int {
+				const (a, b) = p;
+				return a + b;
+			}
+			sum((4, 5));`,
+			expected: 9,
+		},
+		{ // for-in destructuring with an index
+			source: `mut total = 0;
+			for (i, (n, m) in [(1, 2), (3, 4)]) {
+				total = total + i * 100 + n * m;
+			}
+			total;`,
+			expected: 114,
+		},
+		{ // zero value
+			source:   `mut (int, string) z; z[0];`,
+			expected: 0,
+		},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestTryExpr(t *testing.T) {
+	half := `func half(int n) -> result<int> {
+		if (n % 2 != 0) { return err("odd"); }
+		return ok(n / 2);
+	}
+	func quarter(int n) -> result<int> {
+		return ok(half(half(n)?)?);
+	}
+	`
+	find := `func find(array<int> xs, int x) -> option<int> {
+		for (i, v in xs) {
+			if (v == x) { return some(i); }
+		}
+		return none();
+	}
+	func both(array<int> xs, int x, int y) -> option<int> {
+		return some(find(xs, x)? * 10 + find(xs, y)?);
+	}
+	`
+	tests := []vmTestCase{
+		{ // ok unwraps
+			source:   half + `match quarter(12) { ok(v) -> { v; }, err(e) -> { -1; }, };`,
+			expected: 3,
+		},
+		{ // err returns early
+			source:   half + `match quarter(6) { ok(v) -> { v; }, err(e) -> { e; }, };`,
+			expected: "odd",
+		},
+		{ // some unwraps
+			source:   find + `match both([4, 5, 6], 6, 4) { some(v) -> { v; }, none -> { -1; }, };`,
+			expected: 20,
+		},
+		{ // none returns early
+			source:   find + `match both([4, 5, 6], 7, 4) { some(v) -> { v; }, none -> { -1; }, };`,
+			expected: -1,
+		},
+		{ // the err of another result type
+			source: half + `func label(int n) -> result<string> {
+				const q = quarter(n)?;
+				return ok("quarter");
+			}
+			match label(10) { ok(v) -> { v; }, err(e) -> { e; }, };`,
+			expected: "odd",
+		},
+		{ // inside a closure
+			source: find + `const f = func(int x) -> option<int> {
+				return some(find([1, 2, 3], x)? + 100);
+			};
+			match f(3) { some(v) -> { v; }, none -> { -1; }, };`,
+			expected: 102,
+		},
+		{ // boxed into an interface
+			source: `define interface Shape { area() -> int }
+			define struct Sq { side int }
+			func area(Sq s) -> int { return s.side * s.side; }
+			func measure(Shape s) -> int { return s.area(); }
+			func mk(int n) -> option<Sq> { return some(Sq { side: n }); }
+			func total(int n) -> option<int> {
+				return some(measure(mk(n)?) + mk(n)?.area());
+			}
+			match total(3) { some(v) -> { v; }, none -> { -1; }, };`,
+			expected: 18,
+		},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestTypedErrors(t *testing.T) {
+	dial := `define enum NetError { Timeout(int), Refused }
+	func dial(int n) -> result<int, NetError> {
+		if (n == 0) { return err(NetError.Refused); }
+		if (n > 10) { return err(NetError.Timeout(n)); }
+		return ok(n);
+	}
+	func reason(result<int, NetError> r) -> int {
+		return match r {
+			ok(v) -> { v; },
+			err(e) -> {
+				match e {
+					Timeout(s) -> { s * 100; },
+					Refused -> { -1; },
+				};
+			},
+		};
+	}
+	`
+	tests := []vmTestCase{
+		{ // ok
+			source:   dial + `reason(dial(5));`,
+			expected: 5,
+		},
+		{ // enum errors
+			source:   dial + `reason(dial(0));`,
+			expected: -1,
+		},
+		{ // enum errors with a payload
+			source:   dial + `reason(dial(12));`,
+			expected: 1200,
+		},
+		{ // ? keeps the error
+			source: dial + `func twice(int n) -> result<int, NetError> {
+				return ok(dial(n)? * 2);
+			}
+			reason(twice(12));`,
+			expected: 1200,
+		},
+		{ // struct errors
+			source: `define struct ParseError { line int, msg string }
+			func parse(int n) -> result<int, ParseError> {
+				return err(ParseError { line: n, msg: "bad" });
+			}
+			match parse(4) { ok(v) -> { "ok"; }, err(e) -> { e.msg; }, };`,
+			expected: "bad",
+		},
+		{ // interface errors
+			source: `define struct ParseError { line int }
+			define interface Describe { describe() -> int }
+			func describe(ParseError p) -> int { return p.line; }
+			func parse(int n) -> result<int, Describe> {
+				return err(ParseError { line: n });
+			}
+			match parse(7) { ok(v) -> { v; }, err(d) -> { d.describe(); }, };`,
+			expected: 7,
+		},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestDeferStmt(t *testing.T) {
+	trace := `mut string trace = "";
+	func note(string s) { trace = trace + s; }
+	`
+	tests := []vmTestCase{
+		{ // last deferred runs first, on every return
+			source: trace + `func work(int n) -> int {
+				defer note("a");
+				defer note("b");
+				if (n == 0) { return 0; }
+				note("c");
+				return n;
+			}
+			work(0);
+			work(1);
+			trace;`,
+			expected: "bacba",
+		},
+		{ // the return value is kept
+			source: trace + `func work(int n) -> int {
+				defer note("a");
+				return n * 2;
+			}
+			work(21);`,
+			expected: 42,
+		},
+		{ // functions without a return value
+			source: trace + `func work() {
+				defer note("a");
+				note("b");
+			}
+			work();
+			trace;`,
+			expected: "ba",
+		},
+		{ // once per iteration, with the values at the defer
+			source: trace + `func work() {
+				for (x in ["1", "2", "3"]) {
+					defer note(x);
+				}
+				note("-");
+			}
+			work();
+			trace;`,
+			expected: "-321",
+		},
+		{ // inside a closure
+			source: trace + `const work = func(int n) -> int {
+				defer note("a");
+				return n;
+			};
+			work(1) + work(2);
+			trace;`,
+			expected: "aa",
+		},
+		{ // interface methods
+			source: trace + `define interface Closer { close() -> int }
+			define struct Sock { name string }
+			func close(Sock s) -> int { note(s.name); return 0; }
+			func work(Closer c) -> string {
+				defer c.close();
+				note("use");
+				return trace;
+			}
+			work(Sock { name: "sock" }) + trace;`,
+			expected: "useusesock",
+		},
+		{ // arguments as they were at the defer, globals included
+			source: trace + `mut string state = "a";
+			func work() {
+				mut local = "x";
+				defer note(local + state);
+				local = "y";
+				state = "b";
+			}
+			work();
+			trace;`,
+			expected: "xa",
+		},
+		{ // arguments and receivers are evaluated at the defer
+			source: trace + `define interface Closer { close() -> int }
+			define struct Sock { name string }
+			func close(Sock s) -> int { note(s.name); return 0; }
+			func open(string name) -> Closer { note("open "); return Sock { name: name }; }
+			func tick(string s) -> string { note(s); return s; }
+			func work() {
+				defer note(tick("a") + tick("b"));
+				defer open("sock").close();
+				note("-");
+			}
+			work();
+			trace;`,
+			expected: "abopen -sockab",
+		},
+		{ // inside an expression
+			source: trace + `func work(bool c) -> int {
+				const x = if (c) {
+					defer note("a");
+					1
+				} else {
+					2
+				};
+				note("b");
+				return x;
+			}
+			work(true) + work(false);
+			trace;`,
+			expected: "bab",
+		},
+	}
+
+	runVmTests(t, tests)
+}
+
 func TestThreePartForLoops(t *testing.T) {
 	tests := []vmTestCase{
 		{
@@ -969,9 +1306,9 @@ func runVmTests(t *testing.T, tests []vmTestCase) {
 		program := parse(tt.source)
 
 		c := typechecker.New(nil)
-		errors := c.Check(program, nil)
-		if len(errors) != 0 {
-			t.Fatal(errors)
+		typeErrs := c.Check(program, nil)
+		if errors.HasErrors(typeErrs) {
+			t.Fatal(typeErrs)
 		}
 		ast.FilterGenericTemplates(program)
 
@@ -1505,3 +1842,102 @@ func TestAnyArrayTypeMatch(t *testing.T) {
 
 	runVmTests(t, tests)
 }
+
+func TestDeferStmtRunsOnRuntimeError(t *testing.T) {
+	source := `mut string trace = "";
+func note(string s) { trace = trace + s; }
+func divide(int a, int b) -> int {
+	defer note("divide");
+	return a / b;
+}
+func outer(int x) -> int {
+	defer note(" outer");
+	return divide(10, x);
+}
+outer(0);`
+
+	program := parse(source)
+	c := typechecker.New(nil)
+	errs := c.Check(program, nil)
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+
+	comp := compiler.New()
+	err := comp.Compile(program)
+	if err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	globals := make([]object.Object, GlobalsSize)
+	vm := NewWithGlobalStore(comp.Bytecode(), globals)
+	err = vm.Run()
+	if err == nil || err.Error() != "division by zero" {
+		t.Fatalf("expected division by zero, got %v", err)
+	}
+
+	// trace is the only string global
+	var trace *object.String
+	for _, g := range globals {
+		if str, ok := g.(*object.String); ok {
+			trace = str
+			break
+		}
+	}
+	if trace == nil {
+		t.Fatalf("trace global not found")
+	}
+	if trace.Value != "divide outer" {
+		t.Errorf("deferred calls did not run while unwinding. want=%q, got=%q", "divide outer", trace.Value)
+	}
+}
+
+func TestRuntimeErrorStackTrace(t *testing.T) {
+	source := `func divide(int a, int b) -> int {
+	return a / b;
+}
+func outer(int x) -> int {
+	return divide(10, x);
+}
+outer(0);`
+
+	program := parse(source)
+	c := typechecker.New(nil)
+	errs := c.Check(program, nil)
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+
+	comp := compiler.New()
+	comp.SetFileName("main.sy")
+	err := comp.Compile(program)
+	if err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	vm := New(comp.Bytecode())
+	err = vm.Run()
+	rerr, ok := err.(*RuntimeError)
+	if !ok {
+		t.Fatalf("expected *RuntimeError, got %T (%v)", err, err)
+	}
+
+	if rerr.Error() != "division by zero" {
+		t.Fatalf("wrong error message. want=%q, got=%q", "division by zero", rerr.Error())
+	}
+
+	expected := []StackFrame{
+		{Function: "divide", File: "main.sy", Line: 2},
+		{Function: "outer", File: "main.sy", Line: 5},
+		{Function: "<main>", File: "main.sy", Line: 7},
+	}
+	if len(rerr.Frames) != len(expected) {
+		t.Fatalf("wrong number of frames. want=%d, got=%d:\n%s", len(expected), len(rerr.Frames), rerr.StackTrace())
+	}
+	for i, want := range expected {
+		got := rerr.Frames[i]
+		if got.Function != want.Function || got.File != want.File || got.Line != want.Line {
+			t.Errorf("frame %d wrong. want=%s:%s:%d, got=%s:%s:%d", i, want.Function, want.File, want.Line, got.Function, got.File, got.Line)
+		}
+	}
+}